@@ -22,6 +22,7 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -40,8 +41,10 @@ import (
 	"github.com/Azure/azure-pipeline-go/pipeline"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
 	"github.com/spf13/cobra"
 
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
 	"github.com/Azure/azure-storage-azcopy/v10/common"
 	"github.com/Azure/azure-storage-azcopy/v10/ste"
 )
@@ -180,6 +183,16 @@ type rawCopyCmdArgs struct {
 	rehydratePriority string
 	// The priority setting can be changed from Standard to High by calling Set Blob Tier with this header set to High and setting x-ms-access-tier to the same value as previously set. The priority setting cannot be lowered from High to Standard.
 	trailingDot string
+
+	// Optional. Uploads the whole source into a single archive blob. Valid values are Tar/TarGz.
+	archive string
+
+	// Optional. Compresses each file as it is uploaded. Valid values are gzip/zstd.
+	compress                string
+	addCompressionExtension bool
+
+	// Optional. The priority of the job against the other jobs that run in the same process. Valid values are Low/Normal/High.
+	priority string
 }
 
 func (raw *rawCopyCmdArgs) parsePatterns(pattern string) (cookedPatterns []string) {
@@ -285,7 +298,8 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 	}
 
 	// Check if source has a trailing wildcard on a URL
-	if fromTo.From().IsRemote() {
+	// A * is an ordinary character in a generic URL, so there's nothing to strip there
+	if fromTo.From().IsRemote() && fromTo.From() != common.ELocation.HTTP() {
 		tempSrc, cooked.StripTopDir, err = raw.stripTrailingWildcardOnRemoteSource(fromTo.From())
 
 		if err != nil {
@@ -378,6 +392,13 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		return cooked, err
 	}
 
+	if raw.priority == "" {
+		raw.priority = common.EJobPriority.Normal().String()
+	}
+	if err = cooked.priority.Parse(raw.priority); err != nil {
+		return cooked, fmt.Errorf("invalid --priority value %q. Valid values are Low, Normal and High", raw.priority)
+	}
+
 	// Everything uses the new implementation of list-of-files now.
 	// This handles both list-of-files and include-path as a list enumerator.
 	// This saves us time because we know *exactly* what we're looking for right off the bat.
@@ -651,7 +672,7 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 
 	cooked.preservePOSIXProperties = raw.preservePOSIXProperties
 	if cooked.preservePOSIXProperties && !areBothLocationsPOSIXAware(cooked.FromTo) {
-		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (Linux->Blob, Blob->Linux, Blob->Blob)")
+		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (Linux->Blob, Blob->Linux, Blob->Blob, Linux->Linux)")
 	}
 
 	if err = validatePreserveSMBPropertyOption(cooked.preserveSMBInfo, cooked.FromTo, &cooked.ForceWrite, "preserve-smb-info"); err != nil {
@@ -770,8 +791,13 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		}
 	case common.EFromTo.BlobLocal(),
 		common.EFromTo.FileLocal(),
-		common.EFromTo.BlobFSLocal():
-		if cooked.SymlinkHandling.Follow() {
+		common.EFromTo.BlobFSLocal(),
+		common.EFromTo.S3Local(),
+		common.EFromTo.GCPLocal(),
+		common.EFromTo.HTTPLocal(),
+		common.EFromTo.LocalLocal():
+		// a local source can still resolve symlinks, only remote sources can't
+		if cooked.SymlinkHandling.Follow() && cooked.FromTo.From() != common.ELocation.Local() {
 			return cooked, fmt.Errorf("follow-symlinks flag is not supported while downloading")
 		}
 		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
@@ -805,7 +831,9 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		common.EFromTo.BlobBlob(),
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile(),
-		common.EFromTo.GCPBlob():
+		common.EFromTo.GCPBlob(),
+		common.EFromTo.HTTPBlob(),
+		common.EFromTo.BlobS3():
 		if cooked.preserveLastModifiedTime {
 			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while copying from service to service")
 		}
@@ -832,10 +860,57 @@ func (raw rawCopyCmdArgs) cook() (CookedCopyCmdArgs, error) {
 		if cooked.FromTo.To() != common.ELocation.File() && raw.trailingDot != "" {
 			return cooked, fmt.Errorf("trailing-dot is only support for operations on file share accounts")
 		}
+	case common.EFromTo.PipeFile(),
+		common.EFromTo.PipeBlobFS():
+		if cooked.blobType != common.EBlobType.Detect() {
+			return cooked, fmt.Errorf("blob-type is not supported for the scenario (%s)", cooked.FromTo.String())
+		}
+		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+			cooked.pageBlobTier != common.EPageBlobTier.None() {
+			return cooked, fmt.Errorf("blob-tier is not supported for the scenario (%s)", cooked.FromTo.String())
+		}
+		// cooked.trailingDot is enabled by default, so checking raw.trailingDot
+		if cooked.FromTo.To() != common.ELocation.File() && raw.trailingDot != "" {
+			return cooked, fmt.Errorf("trailing-dot is only support for operations on file share accounts")
+		}
+	case common.EFromTo.BlobFSPipe():
+		// cooked.trailingDot is enabled by default, so checking raw.trailingDot
+		if raw.trailingDot != "" {
+			return cooked, fmt.Errorf("trailing-dot is only support for operations on file share accounts")
+		}
 	}
 	if err = validatePutMd5(cooked.putMd5, cooked.FromTo); err != nil {
 		return cooked, err
 	}
+
+	if err = cooked.archiveFormat.Parse(raw.archive); err != nil {
+		return cooked, fmt.Errorf("invalid --archive value %q. Valid values are Tar and TarGz", raw.archive)
+	}
+	if err = validateArchiveFormat(cooked.archiveFormat, cooked.FromTo, cooked.blobType, cooked.putMd5, cooked.preservePOSIXProperties); err != nil {
+		return cooked, err
+	}
+	if cooked.archiveFormat != common.EArchiveFormat.None() && cooked.contentType == "" {
+		// the content type would otherwise be guessed from the name of the source directory
+		cooked.contentType = cooked.archiveFormat.ContentType()
+		cooked.noGuessMimeType = true
+	}
+
+	if raw.compress != "" {
+		if err = cooked.compressionType.Parse(raw.compress); err != nil {
+			return cooked, fmt.Errorf("invalid --compress value %q. Valid values are gzip and zstd", raw.compress)
+		}
+	}
+	if err = validateCompressionType(cooked.compressionType, cooked.FromTo, cooked.blobType, cooked.contentEncoding, cooked.putMd5, cooked.archiveFormat); err != nil {
+		return cooked, err
+	}
+	if cooked.compressionType != common.ECompressionType.None() {
+		cooked.contentEncoding = cooked.compressionType.ContentEncoding()
+		// only the block blob uploader compresses, so don't let a .vhd file be detected as a page blob
+		cooked.blobType = common.EBlobType.BlockBlob()
+	} else if raw.addCompressionExtension {
+		return cooked, errors.New("add-compression-extension can only be used with compress")
+	}
+	cooked.addCompressionExtension = raw.addCompressionExtension
 	if err = validateMd5Option(cooked.md5ValidationOption, cooked.FromTo); err != nil {
 		return cooked, err
 	}
@@ -951,7 +1026,7 @@ func areBothLocationsSMBAware(fromTo common.FromTo) bool {
 func areBothLocationsPOSIXAware(fromTo common.FromTo) bool {
 	// POSIX properties are stored in blob metadata-- They don't need a special persistence strategy for S2S methods.
 	switch fromTo {
-	case common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS():
+	case common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.LocalLocal():
 		return runtime.GOOS == "linux"
 	case common.EFromTo.BlobBlob(), common.EFromTo.BlobFSBlobFS(), common.EFromTo.BlobFSBlob(), common.EFromTo.BlobBlobFS():
 		return true
@@ -991,12 +1066,12 @@ func validatePreserveOwner(preserve bool, fromTo common.FromTo) error {
 func validateSymlinkHandlingMode(symlinkHandling common.SymlinkHandlingType, fromTo common.FromTo) error {
 	if symlinkHandling.Preserve() {
 		switch fromTo {
-		case common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal(), common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS():
+		case common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal(), common.EFromTo.BlobFSLocal(), common.EFromTo.LocalBlobFS(), common.EFromTo.LocalLocal():
 			return nil // Fine on all OSes that support symlink via the OS package. (Win, MacOS, and Linux do, and that's what we officially support.)
 		case common.EFromTo.BlobBlob(), common.EFromTo.BlobFSBlobFS(), common.EFromTo.BlobBlobFS(), common.EFromTo.BlobFSBlob():
 			return nil // Blob->Blob doesn't involve any local requirements
 		default:
-			return fmt.Errorf("flag --%s can only be used on Blob<->Blob or Local<->Blob", common.PreserveSymlinkFlagName)
+			return fmt.Errorf("flag --%s can only be used on Blob<->Blob, Local<->Blob or Local->Local", common.PreserveSymlinkFlagName)
 		}
 	}
 
@@ -1033,6 +1108,53 @@ func validatePutMd5(putMd5 bool, fromTo common.FromTo) error {
 	return nil
 }
 
+func validateArchiveFormat(format common.ArchiveFormat, fromTo common.FromTo, blobType common.BlobType, putMd5 bool, preservePOSIXProperties bool) error {
+	if format == common.EArchiveFormat.None() {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return fmt.Errorf("archive is only supported when uploading to Blob Storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+		return fmt.Errorf("archive is only supported for block blobs")
+	}
+	// the archive is produced as a stream, so its hash isn't known until the blob has been committed
+	if putMd5 {
+		return fmt.Errorf("put-md5 is not supported with archive")
+	}
+	// the mode, owner and timestamps of each file are recorded in the tar headers instead
+	if preservePOSIXProperties {
+		return fmt.Errorf("preserve-posix-properties is not supported with archive, as the properties of each file are recorded in the archive itself")
+	}
+	return nil
+}
+
+func validateCompressionType(ct common.CompressionType, fromTo common.FromTo, blobType common.BlobType, contentEncoding string, putMd5 bool, archiveFormat common.ArchiveFormat) error {
+	if ct == common.ECompressionType.None() {
+		return nil
+	}
+	if ct != common.ECompressionType.GZip() && ct != common.ECompressionType.ZStd() {
+		return fmt.Errorf("compress only supports gzip and zstd")
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return fmt.Errorf("compress is only supported when uploading to Blob Storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+		return fmt.Errorf("compress is only supported for block blobs")
+	}
+	if contentEncoding != "" {
+		return fmt.Errorf("content-encoding cannot be set with compress, as it is set to match the compression")
+	}
+	// the hash would be of the file, not of the compressed content that is stored in the blob
+	if putMd5 {
+		return fmt.Errorf("put-md5 is not supported with compress")
+	}
+	if archiveFormat != common.EArchiveFormat.None() {
+		return fmt.Errorf("compress is not supported with archive. Use --archive=TarGz for a compressed archive")
+	}
+	return nil
+}
+
 func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo) error {
 	hasMd5Validation := option != common.DefaultHashValidationOption
 	if hasMd5Validation && !fromTo.IsDownload() {
@@ -1198,6 +1320,13 @@ type CookedCopyCmdArgs struct {
 	// Whether to rename/share the root
 	asSubdir bool
 
+	// Whether to pack the enumerated files into a single archive blob, rather than uploading them one by one
+	archiveFormat common.ArchiveFormat
+
+	// Whether to compress each file as it is uploaded, and if so, whether to add the extension of the compression to its name
+	compressionType         common.CompressionType
+	addCompressionExtension bool
+
 	// whether user wants to preserve full properties during service to service copy, the default value is true.
 	// For S3 and Azure File non-single file source, as list operation doesn't return full properties of objects/files,
 	// to preserve full properties AzCopy needs to send one additional request per object/file.
@@ -1241,6 +1370,9 @@ type CookedCopyCmdArgs struct {
 	// Optional flag that sets rehydrate priority for rehydration
 	rehydratePriority common.RehydratePriorityType
 
+	// the priority of the job against the other jobs that run in the same process
+	priority common.JobPriority
+
 	// Bitmasked uint checking which properties to transfer
 	propertiesToTransfer common.SetPropertiesFlags
 
@@ -1249,9 +1381,12 @@ type CookedCopyCmdArgs struct {
 
 func (cca *CookedCopyCmdArgs) isRedirection() bool {
 	switch cca.FromTo {
-	case common.EFromTo.BlobPipe():
-		fallthrough
-	case common.EFromTo.PipeBlob():
+	case common.EFromTo.BlobPipe(),
+		common.EFromTo.PipeBlob(),
+		common.EFromTo.FilePipe(),
+		common.EFromTo.PipeFile(),
+		common.EFromTo.BlobFSPipe(),
+		common.EFromTo.PipeBlobFS():
 		return true
 	default:
 		return false
@@ -1280,10 +1415,19 @@ func (cca *CookedCopyCmdArgs) process() error {
 
 // TODO discuss with Jeff what features should be supported by redirection, such as metadata, content-type, etc.
 func (cca *CookedCopyCmdArgs) processRedirectionCopy() error {
-	if cca.FromTo == common.EFromTo.PipeBlob() {
+	switch cca.FromTo {
+	case common.EFromTo.PipeBlob():
 		return cca.processRedirectionUpload(cca.Destination, cca.blockSize)
-	} else if cca.FromTo == common.EFromTo.BlobPipe() {
+	case common.EFromTo.BlobPipe():
 		return cca.processRedirectionDownload(cca.Source)
+	case common.EFromTo.PipeFile():
+		return cca.processRedirectionFileUpload(cca.Destination, cca.blockSize)
+	case common.EFromTo.FilePipe():
+		return cca.processRedirectionFileDownload(cca.Source)
+	case common.EFromTo.PipeBlobFS():
+		return cca.processRedirectionBlobFSUpload(cca.Destination, cca.blockSize)
+	case common.EFromTo.BlobFSPipe():
+		return cca.processRedirectionBlobFSDownload(cca.Source)
 	}
 
 	return fmt.Errorf("unsupported redirection type: %s", cca.FromTo)
@@ -1372,14 +1516,7 @@ func (cca *CookedCopyCmdArgs) processRedirectionUpload(blobResource common.Resou
 
 	// step 2: leverage high-level call in Blob SDK to upload stdin in parallel
 	blockBlobUrl := azblob.NewBlockBlobURL(*u, p)
-	metadataString := cca.metadata
-	metadataMap := common.Metadata{}
-	if len(metadataString) > 0 {
-		for _, keyAndValue := range strings.Split(metadataString, ";") { // key/value pairs are separated by ';'
-			kv := strings.Split(keyAndValue, "=") // key/value are separated by '='
-			metadataMap[kv[0]] = kv[1]
-		}
-	}
+	metadataMap := cca.redirectionMetadata()
 	blobTags := cca.blobTags
 	bbAccessTier := azblob.DefaultAccessTier
 	if cca.blockBlobTier != common.EBlockBlobTier.None() {
@@ -1404,6 +1541,290 @@ func (cca *CookedCopyCmdArgs) processRedirectionUpload(blobResource common.Resou
 	return err
 }
 
+func (cca *CookedCopyCmdArgs) processRedirectionFileDownload(fileResource common.ResourceString) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// step 0: check the Stdout before downloading
+	_, err := os.Stdout.Stat()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot write to Stdout due to error: %s", err.Error())
+	}
+
+	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.File(), fileResource.Value, fileResource.SAS, true, cca.CpkOptions)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot find auth on source file URL: %s", err.Error())
+	}
+
+	// step 1: initialize pipeline
+	p, err := createFilePipeline(ctx, credInfo, pipeline.LogNone, cca.trailingDot)
+	if err != nil {
+		return err
+	}
+
+	// step 2: parse source url
+	u, err := fileResource.FullURL()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot parse source file URL due to error: %s", err.Error())
+	}
+
+	// step 3: start download
+	fileURL := azfile.NewFileURL(*u, p)
+	fileStream, err := fileURL.Download(ctx, 0, azfile.CountToEnd, false)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot download file due to error: %s", err.Error())
+	}
+
+	fileBody := fileStream.Body(azfile.RetryReaderOptions{MaxRetryRequests: ste.MaxRetryPerDownloadBody})
+	defer fileBody.Close()
+
+	// step 4: pipe everything into Stdout
+	_, err = io.Copy(os.Stdout, fileBody)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot download file to Stdout due to error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (cca *CookedCopyCmdArgs) processRedirectionFileUpload(fileResource common.ResourceString, blockSize int64) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// a single range written to Azure Files can't be larger than 4 MiB
+	if blockSize == 0 || blockSize > azfile.FileMaxUploadRangeBytes {
+		blockSize = azfile.FileMaxUploadRangeBytes
+	}
+
+	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.File(), fileResource.Value, fileResource.SAS, false, cca.CpkOptions)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot find auth on destination file URL: %s", err.Error())
+	}
+
+	// step 0: initialize pipeline
+	p, err := createFilePipeline(ctx, credInfo, pipeline.LogNone, cca.trailingDot)
+	if err != nil {
+		return err
+	}
+
+	// step 1: parse destination url
+	u, err := fileResource.FullURL()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot parse destination file URL due to error: %s", err.Error())
+	}
+	fileURL := azfile.NewFileURL(*u, p)
+
+	// step 2: create the parent directories and an empty file.
+	// The length of stdin isn't known up front, so the file is grown before each range is written.
+	err = ste.AzureFileParentDirCreator{}.CreateParentDirToRoot(ctx, fileURL, p, ste.NewFolderCreationTracker(common.EFolderPropertiesOption.NoFolders(), nil))
+	if err != nil {
+		return fmt.Errorf("fatal: cannot create parent directories of destination file due to error: %s", err.Error())
+	}
+	_, err = fileURL.Create(ctx, 0, azfile.FileHTTPHeaders{}, cca.redirectionMetadata().ToAzFileMetadata())
+	if err != nil {
+		return fmt.Errorf("fatal: cannot create destination file due to error: %s", err.Error())
+	}
+
+	// step 3: upload stdin range by range
+	_, err = uploadStreamInChunks(ctx, os.Stdin, blockSize,
+		func(end int64) error {
+			_, err := fileURL.Resize(ctx, end)
+			return err
+		},
+		func(ctx context.Context, offset int64, chunk []byte) error {
+			_, err := fileURL.UploadRange(ctx, offset, bytes.NewReader(chunk), nil)
+			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	// step 4: set the headers last, since resizing the file clears them
+	_, err = fileURL.SetHTTPHeaders(ctx, azfile.FileHTTPHeaders{
+		ContentType:        cca.contentType,
+		ContentLanguage:    cca.contentLanguage,
+		ContentEncoding:    cca.contentEncoding,
+		ContentDisposition: cca.contentDisposition,
+		CacheControl:       cca.cacheControl,
+	})
+	return err
+}
+
+func (cca *CookedCopyCmdArgs) processRedirectionBlobFSDownload(fileResource common.ResourceString) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// step 0: check the Stdout before downloading
+	_, err := os.Stdout.Stat()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot write to Stdout due to error: %s", err.Error())
+	}
+
+	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.BlobFS(), fileResource.Value, fileResource.SAS, true, cca.CpkOptions)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot find auth on source file URL: %s", err.Error())
+	}
+
+	// step 1: initialize pipeline
+	p, err := createBlobFSPipeline(ctx, credInfo, pipeline.LogNone)
+	if err != nil {
+		return err
+	}
+
+	// step 2: parse source url
+	u, err := fileResource.FullURL()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot parse source file URL due to error: %s", err.Error())
+	}
+
+	// step 3: start download
+	fileURL := azbfs.NewFileURL(*u, p)
+	fileStream, err := fileURL.Download(ctx, 0, 0)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot download file due to error: %s", err.Error())
+	}
+
+	fileBody := fileStream.Body(azbfs.RetryReaderOptions{MaxRetryRequests: ste.MaxRetryPerDownloadBody})
+	defer fileBody.Close()
+
+	// step 4: pipe everything into Stdout
+	_, err = io.Copy(os.Stdout, fileBody)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot download file to Stdout due to error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (cca *CookedCopyCmdArgs) processRedirectionBlobFSUpload(fileResource common.ResourceString, blockSize int64) error {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// if no block size is set, then use default value
+	if blockSize == 0 {
+		blockSize = pipingDefaultBlockSize
+	}
+
+	credInfo, _, err := GetCredentialInfoForLocation(ctx, common.ELocation.BlobFS(), fileResource.Value, fileResource.SAS, false, cca.CpkOptions)
+	if err != nil {
+		return fmt.Errorf("fatal: cannot find auth on destination file URL: %s", err.Error())
+	}
+
+	// step 0: initialize pipeline
+	p, err := createBlobFSPipeline(ctx, credInfo, pipeline.LogNone)
+	if err != nil {
+		return err
+	}
+
+	// step 1: parse destination url
+	u, err := fileResource.FullURL()
+	if err != nil {
+		return fmt.Errorf("fatal: cannot parse destination file URL due to error: %s", err.Error())
+	}
+	fileURL := azbfs.NewFileURL(*u, p)
+
+	// step 2: create the file. Missing parent directories are created by the service.
+	headers := azbfs.BlobFSHTTPHeaders{
+		ContentType:        cca.contentType,
+		ContentLanguage:    cca.contentLanguage,
+		ContentEncoding:    cca.contentEncoding,
+		ContentDisposition: cca.contentDisposition,
+		CacheControl:       cca.cacheControl,
+	}
+	_, err = fileURL.CreateWithOptions(ctx, azbfs.CreateFileOptions{Headers: headers, Metadata: cca.redirectionMetadata()}, azbfs.BlobFSAccessControl{})
+	if err != nil {
+		return fmt.Errorf("fatal: cannot create destination file due to error: %s", err.Error())
+	}
+
+	// step 3: append stdin chunk by chunk, then flush everything that was appended
+	fileSize, err := uploadStreamInChunks(ctx, os.Stdin, blockSize, nil,
+		func(ctx context.Context, offset int64, chunk []byte) error {
+			_, err := fileURL.AppendData(ctx, offset, bytes.NewReader(chunk))
+			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	_, err = fileURL.FlushData(ctx, fileSize, nil, headers, false, true)
+	return err
+}
+
+// redirectionMetadata parses the --metadata flag for the redirection uploads, which don't go through the STE
+func (cca *CookedCopyCmdArgs) redirectionMetadata() common.Metadata {
+	metadataString := cca.metadata
+	metadataMap := common.Metadata{}
+	if len(metadataString) > 0 {
+		for _, keyAndValue := range strings.Split(metadataString, ";") { // key/value pairs are separated by ';'
+			kv := strings.Split(keyAndValue, "=") // key/value are separated by '='
+			metadataMap[kv[0]] = kv[1]
+		}
+	}
+	return metadataMap
+}
+
+// uploadStreamInChunks reads stream in chunks of chunkSize, and uploads up to pipingUploadParallelism of them at a time.
+// grow, if not nil, is called in order before each chunk is uploaded, with the size the destination must have to hold it.
+// It returns the number of bytes read from the stream.
+func uploadStreamInChunks(ctx context.Context, stream io.Reader, chunkSize int64, grow func(end int64) error, upload func(ctx context.Context, offset int64, chunk []byte) error) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buffers := make(chan []byte, pipingUploadParallelism)
+	for i := 0; i < pipingUploadParallelism; i++ {
+		buffers <- make([]byte, chunkSize)
+	}
+
+	var wg sync.WaitGroup
+	var firstErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	offset := int64(0)
+	for ctx.Err() == nil {
+		var buffer []byte
+		select {
+		case buffer = <-buffers:
+		case <-ctx.Done():
+			continue
+		}
+
+		n, err := io.ReadFull(stream, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			fail(fmt.Errorf("fatal: cannot read from Stdin due to error: %s", err.Error()))
+			break
+		}
+		if n == 0 {
+			break
+		}
+
+		if grow != nil {
+			if err := grow(offset + int64(n)); err != nil {
+				fail(err)
+				break
+			}
+		}
+
+		wg.Add(1)
+		go func(offset int64, buffer []byte, n int) {
+			defer wg.Done()
+			if err := upload(ctx, offset, buffer[:n]); err != nil {
+				fail(err)
+			}
+			buffers <- buffer
+		}(offset, buffer, n)
+
+		offset += int64(n)
+		if n < len(buffer) {
+			break // the stream has ended
+		}
+	}
+
+	wg.Wait()
+	return offset, firstErr
+}
+
 // get source credential - if there is a token it will be used to get passed along our pipeline
 func (cca *CookedCopyCmdArgs) getSrcCredential(ctx context.Context, jpo *common.CopyJobPartOrderRequest) (common.CredentialInfo, error) {
 	srcCredInfo, isPublic, err := GetCredentialInfoForLocation(ctx, cca.FromTo.From(), cca.Source.Value, cca.Source.SAS, true, cca.CpkOptions)
@@ -1418,7 +1839,7 @@ func (cca *CookedCopyCmdArgs) getSrcCredential(ctx context.Context, jpo *common.
 		return srcCredInfo, errors.New("shared key auth is not supported for S2S operations")
 	}
 
-	if cca.Source.SAS != "" && cca.FromTo.IsS2S() && jpo.CredentialInfo.CredentialType == common.ECredentialType.OAuthToken() {
+	if cca.Source.SAS != "" && cca.FromTo.IsS2S() && cca.FromTo.From() != common.ELocation.HTTP() && jpo.CredentialInfo.CredentialType == common.ECredentialType.OAuthToken() {
 		glcm.Info("Authentication: If the source and destination accounts are in the same AAD tenant & the user/spn/msi has appropriate permissions on both, the source SAS token is not required and OAuth can be used round-trip.")
 	}
 
@@ -1500,7 +1921,7 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		ForceWrite:          cca.ForceWrite,
 		ForceIfReadOnly:     cca.ForceIfReadOnly,
 		AutoDecompress:      cca.autoDecompress,
-		Priority:            common.EJobPriority.Normal(),
+		Priority:            cca.priority,
 		LogLevel:            azcopyLogVerbosity,
 		ExcludeBlobType:     cca.excludeBlobType,
 		SymlinkHandlingType: cca.SymlinkHandling,
@@ -1521,7 +1942,9 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 			MD5ValidationOption:      cca.md5ValidationOption,
 			DeleteSnapshotsOption:    cca.deleteSnapshotsOption,
 			// Setting tags when tags explicitly provided by the user through blob-tags flag
-			BlobTagsString: cca.blobTags.ToString(),
+			BlobTagsString:  cca.blobTags.ToString(),
+			ArchiveFormat:   cca.archiveFormat,
+			CompressionType: cca.compressionType,
 		},
 		CommandString:  cca.commandString,
 		CredentialInfo: cca.credentialInfo,
@@ -1551,7 +1974,7 @@ func (cca *CookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 	}
 
 	switch {
-	case cca.FromTo.IsUpload(), cca.FromTo.IsDownload(), cca.FromTo.IsS2S():
+	case cca.FromTo.IsUpload(), cca.FromTo.IsDownload(), cca.FromTo.IsS2S(), cca.FromTo.IsLocalToLocal():
 		// Execute a standard copy command
 		var e *CopyEnumerator
 		var srcCredInfo common.CredentialInfo
@@ -1924,16 +2347,16 @@ func init() {
 			if len(args) == 1 { // redirection
 				// Enforce the usage of from-to flag when pipes are involved
 				if raw.fromTo == "" {
-					return fmt.Errorf("fatal: from-to argument required, PipeBlob (upload) or BlobPipe (download) is acceptable")
+					return fmt.Errorf("fatal: from-to argument required, PipeBlob, PipeFile, PipeBlobFS (upload) or BlobPipe, FilePipe, BlobFSPipe (download) is acceptable")
 				}
 				var userFromTo common.FromTo
 				err := userFromTo.Parse(raw.fromTo)
-				if err != nil || (userFromTo != common.EFromTo.PipeBlob() && userFromTo != common.EFromTo.BlobPipe()) {
+				if err != nil || !(&CookedCopyCmdArgs{FromTo: userFromTo}).isRedirection() {
 					return fmt.Errorf("fatal: invalid from-to argument passed: %s", raw.fromTo)
 				}
 
-				if userFromTo == common.EFromTo.PipeBlob() {
-					// Case 1: PipeBlob. Check for the std input pipe
+				if userFromTo.From() == common.ELocation.Pipe() {
+					// Case 1: upload from a pipe. Check for the std input pipe
 					stdinPipeIn, err := isStdinPipeIn()
 					if !stdinPipeIn || err != nil {
 						return fmt.Errorf("fatal: failed to read from Stdin due to error: %s", err)
@@ -1941,7 +2364,7 @@ func init() {
 					raw.src = pipeLocation
 					raw.dst = args[0]
 				} else {
-					// Case 2: BlobPipe. In this case if pipe is missing, content will be echoed on the terminal
+					// Case 2: download to a pipe. In this case if pipe is missing, content will be echoed on the terminal
 					raw.src = args[0]
 					raw.dst = pipeLocation
 				}
@@ -2053,6 +2476,10 @@ func init() {
 	// The usage of this hidden flag is to provide fallback to traditional behavior, when service supports returning full properties during list.
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sGetPropertiesInBackend, "s2s-get-properties-in-backend", true, "get S3 objects' or Azure files' properties in backend, if properties need to be accessed. Properties need to be accessed if s2s-preserve-properties is true, and in certain other cases where we need the properties for modification time checks or MD5 checks")
 	cpCmd.PersistentFlags().StringVar(&raw.trailingDot, "trailing-dot", "", "Enabled by default. Options for trailing dot support in file share. Available options: Enable, Disable. Choose disable to go back to legacy (potentially unsafe) treatment of trailing dot files.")
+	cpCmd.PersistentFlags().StringVar(&raw.archive, "archive", "", "Upload the local source as a single tar archive, to the block blob named by the destination, instead of uploading each file as its own blob. Available options: Tar, TarGz (a gzip compressed tar). The index of the archive, with the offset of each file in the uncompressed tar, is uploaded next to it as a blob with the suffix '"+ste.ArchiveIndexBlobSuffix+"'.")
+	cpCmd.PersistentFlags().StringVar(&raw.compress, "compress", "", "Compress each file as it is uploaded to a block blob, and set the content-encoding of the blob to match. Available options: gzip, zstd. Each block of the blob is compressed separately, and the blob as a whole is a valid compressed stream. Such blobs are decompressed again on download by --decompress.")
+	cpCmd.PersistentFlags().StringVar(&raw.priority, "priority", common.EJobPriority.Normal().String(), "The priority of the job against the other jobs that run in the same process, such as the jobs of 'azcopy serve'. Available options: Low, Normal, High. A High job gets four times the share of the connections and bandwidth of a Normal one, which gets four times the share of a Low one. A job that runs alone gets everything.")
+	cpCmd.PersistentFlags().BoolVar(&raw.addCompressionExtension, "add-compression-extension", false, "False by default. Add the extension of the compression ('.gz' or '.zst') to the name of each blob uploaded with --compress, unless the destination is the name of the blob itself. The extension is removed again on download by --decompress.")
 
 	// Public Documentation: https://docs.microsoft.com/en-us/azure/storage/blobs/encryption-customer-provided-keys
 	// Clients making requests against Azure Blob storage have the option to provide an encryption key on a per-request basis.
@@ -0,0 +1,166 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+// the size of a tar header block, and the unit that the content of each tar entry is padded to
+const tarBlockSize = 512
+
+// archiveMemberCollector is the processor of an archive upload. Rather than scheduling a transfer per object,
+// it writes each object to the member list of the job. Once enumeration is done, a single transfer,
+// of the whole source to the archive blob, is scheduled.
+type archiveMemberCollector struct {
+	cca        *CookedCopyCmdArgs
+	namePrefix string
+	writer     *ste.ArchiveMemberListWriter
+
+	memberCount      uint64
+	estimatedSize    int64
+	lastModifiedTime time.Time
+}
+
+func newArchiveMemberCollector(cca *CookedCopyCmdArgs) (*archiveMemberCollector, error) {
+	// like a directory upload, the members are placed under the name of the source directory,
+	// unless just its contents were asked for with a trailing wildcard or --as-subdir=false
+	namePrefix := ""
+	if cca.IsSourceDir && cca.asSubdir && !cca.StripTopDir {
+		rootDir := filepath.Base(cca.Source.ValueLocal())
+		// the root of a volume has no name of its own, so its contents go at the top of the archive
+		if rootDir != common.OS_PATH_SEPARATOR && rootDir != common.AZCOPY_PATH_SEPARATOR_STRING && rootDir != "." {
+			namePrefix = rootDir + common.AZCOPY_PATH_SEPARATOR_STRING
+		}
+	}
+
+	c := &archiveMemberCollector{cca: cca, namePrefix: namePrefix}
+	if cca.dryrunMode {
+		return c, nil
+	}
+
+	var err error
+	c.writer, err = ste.NewArchiveMemberListWriter(cca.jobID)
+	return c, err
+}
+
+func (c *archiveMemberCollector) process(object StoredObject) error {
+	member := ste.ArchiveMember{
+		RelativePath: strings.ReplaceAll(object.relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING),
+		EntityType:   object.entityType,
+	}
+
+	switch {
+	case object.isSingleSourceFile():
+		member.Name = object.name
+	case object.isSourceRootFolder():
+		if c.namePrefix == "" {
+			return nil // the root has no entry of its own when its contents go at the top of the archive
+		}
+		member.Name = c.namePrefix
+	default:
+		member.Name = c.namePrefix + member.RelativePath
+	}
+
+	switch object.entityType {
+	case common.EEntityType.File():
+		c.estimatedSize += tarBlockSize + (object.size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	case common.EEntityType.Folder(), common.EEntityType.Symlink():
+		c.estimatedSize += tarBlockSize
+	default:
+		return nil // there's nothing in a tar to hold anything else, such as the properties of a file on their own
+	}
+	if len(member.Name) > 100 {
+		// names that don't fit in the basic tar header need an extended header too
+		c.estimatedSize += 2 * tarBlockSize
+	}
+	if object.lastModifiedTime.After(c.lastModifiedTime) {
+		c.lastModifiedTime = object.lastModifiedTime
+	}
+	c.memberCount++
+
+	if c.cca.dryrunMode {
+		glcm.Dryrun(func(format common.OutputFormat) string {
+			if format == common.EOutputFormat.Json() {
+				jsonOutput, err := json.Marshal(member)
+				common.PanicIfErr(err)
+				return string(jsonOutput)
+			}
+			return fmt.Sprintf("DRYRUN: archive %v as %v in %v",
+				filepath.Join(common.ToShortPath(c.cca.Source.Value), filepath.FromSlash(member.RelativePath)),
+				member.Name,
+				c.cca.Destination.Value)
+		})
+		return nil
+	}
+
+	return c.writer.Write(member)
+}
+
+func (c *archiveMemberCollector) finalize(jobPartOrder *common.CopyJobPartOrderRequest) error {
+	if c.cca.dryrunMode {
+		return nil
+	}
+
+	if err := c.writer.Close(); err != nil {
+		return fmt.Errorf("couldn't save the archive member list: %w", err)
+	}
+	if c.memberCount == 0 {
+		_ = os.Remove(ste.ArchiveMemberListPath(c.cca.jobID))
+		return NothingScheduledError
+	}
+
+	// the tar ends with two empty blocks
+	c.estimatedSize += 2 * tarBlockSize
+
+	// the only transfer of the job: its source is the source root, and its destination is the archive blob
+	transfer := common.CopyTransfer{
+		EntityType:       common.EEntityType.File(),
+		LastModifiedTime: c.lastModifiedTime,
+		SourceSize:       c.estimatedSize,
+	}
+	if err := addTransfer(jobPartOrder, transfer, c.cca); err != nil {
+		return err
+	}
+
+	return dispatchFinalPart(jobPartOrder, c.cca)
+}
+
+// initArchiveEnumerator creates the enumerator of an archive upload, which collects the enumerated objects into the
+// member list of the job instead of scheduling them.
+func (cca *CookedCopyCmdArgs) initArchiveEnumerator(traverser ResourceTraverser, filters []ObjectFilter, jobPartOrder *common.CopyJobPartOrderRequest) (*CopyEnumerator, error) {
+	collector, err := newArchiveMemberCollector(cca)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCopyEnumerator(traverser, filters, collector.process, func() error {
+		return collector.finalize(jobPartOrder)
+	}), nil
+}
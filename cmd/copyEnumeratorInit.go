@@ -238,6 +238,13 @@ func (cca *CookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		jobsAdmin.JobsAdmin.LogToJobLog(message, pipeline.LogInfo)
 	}
 
+	if cca.archiveFormat != common.EArchiveFormat.None() {
+		if dstLevel != ELocationLevel.Object() || isDestDir {
+			return nil, errors.New("the destination of an archive must be the URL of a blob, not of a container or a virtual directory")
+		}
+		return cca.initArchiveEnumerator(traverser, filters, &jobPartOrder)
+	}
+
 	processor := func(object StoredObject) error {
 		// Start by resolving the name and creating the container
 		if object.ContainerName != "" {
@@ -271,6 +278,10 @@ func (cca *CookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 		srcRelPath := cca.MakeEscapedRelativePath(true, isDestDir, cca.asSubdir, object)
 		dstRelPath := cca.MakeEscapedRelativePath(false, isDestDir, cca.asSubdir, object)
+		// when the destination is the name of the blob itself, the relative path is empty, and the name is left as the user gave it
+		if cca.addCompressionExtension && object.entityType == common.EEntityType.File() && dstRelPath != "" {
+			dstRelPath = appendCompressionExtension(dstRelPath, cca.compressionType)
+		}
 
 		transfer, shouldSendToSte := object.ToNewCopyTransfer(cca.autoDecompress && cca.FromTo.IsDownload(), srcRelPath, dstRelPath, cca.s2sPreserveAccessTier, jobPartOrder.Fpo, cca.SymlinkHandling)
 		if !cca.S2sPreserveBlobTags {
@@ -544,6 +555,33 @@ func (cca *CookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 		} else {
 			return err
 		}
+	case common.ELocation.S3():
+		dstURL, err := url.Parse(cca.Destination.Value)
+		if err != nil {
+			return err
+		}
+
+		s3URLParts, err := common.NewS3URLParts(*dstURL)
+		if err != nil {
+			return err
+		}
+
+		dstCredInfo.S3CredentialInfo = common.S3CredentialInfo{
+			Endpoint: s3URLParts.Endpoint,
+			Region:   s3URLParts.Region,
+			Insecure: s3URLParts.IsInsecure(),
+		}
+		s3Client, err := common.CreateS3Client(ctx, dstCredInfo, common.CredentialOpOptions{LogError: glcm.Error}, azcopyScanningLogger)
+		if err != nil {
+			return err
+		}
+
+		exists, err := s3Client.BucketExists(containerName)
+		if err != nil || exists {
+			return err
+		}
+
+		return s3Client.MakeBucket(containerName, s3URLParts.Region)
 	default:
 		panic(fmt.Sprintf("cannot create a destination container at location %s.", cca.FromTo.To()))
 	}
@@ -471,7 +471,9 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 		if err == nil {
 			host = u.Host
 			parts, err := common.NewS3URLParts(*u) // strip any leading bucket name from URL, to get an endpoint we can pass to s3utils
-			if err == nil {
+			if err == nil && parts.IsCustomEndpoint() {
+				ok = true // the user has explicitly nominated this endpoint with --s3-endpoint
+			} else if err == nil {
 				u, err := url.Parse("https://" + parts.Endpoint)
 				ok = err == nil && s3utils.IsAmazonEndpoint(*u)
 			}
@@ -547,7 +549,7 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 
 	if resourceSAS != "" && !mdAccount {
 		credType = common.ECredentialType.Anonymous()
-	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() || location == common.ELocation.GCP() {
+	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() || location == common.ELocation.GCP() || location == common.ELocation.HTTP() {
 		switch location {
 		case common.ELocation.Local(), common.ELocation.Benchmark():
 			credType = common.ECredentialType.Anonymous()
@@ -579,6 +581,9 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 				return common.ECredentialType.Unknown(), false, errors.New("GOOGLE_APPLICATION_CREDENTIALS environment variable must be set before using GCP transfer feature")
 			}
 			credType = common.ECredentialType.GoogleAppCredentials()
+		case common.ELocation.HTTP():
+			// a generic URL is either public, or carries its own credential in the query string (handled as the SAS, above)
+			return common.ECredentialType.Anonymous(), true, nil
 		}
 	}
 
@@ -691,6 +696,35 @@ func createBlobPipeline(ctx context.Context, credInfo common.CredentialInfo, log
 
 const frontEndMaxIdleConnectionsPerHost = http.DefaultMaxIdleConnsPerHost
 
+// createHTTPPipeline creates the pipeline for generic HTTP(S) sources, which need no credential
+func createHTTPPipeline(logLevel pipeline.LogLevel) pipeline.Pipeline {
+	logOption := pipeline.LogOptions{}
+	if azcopyScanningLogger != nil {
+		logOption = pipeline.LogOptions{
+			Log:       azcopyScanningLogger.Log,
+			ShouldLog: func(level pipeline.LogLevel) bool { return level <= logLevel },
+		}
+	}
+
+	return ste.NewHTTPPipeline(
+		azblob.PipelineOptions{
+			Telemetry: azblob.TelemetryOptions{
+				Value: glcm.AddUserAgentPrefix(common.UserAgent),
+			},
+			Log: logOption,
+		},
+		ste.XferRetryOptions{
+			Policy:        0,
+			MaxTries:      ste.UploadMaxTries,
+			TryTimeout:    ste.UploadTryTimeout,
+			RetryDelay:    ste.UploadRetryDelay,
+			MaxRetryDelay: ste.UploadMaxRetryDelay,
+		},
+		ste.NewAzcopyHTTPClient(frontEndMaxIdleConnectionsPerHost),
+		nil, // we don't gather network stats on the enumeration pipeline
+	)
+}
+
 func createBlobFSPipeline(ctx context.Context, credInfo common.CredentialInfo, logLevel pipeline.LogLevel) (pipeline.Pipeline, error) {
 	credential := common.CreateBlobFSCredential(ctx, credInfo, common.CredentialOpOptions{
 		// LogInfo:  glcm.Info, //Comment out for debugging
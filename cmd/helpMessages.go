@@ -28,7 +28,13 @@ Copies source data to a destination location. The supported directions are:
   - Azure Files (SAS) -> Azure Files (SAS)
   - Azure Files (SAS) -> Azure Blob (SAS or OAuth authentication)
   - AWS S3 (Access Key) -> Azure Block Blob (SAS or OAuth authentication)
+  - AWS S3 (Access Key or public) -> local
+  - Azure Blob (SAS or public) -> AWS S3 (Access Key)
   - Google Cloud Storage (Service Account Key) -> Azure Block Blob (SAS or OAuth authentication)
+  - Google Cloud Storage (Service Account Key) -> local
+  - any HTTP(S) URL (public or pre-signed) -> Azure Block Blob (SAS or OAuth authentication) (requires --from-to=HTTPBlob)
+  - any HTTP(S) URL (public or pre-signed) -> local (requires --from-to=HTTPLocal)
+  - local -> local (requires --from-to=LocalLocal)
 
 Please refer to the examples for more information.
 
@@ -66,12 +72,25 @@ Upload a single file by using OAuth and piping (block blobs only):
 
   - cat "/path/to/file.txt" | azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to PipeBlob
 
+Upload the output of a command to an Azure Files share, or to an ADLS Gen 2 account, by using a SAS token and piping:
+
+  - pg_dump [database] | azcopy cp "https://[account].file.core.windows.net/[share]/[path/to/file]?[SAS]" --from-to PipeFile
+  - pg_dump [database] | azcopy cp "https://[account].dfs.core.windows.net/[filesystem]/[path/to/file]?[SAS]" --from-to PipeBlobFS
+
 Upload an entire directory by using a SAS token:
   
   - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
 or
   - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --put-md5
 
+Upload an entire directory as a single gzip-compressed tar blob, along with an index of its contents, by using a SAS token:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/archive.tar.gz]?[SAS]" --recursive=true --archive=TarGz
+
+Upload an entire directory, compressing each file with gzip and adding '.gz' to the name of each blob, by using a SAS token:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --compress=gzip --add-compression-extension
+
 Upload a set of files by using a SAS token and wildcard (*) characters:
  
   - azcopy cp "/path/*foo/*bar/*.pdf" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]"
@@ -104,6 +123,11 @@ Download a single file by using OAuth and then piping the output to a file (bloc
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to BlobPipe > "/path/to/file.txt"
 
+Download a single file from an Azure Files share, or from an ADLS Gen 2 account, by using a SAS token and then piping the output to a file:
+
+  - azcopy cp "https://[account].file.core.windows.net/[share]/[path/to/file]?[SAS]" --from-to FilePipe > "/path/to/file.txt"
+  - azcopy cp "https://[account].dfs.core.windows.net/[filesystem]/[path/to/file]?[SAS]" --from-to BlobFSPipe > "/path/to/file.txt"
+
 Download an entire directory by using a SAS token:
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true
@@ -172,6 +196,18 @@ Copy a subset of buckets by using a wildcard symbol (*) in the bucket name. Like
 
   - azcopy cp "https://s3.amazonaws.com/[bucket*name]/" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
 
+Copy an entire directory from Blob Storage to AWS S3 by using a SAS token and an access key. First, set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for the AWS S3 destination. Blob metadata, tags and content headers are carried over to the S3 objects.
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "https://s3.[region].amazonaws.com/[bucket]/[folder]" --recursive=true
+
+Download an entire directory from AWS S3 to the local disk by using an access key. First, set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for AWS S3 source.
+
+  - azcopy cp "https://s3.[region].amazonaws.com/[bucket]/[folder]" "/path/to/dir" --recursive=true
+
+Download a bucket from an S3-compatible service, such as MinIO, by using an access key. Set the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY as for AWS S3, and name the service's endpoint with --s3-endpoint. Use an http:// URL if the service doesn't support HTTPS.
+
+  - azcopy cp "http://[host]:[port]/[bucket]" "/path/to/dir" --recursive=true --s3-endpoint=[host]:[port]
+
 Copy blobs from one blob storage to another and preserve the tags from source. To preserve tags, use the following syntax :
   	
   - azcopy cp "https://[account].blob.core.windows.net/[source_container]/[path/to/directory]?[SAS]" "https://[account].blob.core.windows.net/[destination_container]/[path/to/directory]?[SAS]" --s2s-preserve-blob-tags=true
@@ -203,6 +239,26 @@ Copy all buckets to Blob Storage from Google Cloud Storage (GCS) by using a serv
 Copy a subset of buckets by using a wildcard symbol (*) in the bucket name from Google Cloud Storage (GCS) by using a service account key and a SAS token for destination. First, set the environment variables GOOGLE_APPLICATION_CREDENTIALS and GOOGLE_CLOUD_PROJECT=<project-id> for GCS source
  
   - azcopy cp "https://storage.cloud.google.com/[bucket*name]/" "https://[destaccount].blob.core.windows.net/?[SAS]" --recursive=true
+
+Download an entire directory from Google Cloud Storage (GCS) to the local disk by using a service account key. First, set the environment variable GOOGLE_APPLICATION_CREDENTIALS for GCS source. The MD5 hashes supplied by GCS are validated on download.
+
+  - azcopy cp "https://storage.cloud.google.com/[bucket]/[folder]" "/path/to/dir" --recursive=true
+
+Copy an entire directory to another location on the local disk, for example a mounted network share. Local to local copies are never inferred, so the --from-to flag is required.
+
+  - azcopy cp "/path/to/dir" "/mnt/share/dir" --recursive=true --from-to=LocalLocal
+
+Copy a file from any web server to Blob Storage. Since a mistyped storage URL could look like any other URL, a web server is never inferred as the source, so the --from-to flag is required. The service reads the file directly from the URL, so it must be reachable from Azure. Any query string (e.g. of a pre-signed URL from another cloud) is treated like a SAS token, so it isn't saved in the job plan files.
+
+  - azcopy cp "https://[server]/[path/to/file]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" --from-to=HTTPBlob
+
+Download several files from a web server. A web server can't be listed, so give the base URL (ending with /) as the source, and name the files under it, one per line, in the list-of-files.
+
+  - azcopy cp "https://[server]/[path/to/dir]/" "/path/to/dir" --list-of-files=/path/to/list.txt --from-to=HTTPLocal
+
+Copy a file from a web server, only if it has been modified (according to the server's Last-Modified header) since the destination was last written.
+
+  - azcopy cp "https://[server]/[path/to/file]" "/path/to/file" --overwrite=ifSourceNewer --from-to=HTTPLocal
 `
 
 // ===================================== ENV COMMAND ===================================== //
@@ -229,7 +285,19 @@ const showJobsCmdShortDescription = "Show detailed information for the given job
 const showJobsCmdLongDescription = `
 If you provide only a job ID, and not a flag, then this command returns the progress summary only.
 The byte counts and percent complete that appears when you run this command reflect only files that are completed in the job. They don't reflect partially completed files.
-If you set the with-status flag, then only the list of transfers associated with the given status appear.`
+If you set the with-status flag, then only the list of transfers associated with the given status appear.
+The list of transfers can also be filtered by path, size, error code and completion time, sorted, and exported to a CSV or NDJSON file, for instance to hand the failures of a job over to the owners of the data.
+Any of these flags lists the transfers of the job, of all statuses unless the with-status flag is set too.`
+
+const showJobsCmdExample = `  azcopy jobs show e52247de-0323-b14d-4cc8-76e0be2e2d44 --with-status=Failed
+
+Export the transfers that failed with a 403 or a 404 error since a given date, largest first, to a CSV file:
+
+  - azcopy jobs show e52247de-0323-b14d-4cc8-76e0be2e2d44 --with-status=Failed --error-code=403,404 --completed-after=2023-05-01 --sort-by=size --descending --export=failures.csv
+
+List the transfers of PDF files of at least 100 MiB, in the order they were done:
+
+  - azcopy jobs show e52247de-0323-b14d-4cc8-76e0be2e2d44 --path-pattern="*.pdf" --min-size=100M --sort-by=completion-time`
 
 const resumeJobsCmdShortDescription = "Resume the existing job with the given job ID."
 
@@ -252,6 +320,48 @@ Note that you can customize the location where log and plan files are saved. See
 
 const cleanJobsCmdExample = "  azcopy jobs clean --with-status=completed"
 
+const retryFailedJobsCmdShortDescription = "Retry the failed transfers of the given job ID as a new job."
+
+const retryFailedJobsCmdLongDescription = `
+Create a new job from the transfers of the given job ID that failed, and run it. The new job keeps all the options of the original one, so only the failed transfers are retried, while the original job is left as it is.
+The link between the two jobs is recorded, and the show command displays it for either job, so that each round of retries can be audited. A retry job that has failed transfers itself can be retried in turn.
+
+As with the resume command, SAS tokens must be supplied again, with the --source-sas and --destination-sas flags, unless you log in first.
+The failed transfers can also be retried to another destination of the same type, with the --destination flag.`
+
+const retryFailedJobsCmdExample = `  azcopy jobs retry-failed e52247de-0323-b14d-4cc8-76e0be2e2d44 --destination-sas="[SAS]"
+
+Retry the failed transfers to another container:
+
+  - azcopy jobs retry-failed e52247de-0323-b14d-4cc8-76e0be2e2d44 --destination="https://[account].blob.core.windows.net/[container]?[SAS]"`
+
+const exportJobsCmdShortDescription = "Export the plan and log files of the given job ID to an archive."
+
+const exportJobsCmdLongDescription = `
+Export the plan and log files of the given job ID to a portable archive, so that the job can be imported and resumed on another machine with the import command.
+The job should not be running while it's exported.
+
+Credentials are not stored in plan files, so they must be supplied again when the job is resumed. Local paths in the job must exist at the same locations on the other machine.`
+
+const exportJobsCmdExample = `  azcopy jobs export e52247de-0323-b14d-4cc8-76e0be2e2d44
+
+Export the job to a specific file:
+
+  - azcopy jobs export e52247de-0323-b14d-4cc8-76e0be2e2d44 --archive=/mnt/share/job.azcopyjob.tar.gz`
+
+const importJobsCmdShortDescription = "Import a job that was exported from another machine."
+
+const importJobsCmdLongDescription = `
+Import the plan and log files of a job from an archive that was created by the export command, so that the job can be resumed on this machine.
+The plan files must have been written by a version of AzCopy that uses the same plan file format as this one, and the job must not already exist on this machine.
+
+After the import, resume the job with the resume command, supplying SAS tokens with the --source-sas and --destination-sas flags, or by logging in first.
+
+Note that you can customize the location where log and plan files are saved. See the env command to learn more.`
+
+const importJobsCmdExample = `  azcopy jobs import e52247de-0323-b14d-4cc8-76e0be2e2d44.azcopyjob.tar.gz
+  azcopy jobs resume e52247de-0323-b14d-4cc8-76e0be2e2d44 --destination-sas="[SAS]"`
+
 // ===================================== LIST COMMAND ===================================== //
 const listCmdShortDescription = "List the entities in a given resource"
 
@@ -389,6 +499,41 @@ The last modified times are used for comparison. The file is skipped if the last
   - Azure Blob <-> Azure Blob (Source must include a SAS or is publicly accessible; either SAS or OAuth authentication can be used for destination)
   - Azure File <-> Azure File (Source must include a SAS or is publicly accessible; SAS authentication should be used for destination)
   - Azure Blob <-> Azure File
+  - Local -> Local (requires --from-to=LocalLocal)
+  - AWS S3 (Access Key) -> Azure Blob (SAS or OAuth authentication)
+  - Google Cloud Storage (Service Account Key) -> Azure Blob (SAS or OAuth authentication)
+
+When the source is AWS S3 or Google Cloud Storage, a difference in size also causes a file to be transferred. With --compare-hash=MD5, objects without a usable MD5 (e.g. S3 multipart uploads) are compared by last modified time and size instead of being skipped.
+
+Besides MD5, --compare-hash accepts CRC64 (the CRC-64 used by Azure Storage), SHA256 and XXHash64 (a fast non-cryptographic hash). Local files are hashed as needed, and their hashes are stored alongside them as with MD5 (see --local-hash-storage-mode). Remote objects carry these hashes in metadata (azcopycrc64, azcopysha256 or azcopyxxhash64), which sync adds when it uploads a file, so remote objects that weren't uploaded by sync with the same hash type lack a hash.
+
+With --compare-mode, you can choose how sync compares a file that exists at both the source and the destination:
+
+  - Default: the file is transferred if it's more recent at the source, or if its hash differs with --compare-hash.
+  - SizeOnly: the file is transferred if its size differs. This suits sources whose last modified times can't be trusted, such as files restored from tape.
+  - LMTAndSize: the file is transferred if it's more recent at the source, or if its size differs.
+  - HashOnly: the file is transferred if its hash differs, or if either side lacks a hash, so that every file that's skipped is verified to be the same. The hash is of the type given by --compare-hash, MD5 by default.
+  - Metadata: the file is transferred if the value of the metadata key given by --compare-metadata-key differs. Only supported between remote resources.
+
+With --bidirectional, sync works in both directions between a local directory and an Azure Blob or Azure File directory. It keeps the state of each source and destination pair in the job plan folder, and compares both sides against the state of the last sync, so files that were created, modified or deleted at either side are created, modified or deleted at the other. A file that was modified at one side and deleted at the other is copied back. A file that changed at both sides is resolved according to --conflict-policy:
+
+  - NewerWins (default): the version with the later last modified time replaces the other.
+  - KeepBoth: the newer version replaces the older one, and the older version is kept next to it, named like 'report.conflict-20230102T150405Z.docx' after its last modified time (UTC). This takes two syncs: the first keeps the older version on the side of the newer one, and the second completes the exchange.
+  - Fail: the file is left alone, and sync ends with an error.
+
+Only files are synced in both directions; empty folders are neither created nor deleted. A file is compared against its state at the last sync, and with the other side, the way a one-way sync compares it, following --compare-mode and --compare-hash. On the first sync of a pair, with the default compare mode and no --compare-hash, files of the same size at both sides are taken to be the same. --list-of-files, --include-before and --include-after narrow a bidirectional sync as they narrow a one-way sync: the paths that are out of scope at the source are left alone at both sides. Since both directions are transferred in the same job, the job of a bidirectional sync can't be resumed or retried with 'azcopy jobs'; run the sync again instead, which also retries the files that failed.
+
+With --incremental, sync keeps a manifest of the destination (the path, size, last modified time and hash of each file) in the job plan folder once it's done, and the next incremental sync of the same source and destination reads the destination from the manifest instead of listing it. The destination is listed in full instead when there's no manifest, when the last sync didn't finish, when the manifest is older than --manifest-max-age, or when the last sync had other filters, --recursive or --compare-hash settings. Since the manifest only knows of the changes that sync made, changes made to the destination by other means are only noticed by the next full listing.
+
+With --archive-prefix, the files at the destination that sync would delete are moved into a folder under the archive prefix instead, named after the time (UTC) at which the sync started, so 'dir/file.txt' is kept as '.archive/20230102T150405Z/dir/file.txt'. Blobs and Azure Files are copied on the service side and then deleted, while files in accounts with a hierarchical namespace and local files are renamed. With --archive-retention-days, each sync also deletes the archive folders that are older than that.
+
+With --detect-moves, the files that were moved or renamed at the source since the last sync are moved at the destination too, instead of being copied there again and deleted from their old paths. A file that the destination lacks is taken to have been moved from an extra file at the destination with the same size and hash, so --compare-hash and --delete-destination=true are required; empty files and files without a hash are copied as usual. Moves are made the same way as with --archive-prefix, and a move that fails falls back to the copy and the deletion. With --watch, moves are detected when the source and destination are compared in full, but not among the changes synced in between.
+
+With --include-before and --include-after, only the source files modified in that range are synced. Unlike the other filters, they only apply to the source: the destination files of the source files that are excluded are neither compared nor deleted. With --list-of-files, only the listed paths (and the files under them) are synced, and with --delete-destination, only the destination files under the listed paths are deleted. Nothing is deleted under a listed path that couldn't be scanned at the source.
+
+With --dry-run-report, a dry run also writes a report of every file that sync would copy, move, delete or skip, with the reason for each (e.g. the source is more recent than the destination, the destination lacks it, or it's excluded by the filters), so that the sync can be reviewed before it's run. The report is a JSON document, or a CSV file with --dry-run-report-format=CSV. The report is only written once the dry run is done.
+
+With --watch, sync keeps running once the source and destination are in sync, and watches a local source for changes (with inotify on Linux). The files that change are collected until none has changed for --watch-debounce, and then transferred as part of the same job, while the files that are deleted are deleted from the destination with --delete-destination=true. Since changes can be missed (e.g. when the queue of events overflows), the source and destination are compared in full every --watch-reconcile-interval. Press Ctrl-C to stop watching; sync then ends once the transfers in progress are done.
 
 The sync command differs from the copy command in several ways:
 
@@ -455,6 +600,38 @@ Sync an Azure File directory (same syntax as Blob):
 
    - azcopy sync "https://[account].file.core.windows.net/[share]/[path/to/dir]?[SAS]" "https://[account].file.core.windows.net/[share]/[path/to/dir]" --recursive=true
 
+Keep a local directory and a Blob virtual directory in sync with each other, keeping both versions of the files that changed at both:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]?[SAS]" --bidirectional --conflict-policy=KeepBoth
+
+Mirror a local directory to another local directory, deleting files at the destination that no longer exist at the source:
+
+   - azcopy sync "/path/to/dir" "/mnt/backup/dir" --from-to=LocalLocal --delete-destination=true
+
+Mirror a local directory to a Blob container, keeping the blobs that no longer exist at the source under '.archive' for 30 days:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true --archive-prefix=.archive --archive-retention-days=30
+
+Review what mirroring a local directory to a Blob container would do, without doing it:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true --dry-run --dry-run-report=plan.csv --dry-run-report-format=CSV
+
+Keep a Blob container in sync with a local directory as the directory changes, until Ctrl-C is pressed:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true --watch
+
+Mirror a local directory to a Blob container, moving the blobs of the files that were renamed since the last sync instead of uploading them again:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true --compare-hash=MD5 --detect-moves
+
+Mirror an S3 bucket into a Blob container by using an access key and a SAS token. First, set the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for the S3 source:
+
+   - azcopy sync "https://s3.amazonaws.com/[bucket]" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true
+
+Mirror a Google Cloud Storage bucket into a Blob container. First, set the environment variable GOOGLE_APPLICATION_CREDENTIALS for the GCS source:
+
+   - azcopy sync "https://storage.cloud.google.com/[bucket]" "https://[account].blob.core.windows.net/[container]?[SAS]" --delete-destination=true
+
 Note: if include and exclude flags are used together, only files matching the include patterns are used, but those matching the exclude patterns are ignored.
 `
 
@@ -573,3 +750,34 @@ Clear all existing blob-tags of blob:
 	- azcopy set-properties "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --blob-tags=clear
 	- While setting tags on the blobs, there are additional permissions('t' for tags) in SAS without which the service will give authorization error back.
 `
+
+// ===================================== SERVE COMMAND ===================================== //
+
+const serveCmdShortDescription = "Run jobs for other programs through a local API."
+
+const serveCmdLongDescription = `
+Keep AzCopy running and take jobs through a local HTTP API, so that a single process can run, queue and monitor many jobs, which share its pools of connections and memory.
+
+Each operation is a POST, with its request as the JSON body and its response as the JSON result, using the same request and response types that the AzCopy commands send to the transfer engine:
+  /CopyJobPartOrder     order a part of a new job, or of one that is still being ordered
+  /ListJobs             list the jobs, optionally with the given status
+  /ListJobSummary       get the progress of the given job ID
+  /ListJobTransfers     list the transfers of a job
+  /CancelJob            cancel the given job ID
+  /PauseJob             pause the given job ID
+  /ResumeJob            resume a job that is paused, cancelled or done
+  /GetJobFromTo         get the source and destination types of a job
+The requests for a job are handled one at a time, and those for different jobs at the same time. A job runs until it's done, and its progress is read with /ListJobSummary. The resources of the jobs that are done are released after a minute or two, and a job that is read afterwards is read from its plan files.
+Jobs that run at the same time share the connections of the process, and its bandwidth when it is capped with --cap-mbps, in proportion to the Priority of their orders, "Low", "Normal" (the default) or "High": a High job gets four times the share of a Normal one, which gets four times the share of a Low one. A job that runs alone gets everything.
+
+The API is served at a loopback address only, with --listen, or on a Unix socket that only the current user can connect to, with --socket. The requests carry credentials, so use a socket on a machine shared with other users.
+Each time AzCopy starts serving, it writes a new random token to a file that only the current user can read, serve.token in the plan folder unless --token-file is given. A request must carry it in an "Authorization: Bearer <token>" header, have a "Content-Type: application/json" header, and be addressed to localhost or a loopback address.
+Each job has its own log file, and an authentication failure fails its transfers instead of cancelling it, so that it can be resumed with new credentials. Jobs that prompt before overwriting can't be ordered.
+When AzCopy is stopped, with Ctrl-C or SIGTERM, the jobs in progress are paused, and can be resumed later through the API or with the jobs resume command.`
+
+const serveCmdExample = `  azcopy serve
+
+Serve the API on a Unix socket, and list the jobs in progress:
+
+  - azcopy serve --socket=/run/azcopy/api.sock --token-file=/run/azcopy/api.token
+  - curl --unix-socket /run/azcopy/api.sock -X POST -H "Authorization: Bearer $(cat /run/azcopy/api.token)" -H "Content-Type: application/json" -d '"InProgress"' http://localhost/ListJobs`
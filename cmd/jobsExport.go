@@ -0,0 +1,284 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+const (
+	// the version of the layout of job archives, which is independent of the version of the plan files in them
+	jobArchiveVersion = 1
+
+	// a job archive is a gzipped tar file, which starts with its manifest, followed by the plan files (and the member list of an archive job) and then the logs of the job
+	jobArchiveManifestName = "manifest.json"
+	jobArchivePlansFolder  = "plans"
+	jobArchiveLogsFolder   = "logs"
+
+	jobArchiveExtension = ".azcopyjob.tar.gz"
+)
+
+// jobArchiveManifest describes the job in a job archive
+type jobArchiveManifest struct {
+	Version           int
+	JobID             common.JobID
+	DataSchemaVersion common.Version // of the plan files
+	FromTo            string
+	Source            string
+	Destination       string
+	ExportedAt        time.Time
+	ExportedFrom      string // the name of the host
+	PlanFiles         []string
+	LogFiles          []string
+	MemberFiles       []string `json:",omitempty"` // the member list of an archive job, which is kept next to its plan files
+}
+
+// jobArchiveMemberFiles returns the names of the files that an archive job keeps in the plan folder besides its plan files
+func jobArchiveMemberFiles(jobID common.JobID) []string {
+	memberList := filepath.Base(ste.ArchiveMemberListPath(jobID))
+	return []string{memberList, memberList + ".index"}
+}
+
+func init() {
+	type JobsExportReq struct {
+		JobID       common.JobID
+		archivePath string
+	}
+
+	commandLineInput := JobsExportReq{}
+
+	// package a single job's plan files and logs, for it to be resumed on another machine
+	jobsExportCmd := &cobra.Command{
+		Use:     "export [jobID]",
+		Short:   exportJobsCmdShortDescription,
+		Long:    exportJobsCmdLongDescription,
+		Example: exportJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("export job command requires the JobID")
+			}
+			// Parse the JobId
+			jobId, err := common.ParseJobID(args[0])
+			if err != nil {
+				return errors.New("invalid jobId given " + args[0])
+			}
+			commandLineInput.JobID = jobId
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			archivePath := commandLineInput.archivePath
+			if archivePath == "" {
+				archivePath = commandLineInput.JobID.String() + jobArchiveExtension
+			}
+
+			manifest, err := exportJob(commandLineInput.JobID, archivePath)
+			if err == nil {
+				glcm.Exit(func(format common.OutputFormat) string {
+					return fmt.Sprintf("Successfully exported the %d plan files and %d log files of job %s to %s.",
+						len(manifest.PlanFiles), len(manifest.LogFiles), commandLineInput.JobID, archivePath)
+				}, common.EExitCode.Success())
+			} else {
+				glcm.Error(fmt.Sprintf("Failed to export job %s due to error: %s.", commandLineInput.JobID, err))
+			}
+		},
+	}
+
+	jobsCmd.AddCommand(jobsExportCmd)
+	jobsExportCmd.PersistentFlags().StringVar(&commandLineInput.archivePath, "archive", "", "The file to export the job to. By default, '[jobID]"+jobArchiveExtension+"' in the current directory.")
+}
+
+// exportJob writes the plan files and logs of a job to an archive, from which jobs import restores them on another machine
+func exportJob(jobID common.JobID, archivePath string) (manifest jobArchiveManifest, err error) {
+	planExtension := fmt.Sprintf(".steV%d", ste.DataSchemaVersion)
+	planFiles, err := listFilesWithPredicate(common.AzcopyJobPlanFolder, func(s string) bool {
+		return strings.HasPrefix(s, jobID.String()+"--") && strings.HasSuffix(s, planExtension)
+	})
+	if err != nil {
+		return manifest, err
+	}
+	if len(planFiles) == 0 {
+		return manifest, fmt.Errorf("cannot find any job plan file of version %d with the specified ID", ste.DataSchemaVersion)
+	}
+	logFiles, err := listFilesWithPredicate(azcopyLogPathFolder, func(s string) bool {
+		return strings.Contains(s, jobID.String()) && strings.HasSuffix(s, ".log")
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return manifest, err
+	}
+	// like the plan files, the member list of an archive job is needed to resume it
+	var memberFiles []string
+	for _, name := range jobArchiveMemberFiles(jobID) {
+		if _, statErr := os.Stat(filepath.Join(common.AzcopyJobPlanFolder, name)); statErr == nil {
+			memberFiles = append(memberFiles, name)
+		}
+	}
+
+	// the part numbers are padded, so the plan files sort in order
+	sort.Strings(planFiles)
+	header, err := readJobPartPlanHeader(filepath.Join(common.AzcopyJobPlanFolder, planFiles[0]))
+	if err != nil {
+		return manifest, fmt.Errorf("unable to read the job plan file %s: %w", planFiles[0], err)
+	}
+	if header.PartNum != 0 {
+		return manifest, errors.New("the first part of the job is missing from the plan folder")
+	}
+	if status := header.JobStatus(); status == common.EJobStatus.InProgress() || status == common.EJobStatus.Cancelling() {
+		glcm.Info(fmt.Sprintf("The job's status is %s. Make sure that it's no longer running, since its plan files are exported as they are now.", status))
+	}
+
+	hostname, _ := os.Hostname()
+	manifest = jobArchiveManifest{
+		Version:           jobArchiveVersion,
+		JobID:             jobID,
+		DataSchemaVersion: ste.DataSchemaVersion,
+		FromTo:            header.FromTo.String(),
+		Source:            string(header.SourceRoot[:header.SourceRootLength]),
+		Destination:       string(header.DestinationRoot[:header.DestinationRootLength]),
+		ExportedAt:        time.Now().UTC(),
+		ExportedFrom:      hostname,
+		PlanFiles:         planFiles,
+		LogFiles:          logFiles,
+		MemberFiles:       memberFiles,
+	}
+
+	// the archive is written to a temporary file first, so that an export that fails doesn't leave an incomplete archive behind
+	tempPath := archivePath + "." + jobID.String() + "--export"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tempPath)
+		}
+	}()
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = writeJobArchiveManifest(tarWriter, manifest)
+	for _, name := range planFiles {
+		if err == nil {
+			err = addFileToJobArchive(tarWriter, path.Join(jobArchivePlansFolder, name), filepath.Join(common.AzcopyJobPlanFolder, name))
+		}
+	}
+	for _, name := range memberFiles {
+		if err == nil {
+			err = addFileToJobArchive(tarWriter, path.Join(jobArchivePlansFolder, name), filepath.Join(common.AzcopyJobPlanFolder, name))
+		}
+	}
+	for _, name := range logFiles {
+		if err == nil {
+			err = addFileToJobArchive(tarWriter, path.Join(jobArchiveLogsFolder, name), filepath.Join(azcopyLogPathFolder, name))
+		}
+	}
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, archivePath)
+	}
+	return manifest, err
+}
+
+func writeJobArchiveManifest(tarWriter *tar.Writer, manifest jobArchiveManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: jobArchiveManifestName, Size: int64(len(content)), Mode: 0644, ModTime: manifest.ExportedAt})
+	if err == nil {
+		_, err = tarWriter.Write(content)
+	}
+	return err
+}
+
+func addFileToJobArchive(tarWriter *tar.Writer, name string, fullPath string) error {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	err = tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: fileInfo.Size(), Mode: 0644, ModTime: fileInfo.ModTime()})
+	if err != nil {
+		return err
+	}
+	// a log that's still being written to is archived up to the size it had when it was opened
+	_, err = io.CopyN(tarWriter, file, fileInfo.Size())
+	return err
+}
+
+// readJobPartPlanHeader reads the header of a job part plan file without mapping it
+func readJobPartPlanHeader(fullPath string) (*ste.JobPartPlanHeader, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ste.ReadJobPartPlanHeader(file, fileInfo.Size())
+}
+
+// list the names of the files in the targetFolder that are approved by the predicate
+func listFilesWithPredicate(targetFolder string, predicate func(string) bool) ([]string, error) {
+	files, err := os.ReadDir(targetFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, singleFile := range files {
+		if singleFile.Type().IsRegular() && predicate(singleFile.Name()) {
+			names = append(names, singleFile.Name())
+		}
+	}
+	return names, nil
+}
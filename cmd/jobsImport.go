@@ -0,0 +1,270 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+// the suffix of the files that are being imported, which keeps them from being mistaken for plan files until they're all checked
+const jobImportTempSuffix = ".importing"
+
+func init() {
+	var archivePath string
+
+	// restore a job that was exported from another machine, so that it can be resumed here
+	jobsImportCmd := &cobra.Command{
+		Use:     "import [archive]",
+		Short:   importJobsCmdShortDescription,
+		Long:    importJobsCmdLongDescription,
+		Example: importJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("import job command requires the path of the archive")
+			}
+			archivePath = args[0]
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			manifest, err := importJob(archivePath)
+			if err == nil {
+				glcm.Exit(func(format common.OutputFormat) string {
+					return fmt.Sprintf("Successfully imported job %s (%s from %s to %s), which was exported from %s at %s.\n"+
+						"Resume it with 'azcopy jobs resume %s', supplying SAS tokens with --source-sas and --destination-sas, or by logging in first.",
+						manifest.JobID, manifest.FromTo, manifest.Source, manifest.Destination, manifest.ExportedFrom, manifest.ExportedAt.Format(time.RFC3339), manifest.JobID)
+				}, common.EExitCode.Success())
+			} else {
+				glcm.Error(fmt.Sprintf("Failed to import job from %s due to error: %s.", archivePath, err))
+			}
+		},
+	}
+
+	jobsCmd.AddCommand(jobsImportCmd)
+}
+
+// importJob restores the plan files and logs of a job from an archive that was written by exportJob
+func importJob(archivePath string) (manifest jobArchiveManifest, err error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return manifest, fmt.Errorf("the file isn't a job archive: %w", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	manifest, err = readJobArchiveManifest(tarReader)
+	if err != nil {
+		return manifest, err
+	}
+
+	// the job must not be known here already, since its plan files would be overwritten
+	existing, err := listFilesWithPredicate(common.AzcopyJobPlanFolder, func(s string) bool {
+		return strings.HasPrefix(s, manifest.JobID.String()+"--")
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return manifest, err
+	}
+	if len(existing) > 0 {
+		return manifest, fmt.Errorf("job %s already exists on this machine; remove it with the rm command first to replace it", manifest.JobID)
+	}
+
+	for _, folder := range []string{common.AzcopyJobPlanFolder, azcopyLogPathFolder} {
+		if err = os.MkdirAll(folder, os.ModePerm); err != nil {
+			return manifest, err
+		}
+	}
+
+	// the files are staged under temporary names, and only put in place once all of them are in and the plans are checked
+	staged := make(map[string]string) // from the temporary path to the final one
+	defer func() {
+		if err != nil {
+			for tempPath := range staged {
+				_ = os.Remove(tempPath)
+			}
+		}
+	}()
+
+	expected := make(map[string]string) // from the name in the archive to the folder it goes to
+	planPaths := make(map[string]bool)
+	for _, name := range manifest.PlanFiles {
+		expected[path.Join(jobArchivePlansFolder, name)] = common.AzcopyJobPlanFolder
+		planPaths[filepath.Join(common.AzcopyJobPlanFolder, name)] = true
+	}
+	for _, name := range manifest.MemberFiles {
+		expected[path.Join(jobArchivePlansFolder, name)] = common.AzcopyJobPlanFolder
+	}
+	for _, name := range manifest.LogFiles {
+		expected[path.Join(jobArchiveLogsFolder, name)] = azcopyLogPathFolder
+	}
+
+	planParts := make(map[common.PartNumber]bool)
+	for {
+		var entry *tar.Header
+		entry, err = tarReader.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return manifest, err
+		}
+
+		folder, ok := expected[entry.Name]
+		if !ok || entry.Typeflag != tar.TypeReg {
+			return manifest, fmt.Errorf("unexpected entry %s in the job archive", entry.Name)
+		}
+		delete(expected, entry.Name)
+
+		name := path.Base(entry.Name)
+		finalPath := filepath.Join(folder, name)
+		tempPath := finalPath + jobImportTempSuffix
+		staged[tempPath] = finalPath
+		if err = extractJobArchiveEntry(tarReader, tempPath); err != nil {
+			return manifest, err
+		}
+
+		if planPaths[finalPath] {
+			var partNum common.PartNumber
+			partNum, err = checkImportedJobPartPlan(tempPath, name, manifest.JobID)
+			if err != nil {
+				return manifest, fmt.Errorf("invalid job plan file %s: %w", name, err)
+			}
+			planParts[partNum] = true
+		}
+	}
+
+	if len(expected) > 0 {
+		err = fmt.Errorf("the job archive is missing %d of the files that its manifest lists", len(expected))
+		return manifest, err
+	}
+	// the parts are numbered from 0, and jobs resume expects all of them
+	for i := 0; i < len(planParts); i++ {
+		if !planParts[common.PartNumber(i)] {
+			err = fmt.Errorf("the job archive is missing part %d of the job", i)
+			return manifest, err
+		}
+	}
+
+	// the logs and the member list go in first, so that the job is only visible once its plan files are all in place
+	for _, plans := range []bool{false, true} {
+		for tempPath, finalPath := range staged {
+			if planPaths[finalPath] != plans {
+				continue
+			}
+			if err = os.Rename(tempPath, finalPath); err != nil {
+				return manifest, err
+			}
+			delete(staged, tempPath)
+		}
+	}
+	return manifest, nil
+}
+
+func readJobArchiveManifest(tarReader *tar.Reader) (manifest jobArchiveManifest, err error) {
+	entry, err := tarReader.Next()
+	if err != nil {
+		return manifest, fmt.Errorf("the file isn't a job archive: %w", err)
+	}
+	if entry.Name != jobArchiveManifestName {
+		return manifest, errors.New("the file isn't a job archive, since it doesn't start with a manifest")
+	}
+	if err = json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("unable to read the manifest of the job archive: %w", err)
+	}
+
+	if manifest.Version != jobArchiveVersion {
+		return manifest, fmt.Errorf("the job archive has version %d, but this version of AzCopy only supports version %d", manifest.Version, jobArchiveVersion)
+	}
+	if manifest.DataSchemaVersion != ste.DataSchemaVersion {
+		return manifest, fmt.Errorf("the job plan files have version %d, but this version of AzCopy only supports version %d; import the job with the version of AzCopy that exported it",
+			manifest.DataSchemaVersion, ste.DataSchemaVersion)
+	}
+	if len(manifest.PlanFiles) == 0 {
+		return manifest, errors.New("the job archive has no job plan files")
+	}
+
+	// the member list is named after the job, and nothing else may be put next to the plan files
+	allowedMemberFiles := make(map[string]bool)
+	for _, name := range jobArchiveMemberFiles(manifest.JobID) {
+		allowedMemberFiles[name] = true
+	}
+	for _, name := range manifest.MemberFiles {
+		if !allowedMemberFiles[name] {
+			return manifest, fmt.Errorf("invalid archive member list %q in the manifest of the job archive", name)
+		}
+	}
+
+	// the names are used as file names, so they must not lead outside of the plan and log folders
+	for _, name := range append(append([]string{}, manifest.PlanFiles...), manifest.LogFiles...) {
+		if name == "" || name != filepath.Base(name) || name != path.Base(name) || name == "." || name == ".." {
+			return manifest, fmt.Errorf("invalid file name %q in the manifest of the job archive", name)
+		}
+	}
+	return manifest, nil
+}
+
+func extractJobArchiveEntry(tarReader *tar.Reader, tempPath string) error {
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, tarReader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// checkImportedJobPartPlan checks that a plan file belongs to the job, and that it's named after its part, as jobs resume expects
+func checkImportedJobPartPlan(tempPath string, name string, jobID common.JobID) (common.PartNumber, error) {
+	header, err := readJobPartPlanHeader(tempPath)
+	if err != nil {
+		return 0, err
+	}
+	if header.Version != ste.DataSchemaVersion {
+		return 0, fmt.Errorf("it has version %d, but this version of AzCopy only supports version %d", header.Version, ste.DataSchemaVersion)
+	}
+	if header.JobID != jobID {
+		return 0, fmt.Errorf("it belongs to job %s rather than %s", header.JobID, jobID)
+	}
+	if expectedName := fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), header.PartNum, ste.DataSchemaVersion); name != expectedName {
+		return 0, fmt.Errorf("it holds part %d of the job, which belongs in %s", header.PartNum, expectedName)
+	}
+	return header.PartNum, nil
+}
@@ -323,16 +323,32 @@ func (rca resumeCmdArgs) process() error {
 		}
 	}
 
+	// The credential above is for S3, when copying to it, so the source blob is resumed with its SAS, or with OAuth as it was copied
+	var s2sSourceCredentialType common.CredentialType
+	if getJobFromToResponse.FromTo == common.EFromTo.BlobS3() && rca.SourceSAS == "" {
+		if s2sSourceCredentialType, _, err = getCredentialTypeForLocation(ctx, common.ELocation.Blob(), getJobFromToResponse.Source, "", true, common.CpkOptions{}); err != nil {
+			return err
+		} else if s2sSourceCredentialType.IsAzureOAuth() {
+			uotm := GetUserOAuthTokenManagerInstance()
+			if tokenInfo, err := uotm.GetTokenInfo(ctx); err != nil {
+				return err
+			} else {
+				credentialInfo.OAuthTokenInfo = *tokenInfo
+			}
+		}
+	}
+
 	// Send resume job request.
 	var resumeJobResponse common.CancelPauseResumeResponse
 	Rpc(common.ERpcCmd.ResumeJob(),
 		&common.ResumeJobRequest{
-			JobID:           jobID,
-			SourceSAS:       rca.SourceSAS,
-			DestinationSAS:  rca.DestinationSAS,
-			CredentialInfo:  credentialInfo,
-			IncludeTransfer: includeTransfer,
-			ExcludeTransfer: excludeTransfer,
+			JobID:                   jobID,
+			SourceSAS:               rca.SourceSAS,
+			DestinationSAS:          rca.DestinationSAS,
+			CredentialInfo:          credentialInfo,
+			IncludeTransfer:         includeTransfer,
+			ExcludeTransfer:         excludeTransfer,
+			S2SSourceCredentialType: s2sSourceCredentialType,
 		},
 		&resumeJobResponse)
 
@@ -0,0 +1,267 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+func init() {
+	retryCmdArgs := retryFailedCmdArgs{}
+
+	// retryFailedCmd represents the retry-failed command
+	retryFailedCmd := &cobra.Command{
+		Use:     "retry-failed [jobID]",
+		Short:   retryFailedJobsCmdShortDescription,
+		Long:    retryFailedJobsCmdLongDescription,
+		Example: retryFailedJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			// If no argument is passed then it is not valid
+			if len(args) != 1 {
+				return errors.New("this command requires jobId to be passed as argument")
+			}
+			retryCmdArgs.jobID = args[0]
+
+			glcm.EnableInputWatcher()
+			if cancelFromStdin {
+				glcm.EnableCancelFromStdIn()
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := retryCmdArgs.process()
+			if err != nil {
+				glcm.Error(fmt.Sprintf("failed to perform retry-failed command due to error: %s", err.Error()))
+			}
+			glcm.Exit(nil, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(retryFailedCmd)
+	retryFailedCmd.PersistentFlags().StringVar(&retryCmdArgs.destination, "destination", "", "Retry the failed transfers to this destination instead of the destination of the job. "+
+		"It must be of the same type, and it takes the place of the original destination, so the transfers keep their paths relative to it.")
+	retryFailedCmd.PersistentFlags().StringVar(&retryCmdArgs.SourceSAS, "source-sas", "", "Source SAS token of the source of the job.")
+	retryFailedCmd.PersistentFlags().StringVar(&retryCmdArgs.DestinationSAS, "destination-sas", "", "Destination SAS token of the destination of the job, or of the one given with --destination.")
+}
+
+type retryFailedCmdArgs struct {
+	jobID       string
+	destination string
+
+	SourceSAS      string
+	DestinationSAS string
+}
+
+// processes the retry-failed command:
+// creates a new job from the failed transfers of the given one, and then runs it like a resumed job
+func (rca retryFailedCmdArgs) process() error {
+	jobID, err := common.ParseJobID(rca.jobID)
+	if err != nil {
+		// If parsing gives an error, hence it is not a valid JobId format
+		return fmt.Errorf("error parsing the jobId %s. Failed with error %s", rca.jobID, err.Error())
+	}
+
+	var destination *common.ResourceString
+	destinationSAS := rca.DestinationSAS
+	if rca.destination != "" {
+		fromTo, err := readJobFromTo(jobID)
+		if err != nil {
+			return err
+		}
+		d, err := SplitResourceString(rca.destination, fromTo.To())
+		if err != nil {
+			return err
+		}
+		// a SAS in the new destination is used unless one is given separately
+		if destinationSAS == "" {
+			destinationSAS = d.SAS
+		}
+		d.SAS = ""
+		destination = &d
+	}
+
+	retryJobID := common.NewJobID()
+	link, err := createRetryJob(jobID, retryJobID, destination)
+	if err != nil {
+		return err
+	}
+	glcm.Info(fmt.Sprintf("Retrying the %d failed transfers of job %s as job %s.", link.Transfers, jobID, retryJobID))
+
+	return resumeCmdArgs{
+		jobID:          retryJobID.String(),
+		SourceSAS:      rca.SourceSAS,
+		DestinationSAS: destinationSAS,
+	}.process()
+}
+
+// readJobFromTo reads the FromTo of a job from its first plan file, without loading the job
+func readJobFromTo(jobID common.JobID) (common.FromTo, error) {
+	header, err := readJobPartPlanHeader(common.GenerateFullPath(common.AzcopyJobPlanFolder,
+		fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion)))
+	if os.IsNotExist(err) {
+		return common.EFromTo.Unknown(), fmt.Errorf("cannot find any job plan file of version %d with the specified ID", ste.DataSchemaVersion)
+	} else if err != nil {
+		return common.EFromTo.Unknown(), err
+	}
+	return header.FromTo, nil
+}
+
+// createRetryJob writes the plan files of a new job that holds the failed transfers of the given job, with all of its options,
+// and records the link between the two jobs. The parts of the job without failed transfers are left out.
+func createRetryJob(jobID common.JobID, retryJobID common.JobID, destination *common.ResourceString) (link ste.RetryLink, err error) {
+	planExtension := fmt.Sprintf(".steV%d", ste.DataSchemaVersion)
+	planFiles, err := listFilesWithPredicate(common.AzcopyJobPlanFolder, func(s string) bool {
+		return strings.HasPrefix(s, jobID.String()+"--") && strings.HasSuffix(s, planExtension)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return link, err
+	}
+	if len(planFiles) == 0 {
+		return link, fmt.Errorf("cannot find any job plan file of version %d with the specified ID", ste.DataSchemaVersion)
+	}
+	// the part numbers are padded, so the plan files sort in order
+	sort.Strings(planFiles)
+
+	type retryPart struct {
+		mmf    *ste.JobPartPlanMMF
+		failed []uint32
+	}
+	var parts []retryPart
+	defer func() {
+		for _, p := range parts {
+			p.mmf.Unmap()
+		}
+	}()
+
+	link = ste.RetryLink{JobID: retryJobID, RetryOf: jobID, CreatedAt: time.Now().UTC()}
+	for i, name := range planFiles {
+		mmf := ste.JobPartPlanFileName(name).Map()
+		plan := mmf.Plan()
+		parts = append(parts, retryPart{mmf: mmf, failed: plan.FailedTransfers()})
+		link.Transfers += uint32(len(parts[i].failed))
+
+		if i != 0 {
+			// the retry job is resumed, which isn't possible for a job that transfers in both directions, as a two-way sync does
+			if fromTo := parts[0].mmf.Plan().FromTo; plan.FromTo != fromTo {
+				return link, fmt.Errorf("job %s transfers both %s and %s, as a two-way sync does, which can't be retried. Run the sync again instead, which also retries the files that failed",
+					jobID, fromTo, plan.FromTo)
+			}
+			continue
+		}
+		if plan.FromTo.From() == common.ELocation.Benchmark() || plan.FromTo.To() == common.ELocation.Benchmark() {
+			// Doesn't make sense to retry a benchmark job, for the same reasons that it isn't resumed
+			return link, errors.New("retrying benchmark jobs is not supported")
+		}
+		if status := plan.JobStatus(); status == common.EJobStatus.InProgress() || status == common.EJobStatus.Cancelling() {
+			glcm.Info(fmt.Sprintf("The job's status is %s. Make sure that it's no longer running, since only the transfers that have failed so far are retried.", status))
+		}
+	}
+	if link.Transfers == 0 {
+		return link, fmt.Errorf("job %s has no failed transfers to retry", jobID)
+	}
+	if destination != nil {
+		link.Destination = destination.Value
+	}
+
+	var created []string
+	defer func() {
+		if err != nil {
+			for _, path := range created {
+				_ = os.Remove(path)
+			}
+		}
+	}()
+
+	lastPart := len(parts) - 1
+	for lastPart >= 0 && len(parts[lastPart].failed) == 0 {
+		lastPart--
+	}
+	partNum := common.PartNumber(0)
+	for i, p := range parts {
+		if len(p.failed) == 0 {
+			continue
+		}
+		planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, retryJobID.String(), partNum, ste.DataSchemaVersion))
+		if err = planFile.CreateRetry(p.mmf.Plan(), p.failed, i == lastPart, destination); err != nil {
+			return link, err
+		}
+		created = append(created, planFile.GetJobPartPlanPath())
+		partNum++
+	}
+
+	// the members of an archive are listed separately from the plan, so the retry job needs its own copy of the list
+	if _, statErr := os.Stat(ste.ArchiveMemberListPath(jobID)); statErr == nil {
+		if err = copyLocalFile(ste.ArchiveMemberListPath(jobID), ste.ArchiveMemberListPath(retryJobID)); err != nil {
+			return link, err
+		}
+		created = append(created, ste.ArchiveMemberListPath(retryJobID))
+	}
+
+	err = ste.WriteRetryLink(link)
+	return link, err
+}
+
+func copyLocalFile(source string, destination string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destinationFile, err := os.OpenFile(destination, os.O_CREATE|os.O_EXCL|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destinationFile, sourceFile)
+	if closeErr := destinationFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// printRetryLinks tells which job the given one retries, and which jobs retried it, so that a chain of retries can be followed
+func printRetryLinks(jobID common.JobID) {
+	links, err := ste.ReadRetryLinks()
+	if err != nil {
+		return
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.Before(links[j].CreatedAt) })
+
+	for _, link := range links {
+		switch jobID {
+		case link.JobID:
+			glcm.Info(fmt.Sprintf("Job %s retries %d failed transfers of job %s.", link.JobID, link.Transfers, link.RetryOf))
+		case link.RetryOf:
+			glcm.Info(fmt.Sprintf("%d failed transfers of job %s were retried by job %s.", link.Transfers, link.RetryOf, link.JobID))
+		}
+	}
+}
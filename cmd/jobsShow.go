@@ -34,6 +34,18 @@ import (
 type ListReq struct {
 	JobID    common.JobID
 	OfStatus string
+
+	// filters, order and export of the list of transfers
+	PathPattern     string
+	MinSize         string
+	MaxSize         string
+	ErrorCodes      string
+	CompletedAfter  string
+	CompletedBefore string
+	SortBy          string
+	Descending      bool
+	ExportPath      string
+	ExportFormat    string
 }
 
 func init() {
@@ -41,9 +53,10 @@ func init() {
 
 	// shJob represents the ls command
 	shJob := &cobra.Command{
-		Use:   "show [jobID]",
-		Short: showJobsCmdShortDescription,
-		Long:  showJobsCmdLongDescription,
+		Use:     "show [jobID]",
+		Short:   showJobsCmdShortDescription,
+		Long:    showJobsCmdLongDescription,
+		Example: showJobsCmdExample,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("show job command requires only the JobID")
@@ -61,7 +74,10 @@ func init() {
 			listRequest.JobID = commandLineInput.JobID
 			listRequest.OfStatus = commandLineInput.OfStatus
 
-			err := HandleShowCommand(listRequest)
+			options, err := commandLineInput.cookTransferListOptions()
+			if err == nil {
+				err = HandleShowCommand(listRequest, options)
+			}
 			if err == nil {
 				glcm.Exit(nil, common.EExitCode.Success())
 			} else {
@@ -74,28 +90,51 @@ func init() {
 
 	// filters
 	shJob.PersistentFlags().StringVar(&commandLineInput.OfStatus, "with-status", "", "Only list the transfers of job with this status, available values: All, Started, Success, Failed.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.PathPattern, "path-pattern", "", "Only list the transfers whose source matches this wildcard pattern, relative to the source of the job, e.g. 'logs/2023/*'. "+
+		"A pattern without a '/' is matched against the name of the file, e.g. '*.pdf'.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.MinSize, "min-size", "", "Only list the transfers of files of at least this size, in bytes, or as "+sizeStringDescription+".")
+	shJob.PersistentFlags().StringVar(&commandLineInput.MaxSize, "max-size", "", "Only list the transfers of files of at most this size, in bytes, or as "+sizeStringDescription+".")
+	shJob.PersistentFlags().StringVar(&commandLineInput.ErrorCodes, "error-code", "", "Only list the transfers that failed with one of these HTTP status codes, separated by commas, e.g. '403,404'.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.CompletedAfter, "completed-after", "", "Only list the transfers that were done, whether they succeeded or failed, on or after this date/time, in ISO8601 format. "+
+		"If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.CompletedBefore, "completed-before", "", "Only list the transfers that were done, whether they succeeded or failed, on or before this date/time, in ISO8601 format. "+
+		"If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.SortBy, "sort-by", "", "Sort the transfers by one of: "+strings.Join(transferSortKeys, ", ")+". By default, they are listed in the order of the job.")
+	shJob.PersistentFlags().BoolVar(&commandLineInput.Descending, "descending", false, "Sort the transfers in descending order.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.ExportPath, "export", "", "Write the transfers to this file instead of listing them.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.ExportFormat, "export-format", "", "The format of the file given with --export: CSV, or NDJSON for one JSON object per line. "+
+		"By default, NDJSON if the file name ends with '.ndjson' or '.jsonl', and CSV otherwise.")
 }
 
 // handles the list command
 // dispatches the list order to the transfer engine
-func HandleShowCommand(listRequest common.ListRequest) error {
-	if listRequest.OfStatus == "" {
+func HandleShowCommand(listRequest common.ListRequest, options transferListOptions) error {
+	if listRequest.OfStatus == "" && !options.isSet() {
 		resp := common.ListJobSummaryResponse{}
 		rpcCmd := common.ERpcCmd.ListJobSummary()
 		Rpc(rpcCmd, &listRequest.JobID, &resp)
+		printRetryLinks(listRequest.JobID)
 		PrintJobProgressSummary(resp)
 	} else {
-		lsRequest := common.ListJobTransfersRequest{}
+		lsRequest := options.filters
 		lsRequest.JobID = listRequest.JobID
-		// Parse the given expected Transfer Status
-		// If there is an error parsing, then kill return the error
-		err := lsRequest.OfStatus.Parse(listRequest.OfStatus)
-		if err != nil {
-			return fmt.Errorf("cannot parse the given Transfer Status %s", listRequest.OfStatus)
+		// the filters apply to all the transfers unless a status is given too
+		lsRequest.OfStatus = common.ETransferStatus.All()
+		if listRequest.OfStatus != "" {
+			// Parse the given expected Transfer Status
+			// If there is an error parsing, then kill return the error
+			err := lsRequest.OfStatus.Parse(listRequest.OfStatus)
+			if err != nil {
+				return fmt.Errorf("cannot parse the given Transfer Status %s", listRequest.OfStatus)
+			}
 		}
 		resp := common.ListJobTransfersResponse{}
 		rpcCmd := common.ERpcCmd.ListJobTransfers()
 		Rpc(rpcCmd, lsRequest, &resp)
+		options.sort(resp.Details)
+		if options.exportPath != "" {
+			return ExportJobTransfers(resp, options.exportPath, options.exportFormat)
+		}
 		PrintJobTransfers(resp)
 	}
 	return nil
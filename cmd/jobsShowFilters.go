@@ -0,0 +1,209 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// transferListOptions holds the filters, the order and the export file of the list of transfers of a job
+type transferListOptions struct {
+	filters      common.ListJobTransfersRequest // the job ID and the status are set separately
+	sortBy       string
+	descending   bool
+	exportPath   string
+	exportFormat string
+}
+
+const (
+	transferExportFormatCSV    = "csv"
+	transferExportFormatNDJSON = "ndjson"
+)
+
+// the keys that the transfers can be sorted by, with how each one orders a pair of transfers
+var transferSortKeys = []string{"source", "destination", "size", "status", "error-code", "completion-time"}
+
+var transferLessFuncs = map[string]func(a, b common.TransferDetail) bool{
+	"source":      func(a, b common.TransferDetail) bool { return a.Src < b.Src },
+	"destination": func(a, b common.TransferDetail) bool { return a.Dst < b.Dst },
+	"size":        func(a, b common.TransferDetail) bool { return a.TransferSize < b.TransferSize },
+	"status":      func(a, b common.TransferDetail) bool { return a.TransferStatus.String() < b.TransferStatus.String() },
+	"error-code":  func(a, b common.TransferDetail) bool { return a.ErrorCode < b.ErrorCode },
+	"completion-time": func(a, b common.TransferDetail) bool {
+		// transfers that aren't done yet come last
+		if a.CompletionTime == nil || b.CompletionTime == nil {
+			return a.CompletionTime != nil && b.CompletionTime == nil
+		}
+		return a.CompletionTime.Before(*b.CompletionTime)
+	},
+}
+
+func (raw ListReq) cookTransferListOptions() (options transferListOptions, err error) {
+	if raw.PathPattern != "" {
+		if _, err = path.Match(raw.PathPattern, ""); err != nil {
+			return options, fmt.Errorf("invalid path pattern %s: %w", raw.PathPattern, err)
+		}
+		options.filters.PathPattern = raw.PathPattern
+	}
+	if raw.MinSize != "" {
+		if options.filters.MinSize, err = parseByteSize(raw.MinSize, "min-size"); err != nil {
+			return options, err
+		}
+	}
+	if raw.MaxSize != "" {
+		if options.filters.MaxSize, err = parseByteSize(raw.MaxSize, "max-size"); err != nil {
+			return options, err
+		}
+		if options.filters.MaxSize < options.filters.MinSize {
+			return options, fmt.Errorf("max-size must not be smaller than min-size")
+		}
+	}
+	if raw.ErrorCodes != "" {
+		for _, s := range strings.Split(raw.ErrorCodes, ",") {
+			errorCode, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return options, fmt.Errorf("invalid error code %s; error codes must be HTTP status codes, e.g. 403", s)
+			}
+			options.filters.ErrorCodes = append(options.filters.ErrorCodes, int32(errorCode))
+		}
+	}
+	if raw.CompletedAfter != "" {
+		if options.filters.CompletedAfter, err = parseISO8601(raw.CompletedAfter, true); err != nil {
+			return options, err
+		}
+	}
+	if raw.CompletedBefore != "" {
+		if options.filters.CompletedBefore, err = parseISO8601(raw.CompletedBefore, false); err != nil {
+			return options, err
+		}
+	}
+
+	if raw.SortBy != "" {
+		options.sortBy = strings.ToLower(raw.SortBy)
+		if _, ok := transferLessFuncs[options.sortBy]; !ok {
+			return options, fmt.Errorf("cannot sort by %s; the transfers can be sorted by %s", raw.SortBy, strings.Join(transferSortKeys, ", "))
+		}
+	} else if raw.Descending {
+		return options, fmt.Errorf("descending requires sort-by")
+	}
+	options.descending = raw.Descending
+
+	options.exportPath = raw.ExportPath
+	switch strings.ToLower(raw.ExportFormat) {
+	case "":
+		options.exportFormat = transferExportFormatCSV
+		if ext := strings.ToLower(filepath.Ext(raw.ExportPath)); ext == ".ndjson" || ext == ".jsonl" {
+			options.exportFormat = transferExportFormatNDJSON
+		}
+	case transferExportFormatCSV, transferExportFormatNDJSON:
+		if raw.ExportPath == "" {
+			return options, fmt.Errorf("export-format requires export")
+		}
+		options.exportFormat = strings.ToLower(raw.ExportFormat)
+	default:
+		return options, fmt.Errorf("invalid export format %s; the transfers can be exported as CSV or NDJSON", raw.ExportFormat)
+	}
+	return options, nil
+}
+
+// isSet says whether any of the options are set, in which case the transfers are listed, rather than the summary of the job
+func (o transferListOptions) isSet() bool {
+	f := o.filters
+	return f.PathPattern != "" || f.MinSize != 0 || f.MaxSize != 0 || len(f.ErrorCodes) != 0 || !f.CompletedAfter.IsZero() || !f.CompletedBefore.IsZero() ||
+		o.sortBy != "" || o.exportPath != ""
+}
+
+func (o transferListOptions) sort(details []common.TransferDetail) {
+	less, ok := transferLessFuncs[o.sortBy]
+	if !ok {
+		return
+	}
+	sort.SliceStable(details, func(i, j int) bool {
+		if o.descending {
+			return less(details[j], details[i])
+		}
+		return less(details[i], details[j])
+	})
+}
+
+// parseByteSize parses a number of bytes, which may also be given with a K, M or G suffix
+func parseByteSize(s string, name string) (int64, error) {
+	if bytes, err := strconv.ParseInt(s, 10, 64); err == nil && bytes >= 0 {
+		return bytes, nil
+	}
+	return ParseSizeString(s, name)
+}
+
+// ExportJobTransfers writes the transfers of the response to a CSV or NDJSON file
+func ExportJobTransfers(listTransfersResponse common.ListJobTransfersResponse, exportPath string, format string) error {
+	if listTransfersResponse.ErrorMsg != "" {
+		return fmt.Errorf("request failed with following message %s", listTransfersResponse.ErrorMsg)
+	}
+
+	file, err := os.Create(exportPath)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case transferExportFormatNDJSON:
+		encoder := json.NewEncoder(file)
+		for _, detail := range listTransfersResponse.Details {
+			if err = encoder.Encode(detail); err != nil {
+				break
+			}
+		}
+	default:
+		writer := csv.NewWriter(file)
+		_ = writer.Write([]string{"Source", "Destination", "IsFolderProperties", "TransferStatus", "TransferSize", "ErrorCode", "CompletionTime"})
+		for _, detail := range listTransfersResponse.Details {
+			completionTime := ""
+			if detail.CompletionTime != nil {
+				completionTime = detail.CompletionTime.UTC().Format(time.RFC3339)
+			}
+			_ = writer.Write([]string{detail.Src, detail.Dst, strconv.FormatBool(detail.IsFolderProperties), detail.TransferStatus.String(),
+				strconv.FormatUint(detail.TransferSize, 10), strconv.FormatInt(int64(detail.ErrorCode), 10), completionTime})
+		}
+		writer.Flush()
+		err = writer.Error()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot export the transfers to %s: %w", exportPath, err)
+	}
+
+	glcm.Exit(func(format common.OutputFormat) string {
+		return fmt.Sprintf("Exported %d transfers of job %s to %s.", len(listTransfersResponse.Details), listTransfersResponse.JobID, exportPath)
+	}, common.EExitCode.Success())
+	return nil
+}
@@ -56,6 +56,19 @@ func DetermineLocationLevel(location string, locationType common.Location, sourc
 	case common.ELocation.Benchmark():
 		return ELocationLevel.Object(), nil // we always benchmark to a subfolder, not the container root
 
+	case common.ELocation.HTTP():
+		URL, err := url.Parse(location)
+
+		if err != nil {
+			return ELocationLevel.Service(), err
+		}
+
+		// without a listing API, a directory is just a base URL for --list-of-files
+		if isHTTPDirectorySyntactically(URL) {
+			return ELocationLevel.Container(), nil
+		}
+		return ELocationLevel.Object(), nil
+
 	case common.ELocation.Blob(),
 		common.ELocation.File(),
 		common.ELocation.BlobFS(),
@@ -100,7 +113,8 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 	// todo: reduce code-delicateness, maybe?
 	switch location {
 	case common.ELocation.Unknown(),
-		common.ELocation.Benchmark(): // do nothing
+		common.ELocation.Benchmark(),
+		common.ELocation.HTTP(): // do nothing, wildcards have no meaning in a generic URL
 		return resource, nil
 	case common.ELocation.Local():
 		return cleanLocalPath(getPathBeforeFirstWildcard(resource)), nil
@@ -220,6 +234,20 @@ func splitAuthTokenFromResource(resource string, location common.Location) (reso
 		return baseURL.String(), "", nil
 	case common.ELocation.GCP():
 		return resource, "", nil
+	case common.ELocation.HTTP():
+		// We can't tell which query parameters of a generic URL are credentials (e.g. a pre-signed URL from another cloud),
+		// so treat the whole query as the token, which keeps it out of the plan files and logs like a SAS.
+		var baseURL *url.URL
+		baseURL, err = url.Parse(resource)
+
+		if err != nil {
+			return resource, "", err
+		}
+
+		resourceToken = baseURL.RawQuery
+		baseURL.RawQuery = ""
+		resourceBase = baseURL.String()
+		return
 	case common.ELocation.Benchmark(), // cover for benchmark as we generate data for that
 		common.ELocation.Unknown(), // cover for unknown as we treat that as garbage
 		common.ELocation.None():
@@ -69,6 +69,9 @@ type jobLoggerInfo struct {
 // it as a global
 var cmdLineExtraSuffixesAAD string
 
+// Like the AWS credentials, the S3-compatible endpoint applies to the whole process, and is read directly by the S3 URL parsing in common.
+var cmdLineS3Endpoint string
+
 // It would be preferable if this was a local variable, since it just gets altered and shot off to the STE
 var debugSkipFiles string
 
@@ -108,6 +111,11 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+
+		err = common.SetS3CustomEndpoint(cmdLineS3Endpoint)
+		if err != nil {
+			return err
+		}
 		common.AzcopyCurrentJobLogger = common.NewJobLogger(loggerInfo.jobID, azcopyLogVerbosity, loggerInfo.logFileFolder, "")
 		common.AzcopyCurrentJobLogger.OpenLog()
 
@@ -216,6 +224,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cmdLineExtraSuffixesAAD, trustedSuffixesNameAAD, "", "Specifies additional domain suffixes where Azure Active Directory login tokens may be sent.  The default is '"+
 		trustedSuffixesAAD+"'. Any listed here are added to the default. For security, you should only put Microsoft Azure domains here. Separate multiple entries with semi-colons.")
 
+	rootCmd.PersistentFlags().StringVar(&cmdLineS3Endpoint, "s3-endpoint", "", "Specifies the endpoint of an S3-compatible service other than AWS, such as MinIO or Ceph, e.g. 'minio.contoso.com:9000'. "+
+		"URLs on this endpoint are then treated as S3 URLs, in either path style or virtual-hosted style, and plain HTTP is used if the URL starts with http://. "+
+		"The AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are sent to this endpoint. "+
+		"For service-to-service copies to Azure, the endpoint must be reachable from Azure Storage. Supply it again when resuming a job.")
+
 	rootCmd.PersistentFlags().BoolVar(&azcopySkipVersionCheck, "skip-version-check", false, "Do not perform the version check at startup. Intended for automation scenarios & airgapped use.")
 
 	// Note: this is due to Windows not supporting signals properly
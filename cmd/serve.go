@@ -0,0 +1,627 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+const defaultServeListenAddress = "localhost:1337"
+
+// name of the file, in the plan folder, to which the token that authorizes the requests is written by default
+const defaultServeTokenFileName = "serve.token"
+
+// how long the requests in progress are given to finish when the server is stopped
+const serveShutdownTimeout = 30 * time.Second
+
+// how often the job managers of the jobs that are no longer running are cleaned up
+const serveCleanupInterval = time.Minute
+
+func init() {
+	serveArgs := serveCmdArgs{}
+
+	// serveCmd represents the serve command
+	serveCmd := &cobra.Command{
+		Use:     "serve",
+		Short:   serveCmdShortDescription,
+		Long:    serveCmdLongDescription,
+		Example: serveCmdExample,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := serveArgs.process()
+			if err != nil {
+				glcm.Error(fmt.Sprintf("failed to perform serve command due to error: %s", err.Error()))
+			}
+		},
+	}
+
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.PersistentFlags().StringVar(&serveArgs.listen, "listen", defaultServeListenAddress, "Serve the job API over HTTP at this address. "+
+		"It must be a loopback address, since the requests carry the credentials of the jobs.")
+	serveCmd.PersistentFlags().StringVar(&serveArgs.socket, "socket", "", "Serve the job API over HTTP on a Unix socket created at this path, instead of at a TCP address. "+
+		"Only the current user can connect to the socket.")
+	serveCmd.PersistentFlags().StringVar(&serveArgs.tokenFile, "token-file", "", "Write the token that authorizes the requests to this file, "+
+		"which only the current user can read. The default is "+defaultServeTokenFileName+" in the plan folder.")
+}
+
+type serveCmdArgs struct {
+	listen    string
+	socket    string
+	tokenFile string
+}
+
+// processes the serve command:
+// serves the job API until the process is interrupted, and then pauses the jobs still in progress
+func (sca serveCmdArgs) process() error {
+	listener, err := sca.createListener()
+	if err != nil {
+		return err
+	}
+
+	tokenFile := sca.tokenFile
+	if tokenFile == "" {
+		tokenFile = filepath.Join(common.AzcopyJobPlanFolder, defaultServeTokenFileName)
+	}
+	token, err := createServeToken(tokenFile)
+	if err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to create the token file: %w", err)
+	}
+
+	// the jobs of this process each get their own log, and an authentication failure doesn't cancel them,
+	// since the credentials can be replaced by resuming the job
+	jobsAdmin.JobsAdmin.SetDaemonMode()
+
+	js := newJobServer(token)
+	server := &http.Server{Handler: js.handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	go js.cleanUpLoop()
+	glcm.Info(fmt.Sprintf("Serving the job API at %s, to the requests authorized with the token in %s. Press Ctrl-C to stop serving and pause the jobs in progress.",
+		listener.Addr(), tokenFile))
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err = <-serveErr:
+		_ = os.Remove(tokenFile)
+		return err
+	case <-stop:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		glcm.Info("Some requests didn't finish before the job API was stopped: " + err.Error())
+	}
+
+	paused := js.pauseRunningJobs()
+	_ = os.Remove(tokenFile)
+	glcm.Exit(func(format common.OutputFormat) string {
+		return fmt.Sprintf("Stopped serving the job API. %d jobs in progress were paused, and can be resumed later.", paused)
+	}, common.EExitCode.Success())
+	return nil
+}
+
+// createListener listens on the Unix socket if one is given, or else on the TCP address, which must be a loopback one
+func (sca serveCmdArgs) createListener() (net.Listener, error) {
+	if sca.socket != "" {
+		if sca.listen != defaultServeListenAddress {
+			return nil, errors.New("only one of --listen and --socket can be given")
+		}
+		if err := removeStaleSocket(sca.socket); err != nil {
+			return nil, err
+		}
+		return listenOnPrivateSocket(sca.socket)
+	}
+
+	listener, err := net.Listen("tcp", sca.listen)
+	if err != nil {
+		return nil, err
+	}
+	// checked once bound, so that host names and unspecified hosts are resolved the way they're actually listened on
+	if addr, ok := listener.Addr().(*net.TCPAddr); !ok || !addr.IP.IsLoopback() {
+		_ = listener.Close()
+		return nil, fmt.Errorf("the job API can only be served at a loopback address, but %q is listened on at %s", sca.listen, listener.Addr())
+	}
+	return listener, nil
+}
+
+// listenOnPrivateSocket listens on a Unix socket at the given path, which only the current user can connect to.
+// The socket is created in a directory that only the current user can enter, and moved to its path once its permissions
+// are set, so that nobody else can connect to it in between.
+func listenOnPrivateSocket(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".azcopy-serve-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err = os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	privatePath := filepath.Join(dir, filepath.Base(path))
+	listener, err := net.Listen("unix", privatePath)
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed from its final path instead
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(privatePath, 0600); err == nil {
+		err = os.Rename(privatePath, path)
+	}
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return &socketListener{Listener: listener, path: path}, nil
+}
+
+// socketListener removes its socket when it's closed
+type socketListener struct {
+	net.Listener
+	path string
+}
+
+// Addr returns the path of the socket, instead of the one where it was created
+func (l *socketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+// createServeToken writes a new random token to the given file, which only the current user can read
+func createServeToken(path string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	// the file is created anew, so that it can't be one that somebody else prepared
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.WriteString(token); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return token, f.Close()
+}
+
+// removeStaleSocket removes the socket left behind at the given path by a server that is no longer running
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and isn't a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("another server is already listening on %s", path)
+	}
+	return os.Remove(path)
+}
+
+// jobServer serves the RpcCmd operations of the storage engine over HTTP, as cmd/rpc.go does in process,
+// so that one process can run the jobs of many clients, which share its pools of connections and memory.
+// Each operation is a POST to the pattern of its RpcCmd, with the request as the JSON body and the response as the JSON result.
+type jobServer struct {
+	// the token that a request must carry as its bearer token
+	token string
+	// guards jobsRun and jobLocks, and is only held while they're read or changed
+	mu sync.Mutex
+	// the jobs that were ordered or resumed by this process
+	jobsRun map[common.JobID]bool
+	// the requests for a job are handled one at a time, like the front end of a command sends them,
+	// while those for different jobs are handled at the same time. A job is in the map while a request for it is handled or waits.
+	jobLocks map[common.JobID]*jobLock
+}
+
+// jobLock is held while a request for a job is handled, and counts the requests that hold it or wait for it
+type jobLock struct {
+	sync.Mutex
+	refs int
+}
+
+// jobServerError is an error in a request, which is returned to the client with the given HTTP status
+type jobServerError struct {
+	status int
+	msg    string
+}
+
+func (e jobServerError) Error() string {
+	return e.msg
+}
+
+func newJobServer(token string) *jobServer {
+	return &jobServer{token: token, jobsRun: map[common.JobID]bool{}, jobLocks: map[common.JobID]*jobLock{}}
+}
+
+// lockJob waits until no other request for the job is handled, and returns the function that lets the next one in
+func (js *jobServer) lockJob(jobID common.JobID) (unlock func()) {
+	js.mu.Lock()
+	l, found := js.jobLocks[jobID]
+	if !found {
+		l = &jobLock{}
+		js.jobLocks[jobID] = l
+	}
+	l.refs++
+	js.mu.Unlock()
+
+	l.Lock()
+	return func() { js.unlockJob(jobID, l) }
+}
+
+// tryLockJob is lockJob for a job that no request is handled for or waits for, and returns false for any other
+func (js *jobServer) tryLockJob(jobID common.JobID) (unlock func(), ok bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if _, busy := js.jobLocks[jobID]; busy {
+		return nil, false
+	}
+	l := &jobLock{refs: 1}
+	l.Lock()
+	js.jobLocks[jobID] = l
+	return func() { js.unlockJob(jobID, l) }, true
+}
+
+func (js *jobServer) unlockJob(jobID common.JobID, l *jobLock) {
+	l.Unlock()
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if l.refs--; l.refs == 0 {
+		delete(js.jobLocks, jobID)
+	}
+}
+
+func (js *jobServer) hasRun(jobID common.JobID) bool {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.jobsRun[jobID]
+}
+
+func (js *jobServer) setRun(jobID common.JobID, run bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if run {
+		js.jobsRun[jobID] = true
+	} else {
+		delete(js.jobsRun, jobID)
+	}
+}
+
+func (js *jobServer) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	js.handle(mux, common.ERpcCmd.CopyJobPartOrder(), func(decode func(v interface{}) error) (interface{}, error) {
+		var order common.CopyJobPartOrderRequest
+		if err := decode(&order); err != nil {
+			return nil, err
+		}
+		if order.JobID.IsEmpty() {
+			return nil, jobServerError{http.StatusBadRequest, "the order has no JobID"}
+		}
+		defer js.lockJob(order.JobID)()
+		if err := js.checkJobPartOrder(order); err != nil {
+			return nil, err
+		}
+		js.setRun(order.JobID, true)
+		return jobsAdmin.ExecuteNewCopyJobPartOrder(order), nil
+	})
+	js.handle(mux, common.ERpcCmd.ListJobs(), func(decode func(v interface{}) error) (interface{}, error) {
+		// all the jobs are listed unless a status is given
+		status := common.EJobStatus.All()
+		if err := decode(&status); err != nil {
+			return nil, err
+		}
+		return jobsAdmin.ListJobs(status), nil
+	})
+	js.handle(mux, common.ERpcCmd.ListJobSummary(), func(decode func(v interface{}) error) (interface{}, error) {
+		var jobID common.JobID
+		if err := decode(&jobID); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(jobID)()
+		return jobsAdmin.GetJobSummary(jobID), nil
+	})
+	js.handle(mux, common.ERpcCmd.ListJobTransfers(), func(decode func(v interface{}) error) (interface{}, error) {
+		var request common.ListJobTransfersRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(request.JobID)()
+		return jobsAdmin.ListJobTransfers(request), nil
+	})
+	js.handle(mux, common.ERpcCmd.CancelJob(), func(decode func(v interface{}) error) (interface{}, error) {
+		var jobID common.JobID
+		if err := decode(&jobID); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(jobID)()
+		return jobsAdmin.CancelPauseJobOrder(jobID, common.EJobStatus.Cancelling()), nil
+	})
+	js.handle(mux, common.ERpcCmd.PauseJob(), func(decode func(v interface{}) error) (interface{}, error) {
+		var jobID common.JobID
+		if err := decode(&jobID); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(jobID)()
+		return jobsAdmin.CancelPauseJobOrder(jobID, common.EJobStatus.Paused()), nil
+	})
+	js.handle(mux, common.ERpcCmd.ResumeJob(), func(decode func(v interface{}) error) (interface{}, error) {
+		var request common.ResumeJobRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(request.JobID)()
+		if js.isRunning(request.JobID) {
+			return nil, jobServerError{http.StatusConflict, fmt.Sprintf("job %s is still running; it must be paused or cancelled, and stopped, before it's resumed", request.JobID)}
+		}
+		if js.hasRun(request.JobID) {
+			// a job manager runs its job only once, so the job is resumed by a new one
+			jobsAdmin.JobsAdmin.JobMgrCleanUp(request.JobID)
+		}
+		js.setRun(request.JobID, true)
+		return jobsAdmin.ResumeJobOrder(request), nil
+	})
+	js.handle(mux, common.ERpcCmd.GetJobFromTo(), func(decode func(v interface{}) error) (interface{}, error) {
+		var request common.GetJobFromToRequest
+		if err := decode(&request); err != nil {
+			return nil, err
+		}
+		defer js.lockJob(request.JobID)()
+		return jobsAdmin.GetJobFromTo(request), nil
+	})
+
+	return mux
+}
+
+// handle registers the operation of the given RpcCmd. The operation decodes its request with the given function,
+// which leaves the request as it is if the body is empty.
+func (js *jobServer) handle(mux *http.ServeMux, rpcCmd common.RpcCmd, operation func(decode func(v interface{}) error) (interface{}, error)) {
+	mux.HandleFunc(rpcCmd.Pattern(), func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.Header().Set("Allow", http.MethodPost)
+			http.Error(writer, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := js.checkOrigin(request); err != nil {
+			writeJobServerError(writer, err)
+			return
+		}
+
+		decode := func(v interface{}) error {
+			err := json.NewDecoder(request.Body).Decode(v)
+			if err != nil && err != io.EOF {
+				return jobServerError{http.StatusBadRequest, fmt.Sprintf("invalid %s request: %s", rpcCmd.String(), err.Error())}
+			}
+			return nil
+		}
+
+		response, err := js.serve(operation, decode)
+		if err != nil {
+			writeJobServerError(writer, err)
+			return
+		}
+
+		payload, err := json.Marshal(response)
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("failed to serialize the %s response: %s", rpcCmd.String(), err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(payload)
+	})
+}
+
+// writeJobServerError returns the error to the client, with the status of a jobServerError, or else as an internal error
+func writeJobServerError(writer http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var jse jobServerError
+	if errors.As(err, &jse) {
+		status = jse.status
+	}
+	if status == http.StatusUnauthorized {
+		writer.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	http.Error(writer, err.Error(), status)
+}
+
+// checkOrigin rejects the requests that may not come from a client of the current user: those without the token,
+// those addressed to a host that isn't a loopback one, as when a web page's host name is rebound to a loopback address,
+// and those without a JSON body, which a web page can send without asking for permission first
+func (js *jobServer) checkOrigin(request *http.Request) error {
+	host := request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); !strings.EqualFold(host, "localhost") && (ip == nil || !ip.IsLoopback()) {
+		return jobServerError{http.StatusForbidden, fmt.Sprintf("the job API is only served to requests for localhost, not %q", request.Host)}
+	}
+
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(js.token)) != 1 {
+		return jobServerError{http.StatusUnauthorized, "the request must carry the token of the job API as its bearer token"}
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		return jobServerError{http.StatusUnsupportedMediaType, "the request must have a Content-Type of application/json"}
+	}
+	return nil
+}
+
+// serve runs the operation, and turns a panic of the storage engine into an error,
+// so that a bad request can't stop the other jobs of the process
+func (js *jobServer) serve(operation func(decode func(v interface{}) error) (interface{}, error), decode func(v interface{}) error) (response interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the request failed: %v", r)
+		}
+	}()
+	return operation(decode)
+}
+
+// checkJobPartOrder rejects the orders that the storage engine can't take from a client
+func (js *jobServer) checkJobPartOrder(order common.CopyJobPartOrderRequest) error {
+	if order.ForceWrite == common.EOverwriteOption.Prompt() {
+		// nobody is there to answer
+		return jobServerError{http.StatusBadRequest, "the job API can't prompt before overwriting, so ForceWrite must not be prompt"}
+	}
+	planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, order.JobID.String(), order.PartNum, ste.DataSchemaVersion))
+	if planFile.Exists() {
+		return jobServerError{http.StatusConflict, fmt.Sprintf("part %d of job %s was already ordered", order.PartNum, order.JobID)}
+	}
+	return nil
+}
+
+// isRunning returns whether the given job was ordered or resumed by this process, and isn't done since.
+// A job that was cancelled or paused runs until its transfers in progress have stopped.
+func (js *jobServer) isRunning(jobID common.JobID) bool {
+	if !js.hasRun(jobID) {
+		return false
+	}
+	jm, found := jobsAdmin.JobsAdmin.JobMgr(jobID)
+	return found && !jm.IsDone()
+}
+
+// cleanUpLoop periodically cleans up the job managers of the jobs that are no longer running
+func (js *jobServer) cleanUpLoop() {
+	for range time.Tick(serveCleanupInterval) {
+		js.cleanUpIdleJobs()
+	}
+}
+
+// cleanUpIdleJobs cleans up the job managers of the jobs that are done, and of those that were only resurrected to be read,
+// so that a process that runs many jobs doesn't keep their plan files mapped, their logs open and their goroutines running.
+// A job that is read or resumed afterwards is resurrected from its plan files. A job that a request is handled for is left alone.
+// It returns how many it cleaned up.
+func (js *jobServer) cleanUpIdleJobs() int {
+	cleaned := 0
+	for _, jobID := range jobsAdmin.JobsAdmin.JobIDs() {
+		if js.cleanUpIdleJob(jobID) {
+			cleaned++
+		}
+	}
+	return cleaned
+}
+
+func (js *jobServer) cleanUpIdleJob(jobID common.JobID) bool {
+	unlock, ok := js.tryLockJob(jobID)
+	if !ok {
+		return false
+	}
+	defer unlock()
+
+	if js.isRunning(jobID) {
+		return false
+	}
+	jm, found := jobsAdmin.JobsAdmin.JobMgr(jobID)
+	if !found {
+		return false
+	}
+	if jpm, found := jm.JobPartMgr(0); found {
+		if status := jpm.Plan().JobStatus(); status == common.EJobStatus.InProgress() || status == common.EJobStatus.Cancelling() {
+			// left in progress by a process that stopped; its manager would take it for a job it didn't finish
+			return false
+		}
+	}
+	jobsAdmin.JobsAdmin.JobMgrCleanUp(jobID)
+	// a manager resurrected from now on hasn't run the job, so it can resume it
+	js.setRun(jobID, false)
+	return true
+}
+
+// pauseRunningJobs pauses the jobs of this process that are in progress, so that they can be resumed by another,
+// and returns how many it paused
+func (js *jobServer) pauseRunningJobs() int {
+	js.mu.Lock()
+	jobIDs := make([]common.JobID, 0, len(js.jobsRun))
+	for jobID := range js.jobsRun {
+		jobIDs = append(jobIDs, jobID)
+	}
+	js.mu.Unlock()
+
+	paused := 0
+	for _, jobID := range jobIDs {
+		if js.pauseRunningJob(jobID) {
+			paused++
+		}
+	}
+	return paused
+}
+
+func (js *jobServer) pauseRunningJob(jobID common.JobID) bool {
+	defer js.lockJob(jobID)()
+
+	if !js.isRunning(jobID) {
+		return false
+	}
+	jm, _ := jobsAdmin.JobsAdmin.JobMgr(jobID)
+	if jpm, found := jm.JobPartMgr(0); found && jpm.Plan().JobStatus() != common.EJobStatus.InProgress() {
+		// already stopping
+		return false
+	}
+	resp := jobsAdmin.CancelPauseJobOrder(jobID, common.EJobStatus.Paused())
+	if !resp.CancelledPauseResumed {
+		glcm.Info(fmt.Sprintf("Failed to pause job %s: %s", jobID, resp.ErrorMsg))
+	}
+	return resp.CancelledPauseResumed
+}
@@ -56,7 +56,12 @@ type rawSyncCmdArgs struct {
 	legacyExclude         string // for warning messages only
 	includeRegex          string
 	excludeRegex          string
+	includeBefore         string
+	includeAfter          string
+	listOfFiles           string
 	compareHash           string
+	compareMode           string
+	compareMetadataKey    string
 	localHashStorageMode  string
 
 	preservePermissions     bool
@@ -77,6 +82,29 @@ type rawSyncCmdArgs struct {
 	// this flag is to disable comparator and overwrite files at destination irrespective
 	mirrorMode bool
 
+	// the number of objects above which the sync index is spilled to disk
+	indexSpillThreshold int
+
+	// whether changes at the destination are synced back to the source, and how to resolve the files that changed at both
+	bidirectional  bool
+	conflictPolicy string
+
+	// whether the destination is read from the manifest of the last sync instead of being listed, and how old that manifest may be
+	incremental    bool
+	manifestMaxAge time.Duration
+
+	// where the extra files at the destination are moved to instead of being deleted, and for how long they're kept there
+	archivePrefix        string
+	archiveRetentionDays int
+
+	// whether the files that were moved at the source are moved at the destination too, instead of being copied again
+	detectMoves bool
+
+	// whether sync keeps watching a local source for changes after its first scan, and how it batches them
+	watch                  bool
+	watchDebounce          time.Duration
+	watchReconcileInterval time.Duration
+
 	s2sPreserveAccessTier bool
 	// Opt-in flag to preserve the blob index tags during service to service transfer.
 	s2sPreserveBlobTags bool
@@ -93,7 +121,13 @@ type rawSyncCmdArgs struct {
 	cpkScopeInfo string
 	// dry run mode bool
 	dryrun bool
+	// the file that a dry run writes its report to, and the format of the report
+	dryrunReport       string
+	dryrunReportFormat string
 	trailingDot string
+
+	// the priority of the job against the other jobs that run in the same process
+	priority string
 }
 
 func (raw *rawSyncCmdArgs) parsePatterns(pattern string) (cookedPatterns []string) {
@@ -145,6 +179,13 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, err
 	}
 
+	if raw.priority == "" {
+		raw.priority = common.EJobPriority.Normal().String()
+	}
+	if err = cooked.priority.Parse(raw.priority); err != nil {
+		return cooked, fmt.Errorf("invalid --priority value %q. Valid values are Low, Normal and High", raw.priority)
+	}
+
 	cooked.fromTo, err = ValidateFromTo(raw.src, raw.dst, raw.fromTo)
 	if err != nil {
 		return cooked, err
@@ -167,7 +208,10 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		if cooked.fromTo.From() != common.ELocation.File() && raw.trailingDot != "" {
 			return cooked, fmt.Errorf("trailing-dot is only support for operations on file share accounts")
 		}
-	case common.EFromTo.BlobBlob(), common.EFromTo.FileFile(), common.EFromTo.BlobFile(), common.EFromTo.FileBlob(), common.EFromTo.BlobFSBlobFS(), common.EFromTo.BlobFSBlob(), common.EFromTo.BlobFSFile(), common.EFromTo.BlobBlobFS(), common.EFromTo.FileBlobFS():
+	case common.EFromTo.LocalLocal():
+		// both sides are cleaned below
+	case common.EFromTo.BlobBlob(), common.EFromTo.FileFile(), common.EFromTo.BlobFile(), common.EFromTo.FileBlob(), common.EFromTo.BlobFSBlobFS(), common.EFromTo.BlobFSBlob(), common.EFromTo.BlobFSFile(), common.EFromTo.BlobBlobFS(), common.EFromTo.FileBlobFS(),
+		common.EFromTo.S3Blob(), common.EFromTo.GCPBlob():
 		cooked.destination, err = SplitResourceString(raw.dst, cooked.fromTo.To())
 		common.PanicIfErr(err)
 		cooked.source, err = SplitResourceString(raw.src, cooked.fromTo.From())
@@ -183,7 +227,8 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	// Do this check separately so we don't end up with a bunch of code duplication when new src/dstn are added
 	if cooked.fromTo.From() == common.ELocation.Local() {
 		cooked.source = common.ResourceString{Value: common.ToExtendedPath(cleanLocalPath(raw.src))}
-	} else if cooked.fromTo.To() == common.ELocation.Local() {
+	}
+	if cooked.fromTo.To() == common.ELocation.Local() {
 		cooked.destination = common.ResourceString{Value: common.ToExtendedPath(cleanLocalPath(raw.dst))}
 	}
 
@@ -273,18 +318,46 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 
 	cooked.preservePOSIXProperties = raw.preservePOSIXProperties
 	if cooked.preservePOSIXProperties && !areBothLocationsPOSIXAware(cooked.fromTo) {
-		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (valid pairings are Linux->Blob, Blob->Linux, Blob->Blob)")
+		return cooked, fmt.Errorf("in order to use --preserve-posix-properties, both the source and destination must be POSIX-aware (valid pairings are Linux->Blob, Blob->Linux, Blob->Blob, Linux->Linux)")
 	}
 
+	if err = cooked.compareMode.Parse(raw.compareMode); err != nil {
+		return cooked, err
+	}
 	if err = cooked.compareHash.Parse(raw.compareHash); err != nil {
 		return cooked, err
-	} else {
-		switch cooked.compareHash {
-		case common.ESyncHashType.MD5():
-			// Save any new MD5s on files we download.
-			raw.putMd5 = true
-		default: // no need to put a hash of any kind.
+	}
+	switch cooked.compareMode {
+	case common.ESyncCompareMode.SizeOnly(), common.ESyncCompareMode.LMTAndSize():
+		if cooked.compareHash != common.ESyncHashType.None() {
+			return cooked, fmt.Errorf("compare-hash cannot be used with compare-mode %s, which doesn't compare hashes", cooked.compareMode)
+		}
+	case common.ESyncCompareMode.HashOnly():
+		// hashes are compared as they are with compare-hash, which defaults to MD5 here
+		if cooked.compareHash == common.ESyncHashType.None() {
+			cooked.compareHash = common.ESyncHashType.MD5()
+		}
+	case common.ESyncCompareMode.Metadata():
+		if cooked.compareHash != common.ESyncHashType.None() {
+			return cooked, fmt.Errorf("compare-hash cannot be used with compare-mode %s, which doesn't compare hashes", cooked.compareMode)
+		}
+		if raw.compareMetadataKey == "" {
+			return cooked, fmt.Errorf("compare-mode %s requires compare-metadata-key", cooked.compareMode)
 		}
+		if !cooked.fromTo.IsS2S() {
+			return cooked, fmt.Errorf("compare-mode %s is only supported between remote resources, since local files have no metadata", cooked.compareMode)
+		}
+	}
+	if raw.compareMetadataKey != "" && cooked.compareMode != common.ESyncCompareMode.Metadata() {
+		return cooked, fmt.Errorf("compare-metadata-key only applies to compare-mode %s", common.ESyncCompareMode.Metadata())
+	}
+	cooked.compareMetadataKey = raw.compareMetadataKey
+
+	switch cooked.compareHash {
+	case common.ESyncHashType.MD5():
+		// Save any new MD5s on files we download.
+		raw.putMd5 = true
+	default: // the other types are kept by sync itself, in metadata on upload and in the hash data of files on download.
 	}
 
 	if err = common.LocalHashStorageMode.Parse(raw.localHashStorageMode); err != nil {
@@ -344,12 +417,154 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	cooked.cpkOptions = cpkOptions
 
 	cooked.mirrorMode = raw.mirrorMode
+	if cooked.mirrorMode && cooked.compareMode != common.ESyncCompareMode.Default() {
+		return cooked, fmt.Errorf("compare-mode cannot be used with mirror-mode, which doesn't compare files")
+	}
+
+	if raw.indexSpillThreshold < 0 {
+		return cooked, fmt.Errorf("index-spill-threshold cannot be negative")
+	}
+	cooked.indexSpillThreshold = raw.indexSpillThreshold
+
+	cooked.bidirectional = raw.bidirectional
+	if err = cooked.conflictPolicy.Parse(raw.conflictPolicy); err != nil {
+		return cooked, err
+	}
+	if cooked.bidirectional {
+		if _, ok := twoWaySyncReverseFromTo[cooked.fromTo]; !ok {
+			return cooked, fmt.Errorf("bidirectional sync is only supported between a local directory and Azure Blob or Azure Files, not for %s", cooked.fromTo)
+		}
+		if cooked.deleteDestination != common.EDeleteDestination.False() {
+			return cooked, fmt.Errorf("delete-destination cannot be used with bidirectional sync, which propagates deletions in both directions")
+		}
+		if cooked.mirrorMode {
+			return cooked, fmt.Errorf("mirror-mode cannot be used with bidirectional sync")
+		}
+	}
+
+	if raw.archivePrefix != "" {
+		if cooked.archivePrefix, err = validateArchivePrefix(raw.archivePrefix); err != nil {
+			return cooked, err
+		}
+		if cooked.deleteDestination == common.EDeleteDestination.False() {
+			return cooked, fmt.Errorf("archive-prefix only applies when delete-destination is true or prompt")
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("archive-prefix cannot be used with bidirectional sync")
+		}
+	}
+	if raw.archiveRetentionDays < 0 {
+		return cooked, fmt.Errorf("archive-retention-days cannot be negative")
+	} else if raw.archiveRetentionDays > 0 && cooked.archivePrefix == "" {
+		return cooked, fmt.Errorf("archive-retention-days requires archive-prefix")
+	}
+	cooked.archiveRetentionDays = raw.archiveRetentionDays
+
+	cooked.detectMoves = raw.detectMoves
+	if cooked.detectMoves {
+		if cooked.compareHash == common.ESyncHashType.None() {
+			return cooked, fmt.Errorf("detect-moves requires compare-hash, since moved files are recognized by their size and hash")
+		}
+		if cooked.deleteDestination != common.EDeleteDestination.True() {
+			return cooked, fmt.Errorf("detect-moves requires delete-destination to be true, since moving a file removes it from its old path")
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("detect-moves cannot be used with bidirectional sync")
+		}
+	}
+
+	cooked.watch = raw.watch
+	if cooked.watch {
+		if cooked.fromTo.From() != common.ELocation.Local() {
+			return cooked, fmt.Errorf("watch is only supported for a local source, not for %s", cooked.fromTo)
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("watch cannot be used with bidirectional sync")
+		}
+		if raw.incremental {
+			return cooked, fmt.Errorf("watch cannot be used with incremental sync, which records the destination once the job is done")
+		}
+		if raw.dryrun {
+			return cooked, fmt.Errorf("watch cannot be used with dry-run")
+		}
+		if cooked.deleteDestination == common.EDeleteDestination.Prompt() {
+			return cooked, fmt.Errorf("watch cannot prompt before deleting; set delete-destination to true or false")
+		}
+		if raw.watchDebounce <= 0 {
+			return cooked, fmt.Errorf("watch-debounce must be positive")
+		}
+		if raw.watchReconcileInterval < 0 {
+			return cooked, fmt.Errorf("watch-reconcile-interval cannot be negative")
+		}
+	}
+	cooked.watchDebounce = raw.watchDebounce
+	cooked.watchReconcileInterval = raw.watchReconcileInterval
+
+	cooked.incremental = raw.incremental
+	if raw.manifestMaxAge < 0 {
+		return cooked, fmt.Errorf("manifest-max-age cannot be negative")
+	}
+	cooked.manifestMaxAge = raw.manifestMaxAge
+	if cooked.incremental {
+		if cooked.fromTo.To() == common.ELocation.Local() {
+			return cooked, fmt.Errorf("incremental sync is only supported for remote destinations, since local destinations are quick to list")
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("incremental and bidirectional sync cannot be used together")
+		}
+	}
 
 	cooked.includeRegex = raw.parsePatterns(raw.includeRegex)
 	cooked.excludeRegex = raw.parsePatterns(raw.excludeRegex)
 
+	if raw.includeBefore != "" {
+		// the latest of ambiguous local times is chosen, since it's safest to do more work than to miss a changed file
+		parsedIncludeBefore, err := IncludeBeforeDateFilter{}.ParseISO8601(raw.includeBefore, false)
+		if err != nil {
+			return cooked, err
+		}
+		cooked.includeBefore = &parsedIncludeBefore
+	}
+	if raw.includeAfter != "" {
+		// likewise, the earliest of ambiguous local times is chosen
+		parsedIncludeAfter, err := IncludeAfterDateFilter{}.ParseISO8601(raw.includeAfter, true)
+		if err != nil {
+			return cooked, err
+		}
+		cooked.includeAfter = &parsedIncludeAfter
+	}
+	if raw.listOfFiles != "" {
+		if cooked.listOfFiles, err = readSyncListOfFiles(raw.listOfFiles); err != nil {
+			return cooked, err
+		}
+		if cooked.incremental {
+			return cooked, fmt.Errorf("list-of-files cannot be used with incremental sync, whose manifest covers the whole destination")
+		}
+	}
+	if cooked.listOfFiles != nil || cooked.includeBefore != nil || cooked.includeAfter != nil {
+		if cooked.watch {
+			return cooked, fmt.Errorf("list-of-files, include-before and include-after cannot be used with watch")
+		}
+	}
+
 	cooked.dryrunMode = raw.dryrun
 
+	if err = cooked.dryrunReportFormat.Parse(raw.dryrunReportFormat); err != nil {
+		return cooked, err
+	}
+	if raw.dryrunReport != "" {
+		if !cooked.dryrunMode {
+			return cooked, fmt.Errorf("dry-run-report can only be used with dry-run")
+		}
+		if cooked.bidirectional {
+			return cooked, fmt.Errorf("dry-run-report cannot be used with bidirectional sync")
+		}
+		if cooked.deleteDestination == common.EDeleteDestination.Prompt() {
+			return cooked, fmt.Errorf("dry-run-report cannot report the answers to prompts in advance; set delete-destination to true or false")
+		}
+		cooked.dryrunReport = raw.dryrunReport
+	}
+
 	if azcopyOutputVerbosity == common.EOutputVerbosity.Quiet() || azcopyOutputVerbosity == common.EOutputVerbosity.Essential() {
 		if cooked.deleteDestination == common.EDeleteDestination.Prompt() {
 			err = fmt.Errorf("cannot set output level '%s' with delete-destination option '%s'", azcopyOutputVerbosity.String(), cooked.deleteDestination.String())
@@ -381,6 +596,8 @@ type cookedSyncCmdArgs struct {
 
 	// deletion count keeps track of how many extra files from the destination were removed
 	atomicDeletionCount uint32
+	// move count keeps track of how many extra files at the destination were moved to the paths of missing ones
+	atomicMoveCount uint32
 
 	source                  common.ResourceString
 	destination             common.ResourceString
@@ -400,8 +617,15 @@ type cookedSyncCmdArgs struct {
 	includeRegex          []string
 	excludeRegex          []string
 
+	// these narrow the sync down to part of the source; see syncScope
+	includeBefore *time.Time
+	includeAfter  *time.Time
+	listOfFiles   []string
+
 	// options
 	compareHash             common.SyncHashType
+	compareMode             common.SyncCompareMode
+	compareMetadataKey      string
 	preservePermissions     common.PreservePermissionsOption
 	preserveSMBInfo         bool
 	preservePOSIXProperties bool
@@ -445,8 +669,38 @@ type cookedSyncCmdArgs struct {
 
 	mirrorMode bool
 
+	// the number of objects above which the sync index is spilled to disk. Zero means never.
+	indexSpillThreshold int
+
+	bidirectional  bool
+	conflictPolicy common.SyncConflictPolicy
+	// set once the enumerator of a bidirectional sync is created, to record the outcome of the sync when the job is done
+	twoWay *twoWaySync
+
+	incremental    bool
+	manifestMaxAge time.Duration
+	// set once the enumerator of an incremental sync is created, to write the manifest when the job is done
+	manifest *syncManifestRecorder
+
+	// the extra files at the destination are moved under this prefix, instead of being deleted. Zero retention days means forever.
+	archivePrefix        string
+	archiveRetentionDays int
+
+	// the files that the destination lacks are moved from the extra files of the same size and hash there; see syncMoveDetector
+	detectMoves bool
+
+	watch                  bool
+	watchDebounce          time.Duration
+	watchReconcileInterval time.Duration
+	// set before the first scan of a sync that watches its source, to sync the changes after it
+	watcher *syncWatcher
+
 	dryrunMode bool
+	// with dryrunMode, what the sync would do is reported in this file
+	dryrunReport       string
+	dryrunReportFormat common.SyncReportFormat
 	trailingDot common.TrailingDotOption
+	priority    common.JobPriority
 }
 
 func (cca *cookedSyncCmdArgs) incrementDeletionCount() {
@@ -457,6 +711,14 @@ func (cca *cookedSyncCmdArgs) getDeletionCount() uint32 {
 	return atomic.LoadUint32(&cca.atomicDeletionCount)
 }
 
+func (cca *cookedSyncCmdArgs) incrementMoveCount() {
+	atomic.AddUint32(&cca.atomicMoveCount, 1)
+}
+
+func (cca *cookedSyncCmdArgs) getMoveCount() uint32 {
+	return atomic.LoadUint32(&cca.atomicMoveCount)
+}
+
 // setFirstPartOrdered sets the value of atomicFirstPartOrdered to 1
 func (cca *cookedSyncCmdArgs) setFirstPartOrdered() {
 	atomic.StoreUint32(&cca.atomicFirstPartOrdered, 1)
@@ -500,6 +762,11 @@ func (cca *cookedSyncCmdArgs) waitUntilJobCompletion(blocking bool) {
 }
 
 func (cca *cookedSyncCmdArgs) Cancel(lcm common.LifecycleMgr) {
+	// a watching sync first stops watching, and lets the transfers in progress finish
+	if cca.watcher != nil && cca.watcher.stopWatching() {
+		return
+	}
+
 	// prompt for confirmation, except when enumeration is complete
 	if !cca.isEnumerationComplete {
 		answer := lcm.Prompt("The enumeration (source/destination comparison) is not complete, "+
@@ -559,6 +826,7 @@ func (cca *cookedSyncCmdArgs) getJsonOfSyncJobSummary(summary common.ListJobSumm
 	wrapped := common.ListSyncJobSummaryResponse{ListJobSummaryResponse: summary}
 	wrapped.DeleteTotalTransfers = cca.getDeletionCount()
 	wrapped.DeleteTransfersCompleted = cca.getDeletionCount()
+	wrapped.MoveTransfersCompleted = cca.getMoveCount()
 	jsonOutput, err := json.Marshal(wrapped)
 	common.PanicIfErr(err)
 	return string(jsonOutput)
@@ -616,6 +884,21 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			exitCode = common.EExitCode.Error()
 		}
 
+		if cca.manifest != nil {
+			cca.manifest.finish(summary.JobStatus)
+		}
+
+		if cca.twoWay != nil {
+			if err := cca.twoWay.saveState(); err != nil {
+				glcm.Info("Failed to save the state of the bidirectional sync: " + err.Error())
+				exitCode = common.EExitCode.Error()
+			}
+			if cca.twoWay.conflicts > 0 {
+				glcm.Info(cca.twoWay.conflictSummary())
+				exitCode = common.EExitCode.Error()
+			}
+		}
+
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
 				return cca.getJsonOfSyncJobSummary(summary)
@@ -634,6 +917,7 @@ Total Number Of Copy Transfers: %v
 Number of Copy Transfers Completed: %v
 Number of Copy Transfers Failed: %v
 Number of Deletions at Destination: %v
+Number of Moves at Destination: %v
 Total Number of Bytes Transferred: %v
 Total Number of Bytes Enumerated: %v
 Final Job Status: %v%s%s
@@ -648,6 +932,7 @@ Final Job Status: %v%s%s
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				cca.atomicDeletionCount,
+				cca.getMoveCount(),
 				summary.TotalBytesTransferred,
 				summary.TotalBytesEnumerated,
 				summary.JobStatus,
@@ -715,6 +1000,12 @@ func (cca *cookedSyncCmdArgs) process() (err error) {
 		}
 	}
 
+	if cca.watch {
+		if cca.watcher, err = newSyncWatcher(ctx, cca); err != nil {
+			return err
+		}
+	}
+
 	enumerator, err := cca.initEnumerator(ctx)
 	if err != nil {
 		return err
@@ -730,6 +1021,10 @@ func (cca *cookedSyncCmdArgs) process() (err error) {
 	if err != nil {
 		return err
 	}
+
+	if cca.watcher != nil {
+		go cca.watcher.run()
+	}
 	return nil
 }
 
@@ -776,7 +1071,7 @@ func init() {
 
 	rootCmd.AddCommand(syncCmd)
 	syncCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", true, "True by default, look into sub-directories recursively when syncing between directories. (default true).")
-	syncCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalFile, FileLocal, BlobFile, FileBlob, etc.")
+	syncCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalFile, FileLocal, BlobFile, FileBlob, S3Blob, GCPBlob, etc.")
 
 	// TODO: enable for copy with IfSourceNewer
 	// smb info/permissions can be persisted in the scenario of File -> File
@@ -802,6 +1097,12 @@ func init() {
 	syncCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
 	syncCmd.PersistentFlags().StringVar(&raw.includeRegex, "include-regex", "", "Include the relative path of the files that match with the regular expressions. Separate regular expressions with ';'.")
 	syncCmd.PersistentFlags().StringVar(&raw.excludeRegex, "exclude-regex", "", "Exclude the relative path of the files that match with the regular expressions. Separate regular expressions with ';'.")
+	syncCmd.PersistentFlags().StringVar(&raw.includeBefore, common.IncludeBeforeFlagName, "", "Include only those source files modified before or on the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. "+
+		"The destination files of the source files that are excluded are neither compared nor deleted.")
+	syncCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Include only those source files modified on or after the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. "+
+		"The destination files of the source files that are excluded are neither compared nor deleted.")
+	syncCmd.PersistentFlags().StringVar(&raw.listOfFiles, "list-of-files", "", "Defines the location of a file which contains the list of files and directories to be synced, relative to the source and destination, one per line. "+
+		"Only the listed paths are compared, and with delete-destination, only the files under them are deleted.")
 	syncCmd.PersistentFlags().StringVar(&raw.deleteDestination, "delete-destination", "false", "Defines whether to delete extra files from the destination that are not present at the source. Could be set to true, false, or prompt. "+
 		"If set to prompt, the user will be asked a question before scheduling files and blobs for deletion. (default 'false').")
 	syncCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
@@ -817,10 +1118,38 @@ func init() {
 	syncCmd.PersistentFlags().StringVar(&raw.cpkScopeInfo, "cpk-by-name", "", "Client provided key by name let clients making requests against Azure Blob storage an option to provide an encryption key on a per-request basis. Provided key name will be fetched from Azure Key Vault and will be used to encrypt the data")
 	syncCmd.PersistentFlags().BoolVar(&raw.cpkInfo, "cpk-by-value", false, "Client provided key by name let clients making requests against Azure Blob storage an option to provide an encryption key on a per-request basis. Provided key and its hash will be fetched from environment variables")
 	syncCmd.PersistentFlags().BoolVar(&raw.mirrorMode, "mirror-mode", false, "Disable last-modified-time based comparison and overwrites the conflicting files and blobs at the destination if this flag is set to true. Default is false")
+	syncCmd.PersistentFlags().IntVar(&raw.indexSpillThreshold, "index-spill-threshold", defaultSyncIndexSpillThreshold, "The number of files and folders that sync indexes in memory, from the side of the sync that it scans first, before spilling the index to disk, in the job plan folder. Once spilled, the other side is also written to disk as it is scanned, and the two are compared once scanning has finished, using a bounded amount of memory. 0 keeps the index in memory regardless of its size.")
+	syncCmd.PersistentFlags().BoolVar(&raw.bidirectional, "bidirectional", false, "Sync in both directions: files created, modified or deleted at either side since the last bidirectional sync of the same source and destination are created, modified or deleted at the other side. "+
+		"Supported between a local directory and Azure Blob or Azure Files. Only files are synced; empty folders are neither created nor deleted. Cannot be combined with delete-destination or mirror-mode.")
+	syncCmd.PersistentFlags().StringVar(&raw.conflictPolicy, "conflict-policy", common.ESyncConflictPolicy.NewerWins().String(), "Defines how a bidirectional sync resolves a file that changed at both sides since the last sync. "+
+		"NewerWins keeps the version with the later last modified time; KeepBoth also keeps the older version, with '.conflict-<time>' added to its name, on both sides by the following sync; Fail leaves the file alone and fails the sync.")
+	syncCmd.PersistentFlags().StringVar(&raw.archivePrefix, "archive-prefix", "", "With delete-destination, move the extra files at the destination into a folder under this path (relative to the destination) instead of deleting them. "+
+		"Each sync archives into a folder named after the time (UTC) at which it started, for example '.archive/20230102T150405Z/dir/file.txt'. The archive prefix itself is excluded from the sync. "+
+		"Blobs and Azure Files are copied on the service side and then deleted, while files in accounts with a hierarchical namespace and local files are renamed.")
+	syncCmd.PersistentFlags().IntVar(&raw.archiveRetentionDays, "archive-retention-days", 0, "Delete the folders under archive-prefix that were archived more than this many days ago, each time sync runs. 0 keeps them forever.")
+	syncCmd.PersistentFlags().BoolVar(&raw.detectMoves, "detect-moves", false, "Detect the files that were moved or renamed at the source since the last sync, and move them at the destination too, instead of copying them again and deleting them from their old paths. "+
+		"A file that the destination lacks is moved from an extra file at the destination with the same size and hash. Requires compare-hash and delete-destination=true. "+
+		"Blobs and Azure Files are copied on the service side and then deleted, while files in accounts with a hierarchical namespace and local files are renamed.")
+	syncCmd.PersistentFlags().BoolVar(&raw.watch, "watch", false, "Keep running after syncing a local directory, watching it for changes and syncing them as they happen, until stopped with Ctrl-C. "+
+		"The changes are synced in batches, and the source and destination are compared in full every --watch-reconcile-interval, to catch any changes that were missed.")
+	syncCmd.PersistentFlags().DurationVar(&raw.watchDebounce, "watch-debounce", defaultSyncWatchDebounce, "With --watch, sync the changes once the source has had no more changes for this long (for example 2s or 1m).")
+	syncCmd.PersistentFlags().DurationVar(&raw.watchReconcileInterval, "watch-reconcile-interval", defaultSyncWatchReconcileInterval, "With --watch, how often the source and destination are compared in full. 0 turns this off.")
+	syncCmd.PersistentFlags().BoolVar(&raw.incremental, "incremental", false, "Read the state of the destination from the manifest that the last incremental sync of the same source and destination kept in the job plan folder, instead of listing the destination, and keep a new manifest once the sync is done. "+
+		"The destination is listed in full when there's no manifest, when the manifest is older than manifest-max-age, or when it was kept by a sync with other options. Changes made at the destination by anything but sync are not seen until the next full listing. Only supported for remote destinations.")
+	syncCmd.PersistentFlags().DurationVar(&raw.manifestMaxAge, "manifest-max-age", defaultSyncManifestMaxAge, "The age beyond which incremental sync no longer trusts the manifest of the last sync, and lists the destination in full (for example: 24h). 0 always lists the destination in full, while still keeping a manifest for the next sync.")
 	syncCmd.PersistentFlags().BoolVar(&raw.dryrun, "dry-run", false, "Prints the path of files that would be copied or removed by the sync command. This flag does not copy or remove the actual files.")
+	syncCmd.PersistentFlags().StringVar(&raw.dryrunReport, "dry-run-report", "", "With dry-run, write a report to this file of every file that sync would copy, move, delete or skip, and the reason for it, for the sync to be reviewed before it's run.")
+	syncCmd.PersistentFlags().StringVar(&raw.dryrunReportFormat, "dry-run-report-format", "JSON", "The format of the report written with dry-run-report. (JSON, CSV)")
+	syncCmd.PersistentFlags().StringVar(&raw.priority, "priority", common.EJobPriority.Normal().String(), "The priority of the job against the other jobs that run in the same process, such as the jobs of 'azcopy serve'. Available options: Low, Normal, High.")
 	syncCmd.PersistentFlags().StringVar(&raw.trailingDot, "trailing-dot", "", "Enabled by default. Options for trailing dot support in file share. Available options: Enable, Disable. Choose disable to go back to legacy (potentially unsafe) treatment of trailing dot files.")
 
-	syncCmd.PersistentFlags().StringVar(&raw.compareHash, "compare-hash", "None", "Inform sync to rely on hashes as an alternative to LMT. Missing hashes at a remote source will throw an error. (None, MD5) Default: None")
+	syncCmd.PersistentFlags().StringVar(&raw.compareMode, "compare-mode", "Default", "Decide how files that exist at both the source and the destination are compared. "+
+		"Default transfers a file when it's more recent at the source (or its hash differs, with --compare-hash). "+
+		"SizeOnly transfers it when its size differs, for sources whose LMTs can't be trusted. LMTAndSize transfers it when it's more recent at the source or its size differs. "+
+		"HashOnly transfers it when its hash (of the type given by --compare-hash, MD5 by default) differs, or is missing at either side. "+
+		"Metadata transfers it when the value of the metadata key given by --compare-metadata-key differs. (Default, SizeOnly, LMTAndSize, HashOnly, Metadata)")
+	syncCmd.PersistentFlags().StringVar(&raw.compareMetadataKey, "compare-metadata-key", "", "The metadata key whose values are compared with --compare-mode=Metadata, for example one that holds a content hash set by another tool.")
+	syncCmd.PersistentFlags().StringVar(&raw.compareHash, "compare-hash", "None", "Inform sync to rely on hashes as an alternative to LMT. Missing hashes at a remote source will throw an error. (None, MD5, CRC64, SHA256, XXHash64) Default: None")
 	syncCmd.PersistentFlags().StringVar(&common.LocalHashDir, "hash-meta-dir", "", "When using `--local-hash-storage-mode=HiddenFiles` you can specify an alternate directory to store hash metadata files in (as opposed to next to the related files in the source)")
 	syncCmd.PersistentFlags().StringVar(&raw.localHashStorageMode, "local-hash-storage-mode", common.EHashStorageMode.Default().String(), "Specify an alternative way to cache file hashes; valid options are: HiddenFiles (OS Agnostic), XAttr (Linux/MacOS only; requires user_xattr on all filesystems traversed @ source), AlternateDataStreams (Windows only; requires named streams on target volume)")
 
@@ -0,0 +1,374 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
+
+	"github.com/Azure/azure-storage-azcopy/v10/azbfs"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+const (
+	// each sync archives the extra files at the destination in a folder of its own under the archive prefix,
+	// named after the time at which the sync started, e.g. .archive/20230102T150405Z/dir/file.txt
+	syncArchiveTimeFormat = "20060102T150405Z"
+)
+
+var (
+	// how often the status of the server-side copy of an archived (or moved) file is checked
+	syncArchiveCopyPollInterval = time.Second
+	// how long the server-side copy of an archived (or moved) file may take before it's aborted
+	syncArchiveCopyTimeout = 30 * time.Minute
+)
+
+// validateArchivePrefix checks that the archive prefix is a relative path under the destination, and returns it in a clean form
+func validateArchivePrefix(archivePrefix string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(archivePrefix, "\\", "/"))
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || filepath.IsAbs(archivePrefix) {
+		return "", fmt.Errorf("the archive prefix must be a relative path under the destination, not '%s'", archivePrefix)
+	}
+	return cleaned, nil
+}
+
+// syncArchiveFolder returns the folder, relative to the destination, into which a sync that started at the given time archives files
+func syncArchiveFolder(archivePrefix string, startTime time.Time) string {
+	return path.Join(archivePrefix, startTime.UTC().Format(syncArchiveTimeFormat))
+}
+
+// localFileArchiver moves the extra files at a local destination into the archive folder, instead of deleting them
+type localFileArchiver struct {
+	localFileDeleter
+	archiveFolder string
+}
+
+func (l *localFileArchiver) archiveFile(object StoredObject) error {
+	// folders aren't archived; they're deleted once the files in them are archived, as they would have been if the files were deleted
+	if object.entityType != common.EEntityType.File() {
+		return l.deleteFile(object)
+	}
+
+	archivePath := path.Join(l.archiveFolder, object.relativePath)
+	msg := "Archiving extra file: " + object.relativePath + " to " + archivePath
+	glcm.Info(msg)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogInfo, msg)
+	}
+
+	return l.moveFile(object, archivePath)
+}
+
+// moveFile moves a file at a local destination to another path under it
+func (l *localFileDeleter) moveFile(object StoredObject, toRelativePath string) error {
+	objectURI := l.getObjectURL(object)
+	l.folderManager.RecordChildExists(objectURI)
+
+	destination := common.GenerateFullPath(l.rootPath, toRelativePath)
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(common.GenerateFullPath(l.rootPath, object.relativePath), destination); err != nil {
+		return err
+	}
+	l.folderManager.RecordChildDeleted(objectURI)
+	return nil
+}
+
+// remoteResourceArchiver moves the extra objects at a remote destination into the archive folder, instead of deleting them.
+// Blobs and Azure Files are copied on the service side, and deleted once the copy has succeeded. BlobFS files are renamed.
+type remoteResourceArchiver struct {
+	*remoteResourceDeleter
+	archiveFolder string
+}
+
+func newRemoteResourceArchiver(deleter *remoteResourceDeleter, archiveFolder string) *remoteResourceArchiver {
+	return &remoteResourceArchiver{remoteResourceDeleter: deleter, archiveFolder: archiveFolder}
+}
+
+func (b *remoteResourceArchiver) archive(object StoredObject) error {
+	// folders aren't archived; they're deleted once the objects in them are archived, as they would have been if the objects were deleted
+	if object.entityType != common.EEntityType.File() {
+		return b.delete(object)
+	}
+
+	archivePath := path.Join(b.archiveFolder, object.relativePath)
+	msg := "Archiving extra object: " + object.relativePath + " to " + archivePath
+	glcm.Info(msg)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogInfo, msg)
+	}
+
+	if err := b.move(object, archivePath); err != nil {
+		// the object stays where it is, so the folder it's in isn't empty
+		msg := fmt.Sprintf("error %s archiving the object %s", err.Error(), object.relativePath)
+		glcm.Info(msg + "; check the scanning log file for more details")
+		if azcopyScanningLogger != nil {
+			azcopyScanningLogger.Log(pipeline.LogError, msg+": "+err.Error())
+		}
+	}
+	return nil
+}
+
+// move moves an object at a remote destination to another path under it.
+// Blobs and Azure Files are copied on the service side, and deleted once the copy has succeeded. BlobFS files are renamed.
+func (b *remoteResourceDeleter) move(object StoredObject, toRelativePath string) error {
+	objectURL := b.getObjectURL(object)
+	b.folderManager.RecordChildExists(&objectURL)
+
+	var err error
+	switch b.targetLocation {
+	case common.ELocation.Blob():
+		err = b.moveBlob(objectURL, toRelativePath)
+	case common.ELocation.File():
+		err = b.moveFile(objectURL, toRelativePath)
+	case common.ELocation.BlobFS():
+		bfsURLParts := azbfs.NewBfsURLParts(*b.rootURL)
+		destinationPath := path.Join(bfsURLParts.DirectoryOrFilePath, toRelativePath)
+		_, err = azbfs.NewFileURL(objectURL, b.p).Rename(b.ctx, azbfs.RenameFileOptions{DestinationPath: destinationPath})
+	default:
+		panic("not implemented, check your code")
+	}
+	if err != nil {
+		return err
+	}
+
+	b.folderManager.RecordChildDeleted(&objectURL)
+	return nil
+}
+
+func (b *remoteResourceDeleter) moveBlob(objectURL url.URL, toRelativePath string) error {
+	blobURLParts := azblob.NewBlobURLParts(*b.rootURL)
+	blobURLParts.BlobName = path.Join(blobURLParts.BlobName, toRelativePath)
+	targetURL := azblob.NewBlobURL(blobURLParts.URL(), b.p)
+
+	// the metadata of the source is copied when none is given
+	resp, err := targetURL.StartCopyFromURL(b.ctx, objectURL, nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.DefaultAccessTier, nil)
+	if err != nil {
+		return err
+	}
+	err = waitForSyncArchiveCopy(b.ctx, toRelativePath, string(resp.CopyStatus()),
+		func(ctx context.Context) (status string, description string, err error) {
+			props, err := targetURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+			if err != nil {
+				return "", "", err
+			}
+			return string(props.CopyStatus()), props.CopyStatusDescription(), nil
+		},
+		func(ctx context.Context) error {
+			_, err := targetURL.AbortCopyFromURL(ctx, resp.CopyID(), azblob.LeaseAccessConditions{})
+			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	_, err = azblob.NewBlobURL(objectURL, b.p).Delete(b.ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+	return err
+}
+
+func (b *remoteResourceDeleter) moveFile(objectURL url.URL, toRelativePath string) error {
+	fileURLParts := azfile.NewFileURLParts(*b.rootURL)
+	fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, toRelativePath)
+	targetURL := azfile.NewFileURL(fileURLParts.URL(), b.p)
+
+	// unlike blobs, files need their parent directories
+	err := ste.AzureFileParentDirCreator{}.CreateParentDirToRoot(b.ctx, targetURL, b.p, ste.NewFolderCreationTracker(common.EFolderPropertiesOption.NoFolders(), nil))
+	if err != nil {
+		return err
+	}
+
+	resp, err := targetURL.StartCopy(b.ctx, objectURL, nil)
+	if err != nil {
+		return err
+	}
+	err = waitForSyncArchiveCopy(b.ctx, toRelativePath, string(resp.CopyStatus()),
+		func(ctx context.Context) (status string, description string, err error) {
+			props, err := targetURL.GetProperties(ctx)
+			if err != nil {
+				return "", "", err
+			}
+			return string(props.CopyStatus()), props.CopyStatusDescription(), nil
+		},
+		func(ctx context.Context) error {
+			_, err := targetURL.AbortCopy(ctx, resp.CopyID())
+			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	_, err = azfile.NewFileURL(objectURL, b.p).Delete(b.ctx)
+	return err
+}
+
+// waitForSyncArchiveCopy polls the status of the server-side copy to the given path until it's no longer pending,
+// and returns an error unless it succeeded. A copy that is still pending once the context is done, or after syncArchiveCopyTimeout,
+// is aborted, so that the source isn't deleted, and no partial copy is left behind for the next sync to find.
+// The statuses of blobs and Azure Files have the same values, so they're compared as strings.
+func waitForSyncArchiveCopy(ctx context.Context, toRelativePath string, status string,
+	getStatus func(ctx context.Context) (status string, description string, err error), abort func(ctx context.Context) error) error {
+
+	timeout := time.NewTimer(syncArchiveCopyTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(syncArchiveCopyPollInterval)
+	defer ticker.Stop()
+
+	description := ""
+	for status == string(azblob.CopyStatusPending) {
+		var stopped error
+		select {
+		case <-ctx.Done():
+			stopped = ctx.Err()
+		case <-timeout.C:
+			stopped = fmt.Errorf("it didn't finish within %v", syncArchiveCopyTimeout)
+		case <-ticker.C:
+		}
+		if stopped != nil {
+			// the context of the sync may be done, so the copy is aborted under one of its own
+			abortCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := abort(abortCtx); err != nil {
+				return fmt.Errorf("the copy to %s was stopped because %s, but couldn't be aborted: %w", toRelativePath, stopped.Error(), err)
+			}
+			return fmt.Errorf("the copy to %s was aborted because %s", toRelativePath, stopped.Error())
+		}
+
+		newStatus, newDescription, err := getStatus(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				// the copy is still pending, and is aborted above
+				continue
+			}
+			return fmt.Errorf("failed to get the status of the copy to %s: %w", toRelativePath, err)
+		}
+		status, description = newStatus, newDescription
+	}
+
+	if status != string(azblob.CopyStatusSuccess) {
+		if description != "" {
+			return fmt.Errorf("the copy to %s ended with status %s: %s", toRelativePath, status, description)
+		}
+		return fmt.Errorf("the copy to %s ended with status %s", toRelativePath, status)
+	}
+	return nil
+}
+
+// sweepSyncArchive deletes the folders under the archive prefix that were archived more than the retention period ago
+func (cca *cookedSyncCmdArgs) sweepSyncArchive(ctx context.Context, credInfo common.CredentialInfo) error {
+	cutoff := time.Now().Add(-time.Duration(cca.archiveRetentionDays) * 24 * time.Hour)
+	expired := func(relativePath string) bool {
+		archivedAt, err := time.Parse(syncArchiveTimeFormat, strings.SplitN(relativePath, "/", 2)[0])
+		return err == nil && archivedAt.Before(cutoff) // anything that's not named like an archive folder was put there by someone else
+	}
+
+	location := cca.fromTo.To()
+	if location == common.ELocation.Local() {
+		archiveRoot := common.GenerateFullPath(cca.destination.ValueLocal(), cca.archivePrefix)
+		entries, err := os.ReadDir(archiveRoot)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		remover := newInteractiveDeleteProcessor(func(object StoredObject) error {
+			msg := "Deleting expired archive: " + path.Join(cca.archivePrefix, object.relativePath)
+			glcm.Info(msg)
+			if azcopyScanningLogger != nil {
+				azcopyScanningLogger.Log(pipeline.LogInfo, msg)
+			}
+			return os.RemoveAll(common.GenerateFullPath(archiveRoot, object.relativePath))
+		}, common.EDeleteDestination.True(), "local file", common.ResourceString{Value: archiveRoot}, nil, cca.dryrunMode)
+		for _, entry := range entries {
+			if entry.IsDir() && expired(entry.Name()) {
+				_ = remover.removeImmediately(StoredObject{name: entry.Name(), relativePath: entry.Name(), entityType: common.EEntityType.Folder()})
+			}
+		}
+		return nil
+	}
+
+	archiveRoot := cca.destination.Clone()
+	rootURL, err := archiveRoot.FullURL()
+	if err != nil {
+		return err
+	}
+	switch location {
+	case common.ELocation.Blob():
+		blobURLParts := azblob.NewBlobURLParts(*rootURL)
+		blobURLParts.BlobName = path.Join(blobURLParts.BlobName, cca.archivePrefix)
+		*rootURL = blobURLParts.URL()
+	case common.ELocation.File():
+		fileURLParts := azfile.NewFileURLParts(*rootURL)
+		fileURLParts.DirectoryOrFilePath = path.Join(fileURLParts.DirectoryOrFilePath, cca.archivePrefix)
+		*rootURL = fileURLParts.URL()
+	case common.ELocation.BlobFS():
+		bfsURLParts := azbfs.NewBfsURLParts(*rootURL)
+		bfsURLParts.DirectoryOrFilePath = path.Join(bfsURLParts.DirectoryOrFilePath, cca.archivePrefix)
+		*rootURL = bfsURLParts.URL()
+	default:
+		return errors.New("archiving is not supported for " + location.String())
+	}
+	withoutSAS := *rootURL
+	withoutSAS.RawQuery = ""
+	archiveRoot.Value = withoutSAS.String()
+	// the archive prefix is always a folder
+	if location == common.ELocation.Blob() {
+		archiveRoot.Value += "/"
+	}
+
+	// folders are deleted as well, where there are any (i.e. Azure Files and BlobFS)
+	fpo := common.EFolderPropertiesOption.AllFolders()
+	if location == common.ELocation.Blob() {
+		fpo = common.EFolderPropertiesOption.NoFolders()
+	}
+	p, err := InitPipeline(ctx, location, credInfo, azcopyLogVerbosity.ToPipelineLogLevel(), cca.trailingDot)
+	if err != nil {
+		return err
+	}
+	deleter := newInteractiveDeleteProcessor(newRemoteResourceDeleter(rootURL, p, ctx, location, fpo, cca.forceIfReadOnly).delete,
+		common.EDeleteDestination.True(), location.String(), archiveRoot, nil, cca.dryrunMode)
+
+	traverser, err := InitResourceTraverser(archiveRoot, location, &ctx, &credInfo, common.ESymlinkHandlingType.Skip(), nil, true, false, false,
+		common.EPermanentDeleteOption.None(), nil, nil, false, common.ESyncHashType.None(), common.EPreservePermissionsOption.None(),
+		azcopyLogVerbosity.ToPipelineLogLevel(), cca.cpkOptions, nil, false, cca.trailingDot, nil)
+	if err != nil {
+		return err
+	}
+	return traverser.Traverse(noPreProccessor, func(object StoredObject) error {
+		if !expired(object.relativePath) {
+			return nil
+		}
+		return deleter.removeImmediately(object)
+	}, nil)
+}
@@ -31,13 +31,109 @@ import (
 const (
 	syncSkipReasonTime = "the source has an older LMT than the destination"
 	syncSkipReasonMissingHash = "the source lacks an associated hash; please upload with --put-md5"
+	syncSkipReasonMissingSyncHash = "the source lacks an associated hash; please upload with sync and the same --compare-hash"
 	syncSkipReasonSameHash = "the source has the same hash"
 	syncOverwriteReasonNewerHash = "the source has a differing hash"
 	syncOverwriteResaonNewerLMT = "the source is more recent than the destination"
+	syncOverwriteReasonDifferentSize = "the source has a different size than the destination"
 	syncStatusSkipped = "skipped"
 	syncStatusOverwritten = "overwritten"
+	syncSkipReasonSameSize = "the source has the same size as the destination"
+	syncSkipReasonNotNewerSameSize = "the source has an older LMT than the destination, and the same size"
+	syncOverwriteReasonUnverifiableHash = "the source or the destination lacks an associated hash, so they can't be verified to be the same"
+	syncSkipReasonMissingMetadata = "the source lacks the metadata key given by --compare-metadata-key"
+	syncSkipReasonSameMetadata = "the source has the same value of the metadata key"
+	syncOverwriteReasonDifferentMetadata = "the source has a differing value of the metadata key"
 )
 
+// syncSkipReasonMissing returns the reason for skipping a source that has no hash of the given type
+func syncSkipReasonMissing(hashType common.SyncHashType) string {
+	if hashType == common.ESyncHashType.MD5() {
+		return syncSkipReasonMissingHash
+	}
+	return syncSkipReasonMissingSyncHash
+}
+
+// metadataValue returns the value of the given metadata key of the object, and whether it has that key
+func metadataValue(object StoredObject, key string) (string, bool) {
+	for k, v := range object.Metadata {
+		if strings.EqualFold(k, key) { // remote services don't all preserve the case of metadata keys
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// syncCompareFiles decides whether a source file replaces the destination file, for the compare modes other than Default.
+// It returns the reason for its decision, to be logged.
+func syncCompareFiles(mode common.SyncCompareMode, hashType common.SyncHashType, metadataKey string, source, destination StoredObject, preferSMBTime bool) (transfer bool, reason string) {
+	switch mode {
+	case common.ESyncCompareMode.SizeOnly():
+		if source.size != destination.size {
+			return true, syncOverwriteReasonDifferentSize
+		}
+		return false, syncSkipReasonSameSize
+	case common.ESyncCompareMode.LMTAndSize():
+		if source.size != destination.size {
+			return true, syncOverwriteReasonDifferentSize
+		} else if source.isMoreRecentThan(destination, preferSMBTime) {
+			return true, syncOverwriteResaonNewerLMT
+		}
+		return false, syncSkipReasonNotNewerSameSize
+	case common.ESyncCompareMode.HashOnly():
+		sourceHash := source.syncHash(hashType)
+		destinationHash := destination.syncHash(hashType)
+		if sourceHash == nil || destinationHash == nil {
+			return true, syncOverwriteReasonUnverifiableHash
+		} else if !reflect.DeepEqual(sourceHash, destinationHash) {
+			return true, syncOverwriteReasonNewerHash
+		}
+		return false, syncSkipReasonSameHash
+	case common.ESyncCompareMode.Metadata():
+		sourceValue, ok := metadataValue(source, metadataKey)
+		if !ok {
+			return false, syncSkipReasonMissingMetadata
+		}
+		if destinationValue, ok := metadataValue(destination, metadataKey); !ok || sourceValue != destinationValue {
+			return true, syncOverwriteReasonDifferentMetadata
+		}
+		return false, syncSkipReasonSameMetadata
+	default:
+		panic("the default compare mode is handled by the comparators")
+	}
+}
+
+// syncCompareObjects decides whether a source object replaces the destination object, as syncDestinationComparator does:
+// files are compared by syncCompareFiles in the compare modes other than Default, and by hash with --compare-hash,
+// and anything else by last modified time. It returns the reason for its decision, to be logged.
+func syncCompareObjects(mode common.SyncCompareMode, hashType common.SyncHashType, metadataKey string, source, destination StoredObject, preferSMBTime bool) (transfer bool, reason string) {
+	isFile := source.entityType == common.EEntityType.File()
+	if mode != common.ESyncCompareMode.Default() && isFile {
+		return syncCompareFiles(mode, hashType, metadataKey, source, destination, preferSMBTime)
+	}
+
+	if hashType != common.ESyncHashType.None() && isFile {
+		sourceHash := source.syncHash(hashType)
+		if sourceHash == nil {
+			return false, syncSkipReasonMissing(hashType)
+		}
+		if !reflect.DeepEqual(sourceHash, destination.syncHash(hashType)) {
+			// hash inequality = source "newer" in this model.
+			return true, syncOverwriteReasonNewerHash
+		}
+		return false, syncSkipReasonSameHash
+	} else if source.isMoreRecentThan(destination, preferSMBTime) {
+		return true, syncOverwriteResaonNewerLMT
+	}
+	return false, syncSkipReasonTime
+}
+
+// syncSkipIsUnverified says whether a source was skipped for lack of what the comparison needs, rather than because it
+// compared as up to date. These skips are also shown on stdout, since the user can do something about them.
+func syncSkipIsUnverified(reason string) bool {
+	return reason == syncSkipReasonMissingHash || reason == syncSkipReasonMissingSyncHash || reason == syncSkipReasonMissingMetadata
+}
+
 func syncComparatorLog(fileName, status, skipReason string, stdout bool) {
 	out := fmt.Sprintf("File %s was %s because %s", fileName, status, skipReason)
 
@@ -50,6 +146,16 @@ func syncComparatorLog(fileName, status, skipReason string, stdout bool) {
 	}
 }
 
+// logSyncDecision logs the decision on an object, and records it in the report of a dry run if there's one
+func logSyncDecision(report *syncReport, object StoredObject, status, reason string, stdout bool) {
+	syncComparatorLog(object.relativePath, status, reason, stdout)
+	if status == syncStatusOverwritten {
+		report.record(syncReportActionCopy, object, reason)
+	} else {
+		report.record(syncReportActionSkip, object, reason)
+	}
+}
+
 // with the help of an objectIndexer containing the source objects
 // find out the destination objects that should be transferred
 // in other words, this should be used when destination is being enumerated secondly
@@ -65,12 +171,19 @@ type syncDestinationComparator struct {
 
 	comparisonHashType common.SyncHashType
 
+	// how the files that exist on both sides are compared, and the metadata key that's compared in the Metadata mode
+	compareMode        common.SyncCompareMode
+	compareMetadataKey string
+
   	preferSMBTime     bool
 	disableComparison bool
+
+	// records the decisions in the report of a dry run, if there's one
+	report *syncReport
 }
 
-func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison bool) *syncDestinationComparator {
-	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType}
+func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor, comparisonHashType common.SyncHashType, compareMode common.SyncCompareMode, compareMetadataKey string, preferSMBTime, disableComparison bool) *syncDestinationComparator {
+	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType, compareMode: compareMode, compareMetadataKey: compareMetadataKey}
 }
 
 // it will only schedule transfers for destination objects that are present in the indexer but stale compared to the entry in the map
@@ -90,34 +203,16 @@ func (f *syncDestinationComparator) processIfNecessary(destinationObject StoredO
 		defer delete(f.sourceIndex.indexMap, destinationObject.relativePath)
 
 		if f.disableComparison {
+			f.report.record(syncReportActionCopy, sourceObjectInMap, syncReportReasonNoComparison)
 			return f.copyTransferScheduler(sourceObjectInMap)
 		}
 
-		if f.comparisonHashType != common.ESyncHashType.None() && sourceObjectInMap.entityType == common.EEntityType.File() {
-			switch f.comparisonHashType {
-			case common.ESyncHashType.MD5():
-				if sourceObjectInMap.md5 == nil {
-					syncComparatorLog(sourceObjectInMap.relativePath, syncStatusSkipped, syncSkipReasonMissingHash, true)
-					return nil
-				}
-
-				if !reflect.DeepEqual(sourceObjectInMap.md5, destinationObject.md5) {
-					syncComparatorLog(sourceObjectInMap.relativePath, syncStatusOverwritten, syncOverwriteReasonNewerHash, false)
-
-					// hash inequality = source "newer" in this model.
-					return f.copyTransferScheduler(sourceObjectInMap)
-				}
-			default:
-				panic("sanity check: unsupported hash type " + f.comparisonHashType.String())
-			}
-
-			syncComparatorLog(sourceObjectInMap.relativePath, syncStatusSkipped, syncSkipReasonSameHash, false)
-			return nil
-		} else if sourceObjectInMap.isMoreRecentThan(destinationObject, f.preferSMBTime) {
+		transfer, reason := syncCompareObjects(f.compareMode, f.comparisonHashType, f.compareMetadataKey, sourceObjectInMap, destinationObject, f.preferSMBTime)
+		if transfer {
+			logSyncDecision(f.report, sourceObjectInMap, syncStatusOverwritten, reason, false)
 			return f.copyTransferScheduler(sourceObjectInMap)
 		}
-
-		syncComparatorLog(sourceObjectInMap.relativePath, syncStatusOverwritten, syncOverwriteResaonNewerLMT, false)
+		logSyncDecision(f.report, sourceObjectInMap, syncStatusSkipped, reason, syncSkipIsUnverified(reason))
 	} else {
 		// purposefully ignore the error from destinationCleaner
 		// it's a tolerable error, since it just means some extra destination object might hang around a bit longer
@@ -134,17 +229,31 @@ type syncSourceComparator struct {
 	// the processor responsible for scheduling copy transfers
 	copyTransferScheduler objectProcessor
 
+	// schedules the source objects that the destination lacks instead, if set, for them to be matched with moves (see syncMoveDetector)
+	missingScheduler objectProcessor
+
 	// storing the destination objects
 	destinationIndex *objectIndexer
 
 	comparisonHashType common.SyncHashType
 
+	// how the files that exist on both sides are compared, and the metadata key that's compared in the Metadata mode
+	compareMode        common.SyncCompareMode
+	compareMetadataKey string
+
   preferSMBTime     bool
 	disableComparison bool
+
+	// records the decisions in the report of a dry run, if there's one
+	report *syncReport
+
+	// compareSize makes a difference in size count as a change, and lets files without a hash fall back to LMT and size.
+	// Used for sources (S3, GCS) whose objects don't always carry a usable MD5.
+	compareSize bool
 }
 
-func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor, comparisonHashType common.SyncHashType, preferSMBTime, disableComparison bool) *syncSourceComparator {
-	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType}
+func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor, comparisonHashType common.SyncHashType, compareMode common.SyncCompareMode, compareMetadataKey string, preferSMBTime, disableComparison, compareSize bool) *syncSourceComparator {
+	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, preferSMBTime: preferSMBTime, disableComparison: disableComparison, comparisonHashType: comparisonHashType, compareMode: compareMode, compareMetadataKey: compareMetadataKey, compareSize: compareSize}
 }
 
 // it will only transfer source items that are:
@@ -167,39 +276,60 @@ func (f *syncSourceComparator) processIfNecessary(sourceObject StoredObject) err
 
     // if destination is stale, schedule source for transfer
 		if f.disableComparison {
+			f.report.record(syncReportActionCopy, sourceObject, syncReportReasonNoComparison)
 			return f.copyTransferScheduler(sourceObject)
 		}
 
-		if f.comparisonHashType != common.ESyncHashType.None() && sourceObject.entityType == common.EEntityType.File() {
-			switch f.comparisonHashType {
-			case common.ESyncHashType.MD5():
-				if sourceObject.md5 == nil {
-					syncComparatorLog(sourceObject.relativePath, syncStatusSkipped, syncSkipReasonMissingHash, true)
-					return nil
-				}
-
-				if !reflect.DeepEqual(sourceObject.md5, destinationObjectInMap.md5) {
-					// hash inequality = source "newer" in this model.
-					syncComparatorLog(sourceObject.relativePath, syncStatusOverwritten, syncOverwriteReasonNewerHash, false)
-					return f.copyTransferScheduler(sourceObject)
-				}
-			default:
-				panic("sanity check: unsupported hash type " + f.comparisonHashType.String())
+		isFile := sourceObject.entityType == common.EEntityType.File()
+		if f.compareMode != common.ESyncCompareMode.Default() && isFile {
+			transfer, reason := syncCompareFiles(f.compareMode, f.comparisonHashType, f.compareMetadataKey, sourceObject, destinationObjectInMap, f.preferSMBTime)
+			if transfer {
+				logSyncDecision(f.report, sourceObject, syncStatusOverwritten, reason, false)
+				return f.copyTransferScheduler(sourceObject)
+			}
+			logSyncDecision(f.report, sourceObject, syncStatusSkipped, reason, reason == syncSkipReasonMissingMetadata)
+			return nil
+		}
+
+		sourceHash := sourceObject.syncHash(f.comparisonHashType)
+		if sourceHash == nil && f.compareSize && f.comparisonHashType == common.ESyncHashType.MD5() {
+			// the ETag of an S3 object, which is the MD5 of its content unless it was uploaded in parts or encrypted
+			sourceHash = sourceObject.syncComparableMD5
+		}
+		hashUsable := sourceHash != nil || !f.compareSize
+
+		if f.comparisonHashType != common.ESyncHashType.None() && isFile && hashUsable {
+			if sourceHash == nil {
+				logSyncDecision(f.report, sourceObject, syncStatusSkipped, syncSkipReasonMissing(f.comparisonHashType), true)
+				return nil
+			}
+
+			if !reflect.DeepEqual(sourceHash, destinationObjectInMap.syncHash(f.comparisonHashType)) {
+				// hash inequality = source "newer" in this model.
+				logSyncDecision(f.report, sourceObject, syncStatusOverwritten, syncOverwriteReasonNewerHash, false)
+				return f.copyTransferScheduler(sourceObject)
 			}
 
-			syncComparatorLog(sourceObject.relativePath, syncStatusSkipped, syncSkipReasonSameHash, false)
+			logSyncDecision(f.report, sourceObject, syncStatusSkipped, syncSkipReasonSameHash, false)
 			return nil
+		} else if f.compareSize && isFile && sourceObject.size != destinationObjectInMap.size {
+			logSyncDecision(f.report, sourceObject, syncStatusOverwritten, syncOverwriteReasonDifferentSize, false)
+			return f.copyTransferScheduler(sourceObject)
 		} else if sourceObject.isMoreRecentThan(destinationObjectInMap, f.preferSMBTime) {
 			// if destination is stale, schedule source
-			syncComparatorLog(sourceObject.relativePath, syncStatusOverwritten, syncOverwriteResaonNewerLMT, false)
+			logSyncDecision(f.report, sourceObject, syncStatusOverwritten, syncOverwriteResaonNewerLMT, false)
 			return f.copyTransferScheduler(sourceObject)
 		}
 
-		syncComparatorLog(sourceObject.relativePath, syncStatusSkipped, syncSkipReasonTime, false)
+		logSyncDecision(f.report, sourceObject, syncStatusSkipped, syncSkipReasonTime, false)
 		// skip if dest is more recent
 		return nil
 	}
 
 	// if source does not exist at the destination, then schedule it for transfer
+	if f.missingScheduler != nil {
+		return f.missingScheduler(sourceObject)
+	}
+	f.report.record(syncReportActionCopy, sourceObject, syncReportReasonMissingAtDestination)
 	return f.copyTransferScheduler(sourceObject)
 }
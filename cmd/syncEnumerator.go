@@ -27,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
 
@@ -57,11 +58,14 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		}
 	}
 
+	// the source may be narrowed down to the listed paths, and by date
+	scope := newSyncScope(cca)
+
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// TODO: Consider passing an errorChannel so that enumeration errors during sync can be conveyed to the caller.
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	sourceTraverser, err := InitResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, cca.isHNSToHNS, common.EPermanentDeleteOption.None(), func(entityType common.EntityType) {
+	sourceTraverser, err := InitResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, common.ESymlinkHandlingType.Skip(), scope.listChannel(), cca.recursive, true, cca.isHNSToHNS, common.EPermanentDeleteOption.None(), func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
 		}
@@ -82,14 +86,39 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	destinationTraverser, err := InitResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, cca.isHNSToHNS, common.EPermanentDeleteOption.None(), func(entityType common.EntityType) {
+	countDestinationObject := func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicDestinationFilesScanned, 1)
 		}
-	}, nil, cca.s2sPreserveBlobTags, cca.compareHash, cca.preservePermissions, azcopyLogVerbosity.ToPipelineLogLevel(), cca.cpkOptions, nil, false, cca.trailingDot, nil)
+	}
+	destinationTraverser, err := InitResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, cca.isHNSToHNS, common.EPermanentDeleteOption.None(), countDestinationObject, nil, cca.s2sPreserveBlobTags, cca.compareHash, cca.preservePermissions, azcopyLogVerbosity.ToPipelineLogLevel(), cca.cpkOptions, nil, false, cca.trailingDot, nil)
 	if err != nil {
 		return nil, err
 	}
+	if scope.isNarrowed() {
+		sourceTraverser = scope.sourceTraverser(sourceTraverser)
+		destinationTraverser = scope.destinationTraverser(destinationTraverser)
+	}
+
+	// an incremental sync reads the destination from the manifest of the last sync, if there's one that can be used,
+	// and records the destination as it goes, for the manifest of the next sync
+	if cca.incremental {
+		manifestPath := syncPairFilePath(syncManifestFolder, syncManifestExtension, cca.source, cca.destination)
+		inUsePath, header, reason := openSyncManifest(manifestPath, cca)
+		if inUsePath != "" {
+			glcm.Info("Reading the destination from the manifest of the sync that started at " + header.CreatedAt.Format(time.RFC3339) + ", instead of listing it.")
+			destinationTraverser = newManifestTraverser(inUsePath, header, countDestinationObject)
+		} else {
+			glcm.Info("Listing the destination in full, because " + reason + ".")
+		}
+
+		if !cca.dryrunMode {
+			if cca.manifest, err = newSyncManifestRecorder(cca, manifestPath, inUsePath); err != nil {
+				return nil, fmt.Errorf("unable to record the sync manifest due to: %s", err.Error())
+			}
+			destinationTraverser = &recordingTraverser{ResourceTraverser: destinationTraverser, recorder: cca.manifest}
+		}
+	}
 
 	// verify that the traversers are targeting the same type of resources
 	sourceIsDir, _ := sourceTraverser.IsDirectory(true)
@@ -121,6 +150,18 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	filters = append(filters, buildRegexFilters(cca.includeRegex, true)...)
 	filters = append(filters, buildRegexFilters(cca.excludeRegex, false)...)
 
+	// the archive is kept at the destination, but it's not part of what's synced
+	if cca.archivePrefix != "" {
+		filters = append(filters, buildExcludeFilters([]string{cca.archivePrefix}, true)...)
+
+		if cca.archiveRetentionDays > 0 {
+			if err := cca.sweepSyncArchive(ctx, dstCredInfo); err != nil {
+				// the sync itself can go on; the expired archives are deleted by the next sync instead
+				glcm.Info("Failed to delete the expired archives due to error: " + err.Error())
+			}
+		}
+	}
+
 	// after making all filters, log any search prefix computed from them
 	if jobsAdmin.JobsAdmin != nil {
 		if prefixFilter := FilterSet(filters).GetEnumerationPreFilter(cca.recursive); prefixFilter != "" {
@@ -138,12 +179,65 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	}
 
 	transferScheduler := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	dispatchFinalPart := transferScheduler.dispatchFinalPart
+	if cca.watcher != nil {
+		if !sourceIsDir {
+			return nil, errors.New("watch is only supported for a source directory")
+		}
+
+		// a watching sync keeps its job open, and every scan adds to it
+		transferScheduler = cca.watcher.scanStarting(transferScheduler, filters, fpo)
+		dispatchFinalPart = cca.watcher.dispatchPart
+	}
+
+	// a dry run can report everything that sync would do, for it to be reviewed before it's run
+	var report *syncReport
+	if cca.dryrunReport != "" {
+		if report, err = newSyncReport(cca, fpo); err != nil {
+			return nil, fmt.Errorf("unable to write the dry-run report due to: %s", err.Error())
+		}
+		filters = report.recordFiltered(filters)
+		scope.report = report
+
+		dispatch := dispatchFinalPart
+		dispatchFinalPart = func() (bool, error) {
+			// everything that sync would do is known by now
+			if err := report.close(); err != nil {
+				return false, fmt.Errorf("unable to write the dry-run report due to: %s", err.Error())
+			}
+			glcm.Info("The dry-run report was written to " + cca.dryrunReport)
+			return dispatch()
+		}
+	}
+	copyScheduler := transferScheduler.scheduleCopyTransfer
+	if cca.manifest != nil {
+		copyScheduler = cca.manifest.recordTransfers(copyScheduler)
+	}
 
 	// set up the comparator so that the source/destination can be compared
 	indexer := newObjectIndexer()
+	indexer.enableSpill(cca.indexSpillThreshold, common.AzcopyJobPlanFolder, cca.jobID)
 	var comparator objectProcessor
 	var finalize func() error
 
+	if cca.bidirectional {
+		if !sourceIsDir {
+			return nil, errors.New("bidirectional sync is only supported between directories")
+		}
+
+		// the source is indexed first, and each destination object is compared against the source and the state of the last sync
+		twoWay, err := newTwoWaySync(ctx, cca, indexer, scope, fpo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to set up bidirectional sync due to: %s", err.Error())
+		}
+		cca.twoWay = twoWay
+
+		finalize = func() error {
+			return twoWay.finalize(filters)
+		}
+		return newSyncEnumerator(sourceTraverser, destinationTraverser, indexer, filters, twoWay.processIfNecessary, finalize), nil
+	}
+
 	switch cca.fromTo {
 	case common.EFromTo.LocalBlob(), common.EFromTo.LocalFile():
 		// Upload implies transferring from a local disk to a remote resource.
@@ -154,26 +248,56 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 			return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
 		}
 		destCleanerFunc := newFpoAwareProcessor(fpo, destinationCleaner.removeImmediately)
+		if report != nil {
+			destCleanerFunc = report.recordDeletions(destCleanerFunc)
+		}
+
+		missingScheduler := copyScheduler
+		if report != nil {
+			missingScheduler = report.recordCopies(copyScheduler, syncReportReasonMissingAtDestination)
+		}
+
+		// the extra files at the destination are held until the files that it lacks are known, for the moves to be matched
+		var moves *syncMoveDetector
+		if cca.detectMoves {
+			moves = newSyncMoveDetector(cca.compareHash, report)
+			moves.copy, moves.delete, moves.mover = missingScheduler, destCleanerFunc, destinationCleaner
+			destCleanerFunc, missingScheduler = moves.holdExtra, moves.matchMissing
+		}
+		if scope.isNarrowed() {
+			destCleanerFunc = scope.protectDeletions(destCleanerFunc)
+		}
 
 		// when uploading, we can delete remote objects immediately, because as we traverse the remote location
 		// we ALREADY have available a complete map of everything that exists locally
 		// so as soon as we see a remote destination object we can know whether it exists in the local source
 
-		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode).processIfNecessary
+		destinationComparator := newSyncDestinationComparator(indexer, copyScheduler, destCleanerFunc, cca.compareHash, cca.compareMode, cca.compareMetadataKey, cca.preserveSMBInfo, cca.mirrorMode)
+		destinationComparator.report = report
+		comparator = destinationComparator.processIfNecessary
+
 		finalize = func() error {
 			// schedule every local file that doesn't exist at the destination
-			err = indexer.traverse(transferScheduler.scheduleCopyTransfer, filters)
+			err = indexer.traverse(missingScheduler, filters)
 			if err != nil {
 				return err
 			}
+			if moves != nil {
+				if err = moves.releaseExtra(); err != nil {
+					return err
+				}
+			}
 
-			jobInitiated, err := transferScheduler.dispatchFinalPart()
+			jobInitiated, err := dispatchFinalPart()
 			// sync cleanly exits if nothing is scheduled.
 			if err != nil && err != NothingScheduledError {
 				return err
 			}
 
-			quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+			if !jobInitiated && cca.manifest != nil {
+				cca.manifest.finish(common.EJobStatus.Completed())
+			}
+			quitIfInSync(jobInitiated, cca.getDeletionCount() > 0 || cca.getMoveCount() > 0, cca)
 			cca.setScanningComplete()
 			return nil
 		}
@@ -183,38 +307,71 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		indexer.isDestinationCaseInsensitive = IsDestinationCaseInsensitive(cca.fromTo)
 		// in all other cases (download and S2S), the destination is scanned/indexed first
 		// then the source is scanned and filtered based on what the destination contains
-		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer, cca.compareHash, cca.preserveSMBInfo, cca.mirrorMode).processIfNecessary
+		// S3 and GCS objects don't always have an MD5 (e.g. multipart uploads), so their size is compared too
+		compareSize := cca.fromTo.From() == common.ELocation.S3() || cca.fromTo.From() == common.ELocation.GCP()
+		sourceComparator := newSyncSourceComparator(indexer, copyScheduler, cca.compareHash, cca.compareMode, cca.compareMetadataKey, cca.preserveSMBInfo, cca.mirrorMode, compareSize)
+		sourceComparator.report = report
+		comparator = sourceComparator.processIfNecessary
+
+		// the source files that the destination lacks are held until the extra files there are known, for the moves to be matched
+		var moves *syncMoveDetector
+		if cca.detectMoves {
+			moves = newSyncMoveDetector(cca.compareHash, report)
+			moves.copy = copyScheduler
+			if report != nil {
+				moves.copy = report.recordCopies(copyScheduler, syncReportReasonMissingAtDestination)
+			}
+			sourceComparator.missingScheduler = moves.holdMissing
+		}
 
 		finalize = func() error {
 			// remove the extra files at the destination that were not present at the source
 			// we can only know what needs to be deleted when we have FINISHED traversing the remote source
 			// since only then can we know which local files definitely don't exist remotely
-			var deleteScheduler objectProcessor
+			var deleter *interactiveDeleteProcessor
 			switch cca.fromTo.To() {
 			case common.ELocation.Blob(), common.ELocation.File(), common.ELocation.BlobFS():
-				deleter, err := newSyncDeleteProcessor(cca, fpo)
+				deleter, err = newSyncDeleteProcessor(cca, fpo)
 				if err != nil {
 					return err
 				}
-				deleteScheduler = newFpoAwareProcessor(fpo, deleter.removeImmediately)
 			default:
-				deleteScheduler = newFpoAwareProcessor(fpo, newSyncLocalDeleteProcessor(cca, fpo).removeImmediately)
+				deleter = newSyncLocalDeleteProcessor(cca, fpo)
+			}
+			deleteScheduler := newFpoAwareProcessor(fpo, deleter.removeImmediately)
+			if report != nil {
+				deleteScheduler = report.recordDeletions(deleteScheduler)
+			}
+			if moves != nil {
+				moves.delete, moves.mover = deleteScheduler, deleter
+				deleteScheduler = moves.matchExtra
+			}
+			if scope.isNarrowed() {
+				deleteScheduler = scope.protectDeletions(deleteScheduler)
 			}
 
 			err = indexer.traverse(deleteScheduler, nil)
 			if err != nil {
 				return err
 			}
+			if moves != nil {
+				if err = moves.releaseMissing(); err != nil {
+					return err
+				}
+			}
 
 			// let the deletions happen first
 			// otherwise if the final part is executed too quickly, we might quit before deletions could finish
-			jobInitiated, err := transferScheduler.dispatchFinalPart()
+			jobInitiated, err := dispatchFinalPart()
 			// sync cleanly exits if nothing is scheduled.
 			if err != nil && err != NothingScheduledError {
 				return err
 			}
 
-			quitIfInSync(jobInitiated, cca.getDeletionCount() > 0, cca)
+			if !jobInitiated && cca.manifest != nil {
+				cca.manifest.finish(common.EJobStatus.Completed())
+			}
+			quitIfInSync(jobInitiated, cca.getDeletionCount() > 0 || cca.getMoveCount() > 0, cca)
 			cca.setScanningComplete()
 			return nil
 		}
@@ -224,7 +381,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 }
 
 func IsDestinationCaseInsensitive(fromTo common.FromTo) bool {
-	if fromTo.IsDownload() && runtime.GOOS == "windows" {
+	if (fromTo.IsDownload() || fromTo.IsLocalToLocal()) && runtime.GOOS == "windows" {
 		return true
 	} else {
 		return false
@@ -0,0 +1,273 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const (
+	// the number of partitions that a spilled index is split into. The partitions of both sides are open at once,
+	// so this is kept well below the default limit on open files of some systems (256 on macOS).
+	syncIndexSpillPartitions = 64
+	// a partition that is still too big to load into memory is split again, into this many partitions, at most syncIndexSpillMaxDepth times
+	syncIndexSpillSubPartitions = 16
+	syncIndexSpillMaxDepth      = 4
+
+	// by default, the index spills to disk once it holds 10M objects
+	// (which was the fixed limit on the size of the index in earlier versions)
+	defaultSyncIndexSpillThreshold = 10 * 1000 * 1000
+)
+
+// spilledObject is the form in which a StoredObject is written to disk.
+// It mirrors StoredObject, since encoding/gob only sees exported fields.
+type spilledObject struct {
+	Name                string
+	EntityType          common.EntityType
+	LastModifiedTime    time.Time
+	SmbLastModifiedTime time.Time
+	Size                int64
+	Md5                 []byte
+	SyncComparableMD5   []byte
+	BlobType            azblob.BlobType
+	ContentDisposition  string
+	CacheControl        string
+	ContentLanguage     string
+	ContentEncoding     string
+	ContentType         string
+	RelativePath        string
+	ContainerName       string
+	DstContainerName    string
+	BlobAccessTier      azblob.AccessTierType
+	ArchiveStatus       azblob.ArchiveStatusType
+	Metadata            common.Metadata
+	BlobVersionID       string
+	BlobTags            common.BlobTags
+	BlobSnapshotID      string
+	BlobDeleted         bool
+	LeaseState          azblob.LeaseStateType
+	LeaseStatus         azblob.LeaseStatusType
+	LeaseDuration       azblob.LeaseDurationType
+}
+
+func newSpilledObject(o StoredObject) spilledObject {
+	return spilledObject{
+		Name:                o.name,
+		EntityType:          o.entityType,
+		LastModifiedTime:    o.lastModifiedTime,
+		SmbLastModifiedTime: o.smbLastModifiedTime,
+		Size:                o.size,
+		Md5:                 o.md5,
+		SyncComparableMD5:   o.syncComparableMD5,
+		BlobType:            o.blobType,
+		ContentDisposition:  o.contentDisposition,
+		CacheControl:        o.cacheControl,
+		ContentLanguage:     o.contentLanguage,
+		ContentEncoding:     o.contentEncoding,
+		ContentType:         o.contentType,
+		RelativePath:        o.relativePath,
+		ContainerName:       o.ContainerName,
+		DstContainerName:    o.DstContainerName,
+		BlobAccessTier:      o.blobAccessTier,
+		ArchiveStatus:       o.archiveStatus,
+		Metadata:            o.Metadata,
+		BlobVersionID:       o.blobVersionID,
+		BlobTags:            o.blobTags,
+		BlobSnapshotID:      o.blobSnapshotID,
+		BlobDeleted:         o.blobDeleted,
+		LeaseState:          o.leaseState,
+		LeaseStatus:         o.leaseStatus,
+		LeaseDuration:       o.leaseDuration,
+	}
+}
+
+func (s spilledObject) toStoredObject() StoredObject {
+	return StoredObject{
+		name:                s.Name,
+		entityType:          s.EntityType,
+		lastModifiedTime:    s.LastModifiedTime,
+		smbLastModifiedTime: s.SmbLastModifiedTime,
+		size:                s.Size,
+		md5:                 s.Md5,
+		syncComparableMD5:   s.SyncComparableMD5,
+		blobType:            s.BlobType,
+		contentDisposition:  s.ContentDisposition,
+		cacheControl:        s.CacheControl,
+		contentLanguage:     s.ContentLanguage,
+		contentEncoding:     s.ContentEncoding,
+		contentType:         s.ContentType,
+		relativePath:        s.RelativePath,
+		ContainerName:       s.ContainerName,
+		DstContainerName:    s.DstContainerName,
+		blobAccessTier:      s.BlobAccessTier,
+		archiveStatus:       s.ArchiveStatus,
+		Metadata:            s.Metadata,
+		blobVersionID:       s.BlobVersionID,
+		blobTags:            s.BlobTags,
+		blobSnapshotID:      s.BlobSnapshotID,
+		blobDeleted:         s.BlobDeleted,
+		leaseState:          s.LeaseState,
+		leaseStatus:         s.LeaseStatus,
+		leaseDuration:       s.LeaseDuration,
+	}
+}
+
+// spillFile is a file of StoredObjects, which are read back in the order in which they were written
+type spillFile struct {
+	path    string
+	count   int
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *gob.Encoder
+}
+
+func newSpillFile(path string) (*spillFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bufio.NewWriter(file)
+	return &spillFile{path: path, file: file, buffer: buffer, encoder: gob.NewEncoder(buffer)}, nil
+}
+
+func (f *spillFile) write(storedObject StoredObject) error {
+	f.count++
+	return f.encoder.Encode(newSpilledObject(storedObject))
+}
+
+// close finishes the writing of the file. It's safe to call more than once.
+func (f *spillFile) close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.buffer.Flush()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.file = nil
+	return err
+}
+
+// read passes each object in the (closed) file to the given function, in the order in which they were written
+func (f *spillFile) read(process func(StoredObject) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(bufio.NewReader(file))
+	for {
+		var s spilledObject
+		if err = decoder.Decode(&s); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("couldn't read the sync index from %s: %w", f.path, err)
+		}
+		if err = process(s.toStoredObject()); err != nil {
+			return err
+		}
+	}
+}
+
+// objectIndexSpill holds the objects of an objectIndexer on disk, once there are too many of them to hold in memory.
+// The objects are partitioned by a hash of their index key, and the objects of the other side of the sync are partitioned
+// the same way, so every object lands in the same partition as its counterpart on the other side. The two sides can then
+// be compared one partition at a time, and only one partition of the index needs to be in memory at once.
+type objectIndexSpill struct {
+	folder    string
+	primary   []*spillFile
+	secondary []*spillFile
+
+	// the indexed objects that had no counterpart on the other side, once the partitions have been compared
+	remaining *spillFile
+}
+
+func newObjectIndexSpill(parentFolder string, jobID common.JobID) (*objectIndexSpill, error) {
+	folder, err := os.MkdirTemp(parentFolder, jobID.String()+"--syncindex-")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &objectIndexSpill{folder: folder}
+	if s.primary, err = s.newPartitions("primary", syncIndexSpillPartitions); err != nil {
+		return s, err
+	}
+	if s.secondary, err = s.newPartitions("secondary", syncIndexSpillPartitions); err != nil {
+		return s, err
+	}
+	s.remaining, err = newSpillFile(filepath.Join(folder, "remaining"))
+	return s, err
+}
+
+func (s *objectIndexSpill) newPartitions(prefix string, count int) ([]*spillFile, error) {
+	partitions := make([]*spillFile, count)
+	for p := range partitions {
+		var err error
+		if partitions[p], err = newSpillFile(filepath.Join(s.folder, fmt.Sprintf("%s-%d", prefix, p))); err != nil {
+			return partitions, err
+		}
+	}
+	return partitions, nil
+}
+
+// partitionOf returns the partition of the given index key. A different seed is used at each depth of splitting,
+// so that the keys of a partition are spread out when it is split again.
+func partitionOf(key string, depth int, partitionCount int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte{byte(depth)})
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum64() % uint64(partitionCount))
+}
+
+// split divides the given (closed) file into the given partitions
+func split(f *spillFile, partitions []*spillFile, depth int, indexKey func(string) string) error {
+	err := f.read(func(storedObject StoredObject) error {
+		return partitions[partitionOf(indexKey(storedObject.relativePath), depth, len(partitions))].write(storedObject)
+	})
+	for _, partition := range partitions {
+		if closeErr := partition.close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// remove deletes everything that was spilled to disk
+func (s *objectIndexSpill) remove() error {
+	for _, f := range append(append(s.primary, s.secondary...), s.remaining) {
+		if f != nil {
+			_ = f.close()
+		}
+	}
+	return os.RemoveAll(s.folder)
+}
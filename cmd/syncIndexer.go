@@ -21,13 +21,24 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
 )
 
 // the objectIndexer is essential for the generic sync enumerator to work
 // it can serve as a:
 //  1. objectProcessor: accumulate a lookup map with given StoredObjects
 //  2. resourceTraverser: go through the entities in the map like a traverser
+//
+// When it holds more than spillThreshold objects, it spills them to disk (see objectIndexSpill), and the objects of
+// the other side of the sync are compared against the index one partition at a time, once they have all been enumerated.
 type objectIndexer struct {
 	indexMap map[string]StoredObject
 	counter  int
@@ -37,30 +48,155 @@ type objectIndexer struct {
 	// Apple File System (APFS) can be configured to be case-sensitive or case-insensitive.
 	// So for such locations, the key in the indexMap will be lowercase to avoid infinite syncing.
 	isDestinationCaseInsensitive bool
+
+	// spillThreshold is the number of objects above which the index is spilled to spillFolder. Zero means never.
+	spillThreshold int
+	spillFolder    string
+	jobID          common.JobID
+	spill          *objectIndexSpill
 }
 
 func newObjectIndexer() *objectIndexer {
 	return &objectIndexer{indexMap: make(map[string]StoredObject)}
 }
 
+// enableSpill lets the index spill to the given folder once it holds more than the given number of objects
+func (i *objectIndexer) enableSpill(threshold int, folder string, jobID common.JobID) {
+	i.spillThreshold = threshold
+	i.spillFolder = folder
+	i.jobID = jobID
+}
+
+func (i *objectIndexer) indexKey(relativePath string) string {
+	if i.isDestinationCaseInsensitive {
+		return strings.ToLower(relativePath)
+	}
+	return relativePath
+}
+
 // process the given stored object by indexing it using its relative path
 func (i *objectIndexer) store(storedObject StoredObject) (err error) {
-	// TODO we might buffer too much data in memory, figure out whether we should limit the max number of files
-	// TODO previously we used 10M as the max, but it was proven to be too small for some users
-
 	// It is safe to index all StoredObjects just by relative path, regardless of their entity type, because
 	// no filesystem allows a file and a folder to have the exact same full path.  This is true of
 	// Linux file systems, Windows, Azure Files and ADLS Gen 2 (and logically should be true of all file systems).
-	if i.isDestinationCaseInsensitive {
-		lcRelativePath := strings.ToLower(storedObject.relativePath)
-		i.indexMap[lcRelativePath] = storedObject
-	} else {
-		i.indexMap[storedObject.relativePath] = storedObject
-	}
+	key := i.indexKey(storedObject.relativePath)
 	i.counter += 1
+
+	if i.spill != nil {
+		return i.spill.primary[partitionOf(key, 0, len(i.spill.primary))].write(storedObject)
+	}
+
+	i.indexMap[key] = storedObject
+	if i.spillThreshold > 0 && len(i.indexMap) > i.spillThreshold {
+		return i.startSpilling()
+	}
 	return
 }
 
+// startSpilling moves the index from memory to disk, where all further objects are stored too
+func (i *objectIndexer) startSpilling() (err error) {
+	i.spill, err = newObjectIndexSpill(i.spillFolder, i.jobID)
+	if err != nil {
+		return fmt.Errorf("couldn't spill the sync index to disk: %w", err)
+	}
+	if jobsAdmin.JobsAdmin != nil {
+		jobsAdmin.JobsAdmin.LogToJobLog(fmt.Sprintf("The sync index holds more than %d objects, so it is being spilled to disk, in %s", i.spillThreshold, i.spill.folder), pipeline.LogInfo)
+	}
+
+	for key, storedObject := range i.indexMap {
+		if err = i.spill.primary[partitionOf(key, 0, len(i.spill.primary))].write(storedObject); err != nil {
+			return err
+		}
+	}
+	i.indexMap = make(map[string]StoredObject)
+	return nil
+}
+
+// comparatorFor returns the processor for the objects of the other side of the sync.
+// While the index is in memory, that's the comparator itself. Once it has spilled, the objects are spilled too,
+// and compared later by compareSpilled.
+func (i *objectIndexer) comparatorFor(comparator objectProcessor) objectProcessor {
+	if i.spill == nil {
+		return comparator
+	}
+	return func(storedObject StoredObject) error {
+		return i.spill.secondary[partitionOf(i.indexKey(storedObject.relativePath), 0, len(i.spill.secondary))].write(storedObject)
+	}
+}
+
+// compareSpilled passes the spilled objects of the other side of the sync to the comparator, one partition at a time,
+// with the matching partition of the index loaded into indexMap. It does nothing if the index never spilled.
+func (i *objectIndexer) compareSpilled(comparator objectProcessor) error {
+	if i.spill == nil {
+		return nil
+	}
+
+	for p := range i.spill.primary {
+		if err := i.comparePartition(i.spill.primary[p], i.spill.secondary[p], comparator, 0); err != nil {
+			return err
+		}
+	}
+	return i.spill.remaining.close()
+}
+
+func (i *objectIndexer) comparePartition(primary, secondary *spillFile, comparator objectProcessor, depth int) error {
+	if err := primary.close(); err != nil {
+		return err
+	}
+	if err := secondary.close(); err != nil {
+		return err
+	}
+
+	// a partition that's too big to load is split, along with its counterpart, into smaller partitions
+	if primary.count > i.spillThreshold && depth < syncIndexSpillMaxDepth {
+		subPrimary, err := i.spill.newPartitions(fmt.Sprintf("%s-%d", filepath.Base(primary.path), depth), syncIndexSpillSubPartitions)
+		if err == nil {
+			err = split(primary, subPrimary, depth+1, i.indexKey)
+		}
+		if err != nil {
+			return err
+		}
+		subSecondary, err := i.spill.newPartitions(fmt.Sprintf("%s-%d", filepath.Base(secondary.path), depth), syncIndexSpillSubPartitions)
+		if err == nil {
+			err = split(secondary, subSecondary, depth+1, i.indexKey)
+		}
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(primary.path)
+		_ = os.Remove(secondary.path)
+
+		for p := range subPrimary {
+			if err = i.comparePartition(subPrimary[p], subSecondary[p], comparator, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := primary.read(func(storedObject StoredObject) error {
+		i.indexMap[i.indexKey(storedObject.relativePath)] = storedObject
+		return nil
+	})
+	if err == nil {
+		err = secondary.read(comparator)
+	}
+	if err != nil {
+		return err
+	}
+
+	// whatever is left in the index had no counterpart, and is kept for traverse
+	for _, storedObject := range i.indexMap {
+		if err = i.spill.remaining.write(storedObject); err != nil {
+			return err
+		}
+	}
+	i.indexMap = make(map[string]StoredObject)
+	_ = os.Remove(primary.path)
+	_ = os.Remove(secondary.path)
+	return nil
+}
+
 // go through the remaining stored objects in the map to process them
 func (i *objectIndexer) traverse(processor objectProcessor, filters []ObjectFilter) (err error) {
 	for _, value := range i.indexMap {
@@ -70,5 +206,20 @@ func (i *objectIndexer) traverse(processor objectProcessor, filters []ObjectFilt
 			return
 		}
 	}
+
+	if i.spill != nil {
+		return i.spill.remaining.read(func(storedObject StoredObject) error {
+			_, err := getProcessingError(processIfPassedFilters(filters, storedObject, processor))
+			return err
+		})
+	}
 	return
 }
+
+// cleanup removes anything that the index spilled to disk
+func (i *objectIndexer) cleanup() {
+	if i.spill != nil {
+		_ = i.spill.remove()
+		i.spill = nil
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/jobsAdmin"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+const (
+	// the manifest of each source and destination pair is kept in this folder, under the job plan folder
+	syncManifestFolder    = "syncmanifest"
+	syncManifestExtension = ".manifest"
+
+	// a manifest is renamed with this suffix while a sync uses it, so that a sync that doesn't finish leaves no manifest behind
+	syncManifestInUseSuffix = ".inuse"
+
+	// the version of the format of manifests. Manifests of other versions are ignored.
+	syncManifestVersion = 1
+
+	// by default, the destination is listed in full at least once a week
+	defaultSyncManifestMaxAge = 7 * 24 * time.Hour
+)
+
+// syncPairFilePath returns the path of a file that sync keeps about a source and destination pair, under the job plan folder
+func syncPairFilePath(folder, extension string, source, destination common.ResourceString) string {
+	// the SAS is kept apart from Value, so a pair keeps its files when its SAS is renewed
+	sum := sha256.Sum256([]byte(source.Value + "\n" + destination.Value))
+	return filepath.Join(common.AzcopyJobPlanFolder, folder, hex.EncodeToString(sum[:])+extension)
+}
+
+// syncManifestHeader comes first in a manifest, followed by the objects at the destination, one spilledObject each
+type syncManifestHeader struct {
+	Version     int
+	Source      string
+	Destination string
+
+	// when the sync that wrote the manifest started scanning
+	CreatedAt time.Time
+
+	// whether the destination is a directory
+	IsDirectory bool
+
+	// the options that decide which objects are listed, and which of their properties are compared.
+	// A manifest that was written with other options doesn't hold what a listing would find, so it isn't used.
+	Options string
+}
+
+// syncManifestOptions describes the options of a sync that decide what its manifest holds
+func syncManifestOptions(cca *cookedSyncCmdArgs) string {
+	return fmt.Sprintf("recursive=%t;hash=%s;compareMode=%s;compareMetadataKey=%q;include=%q;exclude=%q;excludePath=%q;includeRegex=%q;excludeRegex=%q;includeAttributes=%q;excludeAttributes=%q;archive=%q",
+		cca.recursive, cca.compareHash, cca.compareMode, cca.compareMetadataKey, cca.includePatterns, cca.excludePatterns, cca.excludePaths,
+		cca.includeRegex, cca.excludeRegex, cca.includeFileAttributes, cca.excludeFileAttributes, cca.archivePrefix)
+}
+
+// openSyncManifest prepares the manifest of the pair to be read in place of a listing of the destination.
+// It returns "" along with the reason, if there's no manifest that can be used.
+func openSyncManifest(manifestPath string, cca *cookedSyncCmdArgs) (inUsePath string, header syncManifestHeader, reason string) {
+	file, err := os.Open(manifestPath)
+	if os.IsNotExist(err) {
+		return "", header, "there's no manifest of an earlier sync"
+	} else if err != nil {
+		return "", header, err.Error()
+	}
+
+	err = gob.NewDecoder(bufio.NewReader(file)).Decode(&header)
+	_ = file.Close()
+	switch {
+	case err != nil:
+		return "", header, "the manifest can't be read: " + err.Error()
+	case header.Version != syncManifestVersion:
+		return "", header, "the manifest was written by another version of AzCopy"
+	case header.Source != cca.source.Value || header.Destination != cca.destination.Value:
+		return "", header, "the manifest is of another source or destination"
+	case header.Options != syncManifestOptions(cca):
+		return "", header, "the manifest was written by a sync with other options"
+	case time.Since(header.CreatedAt) > cca.manifestMaxAge:
+		return "", header, fmt.Sprintf("the manifest is older than %v", cca.manifestMaxAge)
+	}
+
+	// in dry-run mode, nothing is changed, so the manifest stays where it is
+	if cca.dryrunMode {
+		return manifestPath, header, ""
+	}
+
+	inUsePath = manifestPath + syncManifestInUseSuffix
+	if err = os.Rename(manifestPath, inUsePath); err != nil {
+		return "", header, err.Error()
+	}
+	return inUsePath, header, ""
+}
+
+// syncManifestRecorder keeps track of the state of the destination as a sync goes, to write the manifest once the job is done.
+// The objects at the destination are written to disk as they're listed (or read from the manifest), so the size of the
+// destination doesn't bound the size of the manifest. Only the paths that the sync changes are held in memory.
+type syncManifestRecorder struct {
+	cca          *cookedSyncCmdArgs
+	manifestPath string
+	// the manifest that was read instead of listing the destination, if any, to be removed once the new manifest is written
+	inUsePath   string
+	isDirectory bool
+
+	// when the sync started scanning. An object that this sync transfers is last modified at the destination after this.
+	startTime time.Time
+
+	// the objects at the destination before the sync
+	destination *spillFile
+	// the objects transferred by the sync, as they will be at the destination
+	transferred *spillFile
+	// the paths that the sync changes (by transferring or deleting)
+	changed map[string]struct{}
+}
+
+func newSyncManifestRecorder(cca *cookedSyncCmdArgs, manifestPath, inUsePath string) (*syncManifestRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(manifestPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	r := &syncManifestRecorder{
+		cca:          cca,
+		manifestPath: manifestPath,
+		inUsePath:    inUsePath,
+		startTime:    time.Now(),
+		changed:      make(map[string]struct{}),
+	}
+	var err error
+	if r.destination, err = newSpillFile(manifestPath + "." + cca.jobID.String() + "--destination"); err != nil {
+		return nil, err
+	}
+	if r.transferred, err = newSpillFile(manifestPath + "." + cca.jobID.String() + "--transferred"); err != nil {
+		r.discard()
+		return nil, err
+	}
+	return r, nil
+}
+
+// recordingTraverser passes on the objects of the traverser under it, and records them as the objects at the destination
+type recordingTraverser struct {
+	ResourceTraverser
+	recorder *syncManifestRecorder
+}
+
+func (t *recordingTraverser) IsDirectory(isSource bool) (bool, error) {
+	isDir, err := t.ResourceTraverser.IsDirectory(isSource)
+	t.recorder.isDirectory = isDir
+	return isDir, err
+}
+
+func (t *recordingTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	return t.ResourceTraverser.Traverse(preprocessor, func(storedObject StoredObject) error {
+		if err := t.recorder.destination.write(storedObject); err != nil {
+			return err
+		}
+		return processor(storedObject)
+	}, filters)
+}
+
+// recordTransfers returns a copy scheduler that records the objects it schedules as transferred
+func (r *syncManifestRecorder) recordTransfers(scheduler objectProcessor) objectProcessor {
+	return func(storedObject StoredObject) error {
+		transferred := storedObject
+		// the exact time is only known to the destination; it's no earlier than the start of the sync
+		transferred.lastModifiedTime = r.startTime
+		if !r.cca.preserveSMBInfo {
+			transferred.smbLastModifiedTime = time.Time{}
+		}
+		if err := r.transferred.write(transferred); err != nil {
+			return err
+		}
+		r.changed[storedObject.relativePath] = struct{}{}
+		return scheduler(storedObject)
+	}
+}
+
+// recordDeletions returns a deleter that records the objects it deletes
+func (r *syncManifestRecorder) recordDeletions(deleter objectProcessor) objectProcessor {
+	return func(storedObject StoredObject) error {
+		err := deleter(storedObject)
+		if err == nil {
+			r.changed[storedObject.relativePath] = struct{}{}
+		}
+		return err
+	}
+}
+
+// recordMoves returns a mover that records the objects it moves, as deleted from their old paths and transferred to their new ones
+func (r *syncManifestRecorder) recordMoves(mover syncObjectMover) syncObjectMover {
+	return func(storedObject StoredObject, toRelativePath string) error {
+		if err := mover(storedObject, toRelativePath); err != nil {
+			return err
+		}
+		moved := storedObject
+		moved.relativePath = toRelativePath
+		moved.name = path.Base(toRelativePath)
+		moved.lastModifiedTime = r.startTime
+		if err := r.transferred.write(moved); err != nil {
+			return err
+		}
+		r.changed[storedObject.relativePath] = struct{}{}
+		r.changed[toRelativePath] = struct{}{}
+		return nil
+	}
+}
+
+// write writes the manifest, once the job (if any) is done. The objects whose transfer failed are left out,
+// since their state at the destination isn't known, so that the next sync transfers them again.
+func (r *syncManifestRecorder) write() error {
+	defer r.discard()
+
+	err := r.destination.close()
+	if err == nil {
+		err = r.transferred.close()
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+	forEachFailedTransfer(r.cca.jobID, func(fromTo common.FromTo, source string) {
+		failed[source] = true
+	})
+
+	tempPath := r.manifestPath + "." + r.cca.jobID.String() + "--manifest"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	buffer := bufio.NewWriter(file)
+	encoder := gob.NewEncoder(buffer)
+
+	err = encoder.Encode(syncManifestHeader{
+		Version:     syncManifestVersion,
+		Source:      r.cca.source.Value,
+		Destination: r.cca.destination.Value,
+		CreatedAt:   r.startTime,
+		IsDirectory: r.isDirectory,
+		Options:     syncManifestOptions(r.cca),
+	})
+	if err == nil {
+		err = r.destination.read(func(storedObject StoredObject) error {
+			if _, changed := r.changed[storedObject.relativePath]; changed {
+				return nil
+			}
+			return encoder.Encode(newSpilledObject(storedObject))
+		})
+	}
+	if err == nil {
+		err = r.transferred.read(func(storedObject StoredObject) error {
+			if failed[pathEncodeRules(storedObject.relativePath, r.cca.fromTo, false, true)] {
+				return nil
+			}
+			return encoder.Encode(newSpilledObject(storedObject))
+		})
+	}
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, r.manifestPath)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// discard removes the files of the recorder, along with the manifest that was in use
+func (r *syncManifestRecorder) discard() {
+	for _, f := range []*spillFile{r.destination, r.transferred} {
+		if f != nil {
+			_ = f.close()
+			_ = os.Remove(f.path)
+		}
+	}
+	if r.inUsePath != "" {
+		_ = os.Remove(r.inUsePath)
+	}
+}
+
+// finish writes the manifest if the job is done, and otherwise discards what was recorded
+func (r *syncManifestRecorder) finish(jobStatus common.JobStatus) {
+	if jobStatus == common.EJobStatus.Cancelled() || jobStatus == common.EJobStatus.Failed() {
+		r.discard()
+		return
+	}
+	if err := r.write(); err != nil {
+		msg := "Failed to write the sync manifest; the next sync will list the destination in full: " + err.Error()
+		glcm.Info(msg)
+		if azcopyScanningLogger != nil {
+			azcopyScanningLogger.Log(pipeline.LogWarning, msg)
+		}
+	}
+}
+
+// forEachFailedTransfer calls the given function with the direction and (encoded) source of each transfer of the job that didn't succeed
+func forEachFailedTransfer(jobID common.JobID, process func(fromTo common.FromTo, source string)) {
+	if jobsAdmin.JobsAdmin == nil {
+		return
+	}
+	jm, found := jobsAdmin.JobsAdmin.JobMgr(jobID)
+	if !found {
+		return
+	}
+
+	for partNum := ste.PartNumber(0); true; partNum++ {
+		jpm, found := jm.JobPartMgr(partNum)
+		if !found {
+			break
+		}
+		plan := jpm.Plan()
+		for i := uint32(0); i < plan.NumTransfers; i++ {
+			if plan.Transfer(i).TransferStatus() == common.ETransferStatus.Success() {
+				continue
+			}
+			source, _ := plan.TransferSrcDstRelatives(i)
+			process(plan.FromTo, source)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// syncObjectMover moves an object at the destination to another path under it
+type syncObjectMover func(object StoredObject, toRelativePath string) error
+
+// syncMoveKey is what a file that was moved is recognized by: its size and hash
+type syncMoveKey struct {
+	size int64
+	hash string
+}
+
+// syncMoveDetector detects the files that were moved (or renamed) at the source since the last sync, so that they're moved
+// at the destination too, instead of being copied there again and deleted from their old paths.
+// A file that the destination lacks is taken to have been moved from an extra file at the destination of the same size and hash.
+// Whichever of the two sets is known first is held, and the other is matched against it once the destination has been scanned:
+// the extra files when uploading, since the source is indexed first, and otherwise the missing files.
+type syncMoveDetector struct {
+	hashType common.SyncHashType
+
+	// schedules the copy of a file that the destination lacks, and deletes an extra file at the destination,
+	// for the files that aren't matched, and for the matches that fail to be moved
+	copy   objectProcessor
+	delete objectProcessor
+
+	mover  *interactiveDeleteProcessor
+	report *syncReport
+
+	held map[syncMoveKey][]StoredObject
+}
+
+func newSyncMoveDetector(hashType common.SyncHashType, report *syncReport) *syncMoveDetector {
+	return &syncMoveDetector{hashType: hashType, report: report, held: make(map[syncMoveKey][]StoredObject)}
+}
+
+// key returns the key of a file whose move can be detected. Empty files, and files without a hash, can't be told apart.
+func (d *syncMoveDetector) key(object StoredObject) (syncMoveKey, bool) {
+	if object.entityType != common.EEntityType.File() || object.size == 0 {
+		return syncMoveKey{}, false
+	}
+	hash := object.syncHash(d.hashType)
+	if len(hash) == 0 {
+		return syncMoveKey{}, false
+	}
+	return syncMoveKey{size: object.size, hash: string(hash)}, true
+}
+
+// hold holds the object, if its move can be detected, and returns whether it did
+func (d *syncMoveDetector) hold(object StoredObject) bool {
+	key, ok := d.key(object)
+	if ok {
+		d.held[key] = append(d.held[key], object)
+	}
+	return ok
+}
+
+// take removes a held file that matches the object, if there's one
+func (d *syncMoveDetector) take(object StoredObject) (StoredObject, bool) {
+	key, ok := d.key(object)
+	if !ok || len(d.held[key]) == 0 {
+		return StoredObject{}, false
+	}
+	matches := d.held[key]
+	match := matches[len(matches)-1]
+	if len(matches) == 1 {
+		delete(d.held, key)
+	} else {
+		d.held[key] = matches[:len(matches)-1]
+	}
+	return match, true
+}
+
+// holdExtra is the deleter of the extra files at the destination, when they're known first
+func (d *syncMoveDetector) holdExtra(object StoredObject) error {
+	if d.hold(object) {
+		return nil
+	}
+	return d.delete(object)
+}
+
+// holdMissing is the copy scheduler of the files that the destination lacks, when they're known first
+func (d *syncMoveDetector) holdMissing(object StoredObject) error {
+	if d.hold(object) {
+		return nil
+	}
+	return d.copy(object)
+}
+
+// matchMissing moves the held extra file that matches a file that the destination lacks, if there's one, and otherwise copies it
+func (d *syncMoveDetector) matchMissing(object StoredObject) error {
+	if extra, ok := d.take(object); ok {
+		return d.move(extra, object)
+	}
+	return d.copy(object)
+}
+
+// matchExtra moves an extra file to the path of the held missing file that matches it, if there's one, and otherwise deletes it
+func (d *syncMoveDetector) matchExtra(object StoredObject) error {
+	if missing, ok := d.take(object); ok {
+		return d.move(object, missing)
+	}
+	return d.delete(object)
+}
+
+// releaseExtra deletes the held extra files that weren't matched
+func (d *syncMoveDetector) releaseExtra() error {
+	return d.release(d.delete)
+}
+
+// releaseMissing copies the held missing files that weren't matched
+func (d *syncMoveDetector) releaseMissing() error {
+	return d.release(d.copy)
+}
+
+func (d *syncMoveDetector) release(processor objectProcessor) error {
+	for key, objects := range d.held {
+		for _, object := range objects {
+			if err := processor(object); err != nil {
+				return err
+			}
+		}
+		delete(d.held, key)
+	}
+	return nil
+}
+
+func (d *syncMoveDetector) move(extra, missing StoredObject) error {
+	d.report.record(syncReportActionMove, missing, fmt.Sprintf(syncReportReasonMovedFrom, extra.relativePath))
+	if err := d.mover.moveImmediately(extra, missing.relativePath); err != nil {
+		// both are still where they were
+		if err := d.delete(extra); err != nil {
+			return err
+		}
+		return d.copy(missing)
+	}
+	return nil
+}
@@ -35,6 +35,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // extract the right info from cooked arguments and instantiate a generic copy transfer processor from it
@@ -48,13 +49,15 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 		SourceRoot:          cca.source.CloneWithConsolidatedSeparators(),
 		DestinationRoot:     cca.destination.CloneWithConsolidatedSeparators(),
 		CredentialInfo:      cca.credentialInfo,
+		Priority:            cca.priority,
 
 		// flags
 		BlobAttributes: common.BlobTransferAttributes{
 			PreserveLastModifiedTime: cca.preserveSMBInfo, // true by default for sync so that future syncs have this information available
 			PutMd5:                   cca.putMd5,
 			MD5ValidationOption:      cca.md5ValidationOption,
-			BlockSizeInBytes:         cca.blockSize},
+			BlockSizeInBytes:         cca.blockSize,
+			SyncHashType:             cca.compareHash},
 		ForceWrite:                     common.EOverwriteOption.True(), // once we decide to transfer for a sync operation, we overwrite the destination regardless
 		ForceIfReadOnly:                cca.forceIfReadOnly,
 		LogLevel:                       azcopyLogVerbosity,
@@ -105,6 +108,11 @@ type interactiveDeleteProcessor struct {
 	// count the deletions that happened
 	incrementDeletionCount func()
 
+	// the plugged-in mover that moves an extra object to the path of a missing one, where moves are detected (see syncMoveDetector),
+	// and the count of the moves that happened
+	mover              syncObjectMover
+	incrementMoveCount func()
+
 	// dryrunMode
 	dryrunMode bool
 }
@@ -143,18 +151,7 @@ func (d *interactiveDeleteProcessor) removeImmediately(object StoredObject) (err
 				common.PanicIfErr(err)
 				return string(jsonOutput)
 			} else { // remove for sync
-				if d.objectTypeToDisplay == "local file" { // removing from local src
-					dryrunValue := fmt.Sprintf("DRYRUN: remove %v", common.ToShortPath(d.objectLocationToDisplay))
-					if runtime.GOOS == "windows" {
-						dryrunValue += "\\" + strings.ReplaceAll(object.relativePath, "/", "\\")
-					} else { // linux and mac
-						dryrunValue += "/" + object.relativePath
-					}
-					return dryrunValue
-				}
-				return fmt.Sprintf("DRYRUN: remove %v/%v",
-					d.objectLocationToDisplay,
-					object.relativePath)
+				return "DRYRUN: remove " + d.dryrunPath(object.relativePath)
 			}
 		})
 		return nil
@@ -175,6 +172,58 @@ func (d *interactiveDeleteProcessor) removeImmediately(object StoredObject) (err
 	return nil // Missing a file is an error, but it's not show-stopping. We logged it earlier; that's OK.
 }
 
+// moveImmediately moves an extra object to the path of a missing one, instead of deleting the one and copying the other.
+// A move that fails is logged, and its error returned, for the caller to fall back to the deletion and the copy.
+func (d *interactiveDeleteProcessor) moveImmediately(object StoredObject, toRelativePath string) error {
+	if d.dryrunMode {
+		glcm.Dryrun(func(format common.OutputFormat) string {
+			if format == common.EOutputFormat.Json() {
+				moveTransfer := newDeleteTransfer(object)
+				moveTransfer.Destination = toRelativePath
+				jsonOutput, err := json.Marshal(moveTransfer)
+				common.PanicIfErr(err)
+				return string(jsonOutput)
+			}
+			return "DRYRUN: move " + d.dryrunPath(object.relativePath) + " to " + d.dryrunPath(toRelativePath)
+		})
+		return nil
+	}
+
+	msg := "Moving extra object: " + object.relativePath + " to " + toRelativePath
+	glcm.Info(msg)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogInfo, msg)
+	}
+
+	if err := d.mover(object, toRelativePath); err != nil {
+		msg := fmt.Sprintf("error %s moving the object %s to %s", err.Error(), object.relativePath, toRelativePath)
+		glcm.Info(msg + "; it's copied instead")
+		if azcopyScanningLogger != nil {
+			azcopyScanningLogger.Log(pipeline.LogError, msg)
+		}
+		return err
+	}
+
+	if d.incrementMoveCount != nil {
+		d.incrementMoveCount()
+	}
+	return nil
+}
+
+// dryrunPath returns the path of an object that a dry run prints
+func (d *interactiveDeleteProcessor) dryrunPath(relativePath string) string {
+	if d.objectTypeToDisplay == "local file" { // removing from local src
+		dryrunValue := common.ToShortPath(d.objectLocationToDisplay)
+		if runtime.GOOS == "windows" {
+			dryrunValue += "\\" + strings.ReplaceAll(relativePath, "/", "\\")
+		} else { // linux and mac
+			dryrunValue += "/" + relativePath
+		}
+		return dryrunValue
+	}
+	return fmt.Sprintf("%v/%v", d.objectLocationToDisplay, relativePath)
+}
+
 func (d *interactiveDeleteProcessor) promptForConfirmation(object StoredObject) (shouldDelete bool, keepPrompting bool) {
 	answer := glcm.Prompt(fmt.Sprintf("The %s '%s' does not exist at the source. "+
 		"Do you wish to delete it from the destination(%s)?",
@@ -225,7 +274,14 @@ func newInteractiveDeleteProcessor(deleter objectProcessor, deleteDestination co
 
 func newSyncLocalDeleteProcessor(cca *cookedSyncCmdArgs, fpo common.FolderPropertyOption) *interactiveDeleteProcessor {
 	localDeleter := localFileDeleter{rootPath: cca.destination.ValueLocal(), fpo: fpo, folderManager: common.NewFolderDeletionManager(context.Background(), fpo, azcopyScanningLogger)}
-	return newInteractiveDeleteProcessor(localDeleter.deleteFile, cca.deleteDestination, "local file", cca.destination, cca.incrementDeletionCount, cca.dryrunMode)
+	deleter := localDeleter.deleteFile
+	if cca.archivePrefix != "" {
+		archiver := &localFileArchiver{localFileDeleter: localDeleter, archiveFolder: syncArchiveFolder(cca.archivePrefix, time.Now())}
+		deleter = archiver.archiveFile
+	}
+	processor := newInteractiveDeleteProcessor(deleter, cca.deleteDestination, "local file", cca.destination, cca.incrementDeletionCount, cca.dryrunMode)
+	processor.mover, processor.incrementMoveCount = localDeleter.moveFile, cca.incrementMoveCount
+	return processor
 }
 
 type localFileDeleter struct {
@@ -282,8 +338,21 @@ func newSyncDeleteProcessor(cca *cookedSyncCmdArgs, fpo common.FolderPropertyOpt
 		return nil, err
 	}
 
-	return newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To(), fpo, cca.forceIfReadOnly).delete,
-		cca.deleteDestination, cca.fromTo.To().String(), cca.destination, cca.incrementDeletionCount, cca.dryrunMode), nil
+	remoteDeleter := newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To(), fpo, cca.forceIfReadOnly)
+	deleter := remoteDeleter.delete
+	if cca.archivePrefix != "" {
+		deleter = newRemoteResourceArchiver(remoteDeleter, syncArchiveFolder(cca.archivePrefix, time.Now())).archive
+	}
+	mover := remoteDeleter.move
+	if cca.manifest != nil {
+		deleter = cca.manifest.recordDeletions(deleter)
+		mover = cca.manifest.recordMoves(mover)
+	}
+
+	processor := newInteractiveDeleteProcessor(deleter,
+		cca.deleteDestination, cca.fromTo.To().String(), cca.destination, cca.incrementDeletionCount, cca.dryrunMode)
+	processor.mover, processor.incrementMoveCount = mover, cca.incrementMoveCount
+	return processor, nil
 }
 
 type remoteResourceDeleter struct {
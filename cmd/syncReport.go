@@ -0,0 +1,258 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// the actions that a dry run of sync reports for each object
+const (
+	syncReportActionCopy   = "Copy"
+	syncReportActionDelete = "Delete"
+	syncReportActionSkip   = "Skip"
+	syncReportActionMove   = "Move"
+)
+
+// the reasons for the actions that the comparators don't log
+const (
+	syncReportReasonMissingAtDestination = "the destination lacks it"
+	syncReportReasonMissingAtSource      = "the source lacks it"
+	syncReportReasonArchived             = "the source lacks it, so it's moved under the archive prefix"
+	syncReportReasonDeletionDisabled     = "the source lacks it, but delete-destination is false"
+	syncReportReasonNoComparison         = "mirror-mode disables the comparison"
+	syncReportReasonFiltered             = "it's excluded by the filters"
+	syncReportReasonMovedFrom            = "the destination lacks it, but has the same file at %s, which the source lacks"
+)
+
+// syncReportEntry is what the report says of one object
+type syncReportEntry struct {
+	Action           string
+	Path             string
+	EntityType       string
+	Size             int64
+	LastModifiedTime time.Time
+	Reason           string
+}
+
+// syncReportHeader opens a JSON report, which is followed by its entries and then its summary
+type syncReportHeader struct {
+	Source      string
+	Destination string
+	CreatedAt   time.Time
+}
+
+// syncReport writes a report of what a dry run of sync would do to each object, for the sync to be reviewed before it's run.
+// Entries are written as they're decided, so the report isn't held in memory. It's written to a temporary file first,
+// so that a dry run that fails doesn't leave an incomplete report behind.
+type syncReport struct {
+	path     string
+	tempPath string
+	format   common.SyncReportFormat
+	fpo      common.FolderPropertyOption
+
+	// what's done to the objects that the source lacks
+	missingAtSourceAction string
+	missingAtSourceReason string
+
+	mu       sync.Mutex
+	file     *os.File
+	buffer   *bufio.Writer
+	csv      *csv.Writer
+	entries  int
+	counts   map[string]int
+	filtered map[string]struct{} // the filters apply to both sides, so a path may be filtered twice
+	err      error
+}
+
+func newSyncReport(cca *cookedSyncCmdArgs, fpo common.FolderPropertyOption) (*syncReport, error) {
+	r := &syncReport{
+		path:                  cca.dryrunReport,
+		tempPath:              cca.dryrunReport + "." + cca.jobID.String() + "--report",
+		format:                cca.dryrunReportFormat,
+		fpo:                   fpo,
+		missingAtSourceAction: syncReportActionSkip,
+		missingAtSourceReason: syncReportReasonDeletionDisabled,
+		counts:                map[string]int{},
+		filtered:              map[string]struct{}{},
+	}
+	if cca.deleteDestination == common.EDeleteDestination.True() {
+		r.missingAtSourceAction, r.missingAtSourceReason = syncReportActionDelete, syncReportReasonMissingAtSource
+		if cca.archivePrefix != "" {
+			r.missingAtSourceReason = syncReportReasonArchived
+		}
+	}
+
+	var err error
+	if r.file, err = os.OpenFile(r.tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.DEFAULT_FILE_PERM); err != nil {
+		return nil, err
+	}
+	r.buffer = bufio.NewWriter(r.file)
+
+	if r.format == common.ESyncReportFormat.Csv() {
+		r.csv = csv.NewWriter(r.buffer)
+		r.err = r.csv.Write([]string{"Action", "Path", "EntityType", "Size", "LastModifiedTime", "Reason"})
+	} else {
+		var header []byte
+		header, r.err = json.Marshal(syncReportHeader{Source: cca.source.Value, Destination: cca.destination.Value, CreatedAt: time.Now()})
+		if r.err == nil {
+			// the entries and the summary are added to the fields of the header
+			_, r.err = fmt.Fprintf(r.buffer, "%s,\"Entries\":[", header[:len(header)-1])
+		}
+	}
+	if r.err != nil {
+		r.discard()
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// record adds an entry for the object to the report, unless sync ignores objects of its type. A nil report records nothing.
+func (r *syncReport) record(action string, object StoredObject, reason string) {
+	if r == nil || !object.isCompatibleWithEntitySettings(r.fpo, common.ESymlinkHandlingType.Skip()) {
+		return
+	}
+	entry := syncReportEntry{
+		Action:           action,
+		Path:             object.relativePath,
+		EntityType:       object.entityType.String(),
+		Size:             object.size,
+		LastModifiedTime: object.lastModifiedTime,
+		Reason:           reason,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if action == syncReportActionSkip && reason == syncReportReasonFiltered {
+		if _, ok := r.filtered[entry.Path]; ok {
+			return
+		}
+		r.filtered[entry.Path] = struct{}{}
+	}
+
+	if r.csv != nil {
+		r.err = r.csv.Write([]string{entry.Action, entry.Path, entry.EntityType, strconv.FormatInt(entry.Size, 10), entry.LastModifiedTime.UTC().Format(time.RFC3339Nano), entry.Reason})
+	} else {
+		var line []byte
+		if line, r.err = json.Marshal(entry); r.err == nil {
+			if r.entries > 0 {
+				_, r.err = r.buffer.WriteString(",")
+			}
+			if r.err == nil {
+				_, r.err = r.buffer.Write(append([]byte("\n"), line...))
+			}
+		}
+	}
+	r.entries++
+	r.counts[action]++
+}
+
+// recordCopies returns a copy scheduler that records the objects it schedules, for the given reason
+func (r *syncReport) recordCopies(scheduler objectProcessor, reason string) objectProcessor {
+	return func(storedObject StoredObject) error {
+		r.record(syncReportActionCopy, storedObject, reason)
+		return scheduler(storedObject)
+	}
+}
+
+// recordDeletions returns a deleter that records the objects that are passed to it, which the source lacks
+func (r *syncReport) recordDeletions(deleter objectProcessor) objectProcessor {
+	return func(storedObject StoredObject) error {
+		r.record(r.missingAtSourceAction, storedObject, r.missingAtSourceReason)
+		return deleter(storedObject)
+	}
+}
+
+// recordFiltered wraps the filters so that the objects they exclude are recorded
+func (r *syncReport) recordFiltered(filters []ObjectFilter) []ObjectFilter {
+	wrapped := make([]ObjectFilter, len(filters))
+	for i, f := range filters {
+		wrapped[i] = &syncReportFilter{ObjectFilter: f, report: r}
+	}
+	return wrapped
+}
+
+// close completes the report and moves it into place. Nothing is recorded after it's closed.
+func (r *syncReport) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.err
+	if err == nil {
+		if r.csv != nil {
+			r.csv.Flush()
+			err = r.csv.Error()
+		} else {
+			var summary []byte
+			if summary, err = json.Marshal(r.counts); err == nil {
+				_, err = fmt.Fprintf(r.buffer, "\n],\"Summary\":%s}\n", summary)
+			}
+		}
+	}
+	if err == nil {
+		err = r.buffer.Flush()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(r.tempPath, r.path)
+	}
+	if err != nil {
+		_ = os.Remove(r.tempPath)
+	}
+
+	r.err = fmt.Errorf("the report is closed")
+	return err
+}
+
+// discard removes the report without completing it
+func (r *syncReport) discard() {
+	_ = r.file.Close()
+	_ = os.Remove(r.tempPath)
+}
+
+// syncReportFilter records the objects that its filter excludes.
+// The filters it wraps don't take part in narrowing down the listing (see FilterSet.GetEnumerationPreFilter), so that everything that's filtered is seen.
+type syncReportFilter struct {
+	ObjectFilter
+	report *syncReport
+}
+
+func (f *syncReportFilter) DoesPass(storedObject StoredObject) bool {
+	if f.ObjectFilter.DoesPass(storedObject) {
+		return true
+	}
+	f.report.record(syncReportActionSkip, storedObject, syncReportReasonFiltered)
+	return false
+}
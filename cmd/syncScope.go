@@ -0,0 +1,264 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+const (
+	syncScopeReasonOutOfScope = "the source isn't in scope, since it's excluded by --include-before or --include-after"
+	syncScopeReasonNotScanned = "the source isn't in scope, since its listed path couldn't be scanned"
+)
+
+// readSyncListOfFiles reads the relative paths that are listed in the file given by --list-of-files, one per line
+func readSyncListOfFiles(listPath string) ([]string, error) {
+	file, err := os.Open(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s file passed with the list-of-files flag", listPath)
+	}
+	defer file.Close()
+
+	// the UTF-8 byte order marker may be on the first line
+	utf8BOM := string([]byte{0xEF, 0xBB, 0xBF})
+
+	var listed []string
+	scanner := bufio.NewScanner(file)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+		// empty paths would select the root, and with it everything
+		if line = strings.Trim(line, "/"); line != "" {
+			listed = append(listed, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read %s file passed with the list-of-files flag: %s", listPath, err.Error())
+	}
+	if len(listed) == 0 {
+		return nil, fmt.Errorf("the %s file passed with the list-of-files flag lists no paths", listPath)
+	}
+	return listed, nil
+}
+
+// syncScope narrows a sync down to part of its source, with --list-of-files and the date filters.
+// Unlike the other filters, these only apply to the source, and the destination objects that the source lacks are only
+// deleted if the source was in scope where they are: a file that's excluded by date still exists at the source,
+// and a listed path that couldn't be scanned at the source may well have files under it.
+type syncScope struct {
+	// the paths that are synced, or nil if the whole source is synced
+	listed []string
+
+	// the date filters of the source
+	dateFilters []ObjectFilter
+
+	// the source paths that are excluded by date, and the listed paths that couldn't be scanned at the source
+	mu         sync.Mutex
+	outOfScope map[string]struct{}
+	notScanned []string
+
+	// the dry-run report, if there's one
+	report *syncReport
+}
+
+func newSyncScope(cca *cookedSyncCmdArgs) *syncScope {
+	s := &syncScope{listed: cca.listOfFiles, outOfScope: map[string]struct{}{}}
+	if cca.includeBefore != nil {
+		s.dateFilters = append(s.dateFilters, &IncludeBeforeDateFilter{Threshold: *cca.includeBefore})
+	}
+	if cca.includeAfter != nil {
+		s.dateFilters = append(s.dateFilters, &IncludeAfterDateFilter{Threshold: *cca.includeAfter})
+	}
+	return s
+}
+
+// isNarrowed says whether the sync is narrowed down to part of its source at all
+func (s *syncScope) isNarrowed() bool {
+	return s.listed != nil || len(s.dateFilters) > 0
+}
+
+// listChannel streams the listed paths, for the list traverser of the source
+func (s *syncScope) listChannel() chan string {
+	if s.listed == nil {
+		return nil
+	}
+	listChan := make(chan string)
+	go func() {
+		defer close(listChan)
+		for _, listed := range s.listed {
+			listChan <- listed
+		}
+	}()
+	return listChan
+}
+
+// sourceTraverser applies the date filters to the source, and notes the listed paths that couldn't be scanned
+func (s *syncScope) sourceTraverser(traverser ResourceTraverser) ResourceTraverser {
+	if list, ok := traverser.(*listTraverser); ok {
+		list.childFailed = func(childPath string) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.notScanned = append(s.notScanned, childPath)
+		}
+	}
+
+	var filters []ObjectFilter
+	for _, f := range s.dateFilters {
+		filters = append(filters, &syncScopeFilter{ObjectFilter: f, scope: s})
+	}
+	return &scopedTraverser{ResourceTraverser: traverser, filters: filters, isDirectory: s.listed != nil}
+}
+
+// destinationTraverser narrows the destination down to the listed paths
+func (s *syncScope) destinationTraverser(traverser ResourceTraverser) ResourceTraverser {
+	if s.listed == nil {
+		return traverser
+	}
+	listed := make(map[string]struct{}, len(s.listed))
+	for _, p := range s.listed {
+		listed[p] = struct{}{}
+	}
+	return &scopedTraverser{ResourceTraverser: traverser, filters: []ObjectFilter{&listedPathsFilter{listed: listed}}}
+}
+
+// protectDeletions returns a deleter that only deletes the destination objects where the source was in scope
+func (s *syncScope) protectDeletions(deleter objectProcessor) objectProcessor {
+	return func(object StoredObject) error {
+		if reason := s.deletionBlocked(object.relativePath); reason != "" {
+			syncComparatorLog(object.relativePath, syncStatusSkipped, reason, false)
+			s.report.record(syncReportActionSkip, object, reason)
+			return nil
+		}
+		return deleter(object)
+	}
+}
+
+func (s *syncScope) deletionBlocked(relativePath string) (reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.outOfScope[relativePath]; ok {
+		return syncScopeReasonOutOfScope
+	}
+	for _, notScanned := range s.notScanned {
+		if isUnderSyncPath(relativePath, notScanned) {
+			return syncScopeReasonNotScanned
+		}
+	}
+	return ""
+}
+
+// covers says whether the source is in scope for a path: whether the path is synced at all, and the source was neither
+// excluded by date there, nor left unscanned
+func (s *syncScope) covers(relativePath string) bool {
+	if s.listed != nil {
+		listed := false
+		for _, p := range s.listed {
+			listed = listed || isUnderSyncPath(relativePath, p)
+		}
+		if !listed {
+			return false
+		}
+	}
+	return s.deletionBlocked(relativePath) == ""
+}
+
+// isUnderSyncPath says whether the relative path is the given path, or is under it
+func isUnderSyncPath(relativePath, parent string) bool {
+	return relativePath == parent || strings.HasPrefix(relativePath, parent+"/")
+}
+
+// scopedTraverser applies filters of its own, on top of the ones it's given
+type scopedTraverser struct {
+	ResourceTraverser
+	filters []ObjectFilter
+
+	// a list traverser doesn't know that its paths are under a directory
+	isDirectory bool
+}
+
+func (t *scopedTraverser) IsDirectory(isSource bool) (bool, error) {
+	if t.isDirectory {
+		return true, nil
+	}
+	return t.ResourceTraverser.IsDirectory(isSource)
+}
+
+func (t *scopedTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	return t.ResourceTraverser.Traverse(preprocessor, processor, append(append([]ObjectFilter{}, filters...), t.filters...))
+}
+
+// syncScopeFilter notes the source objects that a date filter excludes
+type syncScopeFilter struct {
+	ObjectFilter
+	scope *syncScope
+}
+
+func (f *syncScopeFilter) DoesPass(storedObject StoredObject) bool {
+	if f.ObjectFilter.DoesPass(storedObject) {
+		return true
+	}
+
+	f.scope.mu.Lock()
+	f.scope.outOfScope[storedObject.relativePath] = struct{}{}
+	f.scope.mu.Unlock()
+	f.scope.report.record(syncReportActionSkip, storedObject, syncReportReasonFiltered)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogDebug, fmt.Sprintf("File %s is excluded by --include-before or --include-after", storedObject.relativePath))
+	}
+	return false
+}
+
+// listedPathsFilter passes the objects that are at or under the listed paths
+type listedPathsFilter struct {
+	listed map[string]struct{}
+}
+
+func (f *listedPathsFilter) DoesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *listedPathsFilter) AppliesOnlyToFiles() bool {
+	return false
+}
+
+func (f *listedPathsFilter) DoesPass(storedObject StoredObject) bool {
+	// check the path, and then each of its parents
+	for p := storedObject.relativePath; p != ""; {
+		if _, ok := f.listed[p]; ok {
+			return true
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return false
+}
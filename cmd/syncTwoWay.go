@@ -0,0 +1,622 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const (
+	// the state of each two-way sync pair is kept in this folder, under the job plan folder
+	twoWaySyncStateFolder    = "syncstate"
+	twoWaySyncStateExtension = ".state"
+
+	// the format of the timestamp in the name of the older version of a file that's kept by the KeepBoth conflict policy
+	twoWaySyncConflictTimeFormat = "20060102T150405Z"
+)
+
+// the pairs that two-way sync supports, and the direction in which the destination is synced back to the source.
+// Both directions of a sync run in the same job, which has one set of credentials, so one side has to be local.
+// Such a job can't be resumed or retried, since those give each side its own SAS; the sync is run again instead.
+var twoWaySyncReverseFromTo = map[common.FromTo]common.FromTo{
+	common.EFromTo.LocalBlob(): common.EFromTo.BlobLocal(),
+	common.EFromTo.BlobLocal(): common.EFromTo.LocalBlob(),
+	common.EFromTo.LocalFile(): common.EFromTo.FileLocal(),
+	common.EFromTo.FileLocal(): common.EFromTo.LocalFile(),
+}
+
+// twoWaySyncSide is what a two-way sync saw of a file on one side, when the file was last in sync
+type twoWaySyncSide struct {
+	LastModifiedTime time.Time
+	Size             int64
+
+	// the hash of the type given by --compare-hash, if the file had one
+	Hash []byte
+}
+
+type twoWaySyncStateEntry struct {
+	Source      twoWaySyncSide
+	Destination twoWaySyncSide
+}
+
+// twoWaySyncState is the view of both sides of a two-way sync as of the end of the last sync, which is how the next sync
+// tells what was created, modified or deleted on each side since. It's kept in a file per source/destination pair.
+type twoWaySyncState struct {
+	Source      string
+	Destination string
+	Entries     map[string]twoWaySyncStateEntry
+}
+
+// twoWaySyncStatePath returns the path of the state file of the given pair
+func twoWaySyncStatePath(source, destination common.ResourceString) string {
+	return syncPairFilePath(twoWaySyncStateFolder, twoWaySyncStateExtension, source, destination)
+}
+
+// loadTwoWaySyncState reads the state of a pair, which is empty if the pair was never synced before
+func loadTwoWaySyncState(statePath string, source, destination common.ResourceString) (*twoWaySyncState, error) {
+	state := &twoWaySyncState{Source: source.Value, Destination: destination.Value, Entries: make(map[string]twoWaySyncStateEntry)}
+
+	file, err := os.Open(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err = gob.NewDecoder(bufio.NewReader(file)).Decode(state); err != nil {
+		return nil, fmt.Errorf("couldn't read the two-way sync state from %s: %w", statePath, err)
+	}
+	if state.Entries == nil { // gob leaves out empty maps
+		state.Entries = make(map[string]twoWaySyncStateEntry)
+	}
+	return state, nil
+}
+
+// save writes the state to a temporary file first, so that a failure never leaves a partial state behind
+func (s *twoWaySyncState) save(statePath string) error {
+	if err := os.MkdirAll(filepath.Dir(statePath), os.ModePerm); err != nil {
+		return err
+	}
+
+	tempPath := statePath + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	buffer := bufio.NewWriter(file)
+	err = gob.NewEncoder(buffer).Encode(s)
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, statePath)
+}
+
+type twoWaySyncAction uint8
+
+const (
+	twoWaySyncNothing twoWaySyncAction = iota
+	twoWaySyncInSync                   // both sides changed, the same way
+	twoWaySyncCopyToDestination
+	twoWaySyncCopyToSource
+	twoWaySyncDeleteAtDestination
+	twoWaySyncDeleteAtSource
+	twoWaySyncConflict
+)
+
+// twoWaySyncComparison compares two versions of a file the way one-way sync compares a source file with the destination file,
+// following --compare-mode and --compare-hash
+type twoWaySyncComparison struct {
+	mode          common.SyncCompareMode
+	hashType      common.SyncHashType
+	preferSMBTime bool
+}
+
+func newTwoWaySyncComparison(cca *cookedSyncCmdArgs) twoWaySyncComparison {
+	return twoWaySyncComparison{mode: cca.compareMode, hashType: cca.compareHash, preferSMBTime: cca.preserveSMBInfo}
+}
+
+// replaces says whether one-way sync would copy the file over the other version
+func (c twoWaySyncComparison) replaces(file, other StoredObject) (transfer bool, reason string) {
+	// the Metadata mode is only supported between remote resources, which two-way sync isn't
+	return syncCompareObjects(c.mode, c.hashType, "", file, other, c.preferSMBTime)
+}
+
+// changed says whether the file changed since it was last in sync, which is when one-way sync would copy it over the version
+// that was last in sync
+func (c twoWaySyncComparison) changed(file StoredObject, lastInSync twoWaySyncSide) bool {
+	transfer, _ := c.replaces(file, c.lastInSync(file, lastInSync))
+	return transfer
+}
+
+// identical says whether the files at both sides are known to be the same, which is when one-way sync would copy neither
+// over the other, and not for lack of a hash
+func (c twoWaySyncComparison) identical(source, destination StoredObject) bool {
+	for _, pair := range [][2]StoredObject{{source, destination}, {destination, source}} {
+		if transfer, reason := c.replaces(pair[0], pair[1]); transfer || syncSkipIsUnverified(reason) {
+			return false
+		}
+	}
+	return true
+}
+
+// forFirstSync returns the comparison of the files at both sides on the first sync of a pair, when there's no state to tell
+// which side changed. Unless their hashes are compared, files of the same size are taken to be the same then,
+// so that the files that were already on both sides don't all count as conflicts.
+func (c twoWaySyncComparison) forFirstSync() twoWaySyncComparison {
+	if c.mode == common.ESyncCompareMode.Default() && c.hashType == common.ESyncHashType.None() {
+		c.mode = common.ESyncCompareMode.SizeOnly()
+	}
+	return c
+}
+
+// side returns what the state keeps of a file, which is what the comparison needs of it
+func (c twoWaySyncComparison) side(file StoredObject) twoWaySyncSide {
+	lmt := file.lastModifiedTime
+	if c.preferSMBTime && !file.smbLastModifiedTime.IsZero() {
+		lmt = file.smbLastModifiedTime
+	}
+	return twoWaySyncSide{LastModifiedTime: lmt, Size: file.size, Hash: file.syncHash(c.hashType)}
+}
+
+// lastInSync returns the version of the file that was last in sync, as the state keeps it
+func (c twoWaySyncComparison) lastInSync(file StoredObject, side twoWaySyncSide) StoredObject {
+	lastInSync := StoredObject{name: file.name, relativePath: file.relativePath, entityType: file.entityType, lastModifiedTime: side.LastModifiedTime, size: side.Size}
+	lastInSync.setSyncHash(c.hashType, side.Hash)
+	return lastInSync
+}
+
+// decideTwoWaySync works out what to do with a path, given the file on each side (nil if there's none)
+// and the state of the path when it was last in sync (nil if it never was)
+func decideTwoWaySync(source, destination *StoredObject, entry *twoWaySyncStateEntry, comparison twoWaySyncComparison) twoWaySyncAction {
+	sourceChanged := source != nil && (entry == nil || comparison.changed(*source, entry.Source))
+	destinationChanged := destination != nil && (entry == nil || comparison.changed(*destination, entry.Destination))
+
+	switch {
+	case source != nil && destination != nil:
+		switch {
+		case !sourceChanged && !destinationChanged:
+			return twoWaySyncNothing
+		case !destinationChanged:
+			return twoWaySyncCopyToDestination
+		case !sourceChanged:
+			return twoWaySyncCopyToSource
+		case entry == nil && comparison.forFirstSync().identical(*source, *destination),
+			entry != nil && comparison.identical(*source, *destination):
+			return twoWaySyncInSync
+		default:
+			return twoWaySyncConflict
+		}
+	case source != nil:
+		// a file that was modified on one side and deleted on the other is kept, since the modification may be worth keeping
+		if entry != nil && !sourceChanged {
+			return twoWaySyncDeleteAtSource
+		}
+		return twoWaySyncCopyToDestination
+	case destination != nil:
+		if entry != nil && !destinationChanged {
+			return twoWaySyncDeleteAtDestination
+		}
+		return twoWaySyncCopyToSource
+	default:
+		return twoWaySyncNothing
+	}
+}
+
+// twoWaySyncConflictPath returns the path under which the KeepBoth conflict policy keeps the older version of a file,
+// e.g. dir/report.conflict-20230102T150405Z.docx
+func twoWaySyncConflictPath(relativePath string, lastModifiedTime time.Time) string {
+	dir, name := path.Split(relativePath)
+	ext := path.Ext(name)
+	return dir + strings.TrimSuffix(name, ext) + ".conflict-" + lastModifiedTime.UTC().Format(twoWaySyncConflictTimeFormat) + ext
+}
+
+// twoWaySyncOutcome is what becomes of the state of a path once the job is done
+type twoWaySyncOutcome struct {
+	// the new state of the path, or nil if the path should be forgotten
+	entry *twoWaySyncStateEntry
+
+	// whether the outcome depends on the success of a transfer of the path
+	transferred bool
+
+	// whether the path was copied to the source or the destination, in which case the copy is only there once the job is done
+	copiedToSource      bool
+	copiedToDestination bool
+}
+
+// twoWaySync compares the source and destination against the state of the pair as of the last sync,
+// and propagates the creations, modifications and deletions on each side to the other side
+type twoWaySync struct {
+	cca *cookedSyncCmdArgs
+	ctx context.Context
+
+	statePath  string
+	state      *twoWaySyncState
+	comparison twoWaySyncComparison
+
+	// the part of the source that's synced, and the part of the destination with it. Paths that the source isn't in scope for
+	// are left alone at both sides, like one-way sync leaves them alone at the destination.
+	scope *syncScope
+
+	// storing the source objects
+	sourceIndex *objectIndexer
+
+	// both directions are transferred in the same job, so they share the sequence of part numbers
+	toDestination *copyTransferProcessor
+	toSource      *copyTransferProcessor
+	nextPartNum   common.PartNumber
+
+	destinationDeleter objectProcessor
+	sourceDeleter      objectProcessor
+
+	// the outcome of each path, and whether the path was seen on either side, by the key of the path in the state
+	outcomes map[string]twoWaySyncOutcome
+	visited  map[string]bool
+	// the key of the path of each scheduled transfer, by the direction and (encoded) source of the transfer
+	scheduled map[string]string
+	conflicts int
+}
+
+func newTwoWaySync(ctx context.Context, cca *cookedSyncCmdArgs, indexer *objectIndexer, scope *syncScope, fpo common.FolderPropertyOption) (*twoWaySync, error) {
+	statePath := twoWaySyncStatePath(cca.source, cca.destination)
+	state, err := loadTwoWaySyncState(statePath, cca.source, cca.destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// the transfers back to the source are the same, with the roles of the source and destination swapped
+	toSource := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	toSource.copyJobTemplate.FromTo = twoWaySyncReverseFromTo[cca.fromTo]
+	toSource.copyJobTemplate.SourceRoot, toSource.copyJobTemplate.DestinationRoot = toSource.copyJobTemplate.DestinationRoot, toSource.copyJobTemplate.SourceRoot
+	toSource.source, toSource.destination = cca.destination, cca.source
+
+	t := &twoWaySync{
+		cca:           cca,
+		ctx:           ctx,
+		statePath:     statePath,
+		state:         state,
+		comparison:    newTwoWaySyncComparison(cca),
+		scope:         scope,
+		sourceIndex:   indexer,
+		toDestination: newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo),
+		toSource:      toSource,
+		outcomes:      make(map[string]twoWaySyncOutcome),
+		visited:       make(map[string]bool),
+		scheduled:     make(map[string]string),
+	}
+
+	if t.destinationDeleter, err = t.newDeleter(cca.fromTo.To(), cca.destination, fpo); err != nil {
+		return nil, err
+	}
+	if t.sourceDeleter, err = t.newDeleter(cca.fromTo.From(), cca.source, fpo); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newDeleter returns a deleter for one side. A deletion at one side of a two-way sync is propagated to the other without prompting.
+func (t *twoWaySync) newDeleter(location common.Location, resource common.ResourceString, fpo common.FolderPropertyOption) (objectProcessor, error) {
+	if location == common.ELocation.Local() {
+		localDeleter := localFileDeleter{rootPath: resource.ValueLocal(), fpo: fpo, folderManager: common.NewFolderDeletionManager(context.Background(), fpo, azcopyScanningLogger)}
+		return newInteractiveDeleteProcessor(localDeleter.deleteFile, common.EDeleteDestination.True(), "local file", resource, t.cca.incrementDeletionCount, t.cca.dryrunMode).removeImmediately, nil
+	}
+
+	rawURL, err := resource.FullURL()
+	if err != nil {
+		return nil, err
+	}
+	// the job's credential is the one of the remote side, whichever direction the sync is in
+	p, err := InitPipeline(t.ctx, location, t.cca.credentialInfo, azcopyLogVerbosity.ToPipelineLogLevel(), t.cca.trailingDot)
+	if err != nil {
+		return nil, err
+	}
+	return newInteractiveDeleteProcessor(newRemoteResourceDeleter(rawURL, p, t.ctx, location, fpo, t.cca.forceIfReadOnly).delete,
+		common.EDeleteDestination.True(), location.String(), resource, t.cca.incrementDeletionCount, t.cca.dryrunMode).removeImmediately, nil
+}
+
+// processIfNecessary decides what to do with a destination object and its counterpart at the source, if there's one
+func (t *twoWaySync) processIfNecessary(destinationObject StoredObject) error {
+	key := t.stateKey(destinationObject.relativePath)
+	sourceObject, present := t.sourceIndex.indexMap[key]
+	if present {
+		delete(t.sourceIndex.indexMap, key)
+	}
+
+	// only files are synced both ways; folders are created as needed by the transfers of their files
+	if destinationObject.entityType != common.EEntityType.File() {
+		return nil
+	}
+	if present && sourceObject.entityType == common.EEntityType.File() {
+		return t.process(destinationObject.relativePath, &sourceObject, &destinationObject)
+	}
+	return t.process(destinationObject.relativePath, nil, &destinationObject)
+}
+
+// processSourceOnly handles the source objects that have no counterpart at the destination
+func (t *twoWaySync) processSourceOnly(sourceObject StoredObject) error {
+	if sourceObject.entityType != common.EEntityType.File() {
+		return nil
+	}
+	return t.process(sourceObject.relativePath, &sourceObject, nil)
+}
+
+// stateKey returns the key of a path in the index of the source and in the state, which is the same for all the spellings
+// of the path that a case-insensitive destination takes to be the same
+func (t *twoWaySync) stateKey(relativePath string) string {
+	return t.sourceIndex.indexKey(relativePath)
+}
+
+func (t *twoWaySync) process(relativePath string, source, destination *StoredObject) error {
+	key := t.stateKey(relativePath)
+	t.visited[key] = true
+	if source == nil {
+		if reason := t.scope.deletionBlocked(relativePath); reason != "" {
+			// the source may well have the file, so the path is left as it is at both sides, and so is its state
+			syncComparatorLog(relativePath, syncStatusSkipped, reason, false)
+			return nil
+		}
+	}
+
+	var entry *twoWaySyncStateEntry
+	if e, ok := t.state.Entries[key]; ok {
+		entry = &e
+	}
+
+	action := decideTwoWaySync(source, destination, entry, t.comparison)
+	if action == twoWaySyncConflict {
+		return t.resolveConflict(relativePath, *source, *destination)
+	}
+
+	switch action {
+	case twoWaySyncInSync:
+		t.outcomes[key] = twoWaySyncOutcome{entry: &twoWaySyncStateEntry{Source: t.comparison.side(*source), Destination: t.comparison.side(*destination)}}
+	case twoWaySyncCopyToDestination:
+		t.outcomes[key] = twoWaySyncOutcome{entry: &twoWaySyncStateEntry{Source: t.comparison.side(*source)}, transferred: true, copiedToDestination: true}
+		return t.schedule(t.toDestination, *source, relativePath)
+	case twoWaySyncCopyToSource:
+		t.outcomes[key] = twoWaySyncOutcome{entry: &twoWaySyncStateEntry{Destination: t.comparison.side(*destination)}, transferred: true, copiedToSource: true}
+		return t.schedule(t.toSource, *destination, relativePath)
+	case twoWaySyncDeleteAtDestination:
+		t.outcomes[key] = twoWaySyncOutcome{}
+		return t.destinationDeleter(*destination)
+	case twoWaySyncDeleteAtSource:
+		t.outcomes[key] = twoWaySyncOutcome{}
+		return t.sourceDeleter(*source)
+	}
+	return nil
+}
+
+// resolveConflict applies the conflict policy to a file that changed on both sides since the last sync
+func (t *twoWaySync) resolveConflict(relativePath string, source, destination StoredObject) error {
+	key := t.stateKey(relativePath)
+	destinationIsNewer := destination.isMoreRecentThan(source, t.cca.preserveSMBInfo)
+
+	switch t.cca.conflictPolicy {
+	case common.ESyncConflictPolicy.NewerWins():
+		if destinationIsNewer {
+			t.logConflict(relativePath, "the version at the destination is newer, and replaces the one at the source")
+			t.outcomes[key] = twoWaySyncOutcome{entry: &twoWaySyncStateEntry{Destination: t.comparison.side(destination)}, transferred: true, copiedToSource: true}
+			return t.schedule(t.toSource, destination, relativePath)
+		}
+		t.logConflict(relativePath, "the version at the source is newer, and replaces the one at the destination")
+		t.outcomes[key] = twoWaySyncOutcome{entry: &twoWaySyncStateEntry{Source: t.comparison.side(source)}, transferred: true, copiedToDestination: true}
+		return t.schedule(t.toDestination, source, relativePath)
+
+	case common.ESyncConflictPolicy.KeepBoth():
+		// The older version is copied next to the newer one, under a new name. The newer version can't replace the older one
+		// in the same job, since the older one is being read at the same time, so it's done by the next sync instead:
+		// the state records the newer side as changed, and the older side as it is now. The next sync also copies the older
+		// version back to its own side, as a new file.
+		older, olderProcessor := source, t.toDestination
+		entry := &twoWaySyncStateEntry{Source: t.comparison.side(source)}
+		if !destinationIsNewer {
+			older, olderProcessor = destination, t.toSource
+			entry = &twoWaySyncStateEntry{Destination: t.comparison.side(destination)}
+		}
+		conflictPath := twoWaySyncConflictPath(relativePath, older.lastModifiedTime)
+		t.logConflict(relativePath, "both versions are kept; the older one as "+conflictPath+", on the side of the newer one for now, and on both sides after the next sync")
+		t.outcomes[key] = twoWaySyncOutcome{entry: entry, transferred: true}
+		return t.scheduleTo(olderProcessor, older, relativePath, conflictPath)
+
+	default:
+		t.conflicts++
+		t.logConflict(relativePath, "it was left alone")
+		return nil
+	}
+}
+
+func (t *twoWaySync) logConflict(relativePath, resolution string) {
+	msg := fmt.Sprintf("File %s changed at both the source and the destination since the last sync; %s", relativePath, resolution)
+	glcm.Info(msg)
+	if azcopyScanningLogger != nil {
+		azcopyScanningLogger.Log(pipeline.LogWarning, msg)
+	}
+}
+
+func (t *twoWaySync) schedule(processor *copyTransferProcessor, storedObject StoredObject, relativePath string) error {
+	return t.scheduleTo(processor, storedObject, relativePath, relativePath)
+}
+
+func (t *twoWaySync) scheduleTo(processor *copyTransferProcessor, storedObject StoredObject, relativePath, destinationRelativePath string) error {
+	fromTo := processor.copyJobTemplate.FromTo
+	t.scheduled[t.transferKey(fromTo, pathEncodeRules(storedObject.relativePath, fromTo, false, true))] = t.stateKey(relativePath)
+
+	// the processor dispatches a part, and moves on to the next part number, once it has enough transfers
+	processor.copyJobTemplate.PartNum = t.nextPartNum
+	err := processor.scheduleCopyTransferTo(storedObject, destinationRelativePath)
+	t.nextPartNum = processor.copyJobTemplate.PartNum
+	return err
+}
+
+func (t *twoWaySync) transferKey(fromTo common.FromTo, source string) string {
+	return fromTo.String() + "|" + source
+}
+
+// dispatchFinalPart dispatches the transfers that are left in both directions. The final part has to be the last one,
+// so the transfers of the other direction go first, as an ordinary part.
+func (t *twoWaySync) dispatchFinalPart() (copyJobInitiated bool, err error) {
+	first, last := t.toSource, t.toDestination
+	if len(last.copyJobTemplate.Transfers.List) == 0 {
+		first, last = last, first
+	}
+	// A processor only dispatches a part when it's given a transfer that doesn't fit, which it keeps for the next part,
+	// so once a part was dispatched, one of the directions has transfers left, and the final part isn't empty.
+	// Only the first part may be empty, which is how a job with nothing to transfer ends.
+	if len(last.copyJobTemplate.Transfers.List) == 0 && t.nextPartNum > 0 {
+		return false, fmt.Errorf("the final part %d of job %s has no transfers", t.nextPartNum, t.cca.jobID)
+	}
+
+	if len(first.copyJobTemplate.Transfers.List) > 0 {
+		first.copyJobTemplate.PartNum = t.nextPartNum
+		resp := first.sendPartToSte()
+		if resp.ErrorMsg != "" {
+			return false, errors.New(string(resp.ErrorMsg))
+		}
+		first.copyJobTemplate.Transfers = common.Transfers{}
+		t.nextPartNum++
+	}
+
+	last.copyJobTemplate.PartNum = t.nextPartNum
+	return last.dispatchFinalPart()
+}
+
+// finalize handles the source objects that aren't at the destination, and dispatches the final part of the job
+func (t *twoWaySync) finalize(filters []ObjectFilter) error {
+	err := t.sourceIndex.traverse(t.processSourceOnly, filters)
+	if err != nil {
+		return err
+	}
+	t.forgetDeletedOnBothSides(filters)
+
+	jobInitiated, err := t.dispatchFinalPart()
+	// sync cleanly exits if nothing is scheduled.
+	if err != nil && err != NothingScheduledError {
+		return err
+	}
+
+	if !jobInitiated {
+		// with no job to wait for, the sync is done
+		if err = t.saveState(); err != nil {
+			return err
+		}
+		if t.conflicts > 0 {
+			t.cca.reportScanningProgress(glcm, 0)
+			glcm.Exit(func(format common.OutputFormat) string {
+				return t.conflictSummary()
+			}, common.EExitCode.Error())
+		}
+	}
+
+	quitIfInSync(jobInitiated, t.cca.getDeletionCount() > 0, t.cca)
+	t.cca.setScanningComplete()
+	return nil
+}
+
+// forgetDeletedOnBothSides drops the state of the paths that are gone from both sides, since there's nothing left to sync.
+// A path that neither side listed because the filters or the scope of the sync leave it out is remembered, in case
+// a later sync includes it again, and so is a path that the source isn't in scope for.
+func (t *twoWaySync) forgetDeletedOnBothSides(filters []ObjectFilter) {
+	for key, entry := range t.state.Entries {
+		if t.visited[key] || (!t.cca.recursive && strings.Contains(key, "/")) || !t.scope.covers(key) {
+			continue
+		}
+		lastSeen := StoredObject{name: path.Base(key), relativePath: key, entityType: common.EEntityType.File(),
+			lastModifiedTime: entry.Source.LastModifiedTime, size: entry.Source.Size}
+		if passedFilters(filters, lastSeen) {
+			t.outcomes[key] = twoWaySyncOutcome{}
+		}
+	}
+}
+
+func (t *twoWaySync) conflictSummary() string {
+	return fmt.Sprintf("%d file(s) changed at both the source and the destination, and were left alone because of the conflict policy %s.",
+		t.conflicts, t.cca.conflictPolicy)
+}
+
+// saveState records the outcome of the sync, once the job (if any) is done. A path whose transfer failed keeps its
+// earlier state, so that the next sync finds the same changes, and tries again.
+//
+// A file that was copied to a side is recorded there as the file that was copied, as of the time the job was done.
+// That's what one-way sync would compare a later version with: the copy counts as changed once it's more recent than that,
+// or once its size or hash differs, as the compare mode has it.
+func (t *twoWaySync) saveState() error {
+	if t.cca.dryrunMode {
+		return nil
+	}
+
+	failed := t.failedPaths()
+	done := time.Now()
+
+	for key, outcome := range t.outcomes {
+		if outcome.transferred && failed[key] {
+			continue
+		}
+
+		if outcome.entry == nil {
+			delete(t.state.Entries, key)
+			continue
+		}
+
+		entry := *outcome.entry
+		if outcome.copiedToSource {
+			entry.Source = entry.Destination
+			entry.Source.LastModifiedTime = done
+		}
+		if outcome.copiedToDestination {
+			entry.Destination = entry.Source
+			entry.Destination.LastModifiedTime = done
+		}
+		t.state.Entries[key] = entry
+	}
+
+	return t.state.save(t.statePath)
+}
+
+// failedPaths returns the keys of the paths of the transfers of the job that didn't succeed
+func (t *twoWaySync) failedPaths() map[string]bool {
+	failed := make(map[string]bool)
+	forEachFailedTransfer(t.cca.jobID, func(fromTo common.FromTo, source string) {
+		if key, ok := t.scheduled[t.transferKey(fromTo, source)]; ok {
+			failed[key] = true
+		}
+	})
+	return failed
+}
@@ -0,0 +1,469 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const (
+	// by default, the changes are synced once the source has been quiet for this long
+	defaultSyncWatchDebounce = 2 * time.Second
+
+	// by default, the source and destination are compared in full this often, to catch any changes that were missed
+	defaultSyncWatchReconcileInterval = time.Hour
+
+	// the changes are synced at the latest this many debounce periods after the first of them, even if the source keeps changing
+	syncWatchMaxBatchDebounces = 10
+)
+
+// syncWatcher keeps a sync from a local directory running after its first scan. It watches the source for changes,
+// and syncs the changed paths in batches, once the source has been quiet for a while. All the transfers go into the job
+// of the first scan, which is kept open (its final part isn't sent) until the watch is stopped.
+type syncWatcher struct {
+	cca  *cookedSyncCmdArgs
+	ctx  context.Context
+	root string
+
+	watcher *fsnotify.Watcher
+	// passes on the changed paths, hashing them as the local traverser would
+	traverser *localTraverser
+	// the folders being watched, by relative path. Only used by the goroutine that syncs the changes.
+	watchedFolders map[string]struct{}
+
+	// the paths that changed since the last batch. A path maps to true if it was created within the batch,
+	// in which case it doesn't exist at the destination, and there's nothing to delete if it's gone again.
+	mu         sync.Mutex
+	pending    map[string]bool
+	overflowed bool
+	changed    chan struct{}
+
+	// set by each full scan, and used for the changes in between
+	transferScheduler *copyTransferProcessor
+	filters           []ObjectFilter
+	fpo               common.FolderPropertyOption
+	deleter           *interactiveDeleteProcessor
+
+	atomicRunning uint32
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+func newSyncWatcher(ctx context.Context, cca *cookedSyncCmdArgs) (*syncWatcher, error) {
+	traverser, err := newLocalTraverser(ctx, cca.source.ValueLocal(), cca.recursive, true, common.ESymlinkHandlingType.Skip(), cca.compareHash, func(entityType common.EntityType) {
+		if entityType == common.EEntityType.File() {
+			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
+		}
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch the source due to: %s", err.Error())
+	}
+
+	w := &syncWatcher{
+		cca:            cca,
+		ctx:            ctx,
+		root:           traverser.fullPath,
+		watcher:        watcher,
+		traverser:      traverser,
+		watchedFolders: make(map[string]struct{}),
+		pending:        make(map[string]bool),
+		changed:        make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+	}
+
+	// the source is watched before it's first scanned, so that no change falls in between
+	if _, err = w.watchTree(""); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	go w.collectEvents(watcher.Events, watcher.Errors)
+	return w, nil
+}
+
+// watchTree watches the given folder, and the folders under it if the sync is recursive.
+// It returns the relative paths of the folder and everything under it.
+func (w *syncWatcher) watchTree(relativePath string) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(filepath.Join(w.root, relativePath), func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			WarnStdoutAndScanningLog(fmt.Sprintf("Accessing %s failed with error: %s", fullPath, err.Error()))
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, fullPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+
+		if !entry.IsDir() {
+			found = append(found, rel)
+			return nil
+		}
+		if rel != "" && !w.cca.recursive {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(fullPath); err != nil {
+			return fmt.Errorf("unable to watch %s due to: %s; the limit on the number of watches (fs.inotify.max_user_watches on Linux) may need to be raised", fullPath, err.Error())
+		}
+		w.watchedFolders[rel] = struct{}{}
+		found = append(found, rel)
+		return nil
+	})
+	return found, err
+}
+
+// collectEvents records the paths that the given events of the watcher are about, until the watcher is closed
+func (w *syncWatcher) collectEvents(events <-chan fsnotify.Event, errs <-chan error) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// a change of attributes alone doesn't change what sync compares
+			if event.Op == fsnotify.Chmod || strings.HasSuffix(event.Name, common.AzCopyHashDataStream) {
+				continue
+			}
+			rel, err := filepath.Rel(w.root, event.Name)
+			if err != nil || rel == "." {
+				continue
+			}
+
+			w.mu.Lock()
+			if _, seen := w.pending[filepath.ToSlash(rel)]; !seen {
+				w.pending[filepath.ToSlash(rel)] = event.Has(fsnotify.Create)
+			}
+			w.mu.Unlock()
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				WarnStdoutAndScanningLog("Watching the source failed with error: " + err.Error())
+				continue
+			}
+			w.mu.Lock()
+			w.overflowed = true
+			w.mu.Unlock()
+		}
+
+		select {
+		case w.changed <- struct{}{}:
+		default: // already signalled
+		}
+	}
+}
+
+// scanStarting is called by the enumerator of each full scan. It returns the transfer scheduler to be used for the scan,
+// which is the one of the first scan, so that all the transfers go into the same job.
+func (w *syncWatcher) scanStarting(transferScheduler *copyTransferProcessor, filters []ObjectFilter, fpo common.FolderPropertyOption) *copyTransferProcessor {
+	if w.transferScheduler == nil {
+		w.transferScheduler = transferScheduler
+	}
+	w.filters = filters
+	w.fpo = fpo
+	w.deleter = nil
+	return w.transferScheduler
+}
+
+// dispatchPart sends the transfers that were scheduled so far to the job, in a part that isn't final, so that the job stays open
+func (w *syncWatcher) dispatchPart() (copyJobInitiated bool, err error) {
+	s := w.transferScheduler
+	if len(s.copyJobTemplate.Transfers.List) == 0 {
+		// nothing to add to the job. An empty first part would complete it, so it's held back until there's something to transfer
+		return true, nil
+	}
+
+	resp := s.sendPartToSte()
+	if resp.ErrorMsg != "" {
+		return false, errors.New(string(resp.ErrorMsg))
+	}
+	s.copyJobTemplate.Transfers = common.Transfers{}
+	s.copyJobTemplate.PartNum++
+	return true, nil
+}
+
+// run syncs the changes to the source as they come, until the watch is stopped. It's called once the first scan is done.
+func (w *syncWatcher) run() {
+	atomic.StoreUint32(&w.atomicRunning, 1)
+	glcm.Info("Watching " + w.root + " for changes. Press Ctrl-C to stop watching, once the transfers in progress are done.")
+
+	var reconcile <-chan time.Time
+	if w.cca.watchReconcileInterval > 0 {
+		ticker := time.NewTicker(w.cca.watchReconcileInterval)
+		defer ticker.Stop()
+		reconcile = ticker.C
+	}
+
+	// the batch is synced once the source has been quiet for the debounce period, or has been changing for too long
+	debounce := time.NewTimer(w.cca.watchDebounce)
+	debounce.Stop()
+	var batchStart time.Time
+
+	for {
+		select {
+		case <-w.stop:
+			w.finish()
+			return
+		case <-w.changed:
+			if batchStart.IsZero() {
+				batchStart = time.Now()
+			}
+			wait := w.cca.watchDebounce
+			if remaining := time.Until(batchStart.Add(syncWatchMaxBatchDebounces * w.cca.watchDebounce)); remaining < wait {
+				wait = remaining
+			}
+			// a timer that fired while the batch was still growing is drained, so that its stale tick doesn't sync the batch early
+			if !debounce.Stop() {
+				select {
+				case <-debounce.C:
+				default:
+				}
+			}
+			debounce.Reset(wait)
+		case <-debounce.C:
+			batchStart = time.Time{}
+			w.syncChanges()
+		case <-reconcile:
+			w.reconcile()
+		}
+	}
+}
+
+// stopWatching stops the watch, if it's running, and returns whether it did. The job is then completed once
+// the transfers in progress are done.
+func (w *syncWatcher) stopWatching() bool {
+	if atomic.LoadUint32(&w.atomicRunning) == 0 {
+		return false
+	}
+
+	stopped := false
+	w.stopOnce.Do(func() {
+		glcm.Info("Stopping the watch. Waiting for the transfers in progress to finish; press Ctrl-C again to cancel them.")
+		close(w.stop)
+		stopped = true
+	})
+	return stopped
+}
+
+func (w *syncWatcher) finish() {
+	_ = w.watcher.Close()
+	w.syncChanges()
+
+	// the job is completed once its final part is done
+	jobInitiated, err := w.transferScheduler.dispatchFinalPart()
+	if err != nil && err != NothingScheduledError {
+		glcm.Error("Failed to complete the job of the watch due to error: " + err.Error())
+	}
+	quitIfInSync(jobInitiated, w.cca.getDeletionCount() > 0 || w.cca.getMoveCount() > 0, w.cca)
+	w.cca.setScanningComplete()
+}
+
+// reconcile compares the source and destination in full, as a sync without --watch does
+func (w *syncWatcher) reconcile() {
+	glcm.Info("Rescanning the source and destination, to catch any changes that were missed.")
+	enumerator, err := w.cca.initEnumerator(w.ctx)
+	if err == nil {
+		err = enumerator.enumerate()
+	}
+	if err != nil {
+		WarnStdoutAndScanningLog("Failed to rescan the source and destination due to error: " + err.Error())
+	}
+}
+
+// syncChanges transfers the paths that changed since the last batch, and deletes the ones that are gone from the destination
+func (w *syncWatcher) syncChanges() {
+	w.mu.Lock()
+	pending, overflowed := w.pending, w.overflowed
+	w.pending, w.overflowed = make(map[string]bool), false
+	w.mu.Unlock()
+
+	if overflowed {
+		// the folders created in the meantime may not be watched yet, and the changes that were dropped can only be found by a full scan
+		glcm.Info("Some changes to the source were missed, because too many happened at once.")
+		if _, err := w.watchTree(""); err != nil {
+			WarnStdoutAndScanningLog(err.Error())
+		}
+		w.reconcile()
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	relativePaths := make([]string, 0, len(pending))
+	for rel := range pending {
+		relativePaths = append(relativePaths, rel)
+	}
+	sort.Strings(relativePaths)
+
+	var changed, deleted []string
+	for _, rel := range relativePaths {
+		info, err := os.Lstat(filepath.Join(w.root, rel))
+		switch {
+		case os.IsNotExist(err):
+			if _, isFolder := w.watchedFolders[rel]; isFolder {
+				// the watch is gone with the folder. The objects under it are deleted as their own events come,
+				// or by the next full scan if the folder was moved away.
+				delete(w.watchedFolders, rel)
+			} else if !pending[rel] {
+				deleted = append(deleted, rel)
+			}
+		case err != nil:
+			WarnStdoutAndScanningLog(fmt.Sprintf("Accessing %s failed with error: %s", rel, err.Error()))
+		case info.IsDir():
+			if !w.cca.recursive {
+				continue
+			}
+			if _, watched := w.watchedFolders[rel]; watched {
+				changed = append(changed, rel)
+				continue
+			}
+			// the contents of a new folder may have been created before it was watched
+			found, err := w.watchTree(rel)
+			if err != nil {
+				WarnStdoutAndScanningLog(err.Error())
+			}
+			changed = append(changed, found...)
+		default:
+			changed = append(changed, rel)
+		}
+	}
+
+	glcm.Info(fmt.Sprintf("Syncing %d changed and %d deleted paths.", len(changed), len(deleted)))
+	traverser := &localPathsTraverser{localTraverser: w.traverser, relativePaths: changed}
+	if err := traverser.Traverse(noPreProccessor, w.transferScheduler.scheduleCopyTransfer, w.filters); err != nil {
+		WarnStdoutAndScanningLog("Failed to sync the changes due to error: " + err.Error())
+	}
+	w.deleteFromDestination(deleted)
+
+	if _, err := w.dispatchPart(); err != nil {
+		WarnStdoutAndScanningLog("Failed to sync the changes due to error: " + err.Error())
+	}
+}
+
+func (w *syncWatcher) deleteFromDestination(relativePaths []string) {
+	if len(relativePaths) == 0 || w.cca.deleteDestination != common.EDeleteDestination.True() {
+		return
+	}
+
+	if w.deleter == nil {
+		if w.cca.fromTo.To() == common.ELocation.Local() {
+			w.deleter = newSyncLocalDeleteProcessor(w.cca, w.fpo)
+		} else {
+			var err error
+			if w.deleter, err = newSyncDeleteProcessor(w.cca, w.fpo); err != nil {
+				WarnStdoutAndScanningLog("Failed to delete the files that are gone from the source due to error: " + err.Error())
+				return
+			}
+		}
+	}
+
+	for _, rel := range relativePaths {
+		object := StoredObject{name: path.Base(rel), relativePath: rel, entityType: common.EEntityType.File()}
+		if _, err := getProcessingError(processIfPassedFilters(w.filters, object, w.deleter.removeImmediately)); err != nil && azcopyScanningLogger != nil {
+			azcopyScanningLogger.Log(pipeline.LogError, fmt.Sprintf("failed to delete %s: %s", rel, err.Error()))
+		}
+	}
+}
+
+// localPathsTraverser passes on the given paths under the root of a local traverser, instead of walking it
+type localPathsTraverser struct {
+	*localTraverser
+	relativePaths []string
+}
+
+func (t *localPathsTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	finalizer, hashingProcessor := t.prepareHashingThreads(preprocessor, processor, filters)
+
+	for _, rel := range t.relativePaths {
+		fullPath := filepath.Join(t.fullPath, rel)
+		fileInfo, err := os.Lstat(fullPath)
+		if err != nil {
+			continue // it's gone again; the next batch deletes it
+		}
+
+		var entityType common.EntityType
+		switch {
+		case fileInfo.Mode()&os.ModeSymlink != 0:
+			WarnStdoutAndScanningLog(fmt.Sprintf("Skipping over symlink at %s because symlinks are not handled (--follow-symlinks or --preserve-symlinks)", fullPath))
+			continue
+		case fileInfo.IsDir():
+			if rel == "" {
+				continue // the root isn't transferred by sync
+			}
+			if wrapped, err := WrapFolder(fullPath, fileInfo); err == nil {
+				fileInfo = wrapped
+			}
+			entityType = common.EEntityType.Folder()
+		default:
+			entityType = common.EEntityType.File()
+		}
+
+		if t.incrementEnumerationCounter != nil {
+			t.incrementEnumerationCounter(entityType)
+		}
+		err = processIfPassedFilters(filters,
+			newStoredObject(
+				preprocessor,
+				fileInfo.Name(),
+				rel,
+				entityType,
+				fileInfo.ModTime(),
+				fileInfo.Size(),
+				noContentProps, // Local MD5s are computed in the STE, and other props don't apply to local files
+				noBlobProps,
+				noMetdata,
+				"", // Local has no such thing as containers
+			),
+			hashingProcessor,
+		)
+		if _, err = getProcessingError(err); err != nil {
+			return finalizer(err)
+		}
+	}
+
+	return finalizer(nil)
+}
@@ -116,6 +116,13 @@ func inferFromTo(src, dst string) common.FromTo {
 		return false
 	})
 
+	// A mistyped remote URL is inferred as a local path, so a local to local copy must be asked for explicitly.
+	if out == common.EFromTo.LocalLocal() {
+		glcm.Info("Both the source and destination were inferred as local paths. " +
+			"If a local to local transfer is intended, please specify --from-to=LocalLocal.")
+		return common.EFromTo.Unknown()
+	}
+
 	if out != common.EFromTo.Unknown() {
 		return out
 	}
@@ -143,6 +150,9 @@ func InferArgumentLocation(arg string) common.Location {
 		if err == nil && u.Scheme != "" && u.Host != "" {
 			// Is the argument a URL to blob storage?
 			switch host := strings.ToLower(u.Host); true {
+			// an S3-compatible service named by --s3-endpoint may have any host name, including an IP, so check for it first
+			case common.IsS3CustomEndpointURL(*u):
+				return common.ELocation.S3()
 			// Azure Stack does not have the core.windows.net
 			case strings.Contains(host, ".blob"):
 				return common.ELocation.Blob()
@@ -164,6 +174,12 @@ func InferArgumentLocation(arg string) common.Location {
 			if common.IsGCPURL(*u) {
 				return common.ELocation.GCP()
 			}
+
+			// any other URL may be a mistyped one of the services above, so it isn't taken for a generic HTTP(S) URL.
+			// That has to be asked for with --from-to.
+			if scheme := strings.ToLower(u.Scheme); scheme == "http" || scheme == "https" {
+				return common.ELocation.Unknown()
+			}
 		}
 	}
 
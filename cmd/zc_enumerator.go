@@ -54,6 +54,9 @@ type StoredObject struct {
 	md5                 []byte
 	blobType            azblob.BlobType // will be "None" when unknown or not applicable
 
+	// the MD5 that sync compares an S3 object by, which may be its ETag. Unlike md5, it isn't given to the transfer.
+	syncComparableMD5 []byte
+
 	// all of these will be empty when unknown or not applicable.
 	contentDisposition string
 	cacheControl       string
@@ -104,6 +107,29 @@ func (s *StoredObject) isMoreRecentThan(storedObject2 StoredObject, preferSMBTim
 	return lmtA.After(lmtB)
 }
 
+// syncHash returns the hash of the given type that the object carries, or nil if it has none.
+// MD5s are kept in md5, like the Content-MD5 of remote objects, and the other types in the metadata,
+// which is where remote objects keep them, so that a sync upload stores them along with the file.
+func (s *StoredObject) syncHash(hashType common.SyncHashType) []byte {
+	if hashType == common.ESyncHashType.MD5() {
+		return s.md5
+	}
+
+	return hashType.HashFromMetadata(s.Metadata)
+}
+
+// setSyncHash records a hash of the given type on the object, where syncHash looks for it
+func (s *StoredObject) setSyncHash(hashType common.SyncHashType, sum []byte) {
+	if hashType == common.ESyncHashType.MD5() {
+		s.md5 = sum
+		return
+	}
+
+	if hashType.MetadataKey() != "" {
+		s.Metadata = hashType.HashToMetadata(s.Metadata, sum)
+	}
+}
+
 func (s *StoredObject) isSingleSourceFile() bool {
 	return s.relativePath == "" && s.entityType == common.EEntityType.File()
 }
@@ -212,12 +238,20 @@ func stripCompressionExtension(dest string, contentEncoding string) string {
 	ext := strings.ToLower(filepath.Ext(dest))
 	stripGzip := ct == common.ECompressionType.GZip() && (ext == ".gz" || ext == ".gzip")
 	stripZlib := ct == common.ECompressionType.ZLib() && ext == ".zz" // "standard" extension for zlib-wrapped files, according to pigz doc and Stack Overflow
-	if stripGzip || stripZlib {
+	stripZstd := ct == common.ECompressionType.ZStd() && ext == ".zst"
+	if stripGzip || stripZlib || stripZstd {
 		return strings.TrimSuffix(dest, filepath.Ext(dest))
 	}
 	return dest
 }
 
+// appendCompressionExtension is the reverse of stripCompressionExtension, for files that are compressed as they are uploaded.
+// The extension is appended even if the file already has it, since the file is then compressed twice,
+// and after a download with decompression it has that extension again.
+func appendCompressionExtension(dest string, ct common.CompressionType) string {
+	return dest + ct.FileExtension()
+}
+
 // interfaces for standard properties of StoredObjects
 type contentPropsProvider interface {
 	CacheControl() string
@@ -582,6 +616,19 @@ func InitResourceTraverser(resource common.ResourceString, location common.Locat
 				return nil, err
 			}
 		}
+	case common.ELocation.HTTP():
+		resourceURL, err := resource.FullURL()
+		if err != nil {
+			return nil, err
+		}
+
+		recommendHttpsIfNecessary(*resourceURL)
+
+		if ctx == nil || p == nil {
+			return nil, errors.New("a valid context must be supplied to create an HTTP traverser")
+		}
+
+		output = newHTTPTraverser(resourceURL, p, *ctx, incrementEnumerationCounter)
 
 	default:
 		return nil, errors.New("could not choose a traverser from currently available traversers")
@@ -676,6 +723,9 @@ func newSyncEnumerator(primaryTraverser, secondaryTraverser ResourceTraverser, i
 }
 
 func (e *syncEnumerator) enumerate() (err error) {
+	// remove anything that the index spilled to disk
+	defer e.objectIndexer.cleanup()
+
 	// enumerate the primary resource and build lookup map
 	err = e.primaryTraverser.Traverse(noPreProccessor, e.objectIndexer.store, e.filters)
 	if err != nil {
@@ -685,8 +735,13 @@ func (e *syncEnumerator) enumerate() (err error) {
 	// enumerate the secondary resource and as the objects pass the filters
 	// they will be passed to the object comparator
 	// which can process given objects based on what's already indexed
-	// note: transferring can start while scanning is ongoing
-	err = e.secondaryTraverser.Traverse(noPreProccessor, e.objectComparator, e.filters)
+	// note: transferring can start while scanning is ongoing, unless the index was too big and spilled to disk,
+	// in which case the comparison happens once scanning is done
+	err = e.secondaryTraverser.Traverse(noPreProccessor, e.objectIndexer.comparatorFor(e.objectComparator), e.filters)
+	if err != nil {
+		return
+	}
+	err = e.objectIndexer.compareSpilled(e.objectComparator)
 	if err != nil {
 		return
 	}
@@ -290,7 +290,7 @@ func buildRegexFilters(patterns []string, isIncluded bool) []ObjectFilter {
 }
 
 // includeAfterDateFilter includes files with Last Modified Times >= the specified threshold
-// Used for copy, but doesn't make conceptual sense for sync
+// Used for copy, and by sync for its source only (see syncScope)
 type IncludeAfterDateFilter struct {
 	Threshold time.Time
 }
@@ -324,7 +324,7 @@ func (_ IncludeAfterDateFilter) FormatAsUTC(t time.Time) string {
 }
 
 // IncludeBeforeDateFilter includes files with Last Modified Times <= the specified Threshold
-// Used for copy, but doesn't make conceptual sense for sync
+// Used for copy, and by sync for its source only (see syncScope)
 type IncludeBeforeDateFilter struct {
 	Threshold time.Time
 }
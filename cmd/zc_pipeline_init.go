@@ -20,6 +20,8 @@ func InitPipeline(ctx context.Context, location common.Location, credential comm
 		p, err = createFilePipeline(ctx, credential, logLevel, trailingDot)
 	case common.ELocation.BlobFS():
 		p, err = createBlobFSPipeline(ctx, credential, logLevel)
+	case common.ELocation.HTTP():
+		p = createHTTPPipeline(logLevel)
 	case common.ELocation.S3():
 	case common.ELocation.GCP():
 		// Gracefully return because pipelines aren't used for S3 or GCP
@@ -69,11 +69,16 @@ func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, n
 }
 
 func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject StoredObject) (err error) {
+	return s.scheduleCopyTransferTo(storedObject, storedObject.relativePath)
+}
+
+// scheduleCopyTransferTo schedules the transfer of the object to the given relative path at the destination, instead of its own
+func (s *copyTransferProcessor) scheduleCopyTransferTo(storedObject StoredObject, destinationRelativePath string) (err error) {
 
 	// Escape paths on destinations where the characters are invalid
 	// And re-encode them where the characters are valid.
 	srcRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, false, true)
-	dstRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, false, false)
+	dstRelativePath := pathEncodeRules(destinationRelativePath, s.copyJobTemplate.FromTo, false, false)
 
 	copyTransfer, shouldSendToSte := storedObject.ToNewCopyTransfer(false, srcRelativePath, dstRelativePath, s.preserveAccessTier, s.folderPropertiesOption, s.symlinkHandlingType)
 
@@ -99,7 +99,9 @@ func (t *gcpTraverser) Traverse(preprocessor objectMorpher, processor objectProc
 
 			relativePath := strings.TrimPrefix(attrs.Name, searchPrefix)
 
-			oie := common.GCPObjectInfoExtension{ObjectInfo: gcpUtils.ObjectAttrs{}}
+			// GCS returns the MD5 hash as part of the listing, so keep it even when we aren't fetching properties.
+			// Downloads validate against it.
+			oie := common.GCPObjectInfoExtension{ObjectInfo: gcpUtils.ObjectAttrs{MD5: attrs.MD5}}
 
 			if t.getProperties {
 				oi, err := t.gcpClient.Bucket(t.gcpURLParts.BucketName).Object(attrs.Name).Attrs(t.ctx)
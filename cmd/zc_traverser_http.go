@@ -0,0 +1,97 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const httpDirectoryTraversalError = "a generic HTTP(S) source can't be listed. Point to a single file, or use --list-of-files to name the files under a base URL"
+
+// a generic HTTP(S) URL can only ever be a single file, since plain web servers have no listing API.
+// Several files under a common base URL are handled by wrapping this in a list traverser.
+type httpTraverser struct {
+	rawURL *url.URL
+	p      pipeline.Pipeline
+	ctx    context.Context
+
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+// isHTTPDirectorySyntactically returns true if the URL names a directory rather than a file, which is all we can go on without a listing API
+func isHTTPDirectorySyntactically(u *url.URL) bool {
+	return u.Path == "" || strings.HasSuffix(u.Path, "/")
+}
+
+func (t *httpTraverser) IsDirectory(bool) (bool, error) {
+	return isHTTPDirectorySyntactically(t.rawURL), nil
+}
+
+func (t *httpTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	if isHTTPDirectorySyntactically(t.rawURL) {
+		return errors.New(httpDirectoryTraversalError)
+	}
+
+	props, err := common.GetHTTPResourceProperties(t.ctx, t.p, *t.rawURL)
+	if err != nil {
+		return err
+	}
+
+	size, err := props.Size()
+	if err != nil {
+		return err
+	}
+
+	if t.incrementEnumerationCounter != nil {
+		t.incrementEnumerationCounter(common.EEntityType.File())
+	}
+
+	storedObject := newStoredObject(
+		preprocessor,
+		path.Base(t.rawURL.Path),
+		"",
+		common.EEntityType.File(),
+		props.LastModified(),
+		size,
+		props,
+		noBlobProps,
+		noMetdata,
+		"")
+
+	return processIfPassedFilters(filters, storedObject, processor)
+}
+
+func newHTTPTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, incrementEnumerationCounter enumerationCounterFunc) *httpTraverser {
+	return &httpTraverser{
+		rawURL:                      rawURL,
+		p:                           p,
+		ctx:                         ctx,
+		incrementEnumerationCounter: incrementEnumerationCounter,
+	}
+}
@@ -35,6 +35,9 @@ type listTraverser struct {
 	listReader              chan string
 	recursive               bool
 	childTraverserGenerator childTraverserGenerator
+
+	// optionally called for each child path that couldn't be scanned, such that the caller knows which parts of the list were skipped
+	childFailed func(childPath string)
 }
 
 type childTraverserGenerator func(childPath string) (ResourceTraverser, error)
@@ -58,6 +61,9 @@ func (l *listTraverser) Traverse(preprocessor objectMorpher, processor objectPro
 		childTraverser, err := l.childTraverserGenerator(childPath)
 		if err != nil {
 			glcm.Info(fmt.Sprintf("Skipping %s due to error %s", childPath, err))
+			if l.childFailed != nil {
+				l.childFailed(childPath)
+			}
 			continue
 		}
 		// listTraverser will only ever execute on the source
@@ -84,6 +90,9 @@ func (l *listTraverser) Traverse(preprocessor objectMorpher, processor objectPro
 		err = childTraverser.Traverse(preProcessorForThisChild, processor, filters)
 		if err != nil {
 			glcm.Info(fmt.Sprintf("Skipping %s as it cannot be scanned due to error: %s", childPath, err))
+			if l.childFailed != nil {
+				l.childFailed(childPath)
+			}
 		}
 	}
 
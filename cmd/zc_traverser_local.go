@@ -22,14 +22,12 @@ package cmd
 
 import (
 	"context"
-	"crypto/md5"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/v10/common"
 	"github.com/Azure/azure-storage-azcopy/v10/common/parallel"
-	"hash"
 	"io"
 	"io/fs"
 	"os"
@@ -459,6 +457,9 @@ func (t *localTraverser) prepareHashingThreads(preprocessor objectMorpher, proce
 	t.hashTargetChannel = make(chan string, 1_000) // "reasonable" backlog
 	// Use half of the available CPU cores for hashing to prevent throttling the STE too hard if hashing is still occurring when the first job part gets sent out
 	hashingThreadCount := runtime.NumCPU() / 2
+	if hashingThreadCount < 1 {
+		hashingThreadCount = 1 // but at least one, or files needing a hash would never be processed
+	}
 	hashError := make(chan error, hashingThreadCount)
 	wg := &sync.WaitGroup{}
 	immediateStopHashing := int32(0)
@@ -530,11 +531,7 @@ func (t *localTraverser) prepareHashingThreads(preprocessor objectMorpher, proce
 					return
 				}
 
-				var hasher hash.Hash // set up hasher
-				switch t.targetHashType {
-				case common.ESyncHashType.MD5():
-					hasher = md5.New()
-				}
+				hasher := t.targetHashType.NewHasher() // set up hasher
 
 				// hash.Hash provides a writer type, allowing us to do a (small, 32MB to be precise) buffered write into the hasher and avoid memory concerns
 				_, err = io.Copy(hasher, f)
@@ -565,12 +562,7 @@ func (t *localTraverser) prepareHashingThreads(preprocessor objectMorpher, proce
 					newStoredObject(
 						func(storedObject *StoredObject) {
 							// apply the hash data
-							// storedObject.hashData = hashData
-							switch hashData.Mode {
-							case common.ESyncHashType.MD5():
-								storedObject.md5 = sum
-							default: // no-op
-							}
+							storedObject.setSyncHash(hashData.Mode, sum)
 
 							if preprocessor != nil {
 								// apply the original preprocessor
@@ -624,12 +616,9 @@ func (t *localTraverser) prepareHashingThreads(preprocessor objectMorpher, proce
 			}
 		}
 
-		// storedObject.hashData = hashData
-		switch hashData.Mode {
-		case common.ESyncHashType.MD5():
-			md5data, _ := base64.StdEncoding.DecodeString(hashData.Data) // If decode fails, treat it like no hash is present.
-			storedObject.md5 = md5data
-		default: // do nothing, no hash is present.
+		// If decode fails, treat it like no hash is present.
+		if sum, err := base64.StdEncoding.DecodeString(hashData.Data); err == nil {
+			storedObject.setSyncHash(hashData.Mode, sum)
 		}
 
 		// delay the mutex until after potentially long-running operations
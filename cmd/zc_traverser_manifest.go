@@ -0,0 +1,81 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// manifestTraverser goes through the objects in the manifest of an earlier sync, in place of listing the destination
+type manifestTraverser struct {
+	path                        string
+	isDirectory                 bool
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func newManifestTraverser(path string, header syncManifestHeader, incrementEnumerationCounter enumerationCounterFunc) *manifestTraverser {
+	return &manifestTraverser{path: path, isDirectory: header.IsDirectory, incrementEnumerationCounter: incrementEnumerationCounter}
+}
+
+func (t *manifestTraverser) IsDirectory(bool) (bool, error) {
+	return t.isDirectory, nil
+}
+
+func (t *manifestTraverser) Traverse(preprocessor objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(bufio.NewReader(file))
+	var header syncManifestHeader
+	if err = decoder.Decode(&header); err != nil {
+		return fmt.Errorf("couldn't read the sync manifest %s: %w", t.path, err)
+	}
+
+	for {
+		var s spilledObject
+		if err = decoder.Decode(&s); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("couldn't read the sync manifest %s: %w", t.path, err)
+		}
+
+		storedObject := s.toStoredObject()
+		if preprocessor != nil {
+			preprocessor(&storedObject)
+		}
+		if t.incrementEnumerationCounter != nil {
+			t.incrementEnumerationCounter(storedObject.entityType)
+		}
+
+		err = processIfPassedFilters(filters, storedObject, processor)
+		_, err = getProcessingError(err)
+		if err != nil {
+			return err
+		}
+	}
+}
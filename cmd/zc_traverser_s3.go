@@ -101,6 +101,7 @@ func (t *s3Traverser) Traverse(preprocessor objectMorpher, processor objectProce
 				noBlobProps,
 				oie.NewCommonMetadata(),
 				t.s3URLParts.BucketName)
+			storedObject.syncComparableMD5 = oie.SyncComparableMD5()
 
 			err = processIfPassedFilters(
 				filters,
@@ -173,6 +174,7 @@ func (t *s3Traverser) Traverse(preprocessor objectMorpher, processor objectProce
 			noBlobProps,
 			oie.NewCommonMetadata(),
 			t.s3URLParts.BucketName)
+		storedObject.syncComparableMD5 = oie.SyncComparableMD5()
 
 		err = processIfPassedFilters(filters,
 			storedObject,
@@ -207,6 +209,7 @@ func newS3Traverser(credentialType common.CredentialType, rawURL *url.URL, ctx c
 		S3CredentialInfo: common.S3CredentialInfo{
 			Endpoint: t.s3URLParts.Endpoint,
 			Region:   t.s3URLParts.Region,
+			Insecure: t.s3URLParts.IsInsecure(),
 		},
 	}, common.CredentialOpOptions{
 		LogError: glcm.Error,
@@ -147,6 +147,7 @@ func newS3ServiceTraverser(rawURL *url.URL, ctx context.Context, getProperties b
 		CredentialType: common.ECredentialType.S3AccessKey(),
 		S3CredentialInfo: common.S3CredentialInfo{
 			Endpoint: t.s3URL.Endpoint,
+			Insecure: t.s3URL.IsInsecure(),
 		},
 	}, common.CredentialOpOptions{
 		LogError: glcm.Error,
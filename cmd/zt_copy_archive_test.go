@@ -0,0 +1,123 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+type copyArchiveSuite struct{}
+
+var _ = chk.Suite(&copyArchiveSuite{})
+
+func (s *copyArchiveSuite) TestValidateArchiveFormat(c *chk.C) {
+	tar, none := common.EArchiveFormat.Tar(), common.EArchiveFormat.None()
+
+	c.Assert(validateArchiveFormat(none, common.EFromTo.LocalFile(), common.EBlobType.Detect(), true, true), chk.IsNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), false, false), chk.IsNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalBlob(), common.EBlobType.BlockBlob(), false, false), chk.IsNil)
+
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalFile(), common.EBlobType.Detect(), false, false), chk.NotNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.BlobBlob(), common.EBlobType.Detect(), false, false), chk.NotNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalBlob(), common.EBlobType.PageBlob(), false, false), chk.NotNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), true, false), chk.NotNil)
+	c.Assert(validateArchiveFormat(tar, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), false, true), chk.NotNil)
+}
+
+func (s *copyArchiveSuite) collectMembers(c *chk.C, cca *CookedCopyCmdArgs, objects []StoredObject) []ste.ArchiveMember {
+	collector, err := newArchiveMemberCollector(cca)
+	c.Assert(err, chk.IsNil)
+	for _, o := range objects {
+		c.Assert(collector.process(o), chk.IsNil)
+	}
+	c.Assert(collector.writer.Close(), chk.IsNil)
+
+	file, err := os.Open(ste.ArchiveMemberListPath(cca.jobID))
+	c.Assert(err, chk.IsNil)
+	defer file.Close()
+
+	var members []ste.ArchiveMember
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var m ste.ArchiveMember
+		c.Assert(decoder.Decode(&m), chk.IsNil)
+		members = append(members, m)
+	}
+	return members
+}
+
+func (s *copyArchiveSuite) TestArchiveMemberNames(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	lmt := time.Now()
+	objects := []StoredObject{
+		{name: "dir", relativePath: "", entityType: common.EEntityType.Folder(), lastModifiedTime: lmt},
+		{name: "a.txt", relativePath: "a.txt", entityType: common.EEntityType.File(), size: 5, lastModifiedTime: lmt},
+		{name: "b.txt", relativePath: filepath.Join("sub", "b.txt"), entityType: common.EEntityType.File(), size: 513, lastModifiedTime: lmt.Add(time.Hour)},
+	}
+	source := common.ResourceString{Value: filepath.Join(c.MkDir(), "dir")}
+
+	// by default, the members are placed under the name of the source directory
+	cca := &CookedCopyCmdArgs{Source: source, IsSourceDir: true, asSubdir: true, jobID: common.NewJobID()}
+	members := s.collectMembers(c, cca, objects)
+	c.Assert(members, chk.DeepEquals, []ste.ArchiveMember{
+		{Name: "dir/", RelativePath: "", EntityType: common.EEntityType.Folder()},
+		{Name: "dir/a.txt", RelativePath: "a.txt", EntityType: common.EEntityType.File()},
+		{Name: "dir/sub/b.txt", RelativePath: "sub/b.txt", EntityType: common.EEntityType.File()},
+	})
+
+	// with a trailing wildcard, the contents go at the top of the archive, and the root gets no entry
+	cca = &CookedCopyCmdArgs{Source: source, IsSourceDir: true, asSubdir: true, StripTopDir: true, jobID: common.NewJobID()}
+	members = s.collectMembers(c, cca, objects)
+	c.Assert(members, chk.DeepEquals, []ste.ArchiveMember{
+		{Name: "a.txt", RelativePath: "a.txt", EntityType: common.EEntityType.File()},
+		{Name: "sub/b.txt", RelativePath: "sub/b.txt", EntityType: common.EEntityType.File()},
+	})
+}
+
+func (s *copyArchiveSuite) TestArchiveSizeEstimate(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	newest := time.Now()
+	cca := &CookedCopyCmdArgs{Source: common.ResourceString{Value: c.MkDir()}, IsSourceDir: true, StripTopDir: true, jobID: common.NewJobID()}
+	collector, err := newArchiveMemberCollector(cca)
+	c.Assert(err, chk.IsNil)
+	defer collector.writer.Close()
+
+	c.Assert(collector.process(StoredObject{relativePath: "a", entityType: common.EEntityType.File(), size: 1, lastModifiedTime: newest}), chk.IsNil)
+	c.Assert(collector.process(StoredObject{relativePath: "b", entityType: common.EEntityType.File(), size: 512, lastModifiedTime: newest.Add(-time.Hour)}), chk.IsNil)
+	c.Assert(collector.process(StoredObject{relativePath: "c", entityType: common.EEntityType.Folder()}), chk.IsNil)
+
+	// a header block per entry, plus the content padded to whole blocks
+	c.Assert(collector.estimatedSize, chk.Equals, int64(3*512+512+512))
+	c.Assert(collector.lastModifiedTime.Equal(newest), chk.Equals, true)
+	c.Assert(collector.memberCount, chk.Equals, uint64(3))
+}
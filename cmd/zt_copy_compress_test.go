@@ -0,0 +1,59 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type copyCompressSuite struct{}
+
+var _ = chk.Suite(&copyCompressSuite{})
+
+func (s *copyCompressSuite) TestValidateCompressionType(c *chk.C) {
+	gzip, zstd := common.ECompressionType.GZip(), common.ECompressionType.ZStd()
+	localBlob, detect, none := common.EFromTo.LocalBlob(), common.EBlobType.Detect(), common.EArchiveFormat.None()
+
+	c.Assert(validateCompressionType(common.ECompressionType.None(), common.EFromTo.BlobLocal(), common.EBlobType.PageBlob(), "br", true, common.EArchiveFormat.Tar()), chk.IsNil)
+	c.Assert(validateCompressionType(gzip, localBlob, detect, "", false, none), chk.IsNil)
+	c.Assert(validateCompressionType(zstd, localBlob, common.EBlobType.BlockBlob(), "", false, none), chk.IsNil)
+
+	c.Assert(validateCompressionType(common.ECompressionType.ZLib(), localBlob, detect, "", false, none), chk.NotNil)
+	c.Assert(validateCompressionType(gzip, common.EFromTo.LocalFile(), detect, "", false, none), chk.NotNil)
+	c.Assert(validateCompressionType(gzip, localBlob, common.EBlobType.AppendBlob(), "", false, none), chk.NotNil)
+	c.Assert(validateCompressionType(gzip, localBlob, detect, "gzip", false, none), chk.NotNil)
+	c.Assert(validateCompressionType(gzip, localBlob, detect, "", true, none), chk.NotNil)
+	c.Assert(validateCompressionType(gzip, localBlob, detect, "", false, common.EArchiveFormat.TarGz()), chk.NotNil)
+}
+
+func (s *copyCompressSuite) TestCompressionExtensionRoundTrip(c *chk.C) {
+	for _, ct := range []common.CompressionType{common.ECompressionType.GZip(), common.ECompressionType.ZStd()} {
+		for _, name := range []string{"/dir/app.log", "/dir/noextension", "/dir/already.gz"} {
+			uploaded := appendCompressionExtension(name, ct)
+			c.Assert(uploaded, chk.Equals, name+ct.FileExtension())
+
+			// on download with --decompress, the name is given by the content-encoding that was set on upload
+			c.Assert(stripCompressionExtension(uploaded, ct.ContentEncoding()), chk.Equals, name)
+		}
+	}
+}
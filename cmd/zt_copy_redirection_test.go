@@ -0,0 +1,89 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type copyRedirectionSuite struct{}
+
+var _ = chk.Suite(&copyRedirectionSuite{})
+
+func (s *copyRedirectionSuite) TestUploadStreamInChunks(c *chk.C) {
+	var mu sync.Mutex
+	uploaded := map[int64]string{}
+	var grownTo []int64
+
+	n, err := uploadStreamInChunks(context.Background(), strings.NewReader("0123456789"), 4,
+		func(end int64) error {
+			grownTo = append(grownTo, end)
+			return nil
+		},
+		func(ctx context.Context, offset int64, chunk []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			uploaded[offset] = string(chunk)
+			return nil
+		})
+
+	c.Assert(err, chk.IsNil)
+	c.Assert(n, chk.Equals, int64(10))
+	c.Assert(uploaded, chk.DeepEquals, map[int64]string{0: "0123", 4: "4567", 8: "89"})
+	c.Assert(grownTo, chk.DeepEquals, []int64{4, 8, 10})
+
+	// an empty stream uploads nothing
+	n, err = uploadStreamInChunks(context.Background(), strings.NewReader(""), 4, nil,
+		func(ctx context.Context, offset int64, chunk []byte) error {
+			c.Error("unexpected upload")
+			return nil
+		})
+	c.Assert(err, chk.IsNil)
+	c.Assert(n, chk.Equals, int64(0))
+}
+
+func (s *copyRedirectionSuite) TestUploadStreamInChunksFailure(c *chk.C) {
+	uploadErr := errors.New("upload failed")
+
+	_, err := uploadStreamInChunks(context.Background(), strings.NewReader(strings.Repeat("x", 100)), 4, nil,
+		func(ctx context.Context, offset int64, chunk []byte) error {
+			if offset == 8 {
+				return uploadErr
+			}
+			return nil
+		})
+	c.Assert(err, chk.Equals, uploadErr)
+}
+
+func (s *copyRedirectionSuite) TestIsRedirection(c *chk.C) {
+	for _, fromTo := range []common.FromTo{common.EFromTo.PipeBlob(), common.EFromTo.BlobPipe(), common.EFromTo.PipeFile(),
+		common.EFromTo.FilePipe(), common.EFromTo.PipeBlobFS(), common.EFromTo.BlobFSPipe()} {
+		c.Assert((&CookedCopyCmdArgs{FromTo: fromTo}).isRedirection(), chk.Equals, true)
+	}
+	c.Assert((&CookedCopyCmdArgs{FromTo: common.EFromTo.LocalFile()}).isRedirection(), chk.Equals, false)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type jobPrioritySuite struct{}
+
+var _ = chk.Suite(&jobPrioritySuite{})
+
+func (s *jobPrioritySuite) makeLocalDirs(c *chk.C) (src, dst string) {
+	src, dst = c.MkDir(), c.MkDir()
+	c.Assert(os.WriteFile(filepath.Join(src, "file.txt"), []byte("content"), 0644), chk.IsNil)
+	return src, dst
+}
+
+func (s *jobPrioritySuite) TestCopyOrdersTheJobWithItsPriority(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	src, dst := s.makeLocalDirs(c)
+	raw := getDefaultCopyRawInput(src, dst)
+	raw.fromTo = common.EFromTo.LocalLocal().String()
+	raw.recursive = true
+	raw.priority = "high"
+
+	runCopyAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(mockedRPC.lastRequest.(*common.CopyJobPartOrderRequest).Priority, chk.Equals, common.EJobPriority.High())
+	})
+
+	raw.priority = "urgent"
+	_, err := raw.cook()
+	c.Assert(err, chk.ErrorMatches, `invalid --priority value "urgent".*`)
+}
+
+func (s *jobPrioritySuite) TestSyncOrdersTheJobWithItsPriority(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	src, dst := s.makeLocalDirs(c)
+	raw := getDefaultSyncRawInput(src, dst)
+	raw.fromTo = common.EFromTo.LocalLocal().String()
+	raw.priority = "Low"
+
+	runSyncAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+		c.Assert(mockedRPC.lastRequest.(*common.CopyJobPartOrderRequest).Priority, chk.Equals, common.EJobPriority.Low())
+	})
+
+	// without the flag, a job is ordered at the Normal priority, as before
+	raw.priority = ""
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.priority, chk.Equals, common.EJobPriority.Normal())
+}
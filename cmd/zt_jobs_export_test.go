@@ -0,0 +1,187 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unsafe"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+type jobsExportSuite struct{}
+
+var _ = chk.Suite(&jobsExportSuite{})
+
+func (s *jobsExportSuite) createJob(c *chk.C, jobID common.JobID, parts int) {
+	for i := 0; i < parts; i++ {
+		planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), i, ste.DataSchemaVersion))
+		planFile.Create(common.CopyJobPartOrderRequest{
+			JobID:           jobID,
+			PartNum:         common.PartNumber(i),
+			FromTo:          common.EFromTo.LocalBlob(),
+			SourceRoot:      common.ResourceString{Value: "/data/source"},
+			DestinationRoot: common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+			Transfers: common.Transfers{List: []common.CopyTransfer{
+				{Source: fmt.Sprintf("/file%d.txt", i), Destination: fmt.Sprintf("/file%d.txt", i), SourceSize: 10},
+			}},
+		})
+	}
+	c.Assert(os.WriteFile(filepath.Join(azcopyLogPathFolder, jobID.String()+".log"), []byte("log"), 0644), chk.IsNil)
+}
+
+func (s *jobsExportSuite) TestExportImportJob(c *chk.C) {
+	defer func(planFolder, logFolder string) {
+		common.AzcopyJobPlanFolder, azcopyLogPathFolder = planFolder, logFolder
+	}(common.AzcopyJobPlanFolder, azcopyLogPathFolder)
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+
+	jobID, otherJobID := common.NewJobID(), common.NewJobID()
+	s.createJob(c, jobID, 2)
+	s.createJob(c, otherJobID, 1)
+	archivePath := filepath.Join(c.MkDir(), "job"+jobArchiveExtension)
+
+	manifest, err := exportJob(jobID, archivePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(manifest.PlanFiles, chk.HasLen, 2)
+	c.Assert(manifest.LogFiles, chk.DeepEquals, []string{jobID.String() + ".log"})
+	c.Assert(manifest.Source, chk.Equals, "/data/source")
+	c.Assert(manifest.FromTo, chk.Equals, common.EFromTo.LocalBlob().String())
+
+	// the job can't be imported on top of itself
+	_, err = importJob(archivePath)
+	c.Assert(err, chk.NotNil)
+
+	// on another machine, only the exported job shows up
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+	manifest, err = importJob(archivePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(manifest.JobID, chk.Equals, jobID)
+
+	files, err := os.ReadDir(common.AzcopyJobPlanFolder)
+	c.Assert(err, chk.IsNil)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	c.Assert(names, chk.DeepEquals, manifest.PlanFiles)
+
+	for i, name := range names {
+		header, err := readJobPartPlanHeader(filepath.Join(common.AzcopyJobPlanFolder, name))
+		c.Assert(err, chk.IsNil)
+		c.Assert(header.JobID, chk.Equals, jobID)
+		c.Assert(header.PartNum, chk.Equals, common.PartNumber(i))
+		c.Assert(header.NumTransfers, chk.Equals, uint32(1))
+	}
+	content, err := os.ReadFile(filepath.Join(azcopyLogPathFolder, jobID.String()+".log"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, "log")
+}
+
+func (s *jobsExportSuite) TestExportRejectsTruncatedPlan(c *chk.C) {
+	defer func(planFolder, logFolder string) {
+		common.AzcopyJobPlanFolder, azcopyLogPathFolder = planFolder, logFolder
+	}(common.AzcopyJobPlanFolder, azcopyLogPathFolder)
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+
+	jobID := common.NewJobID()
+	s.createJob(c, jobID, 1)
+	planPath := filepath.Join(common.AzcopyJobPlanFolder, fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion))
+	// the header is intact, but the transfers it lists are gone
+	c.Assert(os.Truncate(planPath, int64(unsafe.Sizeof(ste.JobPartPlanHeader{}))), chk.IsNil)
+	archivePath := filepath.Join(c.MkDir(), "job"+jobArchiveExtension)
+	_, err := exportJob(jobID, archivePath)
+	c.Assert(err, chk.NotNil)
+
+	// nothing is left behind by the failed export
+	_, err = os.Stat(archivePath)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *jobsExportSuite) TestExportImportArchiveMemberList(c *chk.C) {
+	defer func(planFolder, logFolder string) {
+		common.AzcopyJobPlanFolder, azcopyLogPathFolder = planFolder, logFolder
+	}(common.AzcopyJobPlanFolder, azcopyLogPathFolder)
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+
+	jobID := common.NewJobID()
+	s.createJob(c, jobID, 1)
+	writer, err := ste.NewArchiveMemberListWriter(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(writer.Write(ste.ArchiveMember{Name: "dir/file", RelativePath: "/dir/file", EntityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(writer.Close(), chk.IsNil)
+	c.Assert(os.WriteFile(ste.ArchiveMemberListPath(jobID)+".index", []byte("index"), 0644), chk.IsNil)
+	memberList, err := os.ReadFile(ste.ArchiveMemberListPath(jobID))
+	c.Assert(err, chk.IsNil)
+
+	archivePath := filepath.Join(c.MkDir(), "job"+jobArchiveExtension)
+	manifest, err := exportJob(jobID, archivePath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(manifest.PlanFiles, chk.HasLen, 1)
+	c.Assert(manifest.MemberFiles, chk.DeepEquals, jobArchiveMemberFiles(jobID))
+
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+	_, err = importJob(archivePath)
+	c.Assert(err, chk.IsNil)
+	content, err := os.ReadFile(ste.ArchiveMemberListPath(jobID))
+	c.Assert(err, chk.IsNil)
+	c.Assert(content, chk.DeepEquals, memberList)
+	content, err = os.ReadFile(ste.ArchiveMemberListPath(jobID) + ".index")
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, "index")
+}
+
+func (s *jobsExportSuite) TestImportRejectsForeignMemberList(c *chk.C) {
+	defer func(planFolder, logFolder string) {
+		common.AzcopyJobPlanFolder, azcopyLogPathFolder = planFolder, logFolder
+	}(common.AzcopyJobPlanFolder, azcopyLogPathFolder)
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+
+	jobID := common.NewJobID()
+	s.createJob(c, jobID, 1)
+	archivePath := filepath.Join(c.MkDir(), "job"+jobArchiveExtension)
+	manifest, err := exportJob(jobID, archivePath)
+	c.Assert(err, chk.IsNil)
+
+	// only the member list of the job itself may be put next to its plan files
+	manifest.MemberFiles = jobArchiveMemberFiles(common.NewJobID())[:1]
+	file, err := os.Create(archivePath)
+	c.Assert(err, chk.IsNil)
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	c.Assert(writeJobArchiveManifest(tarWriter, manifest), chk.IsNil)
+	c.Assert(tarWriter.Close(), chk.IsNil)
+	c.Assert(gzipWriter.Close(), chk.IsNil)
+	c.Assert(file.Close(), chk.IsNil)
+
+	common.AzcopyJobPlanFolder, azcopyLogPathFolder = c.MkDir(), c.MkDir()
+	_, err = importJob(archivePath)
+	c.Assert(err, chk.ErrorMatches, "invalid archive member list.*")
+}
@@ -0,0 +1,182 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+type jobsRetryFailedSuite struct{}
+
+var _ = chk.Suite(&jobsRetryFailedSuite{})
+
+func (s *jobsRetryFailedSuite) planFile(jobID common.JobID, partNum int) ste.JobPartPlanFileName {
+	return ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), partNum, ste.DataSchemaVersion))
+}
+
+// createJob creates a job with parts of 3 transfers each, and gives the transfers of each part the given statuses
+func (s *jobsRetryFailedSuite) createJob(c *chk.C, jobID common.JobID, statuses ...[]common.TransferStatus) {
+	for i, partStatuses := range statuses {
+		var transfers []common.CopyTransfer
+		for t := range partStatuses {
+			transfers = append(transfers, common.CopyTransfer{
+				Source:      fmt.Sprintf("/part%d/file%d.txt", i, t),
+				Destination: fmt.Sprintf("/part%d/file%d.txt", i, t),
+				SourceSize:  int64(10 * t),
+				ContentType: "text/plain",
+				Metadata:    common.Metadata{"key": fmt.Sprintf("value%d", t)},
+			})
+		}
+		planFile := s.planFile(jobID, i)
+		planFile.Create(common.CopyJobPartOrderRequest{
+			JobID:           jobID,
+			PartNum:         common.PartNumber(i),
+			IsFinalPart:     i == len(statuses)-1,
+			FromTo:          common.EFromTo.LocalBlob(),
+			ForceWrite:      common.EOverwriteOption.IfSourceNewer(),
+			SourceRoot:      common.ResourceString{Value: "/data/source"},
+			DestinationRoot: common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+			CommandString:   "copy /data/source https://account.blob.core.windows.net/container",
+			Transfers:       common.Transfers{List: transfers},
+		})
+
+		mmf := planFile.Map()
+		for t, status := range partStatuses {
+			mmf.Plan().Transfer(uint32(t)).SetTransferStatus(status, true)
+			if status.DidFail() {
+				mmf.Plan().Transfer(uint32(t)).SetErrorCode(409, true)
+			}
+		}
+		mmf.Unmap()
+	}
+}
+
+func (s *jobsRetryFailedSuite) TestRetryFailedTransfers(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	success, failed, skipped := common.ETransferStatus.Success(), common.ETransferStatus.Failed(), common.ETransferStatus.SkippedEntityAlreadyExists()
+	jobID, retryJobID := common.NewJobID(), common.NewJobID()
+	s.createJob(c, jobID,
+		[]common.TransferStatus{success, failed, common.ETransferStatus.BlobTierFailure()},
+		[]common.TransferStatus{success, skipped, success},
+		[]common.TransferStatus{failed, success, success})
+
+	destination := &common.ResourceString{Value: "https://account.blob.core.windows.net/other"}
+	link, err := createRetryJob(jobID, retryJobID, destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(link.Transfers, chk.Equals, uint32(3))
+
+	// the part without failures is left out
+	leftOut := s.planFile(retryJobID, 2)
+	c.Assert(leftOut.Exists(), chk.Equals, false)
+	expected := [][]string{{"/part0/file1.txt", "/part0/file2.txt"}, {"/part2/file0.txt"}}
+	for partNum, sources := range expected {
+		mmf := s.planFile(retryJobID, partNum).Map()
+		plan := mmf.Plan()
+		c.Assert(plan.JobID, chk.Equals, retryJobID)
+		c.Assert(plan.PartNum, chk.Equals, common.PartNumber(partNum))
+		c.Assert(plan.IsFinalPart, chk.Equals, partNum == 1)
+		c.Assert(plan.ForceWrite, chk.Equals, common.EOverwriteOption.IfSourceNewer())
+		c.Assert(plan.CommandString(), chk.Equals, "copy /data/source https://account.blob.core.windows.net/container")
+		c.Assert(plan.NumTransfers, chk.Equals, uint32(len(sources)))
+
+		for t, source := range sources {
+			jppt := plan.Transfer(uint32(t))
+			c.Assert(jppt.TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+			c.Assert(jppt.ErrorCode(), chk.Equals, int32(0))
+
+			src, dst, _ := plan.TransferSrcDstStrings(uint32(t))
+			c.Assert(src, chk.Equals, "/data/source"+source)
+			c.Assert(dst, chk.Equals, "https://account.blob.core.windows.net/other"+source)
+			headers, metadata, _, _, _, _, _, _, _, _, _, _ := plan.TransferSrcPropertiesAndMetadata(uint32(t))
+			c.Assert(headers.ContentType, chk.Equals, "text/plain")
+			// the metadata was set from the index of the transfer in the original part
+			c.Assert(metadata["key"], chk.Equals, "value"+strings.TrimSuffix(strings.TrimPrefix(path.Base(source), "file"), ".txt"))
+		}
+		mmf.Unmap()
+	}
+
+	links, err := ste.ReadRetryLinks()
+	c.Assert(err, chk.IsNil)
+	c.Assert(links, chk.HasLen, 1)
+	c.Assert(links[0].RetryOf, chk.Equals, jobID)
+	c.Assert(links[0].JobID, chk.Equals, retryJobID)
+	c.Assert(links[0].Destination, chk.Equals, destination.Value)
+
+	// the original job is left as it was
+	mmf := s.planFile(jobID, 0).Map()
+	c.Assert(mmf.Plan().Transfer(1).TransferStatus(), chk.Equals, failed)
+	mmf.Unmap()
+}
+
+func (s *jobsRetryFailedSuite) TestRetryWithoutFailedTransfers(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	jobID, retryJobID := common.NewJobID(), common.NewJobID()
+	s.createJob(c, jobID, []common.TransferStatus{common.ETransferStatus.Success(), common.ETransferStatus.Cancelled()})
+
+	_, err := createRetryJob(jobID, retryJobID, nil)
+	c.Assert(err, chk.NotNil)
+	planFile := s.planFile(retryJobID, 0)
+	c.Assert(planFile.Exists(), chk.Equals, false)
+
+	links, err := ste.ReadRetryLinks()
+	c.Assert(err, chk.IsNil)
+	c.Assert(links, chk.HasLen, 0)
+}
+
+func (s *jobsRetryFailedSuite) TestRetryMixedDirectionJob(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	// a two-way sync transfers in both directions in the same job
+	jobID, retryJobID := common.NewJobID(), common.NewJobID()
+	failed := common.ETransferStatus.Failed()
+	s.createJob(c, jobID, []common.TransferStatus{failed})
+	reverse := s.planFile(jobID, 1)
+	reverse.Create(common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		PartNum:         1,
+		IsFinalPart:     true,
+		FromTo:          common.EFromTo.BlobLocal(),
+		SourceRoot:      common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+		DestinationRoot: common.ResourceString{Value: "/data/source"},
+		Transfers:       common.Transfers{List: []common.CopyTransfer{{Source: "/file.txt", Destination: "/file.txt"}}},
+	})
+
+	_, err := createRetryJob(jobID, retryJobID, nil)
+	c.Assert(err, chk.ErrorMatches, "job .* transfers both LocalBlob and BlobLocal, as a two-way sync does, which can't be retried.*")
+	planFile := s.planFile(retryJobID, 0)
+	c.Assert(planFile.Exists(), chk.Equals, false)
+
+	links, err := ste.ReadRetryLinks()
+	c.Assert(err, chk.IsNil)
+	c.Assert(links, chk.HasLen, 0)
+}
@@ -0,0 +1,136 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type jobsShowSuite struct{}
+
+var _ = chk.Suite(&jobsShowSuite{})
+
+func (s *jobsShowSuite) TestCookTransferListOptions(c *chk.C) {
+	options, err := ListReq{}.cookTransferListOptions()
+	c.Assert(err, chk.IsNil)
+	c.Assert(options.isSet(), chk.Equals, false)
+
+	options, err = ListReq{PathPattern: "*.pdf", MinSize: "1k", MaxSize: "2048", ErrorCodes: "403, 404", SortBy: "Size", Descending: true, ExportPath: "failures.jsonl"}.cookTransferListOptions()
+	c.Assert(err, chk.IsNil)
+	c.Assert(options.isSet(), chk.Equals, true)
+	c.Assert(options.filters.MinSize, chk.Equals, int64(1024))
+	c.Assert(options.filters.MaxSize, chk.Equals, int64(2048))
+	c.Assert(options.filters.ErrorCodes, chk.DeepEquals, []int32{403, 404})
+	c.Assert(options.sortBy, chk.Equals, "size")
+	c.Assert(options.exportFormat, chk.Equals, transferExportFormatNDJSON)
+
+	for _, raw := range []ListReq{
+		{PathPattern: "[a"},
+		{MinSize: "2k", MaxSize: "1k"},
+		{ErrorCodes: "forbidden"},
+		{CompletedAfter: "yesterday"},
+		{SortBy: "name"},
+		{Descending: true},
+		{ExportPath: "failures.csv", ExportFormat: "xml"},
+		{ExportFormat: "csv"},
+	} {
+		_, err = raw.cookTransferListOptions()
+		c.Assert(err, chk.NotNil, chk.Commentf("%+v", raw))
+	}
+}
+
+func (s *jobsShowSuite) details() []common.TransferDetail {
+	completed := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := completed.Add(time.Hour)
+	return []common.TransferDetail{
+		{Src: "/src/b.txt", Dst: "/dst/b.txt", TransferStatus: common.ETransferStatus.Failed(), TransferSize: 30, ErrorCode: 403, CompletionTime: &later},
+		{Src: "/src/a.txt", Dst: "/dst/a.txt", TransferStatus: common.ETransferStatus.Started(), TransferSize: 10},
+		{Src: "/src/c,d.txt", Dst: "/dst/c,d.txt", TransferStatus: common.ETransferStatus.Success(), TransferSize: 20, CompletionTime: &completed},
+	}
+}
+
+func (s *jobsShowSuite) TestSortTransfers(c *chk.C) {
+	sources := func(details []common.TransferDetail) []string {
+		var result []string
+		for _, d := range details {
+			result = append(result, d.Src)
+		}
+		return result
+	}
+
+	details := s.details()
+	transferListOptions{sortBy: "source"}.sort(details)
+	c.Assert(sources(details), chk.DeepEquals, []string{"/src/a.txt", "/src/b.txt", "/src/c,d.txt"})
+
+	transferListOptions{sortBy: "size", descending: true}.sort(details)
+	c.Assert(sources(details), chk.DeepEquals, []string{"/src/b.txt", "/src/c,d.txt", "/src/a.txt"})
+
+	// transfers that aren't done come last
+	transferListOptions{sortBy: "completion-time"}.sort(details)
+	c.Assert(sources(details), chk.DeepEquals, []string{"/src/c,d.txt", "/src/b.txt", "/src/a.txt"})
+
+	// without a key, the order of the job is kept
+	details = s.details()
+	transferListOptions{}.sort(details)
+	c.Assert(sources(details), chk.DeepEquals, []string{"/src/b.txt", "/src/a.txt", "/src/c,d.txt"})
+}
+
+func (s *jobsShowSuite) TestExportTransfers(c *chk.C) {
+	mockedLcm := mockedLifecycleManager{infoLog: make(chan string, 50)}
+	mockedLcm.SetOutputFormat(common.EOutputFormat.Text())
+	glcm = &mockedLcm
+
+	response := common.ListJobTransfersResponse{JobID: common.NewJobID(), Details: s.details()}
+	dir := c.MkDir()
+
+	csvPath := filepath.Join(dir, "transfers.csv")
+	c.Assert(ExportJobTransfers(response, csvPath, transferExportFormatCSV), chk.IsNil)
+	file, err := os.Open(csvPath)
+	c.Assert(err, chk.IsNil)
+	records, err := csv.NewReader(file).ReadAll()
+	file.Close()
+	c.Assert(err, chk.IsNil)
+	c.Assert(records, chk.HasLen, 4)
+	c.Assert(records[1], chk.DeepEquals, []string{"/src/b.txt", "/dst/b.txt", "false", "Failed", "30", "403", "2023-05-01T13:00:00Z"})
+	c.Assert(records[2][6], chk.Equals, "")
+	c.Assert(records[3][0], chk.Equals, "/src/c,d.txt")
+
+	ndjsonPath := filepath.Join(dir, "transfers.ndjson")
+	c.Assert(ExportJobTransfers(response, ndjsonPath, transferExportFormatNDJSON), chk.IsNil)
+	content, err := os.ReadFile(ndjsonPath)
+	c.Assert(err, chk.IsNil)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	c.Assert(lines, chk.HasLen, 3)
+	var detail common.TransferDetail
+	c.Assert(json.Unmarshal([]byte(lines[0]), &detail), chk.IsNil)
+	c.Assert(detail.Src, chk.Equals, "/src/b.txt")
+	c.Assert(detail.ErrorCode, chk.Equals, int32(403))
+	c.Assert(detail.CompletionTime.Equal(*s.details()[0].CompletionTime), chk.Equals, true)
+}
@@ -870,6 +870,9 @@ func getDefaultSyncRawInput(src, dst string) rawSyncCmdArgs {
 		deleteDestination:   deleteDestination.String(),
 		md5ValidationOption: common.DefaultHashValidationOption.String(),
 		compareHash:         common.ESyncHashType.None().String(),
+		compareMode:         common.ESyncCompareMode.Default().String(),
+		conflictPolicy:      common.ESyncConflictPolicy.NewerWins().String(),
+		dryrunReportFormat:  common.ESyncReportFormat.Json().String(),
 		localHashStorageMode: common.EHashStorageMode.Default().String(),
 	}
 }
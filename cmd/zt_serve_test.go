@@ -0,0 +1,254 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+type serveSuite struct{}
+
+var _ = chk.Suite(&serveSuite{})
+
+const serveTestToken = "test-token"
+
+func (s *serveSuite) newRequest(rpcCmd common.RpcCmd, body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:1337"+rpcCmd.Pattern(), strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+serveTestToken)
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func (s *serveSuite) serve(handler http.Handler, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (s *serveSuite) post(handler http.Handler, rpcCmd common.RpcCmd, body string) *httptest.ResponseRecorder {
+	return s.serve(handler, s.newRequest(rpcCmd, body))
+}
+
+func (s *serveSuite) TestServeRejectsRequestsFromOthers(c *chk.C) {
+	handler := newJobServer(serveTestToken).handler()
+
+	// a request needs the token
+	request := s.newRequest(common.ERpcCmd.ListJobs(), "")
+	request.Header.Del("Authorization")
+	recorder := s.serve(handler, request)
+	c.Assert(recorder.Code, chk.Equals, http.StatusUnauthorized)
+	c.Assert(recorder.Header().Get("WWW-Authenticate"), chk.Equals, "Bearer")
+
+	request = s.newRequest(common.ERpcCmd.ListJobs(), "")
+	request.Header.Set("Authorization", "Bearer not-the-token")
+	c.Assert(s.serve(handler, request).Code, chk.Equals, http.StatusUnauthorized)
+
+	// and a JSON body, which a web page can't send without asking first
+	request = s.newRequest(common.ERpcCmd.ListJobs(), `"All"`)
+	request.Header.Set("Content-Type", "text/plain")
+	c.Assert(s.serve(handler, request).Code, chk.Equals, http.StatusUnsupportedMediaType)
+
+	// and to be addressed to a loopback host, which a rebound host name isn't
+	request = s.newRequest(common.ERpcCmd.ListJobs(), "")
+	request.Host = "attacker.example.com:1337"
+	c.Assert(s.serve(handler, request).Code, chk.Equals, http.StatusForbidden)
+
+	for _, host := range []string{"localhost", "127.0.0.1:1337", "[::1]:1337"} {
+		request = s.newRequest(common.ERpcCmd.GetJobFromTo(), "{not json")
+		request.Host = host
+		c.Assert(s.serve(handler, request).Code, chk.Equals, http.StatusBadRequest, chk.Commentf("host %s", host))
+	}
+}
+
+func (s *serveSuite) TestServeTokenFile(c *chk.C) {
+	path := filepath.Join(c.MkDir(), "serve.token")
+	c.Assert(os.WriteFile(path, []byte("prepared by somebody else"), 0644), chk.IsNil)
+
+	token, err := createServeToken(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(token), chk.Equals, 64)
+	content, err := os.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, token)
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		c.Assert(err, chk.IsNil)
+		c.Assert(info.Mode().Perm(), chk.Equals, os.FileMode(0600))
+	}
+
+	other, err := createServeToken(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(other, chk.Not(chk.Equals), token)
+}
+
+func (s *serveSuite) TestServeRejectsBadRequests(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+	handler := newJobServer(serveTestToken).handler()
+
+	// only POST is supported
+	request := s.newRequest(common.ERpcCmd.ListJobs(), "")
+	request.Method = http.MethodGet
+	recorder := s.serve(handler, request)
+	c.Assert(recorder.Code, chk.Equals, http.StatusMethodNotAllowed)
+	c.Assert(recorder.Header().Get("Allow"), chk.Equals, http.MethodPost)
+
+	recorder = s.post(handler, common.ERpcCmd.ListJobSummary(), "{not json")
+	c.Assert(recorder.Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), chk.Matches, "invalid ListJobSummary request.*\n")
+
+	// an order needs a job ID, and can't prompt
+	recorder = s.post(handler, common.ERpcCmd.CopyJobPartOrder(), "{}")
+	c.Assert(recorder.Code, chk.Equals, http.StatusBadRequest)
+
+	jobID := common.NewJobID()
+	order := common.CopyJobPartOrderRequest{JobID: jobID, ForceWrite: common.EOverwriteOption.Prompt(), FromTo: common.EFromTo.LocalBlob()}
+	payload, err := json.Marshal(order)
+	c.Assert(err, chk.IsNil)
+	recorder = s.post(handler, common.ERpcCmd.CopyJobPartOrder(), string(payload))
+	c.Assert(recorder.Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), chk.Matches, ".*can't prompt.*\n")
+
+	// nor have a priority the engine doesn't know
+	recorder = s.post(handler, common.ERpcCmd.CopyJobPartOrder(), `{"JobID":"`+jobID.String()+`","Priority":"Urgent"}`)
+	c.Assert(recorder.Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(recorder.Body.String(), chk.Matches, ".*couldn't parse \"Urgent\".*\n")
+
+	// a part can't be ordered twice
+	order.ForceWrite = common.EOverwriteOption.True()
+	planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion))
+	planFile.Create(order)
+	payload, err = json.Marshal(order)
+	c.Assert(err, chk.IsNil)
+	recorder = s.post(handler, common.ERpcCmd.CopyJobPartOrder(), string(payload))
+	c.Assert(recorder.Code, chk.Equals, http.StatusConflict)
+	c.Assert(recorder.Body.String(), chk.Equals, fmt.Sprintf("part 0 of job %s was already ordered\n", jobID))
+}
+
+func (s *serveSuite) TestServeHandlesTheRequestsOfOtherJobsMeanwhile(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+	js := newJobServer(serveTestToken)
+	handler := js.handler()
+
+	order := func(jobID common.JobID) <-chan int {
+		// an order that is rejected once the request holds the lock of its job
+		payload, err := json.Marshal(common.CopyJobPartOrderRequest{JobID: jobID, ForceWrite: common.EOverwriteOption.Prompt(), FromTo: common.EFromTo.LocalBlob()})
+		c.Assert(err, chk.IsNil)
+		done := make(chan int, 1)
+		go func() { done <- s.post(handler, common.ERpcCmd.CopyJobPartOrder(), string(payload)).Code }()
+		return done
+	}
+
+	// as if a long request for the job were handled
+	busyJobID, otherJobID := common.NewJobID(), common.NewJobID()
+	unlock := js.lockJob(busyJobID)
+
+	select {
+	case code := <-order(otherJobID):
+		c.Assert(code, chk.Equals, http.StatusBadRequest)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the request for another job waited for the busy one")
+	}
+
+	// a request for the busy job waits, and so does its clean up
+	waiting := order(busyJobID)
+	select {
+	case <-waiting:
+		c.Fatal("the request for the busy job didn't wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+	_, ok := js.tryLockJob(busyJobID)
+	c.Assert(ok, chk.Equals, false)
+
+	unlock()
+	select {
+	case code := <-waiting:
+		c.Assert(code, chk.Equals, http.StatusBadRequest)
+	case <-time.After(5 * time.Second):
+		c.Fatal("the request for the busy job still waits")
+	}
+	c.Assert(js.jobLocks, chk.HasLen, 0)
+}
+
+func (s *serveSuite) TestServeListensOnLoopbackOnly(c *chk.C) {
+	for _, address := range []string{"0.0.0.0:0", ":0"} {
+		_, err := serveCmdArgs{listen: address}.createListener()
+		c.Assert(err, chk.NotNil, chk.Commentf("address %s", address))
+		c.Assert(err.Error(), chk.Matches, ".*loopback.*")
+	}
+
+	listener, err := serveCmdArgs{listen: "127.0.0.1:0"}.createListener()
+	c.Assert(err, chk.IsNil)
+	c.Assert(listener.Addr().(*net.TCPAddr).IP.IsLoopback(), chk.Equals, true)
+	c.Assert(listener.Close(), chk.IsNil)
+}
+
+func (s *serveSuite) TestServeOnSocket(c *chk.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("Unix socket permissions are not enforced on Windows")
+	}
+	socket := filepath.Join(c.MkDir(), "api.sock")
+
+	_, err := serveCmdArgs{listen: "127.0.0.1:0", socket: socket}.createListener()
+	c.Assert(err, chk.NotNil)
+
+	listener, err := serveCmdArgs{listen: defaultServeListenAddress, socket: socket}.createListener()
+	c.Assert(err, chk.IsNil)
+	info, err := os.Stat(socket)
+	c.Assert(err, chk.IsNil)
+	c.Assert(info.Mode().Perm(), chk.Equals, os.FileMode(0600))
+	// and nothing is left of the directory in which it was created
+	entries, err := os.ReadDir(filepath.Dir(socket))
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+
+	// the socket of a running server is left alone, and is removed when it stops
+	_, err = serveCmdArgs{listen: defaultServeListenAddress, socket: socket}.createListener()
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Matches, "another server is already listening.*")
+	c.Assert(listener.Close(), chk.IsNil)
+	_, err = os.Lstat(socket)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+
+	// a stale one is replaced
+	stale, err := net.Listen("unix", socket)
+	c.Assert(err, chk.IsNil)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	c.Assert(stale.Close(), chk.IsNil)
+	listener, err = serveCmdArgs{listen: defaultServeListenAddress, socket: socket}.createListener()
+	c.Assert(err, chk.IsNil)
+	c.Assert(listener.Close(), chk.IsNil)
+}
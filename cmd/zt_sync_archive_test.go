@@ -0,0 +1,204 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncArchiveSuite struct{}
+
+var _ = chk.Suite(&syncArchiveSuite{})
+
+func (s *syncArchiveSuite) TestValidateArchivePrefix(c *chk.C) {
+	for prefix, expected := range map[string]string{
+		".archive":        ".archive",
+		"./.archive/":     ".archive",
+		"old\\deleted":    "old/deleted",
+		"archive/../keep": "keep",
+	} {
+		cleaned, err := validateArchivePrefix(prefix)
+		c.Assert(err, chk.IsNil, chk.Commentf(prefix))
+		c.Assert(cleaned, chk.Equals, expected)
+	}
+
+	for _, prefix := range []string{"/archive", ".", "..", "../archive", "archive/../.."} {
+		_, err := validateArchivePrefix(prefix)
+		c.Assert(err, chk.NotNil, chk.Commentf(prefix))
+	}
+
+	c.Assert(syncArchiveFolder(".archive", time.Date(2023, 1, 2, 16, 4, 5, 0, time.FixedZone("CET", 3600))), chk.Equals, ".archive/20230102T150405Z")
+}
+
+func (s *syncArchiveSuite) TestLocalFileArchiver(c *chk.C) {
+	root := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(root, "dir"), os.ModePerm), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "dir", "extra.txt"), []byte("extra"), 0644), chk.IsNil)
+
+	fpo := common.EFolderPropertiesOption.NoFolders()
+	archiver := &localFileArchiver{
+		localFileDeleter: localFileDeleter{rootPath: root, fpo: fpo, folderManager: common.NewFolderDeletionManager(context.Background(), fpo, nil)},
+		archiveFolder:    ".archive/20230102T150405Z",
+	}
+	err := archiver.archiveFile(StoredObject{name: "extra.txt", relativePath: "dir/extra.txt", entityType: common.EEntityType.File()})
+	c.Assert(err, chk.IsNil)
+
+	_, err = os.Stat(filepath.Join(root, "dir", "extra.txt"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	content, err := os.ReadFile(filepath.Join(root, ".archive", "20230102T150405Z", "dir", "extra.txt"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, "extra")
+}
+
+func (s *syncArchiveSuite) TestSweepLocalSyncArchive(c *chk.C) {
+	root := c.MkDir()
+	now := time.Now()
+	expired := now.Add(-10 * 24 * time.Hour).UTC().Format(syncArchiveTimeFormat)
+	kept := now.Add(-2 * 24 * time.Hour).UTC().Format(syncArchiveTimeFormat)
+	for _, dir := range []string{expired, kept, "not-an-archive"} {
+		c.Assert(os.MkdirAll(filepath.Join(root, ".archive", dir, "dir"), os.ModePerm), chk.IsNil)
+		c.Assert(os.WriteFile(filepath.Join(root, ".archive", dir, "dir", "file.txt"), []byte("archived"), 0644), chk.IsNil)
+	}
+
+	cca := &cookedSyncCmdArgs{
+		destination:          common.ResourceString{Value: root},
+		fromTo:               common.EFromTo.LocalLocal(),
+		archivePrefix:        ".archive",
+		archiveRetentionDays: 7,
+	}
+
+	// nothing is deleted in dry-run mode
+	cca.dryrunMode = true
+	c.Assert(cca.sweepSyncArchive(context.Background(), common.CredentialInfo{}), chk.IsNil)
+	_, err := os.Stat(filepath.Join(root, ".archive", expired))
+	c.Assert(err, chk.IsNil)
+
+	cca.dryrunMode = false
+	c.Assert(cca.sweepSyncArchive(context.Background(), common.CredentialInfo{}), chk.IsNil)
+	_, err = os.Stat(filepath.Join(root, ".archive", expired))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	for _, dir := range []string{kept, "not-an-archive"} {
+		_, err = os.Stat(filepath.Join(root, ".archive", dir, "dir", "file.txt"))
+		c.Assert(err, chk.IsNil)
+	}
+}
+
+// fakeCopyService is a blob endpoint whose server-side copies have the given status once they've started,
+// and which records the copies that were aborted and the blobs that were deleted
+type fakeCopyService struct {
+	status      azblob.CopyStatusType
+	description string
+
+	mu      sync.Mutex
+	aborted []string
+	deleted []string
+}
+
+func (f *fakeCopyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("x-ms-request-id", "fake")
+	switch {
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "copy":
+		f.aborted = append(f.aborted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		w.Header().Set("x-ms-copy-id", "copy-id")
+		w.Header().Set("x-ms-copy-status", string(azblob.CopyStatusPending))
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodHead:
+		w.Header().Set("x-ms-copy-status", string(f.status))
+		w.Header().Set("x-ms-copy-status-description", f.description)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (s *syncArchiveSuite) archiveToFakeService(c *chk.C, ctx context.Context, service *fakeCopyService) error {
+	server := httptest.NewServer(service)
+	defer server.Close()
+	rootURL, err := url.Parse(server.URL + "/account/container/dir")
+	c.Assert(err, chk.IsNil)
+
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+	deleter := newRemoteResourceDeleter(rootURL, p, ctx, common.ELocation.Blob(), common.EFolderPropertiesOption.NoFolders(), false)
+	return deleter.move(StoredObject{name: "extra.txt", relativePath: "extra.txt", entityType: common.EEntityType.File()}, ".archive/20230102T150405Z/extra.txt")
+}
+
+func (s *syncArchiveSuite) TestRemoteArchiveReportsCopiesThatDontSucceed(c *chk.C) {
+	defer func(interval, timeout time.Duration) {
+		syncArchiveCopyPollInterval, syncArchiveCopyTimeout = interval, timeout
+	}(syncArchiveCopyPollInterval, syncArchiveCopyTimeout)
+	syncArchiveCopyPollInterval, syncArchiveCopyTimeout = 10*time.Millisecond, time.Minute
+
+	// a copy that succeeds moves the blob
+	service := &fakeCopyService{status: azblob.CopyStatusSuccess}
+	c.Assert(s.archiveToFakeService(c, context.Background(), service), chk.IsNil)
+	c.Assert(service.deleted, chk.DeepEquals, []string{"/account/container/dir/extra.txt"})
+
+	// a copy that fails is reported with its description, and the blob stays where it is
+	service = &fakeCopyService{status: azblob.CopyStatusFailed, description: "500 InternalError"}
+	err := s.archiveToFakeService(c, context.Background(), service)
+	c.Assert(err, chk.ErrorMatches, "the copy to .archive/20230102T150405Z/extra.txt ended with status failed: 500 InternalError")
+	c.Assert(service.deleted, chk.HasLen, 0)
+
+	// and so does one that was aborted by somebody else
+	service = &fakeCopyService{status: azblob.CopyStatusAborted}
+	err = s.archiveToFakeService(c, context.Background(), service)
+	c.Assert(err, chk.ErrorMatches, "the copy to .* ended with status aborted")
+	c.Assert(service.deleted, chk.HasLen, 0)
+
+	// a copy that takes too long is aborted
+	syncArchiveCopyTimeout = 50 * time.Millisecond
+	service = &fakeCopyService{status: azblob.CopyStatusPending}
+	err = s.archiveToFakeService(c, context.Background(), service)
+	c.Assert(err, chk.ErrorMatches, "the copy to .* was aborted because it didn't finish within 50ms")
+	c.Assert(service.aborted, chk.DeepEquals, []string{"/account/container/dir/.archive/20230102T150405Z/extra.txt"})
+	c.Assert(service.deleted, chk.HasLen, 0)
+
+	// and so is one that is still pending when the sync is cancelled
+	syncArchiveCopyTimeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	service = &fakeCopyService{status: azblob.CopyStatusPending}
+	start := time.Now()
+	err = s.archiveToFakeService(c, ctx, service)
+	c.Assert(err, chk.ErrorMatches, "the copy to .* was aborted because context deadline exceeded")
+	c.Assert(time.Since(start) < 10*time.Second, chk.Equals, true)
+	c.Assert(service.aborted, chk.HasLen, 1)
+	c.Assert(service.deleted, chk.HasLen, 0)
+}
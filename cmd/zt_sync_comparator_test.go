@@ -21,8 +21,11 @@
 package cmd
 
 import (
+	"context"
 	"github.com/Azure/azure-storage-azcopy/v10/common"
 	chk "gopkg.in/check.v1"
+	"os"
+	"path/filepath"
 	"time"
 )
 
@@ -37,7 +40,7 @@ func (s *syncComparatorSuite) TestSyncSourceComparator(c *chk.C) {
 
 	// set up the indexer as well as the source comparator
 	indexer := newObjectIndexer()
-	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), false, false)
+	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, false, false)
 
 	// create a sample destination object
 	sampleDestinationObject := StoredObject{name: "test", relativePath: "/usr/test", lastModifiedTime: time.Now(), md5: destMD5}
@@ -89,7 +92,7 @@ func (s *syncComparatorSuite) TestSyncSrcCompDisableComparator(c *chk.C) {
 
 	// set up the indexer as well as the source comparator
 	indexer := newObjectIndexer()
-	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), false, true)
+	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, true, false)
 
 	// test the comparator in case a given source object is not present at the destination
 	// meaning no entry in the index, so the comparator should pass the given object to schedule a transfer
@@ -130,6 +133,62 @@ func (s *syncComparatorSuite) TestSyncSrcCompDisableComparator(c *chk.C) {
 	}
 }
 
+func (s *syncComparatorSuite) TestSyncSrcCompCompareSize(c *chk.C) {
+	dummyCopyScheduler := dummyProcessor{}
+	md5 := []byte{'m'}
+	currTime := time.Now()
+
+	// set up the indexer as well as a source comparator for a source without reliable hashes (S3, GCS)
+	indexer := newObjectIndexer()
+	sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, false, true)
+
+	// a source object older than the destination is still transferred if its size differs
+	err := indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(-time.Hour), size: 11})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	// but not if the size matches
+	err = indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(-time.Hour), size: 10})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	// when comparing hashes, a source object without one falls back to LMT and size instead of being skipped
+	dummyCopyScheduler = dummyProcessor{}
+	sourceComparator = newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.MD5(), common.ESyncCompareMode.Default(), "", false, false, true)
+	err = indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10, md5: md5})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(time.Hour), size: 10})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	// and a source object with a matching hash is skipped regardless of its LMT
+	err = indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10, md5: md5})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(time.Hour), size: 10, md5: md5})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+	c.Assert(len(indexer.indexMap), chk.Equals, 0)
+
+	// as is an S3 object whose ETag is the MD5 of the content, which isn't passed on to the transfer as its Content-MD5
+	err = indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10, md5: md5})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(time.Hour), size: 10, syncComparableMD5: md5})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+	// while one whose ETag differs is transferred, without it
+	err = indexer.store(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime, size: 10, md5: md5})
+	c.Assert(err, chk.IsNil)
+	err = sourceComparator.processIfNecessary(StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(-time.Hour), size: 10, syncComparableMD5: []byte{'e'}})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 2)
+	c.Assert(dummyCopyScheduler.record[1].md5, chk.IsNil)
+}
+
 func (s *syncComparatorSuite) TestSyncDestinationComparator(c *chk.C) {
 	dummyCopyScheduler := dummyProcessor{}
 	dummyCleaner := dummyProcessor{}
@@ -138,7 +197,7 @@ func (s *syncComparatorSuite) TestSyncDestinationComparator(c *chk.C) {
 
 	// set up the indexer as well as the destination comparator
 	indexer := newObjectIndexer()
-	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, false)
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, false)
 
 	// create a sample source object
 	sampleSourceObject := StoredObject{name: "test", relativePath: "/usr/test", lastModifiedTime: time.Now(), md5: srcMD5}
@@ -195,7 +254,7 @@ func (s *syncComparatorSuite) TestSyncDestCompDisableComparison(c *chk.C) {
 
 	// set up the indexer as well as the destination comparator
 	indexer := newObjectIndexer()
-	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), false, true)
+	destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, true)
 
 	// create a sample source object
 	currTime := time.Now()
@@ -238,3 +297,156 @@ func (s *syncComparatorSuite) TestSyncDestCompDisableComparison(c *chk.C) {
 		c.Assert(len(dummyCopyScheduler.record), chk.Equals, key+1)
 	}
 }
+
+func (s *syncComparatorSuite) TestSyncComparatorsNonMD5Hashes(c *chk.C) {
+	currTime := time.Now()
+
+	for _, hashType := range []common.SyncHashType{common.ESyncHashType.CRC64(), common.ESyncHashType.SHA256(), common.ESyncHashType.XXHash64()} {
+		withHash := func(o StoredObject, sum string) StoredObject {
+			o.setSyncHash(hashType, []byte(sum))
+			return o
+		}
+		older := StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime}
+		newer := StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: currTime.Add(time.Hour)}
+
+		// the source comparator transfers a differing hash, even from an older source
+		dummyCopyScheduler := dummyProcessor{}
+		indexer := newObjectIndexer()
+		sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, hashType, common.ESyncCompareMode.Default(), "", false, false, false)
+		c.Assert(indexer.store(withHash(newer, "d")), chk.IsNil)
+		c.Assert(sourceComparator.processIfNecessary(withHash(older, "s")), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+		c.Assert(dummyCopyScheduler.record[0].syncHash(hashType), chk.DeepEquals, []byte("s"))
+
+		// and skips a matching hash, even from a newer source
+		c.Assert(indexer.store(withHash(older, "s")), chk.IsNil)
+		c.Assert(sourceComparator.processIfNecessary(withHash(newer, "s")), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+		// as well as a source without a hash
+		c.Assert(indexer.store(withHash(older, "d")), chk.IsNil)
+		c.Assert(sourceComparator.processIfNecessary(newer), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+		c.Assert(len(indexer.indexMap), chk.Equals, 0)
+
+		// the destination comparator behaves the same way
+		dummyCopyScheduler = dummyProcessor{}
+		dummyCleaner := dummyProcessor{}
+		destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, hashType, common.ESyncCompareMode.Default(), "", false, false)
+		c.Assert(indexer.store(withHash(older, "s")), chk.IsNil)
+		c.Assert(destinationComparator.processIfNecessary(withHash(newer, "d")), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+		c.Assert(indexer.store(withHash(newer, "s")), chk.IsNil)
+		c.Assert(destinationComparator.processIfNecessary(withHash(older, "s")), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+
+		c.Assert(indexer.store(newer), chk.IsNil)
+		c.Assert(destinationComparator.processIfNecessary(withHash(older, "d")), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, 1)
+		c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+	}
+}
+
+func (s *syncComparatorSuite) TestSyncComparatorsCompareModes(c *chk.C) {
+	currTime := time.Now()
+	file := func(lmt time.Time, size int64, md5 string, metadata common.Metadata) StoredObject {
+		o := StoredObject{name: "test", relativePath: "test", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: size, Metadata: metadata}
+		if md5 != "" {
+			o.md5 = []byte(md5)
+		}
+		return o
+	}
+	older, newer := currTime, currTime.Add(time.Hour)
+
+	testCases := []struct {
+		name        string
+		mode        common.SyncCompareMode
+		source      StoredObject
+		destination StoredObject
+		transfer    bool
+	}{
+		{"size-only ignores a newer source of the same size", common.ESyncCompareMode.SizeOnly(), file(newer, 10, "", nil), file(older, 10, "", nil), false},
+		{"size-only transfers an older source of a different size", common.ESyncCompareMode.SizeOnly(), file(older, 11, "", nil), file(newer, 10, "", nil), true},
+		{"lmt-and-size transfers a newer source of the same size", common.ESyncCompareMode.LMTAndSize(), file(newer, 10, "", nil), file(older, 10, "", nil), true},
+		{"lmt-and-size transfers an older source of a different size", common.ESyncCompareMode.LMTAndSize(), file(older, 11, "", nil), file(newer, 10, "", nil), true},
+		{"lmt-and-size skips an older source of the same size", common.ESyncCompareMode.LMTAndSize(), file(older, 10, "", nil), file(newer, 10, "", nil), false},
+		{"hash-only skips a newer source with the same hash", common.ESyncCompareMode.HashOnly(), file(newer, 11, "a", nil), file(older, 10, "a", nil), false},
+		{"hash-only transfers an older source with a differing hash", common.ESyncCompareMode.HashOnly(), file(older, 10, "a", nil), file(newer, 10, "b", nil), true},
+		{"hash-only transfers a source without a hash", common.ESyncCompareMode.HashOnly(), file(older, 10, "", nil), file(newer, 10, "b", nil), true},
+		{"hash-only transfers to a destination without a hash", common.ESyncCompareMode.HashOnly(), file(older, 10, "a", nil), file(newer, 10, "", nil), true},
+		{"metadata skips the same value", common.ESyncCompareMode.Metadata(), file(newer, 11, "", common.Metadata{"ContentSum": "x"}), file(older, 10, "", common.Metadata{"contentsum": "x"}), false},
+		{"metadata transfers a differing value", common.ESyncCompareMode.Metadata(), file(older, 10, "", common.Metadata{"contentsum": "x"}), file(newer, 10, "", common.Metadata{"contentsum": "y"}), true},
+		{"metadata transfers to a destination without the key", common.ESyncCompareMode.Metadata(), file(older, 10, "", common.Metadata{"contentsum": "x"}), file(newer, 10, "", nil), true},
+		{"metadata skips a source without the key", common.ESyncCompareMode.Metadata(), file(newer, 11, "", nil), file(older, 10, "", common.Metadata{"contentsum": "y"}), false},
+	}
+
+	for _, tc := range testCases {
+		expected := 0
+		if tc.transfer {
+			expected = 1
+		}
+
+		dummyCopyScheduler := dummyProcessor{}
+		indexer := newObjectIndexer()
+		sourceComparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.MD5(), tc.mode, "contentsum", false, false, false)
+		c.Assert(indexer.store(tc.destination), chk.IsNil)
+		c.Assert(sourceComparator.processIfNecessary(tc.source), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, expected, chk.Commentf("source comparator: %s", tc.name))
+
+		dummyCopyScheduler = dummyProcessor{}
+		dummyCleaner := dummyProcessor{}
+		destinationComparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, dummyCleaner.process, common.ESyncHashType.MD5(), tc.mode, "contentsum", false, false)
+		c.Assert(indexer.store(tc.source), chk.IsNil)
+		c.Assert(destinationComparator.processIfNecessary(tc.destination), chk.IsNil)
+		c.Assert(len(dummyCopyScheduler.record), chk.Equals, expected, chk.Commentf("destination comparator: %s", tc.name))
+		c.Assert(len(dummyCleaner.record), chk.Equals, 0)
+	}
+}
+
+func (s *syncComparatorSuite) TestSyncHashInMetadata(c *chk.C) {
+	// hashes other than MD5 are kept in metadata, so that remote objects and uploads carry them
+	obj := StoredObject{Metadata: common.Metadata{"other": "value"}}
+	obj.setSyncHash(common.ESyncHashType.SHA256(), []byte{1, 2, 3})
+	c.Assert(obj.Metadata, chk.DeepEquals, common.Metadata{"other": "value", "azcopysha256": "AQID"})
+	c.Assert(obj.syncHash(common.ESyncHashType.SHA256()), chk.DeepEquals, []byte{1, 2, 3})
+	c.Assert(obj.syncHash(common.ESyncHashType.CRC64()), chk.IsNil)
+	c.Assert(obj.md5, chk.IsNil)
+
+	// remote services may not preserve the case of the key, and an unreadable value counts as missing
+	obj = StoredObject{Metadata: common.Metadata{"AzCopyXXHash64": "AQID", "azcopycrc64": "not base64!"}}
+	c.Assert(obj.syncHash(common.ESyncHashType.XXHash64()), chk.DeepEquals, []byte{1, 2, 3})
+	c.Assert(obj.syncHash(common.ESyncHashType.CRC64()), chk.IsNil)
+
+	// MD5 keeps using the md5 field
+	obj.setSyncHash(common.ESyncHashType.MD5(), []byte{4})
+	c.Assert(obj.md5, chk.DeepEquals, []byte{4})
+	c.Assert(obj.syncHash(common.ESyncHashType.MD5()), chk.DeepEquals, []byte{4})
+}
+
+func (s *syncComparatorSuite) TestLocalTraverserNonMD5Hash(c *chk.C) {
+	dir := c.MkDir()
+	content := []byte("hash me")
+	c.Assert(os.WriteFile(filepath.Join(dir, "file"), content, 0644), chk.IsNil)
+
+	for _, hashType := range []common.SyncHashType{common.ESyncHashType.CRC64(), common.ESyncHashType.SHA256(), common.ESyncHashType.XXHash64()} {
+		hasher := hashType.NewHasher()
+		_, _ = hasher.Write(content)
+		expected := hasher.Sum(nil)
+
+		traverser, err := newLocalTraverser(context.TODO(), dir, true, false, common.ESymlinkHandlingType.Skip(), hashType, func(common.EntityType) {}, nil)
+		c.Assert(err, chk.IsNil)
+		dummy := dummyProcessor{}
+		c.Assert(traverser.Traverse(noPreProccessor, dummy.process, nil), chk.IsNil)
+
+		var files []StoredObject
+		for _, o := range dummy.record {
+			if o.entityType == common.EEntityType.File() {
+				files = append(files, o)
+			}
+		}
+		c.Assert(len(files), chk.Equals, 1)
+		c.Assert(files[0].syncHash(hashType), chk.DeepEquals, expected)
+		c.Assert(len(files[0].md5), chk.Equals, 0)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncIndexSpillSuite struct{}
+
+var _ = chk.Suite(&syncIndexSpillSuite{})
+
+func (s *syncIndexSpillSuite) TestSpilledObjectRoundTrip(c *chk.C) {
+	// every field of StoredObject must survive the trip to disk
+	c.Assert(reflect.TypeOf(spilledObject{}).NumField(), chk.Equals, reflect.TypeOf(StoredObject{}).NumField())
+
+	original := StoredObject{
+		name:                "b.txt",
+		entityType:          common.EEntityType.File(),
+		lastModifiedTime:    time.Now().UTC(),
+		smbLastModifiedTime: time.Now().Add(-time.Hour).UTC(),
+		size:                1234,
+		md5:                 []byte{1, 2, 3},
+		blobType:            azblob.BlobBlockBlob,
+		contentDisposition:  "inline",
+		cacheControl:        "no-cache",
+		contentLanguage:     "en",
+		contentEncoding:     "gzip",
+		contentType:         "text/plain",
+		relativePath:        "a/b.txt",
+		ContainerName:       "src",
+		DstContainerName:    "dst",
+		blobAccessTier:      azblob.AccessTierCool,
+		archiveStatus:       azblob.ArchiveStatusRehydratePendingToHot,
+		Metadata:            common.Metadata{"key": "value"},
+		blobVersionID:       "version",
+		blobTags:            common.BlobTags{"tag": "value"},
+		blobSnapshotID:      "snapshot",
+		blobDeleted:         true,
+		leaseState:          azblob.LeaseStateLeased,
+		leaseStatus:         azblob.LeaseStatusLocked,
+		leaseDuration:       azblob.LeaseDurationInfinite,
+	}
+
+	f, err := newSpillFile(c.MkDir() + "/objects")
+	c.Assert(err, chk.IsNil)
+	c.Assert(f.write(original), chk.IsNil)
+	c.Assert(f.close(), chk.IsNil)
+
+	var read []StoredObject
+	c.Assert(f.read(func(o StoredObject) error { read = append(read, o); return nil }), chk.IsNil)
+	c.Assert(read, chk.HasLen, 1)
+	c.Assert(read[0].lastModifiedTime.Equal(original.lastModifiedTime), chk.Equals, true)
+	read[0].lastModifiedTime, read[0].smbLastModifiedTime = original.lastModifiedTime, original.smbLastModifiedTime
+	c.Assert(read[0], chk.DeepEquals, original)
+}
+
+// runSyncComparison indexes the destination objects, compares the source objects against them,
+// and returns what was scheduled for transfer and what was left in the index
+func (s *syncIndexSpillSuite) runSyncComparison(c *chk.C, spillThreshold int, destination, source []StoredObject) (scheduled, remaining []string) {
+	spillFolder := c.MkDir()
+	indexer := newObjectIndexer()
+	indexer.enableSpill(spillThreshold, spillFolder, common.NewJobID())
+	copyScheduler := dummyProcessor{}
+	comparator := newSyncSourceComparator(indexer, copyScheduler.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, false, false).processIfNecessary
+
+	for _, o := range destination {
+		c.Assert(indexer.store(o), chk.IsNil)
+	}
+	processor := indexer.comparatorFor(comparator)
+	for _, o := range source {
+		c.Assert(processor(o), chk.IsNil)
+	}
+	c.Assert(indexer.compareSpilled(comparator), chk.IsNil)
+
+	deleteScheduler := dummyProcessor{}
+	c.Assert(indexer.traverse(deleteScheduler.process, nil), chk.IsNil)
+	indexer.cleanup()
+
+	// nothing is left behind on disk
+	entries, err := os.ReadDir(spillFolder)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 0)
+
+	for _, o := range copyScheduler.record {
+		scheduled = append(scheduled, o.relativePath)
+	}
+	for _, o := range deleteScheduler.record {
+		remaining = append(remaining, o.relativePath)
+	}
+	sort.Strings(scheduled)
+	sort.Strings(remaining)
+	return
+}
+
+func (s *syncIndexSpillSuite) TestSpilledIndexMatchesInMemoryIndex(c *chk.C) {
+	now := time.Now()
+	var destination, source []StoredObject
+	for n := 0; n < 3000; n++ {
+		name := fmt.Sprintf("dir%d/file%d", n%7, n)
+		switch n % 4 {
+		case 0: // only at the destination
+			destination = append(destination, StoredObject{name: name, relativePath: name, lastModifiedTime: now})
+		case 1: // only at the source
+			source = append(source, StoredObject{name: name, relativePath: name, lastModifiedTime: now})
+		case 2: // newer at the source
+			destination = append(destination, StoredObject{name: name, relativePath: name, lastModifiedTime: now})
+			source = append(source, StoredObject{name: name, relativePath: name, lastModifiedTime: now.Add(time.Hour)})
+		case 3: // older at the source
+			destination = append(destination, StoredObject{name: name, relativePath: name, lastModifiedTime: now})
+			source = append(source, StoredObject{name: name, relativePath: name, lastModifiedTime: now.Add(-time.Hour)})
+		}
+	}
+
+	expectedScheduled, expectedRemaining := s.runSyncComparison(c, 0, destination, source)
+	c.Assert(expectedScheduled, chk.HasLen, 1500)
+	c.Assert(expectedRemaining, chk.HasLen, 750)
+
+	// spilled, with partitions small enough to load as they are
+	scheduled, remaining := s.runSyncComparison(c, 1000, destination, source)
+	c.Assert(scheduled, chk.DeepEquals, expectedScheduled)
+	c.Assert(remaining, chk.DeepEquals, expectedRemaining)
+
+	// spilled, with partitions that have to be split again before they can be loaded
+	scheduled, remaining = s.runSyncComparison(c, 2, destination, source)
+	c.Assert(scheduled, chk.DeepEquals, expectedScheduled)
+	c.Assert(remaining, chk.DeepEquals, expectedRemaining)
+}
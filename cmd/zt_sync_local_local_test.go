@@ -0,0 +1,78 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncLocalLocalSuite struct{}
+
+var _ = chk.Suite(&syncLocalLocalSuite{})
+
+// local->local sync with --delete-destination, where the destination has both stale files and files that are up to date
+func (s *syncLocalLocalSuite) TestSyncLocalToLocalWithDeleteDestination(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	mockedRPC := interceptor{}
+	Rpc = mockedRPC.intercept
+	mockedRPC.init()
+
+	srcDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(srcDirName)
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+
+	scenarioHelper{}.generateLocalFilesFromList(c, srcDirName, []string{"changed.txt", "current.txt", "new.txt", "sub/new.txt"})
+	scenarioHelper{}.generateLocalFilesFromList(c, dstDirName, []string{"changed.txt", "current.txt", "extra.txt", "sub/extra.txt"})
+
+	// the destination's copy of changed.txt predates the source's, while current.txt was updated at the destination since
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	c.Assert(os.Chtimes(filepath.Join(dstDirName, "changed.txt"), past, past), chk.IsNil)
+	c.Assert(os.Chtimes(filepath.Join(dstDirName, "current.txt"), future, future), chk.IsNil)
+
+	raw := getDefaultSyncRawInput(srcDirName, dstDirName)
+	raw.fromTo = common.EFromTo.LocalLocal().String()
+	raw.deleteDestination = "true"
+
+	runSyncAndVerify(c, raw, func(err error) {
+		c.Assert(err, chk.IsNil)
+
+		// the local->local job copies what is new or changed at the source, like a download
+		c.Assert(mockedRPC.lastRequest.(*common.CopyJobPartOrderRequest).FromTo, chk.Equals, common.EFromTo.LocalLocal())
+		validateCopyTransfersAreScheduled(c, false, false, "", "", []string{"changed.txt", "new.txt", "sub/new.txt"}, mockedRPC)
+
+		// and what is at the destination only is deleted from it
+		for _, name := range []string{"extra.txt", "sub/extra.txt"} {
+			_, err := os.Stat(filepath.Join(dstDirName, name))
+			c.Assert(os.IsNotExist(err), chk.Equals, true, chk.Commentf("%s was not deleted", name))
+		}
+		for _, name := range []string{"changed.txt", "current.txt"} {
+			_, err := os.Stat(filepath.Join(dstDirName, name))
+			c.Assert(err, chk.IsNil)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncManifestSuite struct{}
+
+var _ = chk.Suite(&syncManifestSuite{})
+
+// sliceTraverser passes on a fixed list of objects
+type sliceTraverser struct {
+	objects []StoredObject
+}
+
+func (t *sliceTraverser) IsDirectory(bool) (bool, error) {
+	return true, nil
+}
+
+func (t *sliceTraverser) Traverse(_ objectMorpher, processor objectProcessor, filters []ObjectFilter) error {
+	for _, o := range t.objects {
+		if _, err := getProcessingError(processIfPassedFilters(filters, o, processor)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syncManifestSuite) newCookedArgs() *cookedSyncCmdArgs {
+	return &cookedSyncCmdArgs{
+		source:         common.ResourceString{Value: "/data/photos"},
+		destination:    common.ResourceString{Value: "https://account.blob.core.windows.net/photos", SAS: "sig=a"},
+		fromTo:         common.EFromTo.LocalBlob(),
+		recursive:      true,
+		jobID:          common.NewJobID(),
+		manifestMaxAge: time.Hour,
+	}
+}
+
+func (s *syncManifestSuite) TestSyncManifestRoundTrip(c *chk.C) {
+	cca := s.newCookedArgs()
+	manifestPath := filepath.Join(c.MkDir(), "pair.manifest")
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	// the first sync lists the destination, and records it
+	_, _, reason := openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Not(chk.Equals), "")
+
+	recorder, err := newSyncManifestRecorder(cca, manifestPath, "")
+	c.Assert(err, chk.IsNil)
+	destination := &recordingTraverser{ResourceTraverser: &sliceTraverser{objects: []StoredObject{
+		{name: "unchanged.txt", relativePath: "unchanged.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1},
+		{name: "modified.txt", relativePath: "modified.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 2},
+		{name: "extra.txt", relativePath: "extra.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 3},
+	}}, recorder: recorder}
+	isDir, err := destination.IsDirectory(false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(isDir, chk.Equals, true)
+
+	listed := dummyProcessor{}
+	c.Assert(destination.Traverse(noPreProccessor, listed.process, nil), chk.IsNil)
+	c.Assert(listed.record, chk.HasLen, 3)
+
+	scheduled := dummyProcessor{}
+	deleted := dummyProcessor{}
+	scheduleCopy := recorder.recordTransfers(scheduled.process)
+	deleteObject := recorder.recordDeletions(deleted.process)
+	c.Assert(scheduleCopy(StoredObject{name: "modified.txt", relativePath: "modified.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt.Add(time.Hour), size: 20}), chk.IsNil)
+	c.Assert(scheduleCopy(StoredObject{name: "new.txt", relativePath: "new.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt.Add(time.Hour), size: 4}), chk.IsNil)
+	c.Assert(deleteObject(StoredObject{name: "extra.txt", relativePath: "extra.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(scheduled.record, chk.HasLen, 2)
+	c.Assert(deleted.record, chk.HasLen, 1)
+	recorder.finish(common.EJobStatus.Completed())
+
+	// the next sync reads the destination from the manifest
+	inUsePath, header, reason := openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "")
+	c.Assert(header.IsDirectory, chk.Equals, true)
+	_, err = os.Stat(manifestPath)
+	c.Assert(os.IsNotExist(err), chk.Equals, true) // while in use, the manifest is out of the way
+
+	read := dummyProcessor{}
+	c.Assert(newManifestTraverser(inUsePath, header, nil).Traverse(noPreProccessor, read.process, nil), chk.IsNil)
+	objects := make(map[string]StoredObject)
+	for _, o := range read.record {
+		objects[o.relativePath] = o
+	}
+	c.Assert(objects, chk.HasLen, 3)
+	c.Assert(objects["unchanged.txt"].lastModifiedTime.Equal(lmt), chk.Equals, true)
+	c.Assert(objects["modified.txt"].size, chk.Equals, int64(20))
+	c.Assert(objects["modified.txt"].lastModifiedTime.Equal(recorder.startTime), chk.Equals, true)
+	c.Assert(objects["new.txt"].size, chk.Equals, int64(4))
+
+	// a sync that doesn't finish leaves no manifest behind
+	recorder, err = newSyncManifestRecorder(cca, manifestPath, inUsePath)
+	c.Assert(err, chk.IsNil)
+	recorder.finish(common.EJobStatus.Cancelled())
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "there's no manifest of an earlier sync")
+	_, err = os.Stat(inUsePath)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *syncManifestSuite) TestSyncManifestStaleness(c *chk.C) {
+	cca := s.newCookedArgs()
+	manifestPath := filepath.Join(c.MkDir(), "pair.manifest")
+
+	recorder, err := newSyncManifestRecorder(cca, manifestPath, "")
+	c.Assert(err, chk.IsNil)
+	recorder.finish(common.EJobStatus.Completed())
+
+	// a dry run reads the manifest where it is
+	cca.dryrunMode = true
+	inUsePath, _, reason := openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "")
+	c.Assert(inUsePath, chk.Equals, manifestPath)
+	cca.dryrunMode = false
+
+	// other filters may list other objects
+	cca.includePatterns = []string{"*.jpg"}
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "the manifest was written by a sync with other options")
+	cca.includePatterns = nil
+
+	// and other comparisons compare other properties, which the manifest may not hold
+	cca.compareMode = common.ESyncCompareMode.Metadata()
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "the manifest was written by a sync with other options")
+	cca.compareMode, cca.compareMetadataKey = common.ESyncCompareMode.Default(), "etag"
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "the manifest was written by a sync with other options")
+	cca.compareMode, cca.compareMetadataKey = common.ESyncCompareMode.Default(), ""
+
+	cca.manifestMaxAge = 0
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "the manifest is older than 0s")
+	cca.manifestMaxAge = time.Hour
+
+	// the SAS doesn't matter, but the resources do
+	cca.destination.SAS = "sig=b"
+	c.Assert(syncPairFilePath(syncManifestFolder, syncManifestExtension, cca.source, cca.destination), chk.Equals,
+		syncPairFilePath(syncManifestFolder, syncManifestExtension, cca.source, s.newCookedArgs().destination))
+	cca.destination.Value += "2"
+	_, _, reason = openSyncManifest(manifestPath, cca)
+	c.Assert(reason, chk.Equals, "the manifest is of another source or destination")
+}
@@ -0,0 +1,147 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncMovesSuite struct{}
+
+var _ = chk.Suite(&syncMovesSuite{})
+
+// testMover records the moves it's asked to make, and fails those to the given path
+type testMover struct {
+	moves  map[string]string
+	failTo string
+}
+
+func (m *testMover) move(object StoredObject, toRelativePath string) error {
+	if toRelativePath == m.failTo {
+		return errors.New("move failed")
+	}
+	m.moves[object.relativePath] = toRelativePath
+	return nil
+}
+
+func newTestSyncMoveDetector(mover *testMover) (detector *syncMoveDetector, copied, deleted *dummyProcessor) {
+	copied, deleted = &dummyProcessor{}, &dummyProcessor{}
+	processor := newInteractiveDeleteProcessor(deleted.process, common.EDeleteDestination.True(), "blob", common.ResourceString{}, nil, false)
+	processor.mover = mover.move
+
+	detector = newSyncMoveDetector(common.ESyncHashType.MD5(), nil)
+	detector.copy, detector.delete, detector.mover = copied.process, deleted.process, processor
+	return detector, copied, deleted
+}
+
+func testMoveObject(relativePath string, size int64, md5 string) StoredObject {
+	object := StoredObject{name: filepath.Base(relativePath), relativePath: relativePath, entityType: common.EEntityType.File(), size: size}
+	if md5 != "" {
+		object.md5 = []byte(md5)
+	}
+	return object
+}
+
+func (s *syncMovesSuite) TestSyncMoveDetectorHoldsExtras(c *chk.C) {
+	mover := &testMover{moves: map[string]string{}, failTo: "new/failed.txt"}
+	detector, copied, deleted := newTestSyncMoveDetector(mover)
+
+	// when uploading, the extra files at the destination are held, unless their moves can't be detected
+	for _, extra := range []StoredObject{
+		testMoveObject("old/a.txt", 10, "a"),
+		testMoveObject("old/b.txt", 20, "b"),
+		testMoveObject("old/failed.txt", 30, "f"),
+		testMoveObject("old/nohash.txt", 40, ""),
+		testMoveObject("old/empty.txt", 0, "e"),
+		testMoveObject("old/unmatched.txt", 50, "u"),
+	} {
+		c.Assert(detector.holdExtra(extra), chk.IsNil)
+	}
+	c.Assert(len(deleted.record), chk.Equals, 2)
+
+	// and are matched by size and hash with the files that the destination lacks
+	for _, missing := range []StoredObject{
+		testMoveObject("new/a.txt", 10, "a"),
+		testMoveObject("new/b.txt", 20, "b-changed"),
+		testMoveObject("new/failed.txt", 30, "f"),
+		testMoveObject("new/new.txt", 60, "n"),
+	} {
+		c.Assert(detector.matchMissing(missing), chk.IsNil)
+	}
+	c.Assert(detector.releaseExtra(), chk.IsNil)
+
+	c.Assert(mover.moves, chk.DeepEquals, map[string]string{"old/a.txt": "new/a.txt"})
+	copiedPaths := map[string]bool{}
+	for _, object := range copied.record {
+		copiedPaths[object.relativePath] = true
+	}
+	c.Assert(copiedPaths, chk.DeepEquals, map[string]bool{"new/b.txt": true, "new/failed.txt": true, "new/new.txt": true})
+	deletedPaths := map[string]bool{}
+	for _, object := range deleted.record {
+		deletedPaths[object.relativePath] = true
+	}
+	c.Assert(deletedPaths, chk.DeepEquals, map[string]bool{"old/b.txt": true, "old/failed.txt": true, "old/nohash.txt": true, "old/empty.txt": true, "old/unmatched.txt": true})
+	c.Assert(detector.held, chk.HasLen, 0)
+}
+
+func (s *syncMovesSuite) TestSyncMoveDetectorHoldsMissing(c *chk.C) {
+	mover := &testMover{moves: map[string]string{}}
+	detector, copied, deleted := newTestSyncMoveDetector(mover)
+
+	// otherwise, the files that the destination lacks are held, and files with the same content are matched once each
+	c.Assert(detector.holdMissing(testMoveObject("new/copy1.txt", 10, "a")), chk.IsNil)
+	c.Assert(detector.holdMissing(testMoveObject("new/copy2.txt", 10, "a")), chk.IsNil)
+	c.Assert(detector.holdMissing(testMoveObject("new/b.txt", 20, "b")), chk.IsNil)
+	c.Assert(copied.record, chk.HasLen, 0)
+
+	c.Assert(detector.matchExtra(testMoveObject("old/a.txt", 10, "a")), chk.IsNil)
+	c.Assert(detector.matchExtra(testMoveObject("old/c.txt", 30, "c")), chk.IsNil)
+	c.Assert(detector.releaseMissing(), chk.IsNil)
+
+	c.Assert(mover.moves, chk.HasLen, 1)
+	c.Assert(mover.moves["old/a.txt"] == "new/copy1.txt" || mover.moves["old/a.txt"] == "new/copy2.txt", chk.Equals, true)
+	c.Assert(copied.record, chk.HasLen, 2)
+	c.Assert(deleted.record, chk.HasLen, 1)
+	c.Assert(deleted.record[0].relativePath, chk.Equals, "old/c.txt")
+}
+
+func (s *syncMovesSuite) TestLocalFileMover(c *chk.C) {
+	root := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(root, "old"), os.ModePerm), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "old", "file.txt"), []byte("moved"), 0644), chk.IsNil)
+
+	fpo := common.EFolderPropertiesOption.NoFolders()
+	deleter := &localFileDeleter{rootPath: root, fpo: fpo, folderManager: common.NewFolderDeletionManager(context.Background(), fpo, nil)}
+	c.Assert(deleter.moveFile(testMoveObject("old/file.txt", 5, "m"), "new/dir/file.txt"), chk.IsNil)
+
+	_, err := os.Stat(filepath.Join(root, "old", "file.txt"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	content, err := os.ReadFile(filepath.Join(root, "new", "dir", "file.txt"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, "moved")
+}
@@ -0,0 +1,131 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncReportSuite struct{}
+
+var _ = chk.Suite(&syncReportSuite{})
+
+// newTestSyncReport makes a report in a temporary folder, which reports the deletions as deletion-destination would be true
+func (s *syncReportSuite) newTestSyncReport(c *chk.C, format common.SyncReportFormat) *syncReport {
+	cca := &cookedSyncCmdArgs{
+		jobID:              common.NewJobID(),
+		source:             common.ResourceString{Value: "/source"},
+		destination:        common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+		deleteDestination:  common.EDeleteDestination.True(),
+		dryrunReport:       filepath.Join(c.MkDir(), "report"),
+		dryrunReportFormat: format,
+	}
+	report, err := newSyncReport(cca, common.EFolderPropertiesOption.NoFolders())
+	c.Assert(err, chk.IsNil)
+	return report
+}
+
+func (s *syncReportSuite) TestSyncReportJson(c *chk.C) {
+	report := s.newTestSyncReport(c, common.ESyncReportFormat.Json())
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	// the source comparator decides on the source objects
+	indexer := newObjectIndexer()
+	c.Assert(indexer.store(StoredObject{name: "changed.txt", relativePath: "changed.txt", lastModifiedTime: lmt.Add(-time.Hour)}), chk.IsNil)
+	c.Assert(indexer.store(StoredObject{name: "same.txt", relativePath: "same.txt", lastModifiedTime: lmt.Add(time.Hour)}), chk.IsNil)
+	dummyCopyScheduler := dummyProcessor{}
+	comparator := newSyncSourceComparator(indexer, dummyCopyScheduler.process, common.ESyncHashType.None(), common.ESyncCompareMode.Default(), "", false, false, false)
+	comparator.report = report
+	for _, name := range []string{"changed.txt", "same.txt", "new.txt"} {
+		c.Assert(comparator.processIfNecessary(StoredObject{name: name, relativePath: name, lastModifiedTime: lmt, size: 1}), chk.IsNil)
+	}
+	c.Assert(len(dummyCopyScheduler.record), chk.Equals, 2)
+
+	// the objects that are left over at the destination are deleted, and the filtered ones are reported once
+	dummyDeleter := dummyProcessor{}
+	c.Assert(report.recordDeletions(dummyDeleter.process)(StoredObject{name: "extra.txt", relativePath: "extra.txt", lastModifiedTime: lmt}), chk.IsNil)
+	filters := report.recordFiltered(buildExcludeFilters([]string{"*.log"}, false))
+	c.Assert(passedFilters(filters, StoredObject{name: "a.log", relativePath: "dir/a.log"}), chk.Equals, false)
+	c.Assert(passedFilters(filters, StoredObject{name: "a.log", relativePath: "dir/a.log"}), chk.Equals, false)
+	c.Assert(passedFilters(filters, StoredObject{name: "a.txt", relativePath: "dir/a.txt"}), chk.Equals, true)
+
+	// folders aren't reported when sync doesn't transfer them
+	report.record(syncReportActionCopy, StoredObject{name: "dir", relativePath: "dir", entityType: common.EEntityType.Folder()}, syncReportReasonMissingAtDestination)
+
+	c.Assert(report.close(), chk.IsNil)
+	_, err := os.Stat(report.tempPath)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+
+	content, err := os.ReadFile(report.path)
+	c.Assert(err, chk.IsNil)
+	var parsed struct {
+		syncReportHeader
+		Entries []syncReportEntry
+		Summary map[string]int
+	}
+	c.Assert(json.Unmarshal(content, &parsed), chk.IsNil)
+
+	c.Assert(parsed.Source, chk.Equals, "/source")
+	c.Assert(parsed.Destination, chk.Equals, "https://account.blob.core.windows.net/container")
+	c.Assert(parsed.Entries, chk.DeepEquals, []syncReportEntry{
+		{Action: syncReportActionCopy, Path: "changed.txt", EntityType: "File", Size: 1, LastModifiedTime: lmt, Reason: syncOverwriteResaonNewerLMT},
+		{Action: syncReportActionSkip, Path: "same.txt", EntityType: "File", Size: 1, LastModifiedTime: lmt, Reason: syncSkipReasonTime},
+		{Action: syncReportActionCopy, Path: "new.txt", EntityType: "File", Size: 1, LastModifiedTime: lmt, Reason: syncReportReasonMissingAtDestination},
+		{Action: syncReportActionDelete, Path: "extra.txt", EntityType: "File", LastModifiedTime: lmt, Reason: syncReportReasonMissingAtSource},
+		{Action: syncReportActionSkip, Path: "dir/a.log", EntityType: "File", LastModifiedTime: time.Time{}, Reason: syncReportReasonFiltered},
+	})
+	c.Assert(parsed.Summary, chk.DeepEquals, map[string]int{syncReportActionCopy: 2, syncReportActionSkip: 2, syncReportActionDelete: 1})
+}
+
+func (s *syncReportSuite) TestSyncReportCsv(c *chk.C) {
+	report := s.newTestSyncReport(c, common.ESyncReportFormat.Csv())
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	// the destination comparator decides on the source objects as it sees the destination objects
+	indexer := newObjectIndexer()
+	c.Assert(indexer.store(StoredObject{name: "a.txt", relativePath: "a.txt", lastModifiedTime: lmt, size: 2}), chk.IsNil)
+	dummyCopyScheduler := dummyProcessor{}
+	dummyCleaner := dummyProcessor{}
+	comparator := newSyncDestinationComparator(indexer, dummyCopyScheduler.process, report.recordDeletions(dummyCleaner.process), common.ESyncHashType.None(), common.ESyncCompareMode.SizeOnly(), "", false, false)
+	comparator.report = report
+	c.Assert(comparator.processIfNecessary(StoredObject{name: "a.txt", relativePath: "a.txt", lastModifiedTime: lmt, size: 3}), chk.IsNil)
+	c.Assert(comparator.processIfNecessary(StoredObject{name: "b, c.txt", relativePath: "b, c.txt", lastModifiedTime: lmt}), chk.IsNil)
+	c.Assert(report.close(), chk.IsNil)
+
+	file, err := os.Open(report.path)
+	c.Assert(err, chk.IsNil)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	c.Assert(err, chk.IsNil)
+	c.Assert(records, chk.DeepEquals, [][]string{
+		{"Action", "Path", "EntityType", "Size", "LastModifiedTime", "Reason"},
+		{"Copy", "a.txt", "File", "2", "2023-01-02T15:04:05Z", syncOverwriteReasonDifferentSize},
+		{"Delete", "b, c.txt", "File", "0", "2023-01-02T15:04:05Z", syncReportReasonMissingAtSource},
+	})
+}
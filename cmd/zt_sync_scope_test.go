@@ -0,0 +1,136 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncScopeSuite struct{}
+
+var _ = chk.Suite(&syncScopeSuite{})
+
+func (s *syncScopeSuite) TestReadSyncListOfFiles(c *chk.C) {
+	listPath := filepath.Join(c.MkDir(), "list")
+	c.Assert(os.WriteFile(listPath, []byte("\xEF\xBB\xBFdir/\n\n/top.txt\ndir/sub/file.txt\n"), 0644), chk.IsNil)
+
+	listed, err := readSyncListOfFiles(listPath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(listed, chk.DeepEquals, []string{"dir", "top.txt", "dir/sub/file.txt"})
+
+	c.Assert(os.WriteFile(listPath, []byte("\n/\n"), 0644), chk.IsNil)
+	_, err = readSyncListOfFiles(listPath)
+	c.Assert(err, chk.NotNil)
+}
+
+// traverseSyncScope lists the relative paths that the traverser finds, in order
+func (s *syncScopeSuite) traverseSyncScope(c *chk.C, traverser ResourceTraverser) []string {
+	var found []string
+	err := traverser.Traverse(noPreProccessor, func(object StoredObject) error {
+		found = append(found, object.relativePath)
+		return nil
+	}, nil)
+	c.Assert(err, chk.IsNil)
+	sort.Strings(found)
+	return found
+}
+
+func (s *syncScopeSuite) newLocalTraverser(c *chk.C, root string, listChan chan string) ResourceTraverser {
+	ctx := context.Background()
+	traverser, err := InitResourceTraverser(common.ResourceString{Value: root}, common.ELocation.Local(), &ctx, nil, common.ESymlinkHandlingType.Skip(), listChan, true, false, false, common.EPermanentDeleteOption.None(), nil, nil, false, common.ESyncHashType.None(), common.EPreservePermissionsOption.None(), pipeline.LogInfo, common.CpkOptions{}, nil, false, common.ETrailingDotOption.Enable(), nil)
+	c.Assert(err, chk.IsNil)
+	return traverser
+}
+
+func (s *syncScopeSuite) TestSyncScopeListOfFiles(c *chk.C) {
+	source, destination := c.MkDir(), c.MkDir()
+	for _, file := range []string{"a/1.txt", "b/2.txt", "top.txt"} {
+		c.Assert(os.MkdirAll(filepath.Dir(filepath.Join(source, file)), os.ModePerm), chk.IsNil)
+		c.Assert(os.WriteFile(filepath.Join(source, file), []byte(file), 0644), chk.IsNil)
+	}
+	for _, file := range []string{"a/extra.txt", "b/2.txt", "missing/extra.txt", "ab.txt"} {
+		c.Assert(os.MkdirAll(filepath.Dir(filepath.Join(destination, file)), os.ModePerm), chk.IsNil)
+		c.Assert(os.WriteFile(filepath.Join(destination, file), []byte(file), 0644), chk.IsNil)
+	}
+
+	// the local traverser logs the path that it can't scan
+	oldScanningLogger := azcopyScanningLogger
+	azcopyScanningLogger = common.NewJobLogger(common.NewJobID(), common.ELogLevel.Info(), c.MkDir(), "-scanning")
+	azcopyScanningLogger.OpenLog()
+	defer func() {
+		azcopyScanningLogger.CloseLog()
+		azcopyScanningLogger = oldScanningLogger
+	}()
+
+	scope := newSyncScope(&cookedSyncCmdArgs{listOfFiles: []string{"a", "top.txt", "missing"}})
+	c.Assert(scope.isNarrowed(), chk.Equals, true)
+
+	// only the listed paths are traversed, and the source is a directory even though it's traversed as a list
+	sourceTraverser := scope.sourceTraverser(s.newLocalTraverser(c, source, scope.listChannel()))
+	isDir, err := sourceTraverser.IsDirectory(true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(isDir, chk.Equals, true)
+	c.Assert(s.traverseSyncScope(c, sourceTraverser), chk.DeepEquals, []string{"a", "a/1.txt", "top.txt"})
+	c.Assert(scope.notScanned, chk.DeepEquals, []string{"missing"})
+
+	destinationTraverser := scope.destinationTraverser(s.newLocalTraverser(c, destination, nil))
+	c.Assert(s.traverseSyncScope(c, destinationTraverser), chk.DeepEquals, []string{"a", "a/extra.txt", "missing", "missing/extra.txt"})
+
+	// nothing is deleted under a listed path that couldn't be scanned at the source
+	dummyDeleter := dummyProcessor{}
+	deleter := scope.protectDeletions(dummyDeleter.process)
+	for _, path := range []string{"a/extra.txt", "missing/extra.txt"} {
+		c.Assert(deleter(StoredObject{relativePath: path}), chk.IsNil)
+	}
+	c.Assert(len(dummyDeleter.record), chk.Equals, 1)
+	c.Assert(dummyDeleter.record[0].relativePath, chk.Equals, "a/extra.txt")
+}
+
+func (s *syncScopeSuite) TestSyncScopeDates(c *chk.C) {
+	source := c.MkDir()
+	threshold := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for file, lmt := range map[string]time.Time{"old.txt": threshold.Add(-time.Hour), "new.txt": threshold.Add(time.Hour)} {
+		c.Assert(os.WriteFile(filepath.Join(source, file), []byte(file), 0644), chk.IsNil)
+		c.Assert(os.Chtimes(filepath.Join(source, file), lmt, lmt), chk.IsNil)
+	}
+
+	scope := newSyncScope(&cookedSyncCmdArgs{includeAfter: &threshold})
+	c.Assert(scope.isNarrowed(), chk.Equals, true)
+	c.Assert(s.traverseSyncScope(c, scope.sourceTraverser(s.newLocalTraverser(c, source, nil))), chk.DeepEquals, []string{"", "new.txt"})
+
+	// the file that's excluded by date still exists at the source, so it's not deleted at the destination
+	dummyDeleter := dummyProcessor{}
+	deleter := scope.protectDeletions(dummyDeleter.process)
+	for _, path := range []string{"old.txt", "gone.txt"} {
+		c.Assert(deleter(StoredObject{relativePath: path}), chk.IsNil)
+	}
+	c.Assert(len(dummyDeleter.record), chk.Equals, 1)
+	c.Assert(dummyDeleter.record[0].relativePath, chk.Equals, "gone.txt")
+}
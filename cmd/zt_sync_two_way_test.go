@@ -0,0 +1,372 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncTwoWaySuite struct{}
+
+var _ = chk.Suite(&syncTwoWaySuite{})
+
+func (s *syncTwoWaySuite) TestDecideTwoWaySync(c *chk.C) {
+	then := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	later := then.Add(time.Hour)
+
+	file := func(lmt time.Time, size int64) *StoredObject {
+		return &StoredObject{relativePath: "a.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: size}
+	}
+	synced, modified := *file(then, 10), *file(later, 12)
+	comparison := twoWaySyncComparison{}
+	entry := &twoWaySyncStateEntry{Source: comparison.side(synced), Destination: comparison.side(synced)}
+
+	testCases := []struct {
+		name        string
+		source      *StoredObject
+		destination *StoredObject
+		entry       *twoWaySyncStateEntry
+		expected    twoWaySyncAction
+	}{
+		{"unchanged", &synced, &synced, entry, twoWaySyncNothing},
+		{"modified at source", &modified, &synced, entry, twoWaySyncCopyToDestination},
+		{"modified at destination", &synced, &modified, entry, twoWaySyncCopyToSource},
+		{"modified at both", &modified, file(later.Add(time.Minute), 13), entry, twoWaySyncConflict},
+		{"modified at both, the same way", &modified, &modified, entry, twoWaySyncInSync},
+		{"restored to an older version at source", file(then.Add(-time.Hour), 8), &synced, entry, twoWaySyncNothing}, // like one-way sync, which only copies newer files
+		{"created at source", &synced, nil, nil, twoWaySyncCopyToDestination},
+		{"created at destination", nil, &synced, nil, twoWaySyncCopyToSource},
+		{"deleted at destination", &synced, nil, entry, twoWaySyncDeleteAtSource},
+		{"deleted at source", nil, &synced, entry, twoWaySyncDeleteAtDestination},
+		{"deleted at destination, modified at source", &modified, nil, entry, twoWaySyncCopyToDestination},
+		{"deleted at source, modified at destination", nil, &modified, entry, twoWaySyncCopyToSource},
+		{"deleted at both", nil, nil, entry, twoWaySyncNothing},
+		{"first sync, different files", &synced, &modified, nil, twoWaySyncConflict},
+		{"first sync, same file", &synced, file(later, 10), nil, twoWaySyncInSync},
+	}
+
+	for _, t := range testCases {
+		c.Check(decideTwoWaySync(t.source, t.destination, t.entry, comparison), chk.Equals, t.expected, chk.Commentf(t.name))
+	}
+}
+
+func (s *syncTwoWaySuite) TestDecideTwoWaySyncWithHashes(c *chk.C) {
+	then := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	synced := StoredObject{relativePath: "a.txt", entityType: common.EEntityType.File(), lastModifiedTime: then, size: 10, md5: []byte{1}}
+	md5, none := twoWaySyncComparison{hashType: common.ESyncHashType.MD5()}, twoWaySyncComparison{}
+	entry := &twoWaySyncStateEntry{Source: md5.side(synced), Destination: md5.side(synced)}
+
+	// both sides were touched, but their content is the same
+	source := StoredObject{relativePath: "a.txt", entityType: common.EEntityType.File(), lastModifiedTime: then.Add(time.Hour), size: 10, md5: []byte{2}}
+	destination := StoredObject{relativePath: "a.txt", entityType: common.EEntityType.File(), lastModifiedTime: then.Add(2 * time.Hour), size: 10, md5: []byte{2}}
+	c.Assert(decideTwoWaySync(&source, &destination, entry, md5), chk.Equals, twoWaySyncInSync)
+
+	// without hashes, the same change can't be told apart from a conflict once the pair has been synced
+	c.Assert(decideTwoWaySync(&source, &destination, entry, none), chk.Equals, twoWaySyncConflict)
+
+	// and different hashes are a conflict, even on the first sync
+	destination.md5 = []byte{3}
+	c.Assert(decideTwoWaySync(&source, &destination, nil, md5), chk.Equals, twoWaySyncConflict)
+
+	// as is a file that lacks a hash, which can't be shown to be the same
+	destination.md5 = nil
+	c.Assert(decideTwoWaySync(&source, &destination, nil, md5), chk.Equals, twoWaySyncConflict)
+
+	// touching a file doesn't change its hash
+	touched := synced
+	touched.lastModifiedTime = then.Add(time.Hour)
+	c.Assert(decideTwoWaySync(&touched, &synced, entry, md5), chk.Equals, twoWaySyncNothing)
+}
+
+// TestDecideTwoWaySyncFollowsTheCompareMode checks that a file counts as changed when one-way sync would copy it
+// over the version that was last in sync, in each compare mode
+func (s *syncTwoWaySuite) TestDecideTwoWaySyncFollowsTheCompareMode(c *chk.C) {
+	then := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	file := func(lmt time.Time, size int64, md5 byte) StoredObject {
+		return StoredObject{relativePath: "a.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: size, md5: []byte{md5}}
+	}
+	synced := file(then, 10, 1)
+	touched := file(then.Add(time.Hour), 10, 1)
+	rewritten := file(then.Add(time.Hour), 10, 2)
+	resized := file(then.Add(-time.Hour), 12, 3)
+
+	testCases := []struct {
+		mode     common.SyncCompareMode
+		hashType common.SyncHashType
+		source   StoredObject
+		expected twoWaySyncAction
+	}{
+		{common.ESyncCompareMode.Default(), common.ESyncHashType.None(), touched, twoWaySyncCopyToDestination},
+		{common.ESyncCompareMode.Default(), common.ESyncHashType.None(), resized, twoWaySyncNothing},
+		{common.ESyncCompareMode.SizeOnly(), common.ESyncHashType.None(), touched, twoWaySyncNothing},
+		{common.ESyncCompareMode.SizeOnly(), common.ESyncHashType.None(), resized, twoWaySyncCopyToDestination},
+		{common.ESyncCompareMode.LMTAndSize(), common.ESyncHashType.None(), touched, twoWaySyncCopyToDestination},
+		{common.ESyncCompareMode.LMTAndSize(), common.ESyncHashType.None(), resized, twoWaySyncCopyToDestination},
+		{common.ESyncCompareMode.HashOnly(), common.ESyncHashType.MD5(), touched, twoWaySyncNothing},
+		{common.ESyncCompareMode.HashOnly(), common.ESyncHashType.MD5(), rewritten, twoWaySyncCopyToDestination},
+	}
+	for _, t := range testCases {
+		comparison := twoWaySyncComparison{mode: t.mode, hashType: t.hashType}
+		entry := &twoWaySyncStateEntry{Source: comparison.side(synced), Destination: comparison.side(synced)}
+		c.Check(decideTwoWaySync(&t.source, &synced, entry, comparison), chk.Equals, t.expected,
+			chk.Commentf("%s, changed to %d bytes at %s", t.mode, t.source.size, t.source.lastModifiedTime))
+	}
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncConflictPath(c *chk.C) {
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.FixedZone("", 3600))
+	c.Assert(twoWaySyncConflictPath("dir/report.docx", lmt), chk.Equals, "dir/report.conflict-20230102T140405Z.docx")
+	c.Assert(twoWaySyncConflictPath("README", lmt), chk.Equals, "README.conflict-20230102T140405Z")
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncStateRoundTrip(c *chk.C) {
+	source := common.ResourceString{Value: "/data/photos"}
+	destination := common.ResourceString{Value: "https://account.blob.core.windows.net/photos", SAS: "sig=a"}
+	statePath := filepath.Join(c.MkDir(), "pair.state")
+
+	// a pair that was never synced has an empty state
+	state, err := loadTwoWaySyncState(statePath, source, destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(state.Entries, chk.HasLen, 0)
+
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	state.Entries["a/b.txt"] = twoWaySyncStateEntry{
+		Source:      twoWaySyncSide{LastModifiedTime: lmt, Size: 1, Hash: []byte{1, 2}},
+		Destination: twoWaySyncSide{LastModifiedTime: lmt.Add(time.Second), Size: 1},
+	}
+	c.Assert(state.save(statePath), chk.IsNil)
+
+	loaded, err := loadTwoWaySyncState(statePath, source, destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(loaded.Entries, chk.HasLen, 1)
+	c.Assert(loaded.Entries["a/b.txt"].Source.LastModifiedTime.Equal(lmt), chk.Equals, true)
+	c.Assert(loaded.Entries["a/b.txt"].Source.Hash, chk.DeepEquals, []byte{1, 2})
+	c.Assert(loaded.Entries["a/b.txt"].Destination.LastModifiedTime.Equal(lmt.Add(time.Second)), chk.Equals, true)
+	c.Assert(loaded.Entries["a/b.txt"].Destination.Size, chk.Equals, int64(1))
+
+	// the state of a pair doesn't depend on its SAS
+	renewed := destination
+	renewed.SAS = "sig=b"
+	c.Assert(twoWaySyncStatePath(source, renewed), chk.Equals, twoWaySyncStatePath(source, destination))
+	c.Assert(twoWaySyncStatePath(destination, source), chk.Not(chk.Equals), twoWaySyncStatePath(source, destination))
+}
+
+// newTestTwoWaySync returns a two-way sync of a local folder and a container, which dispatches a part every two transfers
+func (s *syncTwoWaySuite) newTestTwoWaySync(caseInsensitive bool) *twoWaySync {
+	cca := &cookedSyncCmdArgs{
+		source:      common.ResourceString{Value: "/data/photos"},
+		destination: common.ResourceString{Value: "https://account.blob.core.windows.net/photos"},
+		fromTo:      common.EFromTo.LocalBlob(),
+		recursive:   true,
+		jobID:       common.NewJobID(),
+	}
+	indexer := newObjectIndexer()
+	indexer.isDestinationCaseInsensitive = caseInsensitive
+
+	toSource := newSyncTransferProcessor(cca, 2, common.EFolderPropertiesOption.NoFolders())
+	toSource.copyJobTemplate.FromTo = twoWaySyncReverseFromTo[cca.fromTo]
+	toSource.copyJobTemplate.SourceRoot, toSource.copyJobTemplate.DestinationRoot = toSource.copyJobTemplate.DestinationRoot, toSource.copyJobTemplate.SourceRoot
+	toSource.source, toSource.destination = cca.destination, cca.source
+
+	return &twoWaySync{
+		cca:           cca,
+		state:         &twoWaySyncState{Entries: make(map[string]twoWaySyncStateEntry)},
+		scope:         newSyncScope(cca),
+		sourceIndex:   indexer,
+		toDestination: newSyncTransferProcessor(cca, 2, common.EFolderPropertiesOption.NoFolders()),
+		toSource:      toSource,
+		outcomes:      make(map[string]twoWaySyncOutcome),
+		visited:       make(map[string]bool),
+		scheduled:     make(map[string]string),
+	}
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncFinalPartHasTransfers(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	var parts []common.CopyJobPartOrderRequest
+	Rpc = func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		parts = append(parts, *request.(*common.CopyJobPartOrderRequest))
+		*(response.(*common.CopyJobPartOrderResponse)) = common.CopyJobPartOrderResponse{JobStarted: true}
+	}
+
+	t := s.newTestTwoWaySync(false)
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	file := func(name string) StoredObject {
+		return StoredObject{name: name, relativePath: name, entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1}
+	}
+	// both directions fill up a part, and the final part is left with the last transfer of one direction
+	for _, name := range []string{"a", "b", "c"} {
+		c.Assert(t.schedule(t.toDestination, file(name), name), chk.IsNil)
+	}
+	for _, name := range []string{"d", "e"} {
+		c.Assert(t.schedule(t.toSource, file(name), name), chk.IsNil)
+	}
+	jobInitiated, err := t.dispatchFinalPart()
+	c.Assert(err, chk.IsNil)
+	c.Assert(jobInitiated, chk.Equals, true)
+
+	transfers := 0
+	for i, part := range parts {
+		c.Assert(part.PartNum, chk.Equals, common.PartNumber(i))
+		c.Assert(part.IsFinalPart, chk.Equals, i == len(parts)-1)
+		c.Assert(part.Transfers.List, chk.Not(chk.HasLen), 0)
+		transfers += len(part.Transfers.List)
+	}
+	c.Assert(transfers, chk.Equals, 5)
+
+	// the final part is only empty when it's the only one
+	parts = nil
+	t = s.newTestTwoWaySync(false)
+	_, err = t.dispatchFinalPart()
+	c.Assert(err, chk.IsNil)
+	c.Assert(parts, chk.HasLen, 1)
+	c.Assert(parts[0].PartNum, chk.Equals, common.PartNumber(0))
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncMatchesCaseInsensitiveDestination(c *chk.C) {
+	t := s.newTestTwoWaySync(true)
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	source := StoredObject{name: "Report.txt", relativePath: "Dir/Report.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1}
+	destination := StoredObject{name: "REPORT.txt", relativePath: "DIR/REPORT.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1}
+	t.state.Entries["dir/report.txt"] = twoWaySyncStateEntry{Source: t.comparison.side(source), Destination: t.comparison.side(destination)}
+	c.Assert(t.sourceIndex.store(source), chk.IsNil)
+
+	// the destination is matched with the source, rather than taken for a file that's only at the destination
+	c.Assert(t.processIfNecessary(destination), chk.IsNil)
+	c.Assert(t.sourceIndex.indexMap, chk.HasLen, 0)
+	c.Assert(t.visited["dir/report.txt"], chk.Equals, true)
+	c.Assert(t.outcomes, chk.HasLen, 0)
+	c.Assert(t.toSource.copyJobTemplate.Transfers.List, chk.HasLen, 0)
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncForgetsPathsDeletedOnBothSides(c *chk.C) {
+	t := s.newTestTwoWaySync(false)
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	synced := twoWaySyncStateEntry{Source: twoWaySyncSide{LastModifiedTime: lmt, Size: 1}, Destination: twoWaySyncSide{LastModifiedTime: lmt, Size: 1}}
+	for _, key := range []string{"gone.txt", "dir/gone.txt", "kept.txt", "excluded.log"} {
+		t.state.Entries[key] = synced
+	}
+	kept := StoredObject{name: "kept.txt", relativePath: "kept.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1}
+	c.Assert(t.process("kept.txt", &kept, &kept), chk.IsNil)
+
+	// the paths that the filters leave out weren't listed, but may still be on both sides
+	t.forgetDeletedOnBothSides([]ObjectFilter{&excludeFilter{pattern: "*.log"}})
+	c.Assert(t.outcomes, chk.HasLen, 2)
+	c.Assert(t.outcomes["gone.txt"].entry, chk.IsNil)
+	c.Assert(t.outcomes["dir/gone.txt"].entry, chk.IsNil)
+
+	// without recursion, the paths in folders weren't listed either
+	t = s.newTestTwoWaySync(false)
+	t.cca.recursive = false
+	t.state.Entries["gone.txt"], t.state.Entries["dir/gone.txt"] = synced, synced
+	t.forgetDeletedOnBothSides(nil)
+	c.Assert(t.outcomes, chk.HasLen, 1)
+	_, ok := t.outcomes["gone.txt"]
+	c.Assert(ok, chk.Equals, true)
+}
+
+// TestTwoWaySyncSavesTheStateOfItsTransfers checks that the state is updated from the transfers that the sync scheduled,
+// without listing either side again, and that the copies count as unchanged on the next sync
+func (s *syncTwoWaySuite) TestTwoWaySyncSavesTheStateOfItsTransfers(c *chk.C) {
+	t := s.newTestTwoWaySync(false)
+	t.statePath = filepath.Join(c.MkDir(), "pair.state")
+	var deleted []string
+	t.destinationDeleter = func(object StoredObject) error {
+		deleted = append(deleted, object.relativePath)
+		return nil
+	}
+
+	then := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	file := func(name string, lmt time.Time, size int64) StoredObject {
+		return StoredObject{name: name, relativePath: name, entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: size}
+	}
+	up, down, same, gone := file("up.txt", then, 1), file("down.txt", then, 2), file("same.txt", then, 3), file("gone.txt", then, 4)
+	t.state.Entries["gone.txt"] = twoWaySyncStateEntry{Source: t.comparison.side(gone), Destination: t.comparison.side(gone)}
+	c.Assert(t.process("up.txt", &up, nil), chk.IsNil)
+	c.Assert(t.process("down.txt", nil, &down), chk.IsNil)
+	c.Assert(t.process("same.txt", &same, &same), chk.IsNil)
+	c.Assert(t.process("gone.txt", nil, &gone), chk.IsNil)
+	c.Assert(deleted, chk.DeepEquals, []string{"gone.txt"})
+
+	before := time.Now()
+	c.Assert(t.saveState(), chk.IsNil)
+	state, err := loadTwoWaySyncState(t.statePath, t.cca.source, t.cca.destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(state.Entries, chk.HasLen, 3)
+
+	// a copy is recorded as the file that was copied, as of the time the job was done
+	c.Assert(state.Entries["up.txt"].Source.LastModifiedTime.Equal(then), chk.Equals, true)
+	c.Assert(state.Entries["up.txt"].Destination.Size, chk.Equals, int64(1))
+	c.Assert(state.Entries["up.txt"].Destination.LastModifiedTime.Before(before), chk.Equals, false)
+	c.Assert(state.Entries["down.txt"].Destination.LastModifiedTime.Equal(then), chk.Equals, true)
+	c.Assert(state.Entries["down.txt"].Source.Size, chk.Equals, int64(2))
+	c.Assert(state.Entries["down.txt"].Source.LastModifiedTime.Before(before), chk.Equals, false)
+	c.Assert(state.Entries["same.txt"].Source.Size, chk.Equals, int64(3))
+
+	// so on the next sync, the copies that the job made are in sync with the files they were copied from
+	uploaded := file("up.txt", before, 1)
+	downloaded := file("down.txt", before, 2)
+	for _, pair := range [][2]StoredObject{{up, uploaded}, {downloaded, down}, {same, same}} {
+		entry := state.Entries[pair[0].relativePath]
+		c.Check(decideTwoWaySync(&pair[0], &pair[1], &entry, t.comparison), chk.Equals, twoWaySyncNothing, chk.Commentf(pair[0].relativePath))
+	}
+
+	// while a copy that's changed after the sync is copied back
+	edited := file("up.txt", time.Now().Add(time.Minute), 5)
+	entry := state.Entries["up.txt"]
+	c.Assert(decideTwoWaySync(&up, &edited, &entry, t.comparison), chk.Equals, twoWaySyncCopyToSource)
+}
+
+func (s *syncTwoWaySuite) TestTwoWaySyncLeavesPathsOutOfScopeAlone(c *chk.C) {
+	t := s.newTestTwoWaySync(false)
+	t.scope.listed = []string{"dir"}
+	t.scope.outOfScope["dir/old.txt"] = struct{}{} // excluded by date at the source
+	t.destinationDeleter = func(object StoredObject) error {
+		c.Errorf("%s was deleted", object.relativePath)
+		return nil
+	}
+
+	lmt := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	old := StoredObject{name: "old.txt", relativePath: "dir/old.txt", entityType: common.EEntityType.File(), lastModifiedTime: lmt, size: 1}
+	synced := twoWaySyncStateEntry{Source: t.comparison.side(old), Destination: t.comparison.side(old)}
+	for _, key := range []string{"dir/old.txt", "dir/gone.txt", "other/gone.txt"} {
+		t.state.Entries[key] = synced
+	}
+
+	// the source isn't in scope for the file, so it isn't taken to be deleted there, nor created at the destination
+	c.Assert(t.process("dir/old.txt", nil, &old), chk.IsNil)
+	edited := old
+	edited.lastModifiedTime = lmt.Add(time.Hour)
+	c.Assert(t.process("dir/old.txt", nil, &edited), chk.IsNil)
+	c.Assert(t.outcomes, chk.HasLen, 0)
+	c.Assert(t.toSource.copyJobTemplate.Transfers.List, chk.HasLen, 0)
+
+	// and only the paths that are in scope are forgotten when they're gone from both sides
+	t.forgetDeletedOnBothSides(nil)
+	c.Assert(t.outcomes, chk.HasLen, 1)
+	_, ok := t.outcomes["dir/gone.txt"]
+	c.Assert(ok, chk.Equals, true)
+}
@@ -0,0 +1,273 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type syncWatchSuite struct{}
+
+var _ = chk.Suite(&syncWatchSuite{})
+
+func (s *syncWatchSuite) createTree(c *chk.C) string {
+	root := c.MkDir()
+	c.Assert(os.MkdirAll(filepath.Join(root, "sub", "nested"), os.ModePerm), chk.IsNil)
+	for _, file := range []string{"top.txt", "sub/middle.txt", "sub/nested/bottom.txt"} {
+		c.Assert(os.WriteFile(filepath.Join(root, file), []byte(file), 0644), chk.IsNil)
+	}
+	return root
+}
+
+func (s *syncWatchSuite) TestWatchTree(c *chk.C) {
+	root := s.createTree(c)
+
+	for _, recursive := range []bool{true, false} {
+		watcher, err := fsnotify.NewWatcher()
+		c.Assert(err, chk.IsNil)
+		w := &syncWatcher{
+			cca:            &cookedSyncCmdArgs{recursive: recursive},
+			root:           root,
+			watcher:        watcher,
+			watchedFolders: map[string]struct{}{},
+		}
+
+		found, err := w.watchTree("")
+		c.Assert(err, chk.IsNil)
+		_ = watcher.Close()
+		sort.Strings(found)
+
+		if recursive {
+			c.Assert(found, chk.DeepEquals, []string{"", "sub", "sub/middle.txt", "sub/nested", "sub/nested/bottom.txt", "top.txt"})
+			c.Assert(w.watchedFolders, chk.DeepEquals, map[string]struct{}{"": {}, "sub": {}, "sub/nested": {}})
+		} else {
+			c.Assert(found, chk.DeepEquals, []string{"", "top.txt"})
+			c.Assert(w.watchedFolders, chk.DeepEquals, map[string]struct{}{"": {}})
+		}
+	}
+}
+
+func (s *syncWatchSuite) TestLocalPathsTraverser(c *chk.C) {
+	root := s.createTree(c)
+
+	traverser, err := newLocalTraverser(context.Background(), root, true, false, common.ESymlinkHandlingType.Skip(), common.ESyncHashType.None(), nil, nil)
+	c.Assert(err, chk.IsNil)
+	paths := &localPathsTraverser{
+		localTraverser: traverser,
+		relativePaths:  []string{"", "sub", "sub/nested/bottom.txt", "gone.txt"},
+	}
+
+	found := map[string]common.EntityType{}
+	err = paths.Traverse(noPreProccessor, func(object StoredObject) error {
+		found[object.relativePath] = object.entityType
+		return nil
+	}, nil)
+	c.Assert(err, chk.IsNil)
+
+	// the root and the paths that are gone again aren't enumerated
+	c.Assert(found, chk.DeepEquals, map[string]common.EntityType{
+		"sub":                   common.EEntityType.Folder(),
+		"sub/nested/bottom.txt": common.EEntityType.File(),
+	})
+}
+
+// watchedSync is a sync with --watch from a local folder to another, whose events are sent by the test instead of the file system,
+// and whose job parts are recorded instead of being run
+type watchedSync struct {
+	src, dst string
+	w        *syncWatcher
+	events   chan fsnotify.Event
+
+	mu sync.Mutex
+	// the sources of the transfers of each part, and whether the last one was final
+	parts [][]string
+	final bool
+}
+
+func (s *syncWatchSuite) startWatchedSync(c *chk.C, debounce, reconcileInterval time.Duration) *watchedSync {
+	ws := &watchedSync{src: c.MkDir(), dst: c.MkDir(), events: make(chan fsnotify.Event)}
+	c.Assert(os.WriteFile(filepath.Join(ws.src, "first.txt"), []byte("first"), 0644), chk.IsNil)
+
+	mockedRPC := interceptor{}
+	mockedRPC.init()
+	Rpc = func(cmd common.RpcCmd, request interface{}, response interface{}) {
+		if cmd != common.ERpcCmd.CopyJobPartOrder() {
+			panic("RPC mock not implemented")
+		}
+		order := request.(*common.CopyJobPartOrderRequest)
+		sources := []string{}
+		for _, transfer := range order.Transfers.List {
+			sources = append(sources, strings.TrimPrefix(transfer.Source, "/"))
+		}
+		sort.Strings(sources)
+
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		ws.parts = append(ws.parts, sources)
+		ws.final = order.IsFinalPart
+		*(response.(*common.CopyJobPartOrderResponse)) = common.CopyJobPartOrderResponse{JobStarted: true}
+	}
+
+	raw := getDefaultSyncRawInput(ws.src, ws.dst)
+	raw.fromTo = common.EFromTo.LocalLocal().String()
+	raw.watch = true
+	raw.watchDebounce = debounce
+	raw.watchReconcileInterval = reconcileInterval
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.process(), chk.IsNil)
+
+	// the events of the file system are replaced by those of the test
+	ws.w = cooked.watcher
+	c.Assert(ws.w.watcher.Close(), chk.IsNil)
+	go ws.w.collectEvents(ws.events, nil)
+	return ws
+}
+
+// change writes or removes the file, and sends the event of the change
+func (ws *watchedSync) change(c *chk.C, relativePath string, op fsnotify.Op) {
+	fullPath := filepath.Join(ws.src, relativePath)
+	if op == fsnotify.Remove {
+		c.Assert(os.Remove(fullPath), chk.IsNil)
+	} else {
+		c.Assert(os.WriteFile(fullPath, []byte(relativePath), 0644), chk.IsNil)
+	}
+	ws.events <- fsnotify.Event{Name: fullPath, Op: op}
+}
+
+// waitForPending waits until the change of the path is recorded, to be synced with the next batch
+func (ws *watchedSync) waitForPending(c *chk.C, relativePath string) {
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(time.Millisecond) {
+		ws.w.mu.Lock()
+		_, pending := ws.w.pending[relativePath]
+		ws.w.mu.Unlock()
+		if pending {
+			return
+		}
+		if time.Now().After(deadline) {
+			c.Fatalf("the change of %s isn't recorded", relativePath)
+		}
+	}
+}
+
+func (ws *watchedSync) partCount() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return len(ws.parts)
+}
+
+// waitForPart waits until the job has the given number of parts, and returns the last one
+func (ws *watchedSync) waitForPart(c *chk.C, count int) []string {
+	for deadline := time.Now().Add(10 * time.Second); ws.partCount() < count; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			c.Fatalf("the job has %d parts instead of %d", ws.partCount(), count)
+		}
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.parts[count-1]
+}
+
+func (s *syncWatchSuite) TestWatchBatchesChangesUntilTheSourceIsQuiet(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	debounce := 200 * time.Millisecond
+	ws := s.startWatchedSync(c, debounce, 0)
+	c.Assert(ws.waitForPart(c, 1), chk.DeepEquals, []string{"first.txt"})
+
+	// the changes that come within the debounce period of each other are synced together, once the source is quiet
+	ws.change(c, "a.txt", fsnotify.Create)
+	time.Sleep(debounce / 2)
+	ws.change(c, "b.txt", fsnotify.Create)
+	ws.change(c, "a.txt", fsnotify.Write)
+	time.Sleep(debounce / 2)
+	c.Assert(ws.partCount(), chk.Equals, 1)
+	c.Assert(ws.waitForPart(c, 2), chk.DeepEquals, []string{"a.txt", "b.txt"})
+
+	// a source that keeps changing is still synced, once the batch is as old as syncWatchMaxBatchDebounces debounce periods
+	for start := time.Now(); time.Since(start) < 2*syncWatchMaxBatchDebounces*debounce && ws.partCount() < 3; time.Sleep(debounce / 4) {
+		ws.change(c, "busy.txt", fsnotify.Write)
+	}
+	c.Assert(ws.waitForPart(c, 3), chk.DeepEquals, []string{"busy.txt"})
+
+	// the changes that are left when the watch is stopped go into the final part
+	ws.change(c, "last.txt", fsnotify.Create)
+	ws.waitForPending(c, "last.txt")
+	c.Assert(ws.w.stopWatching(), chk.Equals, true)
+	c.Assert(ws.waitForPart(c, 5), chk.DeepEquals, []string{})
+	// with the last change of the busy file, if it came after the batch before was taken
+	c.Assert(ws.parts[3][len(ws.parts[3])-1], chk.Equals, "last.txt")
+	c.Assert(ws.final, chk.Equals, true)
+}
+
+func (s *syncWatchSuite) TestWatchDeletesWhatIsGoneFromTheSource(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	ws := s.startWatchedSync(c, 50*time.Millisecond, 0)
+	ws.waitForPart(c, 1)
+
+	// the files that were synced before are deleted at the destination once they're gone from the source,
+	// while a file that was created and removed again within a batch was never synced, and the one at its path at the destination is left alone
+	for _, name := range []string{"synced.txt", "temporary.txt"} {
+		c.Assert(os.WriteFile(filepath.Join(ws.dst, name), []byte(name), 0644), chk.IsNil)
+	}
+	c.Assert(os.WriteFile(filepath.Join(ws.src, "synced.txt"), []byte("synced.txt"), 0644), chk.IsNil)
+	ws.change(c, "synced.txt", fsnotify.Remove)
+	ws.change(c, "temporary.txt", fsnotify.Create)
+	ws.change(c, "temporary.txt", fsnotify.Remove)
+	for deadline := time.Now().Add(10 * time.Second); ws.w.cca.getDeletionCount() == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			c.Fatal("nothing was deleted at the destination")
+		}
+	}
+	c.Assert(ws.w.stopWatching(), chk.Equals, true)
+	// nothing was transferred since the first part
+	c.Assert(ws.waitForPart(c, 2), chk.DeepEquals, []string{})
+
+	_, err := os.Stat(filepath.Join(ws.dst, "synced.txt"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	_, err = os.Stat(filepath.Join(ws.dst, "temporary.txt"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(ws.w.cca.getDeletionCount(), chk.Equals, uint32(1))
+}
+
+func (s *syncWatchSuite) TestWatchReconcilesPeriodically(c *chk.C) {
+	defer func(rpc func(common.RpcCmd, interface{}, interface{})) { Rpc = rpc }(Rpc)
+	ws := s.startWatchedSync(c, 50*time.Millisecond, 300*time.Millisecond)
+	ws.waitForPart(c, 1)
+
+	// a change that no event was sent for is found by the full scan. The destination lacks the first file too,
+	// since the parts aren't run.
+	c.Assert(os.WriteFile(filepath.Join(ws.src, "missed.txt"), []byte("missed"), 0644), chk.IsNil)
+	c.Assert(ws.waitForPart(c, 2), chk.DeepEquals, []string{"first.txt", "missed.txt"})
+	c.Assert(ws.final, chk.Equals, false)
+	ws.w.stopWatching()
+	ws.waitForPart(c, 3)
+}
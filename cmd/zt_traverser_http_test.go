@@ -0,0 +1,125 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type httpTraverserSuite struct{}
+
+var _ = chk.Suite(&httpTraverserSuite{})
+
+var httpTraverserTestLMT = time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+// newHTTPTraverserTestServer serves a 10 byte file at every path, optionally refusing HEAD like some servers do
+func newHTTPTraverserTestServer(allowHead bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && !allowHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", httpTraverserTestLMT.Format(http.TimeFormat))
+		if r.Header.Get("Range") == "bytes=0-0" {
+			w.Header().Set("Content-Range", "bytes 0-0/10")
+			w.Header().Set("Content-Length", "1")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("0"))
+			return
+		}
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("0123456789"))
+		}
+	}))
+}
+
+func (s *httpTraverserSuite) traverse(c *chk.C, rawURL string) ([]StoredObject, error) {
+	u, err := url.Parse(rawURL)
+	c.Assert(err, chk.IsNil)
+
+	var p pipeline.Pipeline = createHTTPPipeline(pipeline.LogNone)
+	traverser := newHTTPTraverser(u, p, context.Background(), nil)
+
+	var objects []StoredObject
+	err = traverser.Traverse(noPreProccessor, func(o StoredObject) error {
+		objects = append(objects, o)
+		return nil
+	}, nil)
+	return objects, err
+}
+
+func (s *httpTraverserSuite) TestHttpTraverserSingleFile(c *chk.C) {
+	for _, allowHead := range []bool{true, false} {
+		server := newHTTPTraverserTestServer(allowHead)
+
+		objects, err := s.traverse(c, server.URL+"/downloads/file.txt?token=abc")
+		server.Close()
+
+		c.Assert(err, chk.IsNil)
+		c.Assert(objects, chk.HasLen, 1)
+		c.Assert(objects[0].name, chk.Equals, "file.txt")
+		c.Assert(objects[0].relativePath, chk.Equals, "")
+		c.Assert(objects[0].entityType, chk.Equals, common.EEntityType.File())
+		c.Assert(objects[0].size, chk.Equals, int64(10))
+		c.Assert(objects[0].lastModifiedTime.Equal(httpTraverserTestLMT), chk.Equals, true)
+		c.Assert(objects[0].contentType, chk.Equals, "text/plain")
+	}
+}
+
+func (s *httpTraverserSuite) TestHttpTraverserRejectsDirectory(c *chk.C) {
+	server := newHTTPTraverserTestServer(true)
+	defer server.Close()
+
+	_, err := s.traverse(c, server.URL+"/downloads/")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "list-of-files"), chk.Equals, true)
+}
+
+func (s *httpTraverserSuite) TestHttpTraverserMissingFile(c *chk.C) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := s.traverse(c, server.URL+"/downloads/file.txt?token=secret")
+	c.Assert(err, chk.NotNil)
+	c.Assert(strings.Contains(err.Error(), "404"), chk.Equals, true)
+	c.Assert(strings.Contains(err.Error(), "secret"), chk.Equals, false)
+}
+
+func (s *httpTraverserSuite) TestSplitAuthTokenFromHttpResource(c *chk.C) {
+	// every query parameter of a generic URL could be a credential
+	base, token, err := splitAuthTokenFromResource("https://example.com/a/file.zip?X-Goog-Signature=abc&expires=1", common.ELocation.HTTP())
+	c.Assert(err, chk.IsNil)
+	c.Assert(base, chk.Equals, "https://example.com/a/file.zip")
+	c.Assert(token, chk.Equals, "X-Goog-Signature=abc&expires=1")
+}
@@ -0,0 +1,93 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type traverserS3Suite struct{}
+
+var _ = chk.Suite(&traverserS3Suite{})
+
+// newS3StandIn starts a minimal stand-in for an S3-compatible service such as MinIO.
+// It serves a single bucket, and only knows how to report the bucket's location and list its objects.
+func newS3StandIn(bucket string, objects map[string]int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+bucket && r.URL.Path != "/"+bucket+"/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		if _, ok := r.URL.Query()["location"]; ok {
+			_, _ = fmt.Fprint(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+			return
+		}
+
+		keys := make([]string, 0, len(objects))
+		for k := range objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		_, _ = fmt.Fprintf(w, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%s</Name><Prefix></Prefix><KeyCount>%d</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>`, bucket, len(keys))
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>2023-01-01T00:00:00.000Z</LastModified><ETag>"00000000000000000000000000000000"</ETag><Size>%d</Size><StorageClass>STANDARD</StorageClass></Contents>`, k, objects[k])
+		}
+		_, _ = fmt.Fprint(w, `</ListBucketResult>`)
+	}))
+}
+
+func (s *traverserS3Suite) TestS3TraverserCustomEndpoint(c *chk.C) {
+	objects := map[string]int64{"a.txt": 5, "dir/b.txt": 10}
+	standIn := newS3StandIn("bucket", objects)
+	defer standIn.Close()
+
+	c.Assert(common.SetS3CustomEndpoint(standIn.URL), chk.IsNil)
+	defer func() { _ = common.SetS3CustomEndpoint("") }()
+
+	// the stand-in listens on an IP, over plain HTTP, and is addressed path-style
+	rawURL, err := url.Parse(standIn.URL + "/bucket/")
+	c.Assert(err, chk.IsNil)
+	c.Assert(InferArgumentLocation(rawURL.String()), chk.Equals, common.ELocation.S3())
+
+	traverser, err := newS3Traverser(common.ECredentialType.S3PublicBucket(), rawURL, context.TODO(), true, false, func(common.EntityType) {})
+	c.Assert(err, chk.IsNil)
+	c.Assert(traverser.s3URLParts.IsInsecure(), chk.Equals, true)
+
+	processor := &dummyProcessor{}
+	err = traverser.Traverse(noPreProccessor, processor.process, nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(processor.record, chk.HasLen, len(objects))
+	for _, storedObject := range processor.record {
+		c.Assert(storedObject.size, chk.Equals, objects[storedObject.relativePath])
+		c.Assert(storedObject.ContainerName, chk.Equals, "bucket")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type validatorsSuite struct{}
+
+var _ = chk.Suite(&validatorsSuite{})
+
+func (s *validatorsSuite) TestInferFromToS3Download(c *chk.C) {
+	fromTo := inferFromTo("https://s3.us-west-2.amazonaws.com/bucket/folder", "/tmp/dest")
+	c.Assert(fromTo, chk.Equals, common.EFromTo.S3Local())
+
+	fromTo, err := ValidateFromTo("https://s3.amazonaws.com/bucket/object", "/tmp/dest", "S3Local")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.S3Local())
+}
+
+func (s *validatorsSuite) TestInferFromToGCPDownload(c *chk.C) {
+	fromTo := inferFromTo("https://storage.cloud.google.com/bucket/folder", "/tmp/dest")
+	c.Assert(fromTo, chk.Equals, common.EFromTo.GCPLocal())
+}
+
+func (s *validatorsSuite) TestValidateFromToLocalLocal(c *chk.C) {
+	// two local paths are never inferred as a local copy, the user has to ask for it
+	_, err := ValidateFromTo("/tmp/source", "/tmp/dest", "")
+	c.Assert(err, chk.NotNil)
+
+	fromTo, err := ValidateFromTo("/tmp/source", "/tmp/dest", "LocalLocal")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.LocalLocal())
+	c.Assert(fromTo.IsLocalToLocal(), chk.Equals, true)
+	c.Assert(fromTo.IsDownload(), chk.Equals, false) // it's transferred like one, but nothing about it is remote
+}
+
+func (s *validatorsSuite) TestInferFromToHttp(c *chk.C) {
+	// a URL that isn't any of the storage services may be a mistyped one, so it's never inferred as a generic HTTP(S) source
+	c.Assert(InferArgumentLocation("https://example.com/downloads/file.zip?token=abc"), chk.Equals, common.ELocation.Unknown())
+	_, err := ValidateFromTo("https://example.com/downloads/file.zip?token=abc", "/tmp/dest", "")
+	c.Assert(err, chk.NotNil)
+	_, err = ValidateFromTo("https://account.blbo.core.windows.net/container/file.zip", "https://account.blob.core.windows.net/container/file.zip", "")
+	c.Assert(err, chk.NotNil)
+
+	// the user has to ask for it
+	fromTo, err := ValidateFromTo("https://example.com/downloads/file.zip?token=abc", "/tmp/dest", "HTTPLocal")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.HTTPLocal())
+
+	fromTo, err = ValidateFromTo("https://example.com/downloads/file.zip", "https://account.blob.core.windows.net/container/file.zip", "HTTPBlob")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.HTTPBlob())
+
+	// and it can only ever be a source
+	c.Assert(inferFromTo("/tmp/source", "https://example.com/uploads/"), chk.Equals, common.EFromTo.Unknown())
+}
@@ -0,0 +1,75 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// the compressors are relatively expensive to create, and every chunk of every compressed upload needs one,
+// so they are reused
+var gzipWriterPool = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+var zstdEncoderPool = sync.Pool{New: func() interface{} {
+	// One goroutine per encoder is enough, since chunks are already compressed in parallel with each other.
+	// Zero frames are needed so that an empty file still compresses to a valid stream.
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithZeroFrames(true))
+	PanicIfErr(err) // only fails on invalid options
+	return enc
+}}
+
+// CompressChunk compresses the given data as a complete, self-contained gzip member or ZStandard frame.
+// Both formats define a compressed stream as a sequence of those, so the chunks of a file may be compressed
+// independently (and in parallel), and the concatenation of the compressed chunks, in their original order,
+// decompresses to the whole file. That lets compressed uploads keep using one block per chunk.
+func CompressChunk(ct CompressionType, data io.Reader) ([]byte, error) {
+	compressed := &bytes.Buffer{}
+
+	var compressor io.WriteCloser
+	switch ct {
+	case ECompressionType.GZip():
+		w := gzipWriterPool.Get().(*gzip.Writer)
+		defer gzipWriterPool.Put(w)
+		w.Reset(compressed)
+		compressor = w
+	case ECompressionType.ZStd():
+		enc := zstdEncoderPool.Get().(*zstd.Encoder)
+		defer zstdEncoderPool.Put(enc)
+		enc.Reset(compressed)
+		compressor = enc
+	default:
+		return nil, errors.New("unexpected compression type")
+	}
+
+	if _, err := io.Copy(compressor, data); err != nil {
+		_ = compressor.Close()
+		return nil, err
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
@@ -250,14 +250,14 @@ func refreshBlobFSToken(ctx context.Context, tokenInfo OAuthTokenInfo, tokenCred
 func CreateS3Client(ctx context.Context, credInfo CredentialInfo, option CredentialOpOptions, logger ILogger) (*minio.Client, error) {
 	if credInfo.CredentialType == ECredentialType.S3PublicBucket() {
 		cred := credentials.NewStatic("", "", "", credentials.SignatureAnonymous)
-		return minio.NewWithOptions(credInfo.S3CredentialInfo.Endpoint, &minio.Options{Creds: cred, Secure: true, Region: credInfo.S3CredentialInfo.Region})
+		return minio.NewWithOptions(credInfo.S3CredentialInfo.Endpoint, &minio.Options{Creds: cred, Secure: !credInfo.S3CredentialInfo.Insecure, Region: credInfo.S3CredentialInfo.Region})
 	}
 	// Support access key
 	credential, err := CreateS3Credential(ctx, credInfo, option)
 	if err != nil {
 		return nil, err
 	}
-	s3Client, err := minio.NewWithCredentials(credInfo.S3CredentialInfo.Endpoint, credential, true, credInfo.S3CredentialInfo.Region)
+	s3Client, err := minio.NewWithCredentials(credInfo.S3CredentialInfo.Endpoint, credential, !credInfo.S3CredentialInfo.Insecure, credInfo.S3CredentialInfo.Region)
 
 	if logger != nil {
 		s3Client.TraceOn(NewS3HTTPTraceLogger(logger, pipeline.LogDebug))
@@ -26,6 +26,8 @@ import (
 	"errors"
 	"io"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 type decompressingWriter struct {
@@ -39,7 +41,7 @@ var decompressingWriterBufferPool = NewMultiSizeSlicePool(decompressingWriterCop
 // NewDecompressingWriter returns a WriteCloser which decompresses the data
 // that is written to it, before passing the decompressed data on to a final destination.
 // This decompressor is intended to work with compressed data wrapped in either the ZLib headers or the slightly larger
-// Gzip headers. Both of those formats compress a single file (often a .tar archive in the case of Gzip).
+// Gzip headers, or in ZStandard frames. All of those formats compress a single file (often a .tar archive in the case of Gzip).
 // So there is no need to to expand the decompressed info out into multiple files (as we would have to do,
 // if we were to support "zip" compression). See https://stackoverflow.com/a/20765054
 func NewDecompressingWriter(destination io.WriteCloser, ct CompressionType) io.WriteCloser {
@@ -62,6 +64,12 @@ func (d decompressingWriter) decompressorFactory(tp CompressionType, preader *io
 		return zlib.NewReader(preader)
 	case ECompressionType.GZip():
 		return gzip.NewReader(preader)
+	case ECompressionType.ZStd():
+		dec, err := zstd.NewReader(preader)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
 	default:
 		return nil, errors.New("unexpected compression type")
 	}
@@ -85,6 +93,7 @@ func (d decompressingWriter) worker(tp CompressionType, preader *io.PipeReader,
 	if err != nil {
 		return
 	}
+	defer dec.Close() // releases any resources of the decompressor (the ZStandard one runs goroutines of its own)
 
 	// Now read from the pipe, decompressing as we go, until
 	// reach EOF on the pipe (or encounter an error)
@@ -34,7 +34,7 @@ type ExclusiveStringMap struct {
 
 func NewExclusiveStringMap(fromTo FromTo, goos string) *ExclusiveStringMap {
 
-	caseInsenstiveDownload := fromTo.IsDownload() &&
+	caseInsenstiveDownload := (fromTo.IsDownload() || fromTo.IsLocalToLocal()) &&
 		(strings.EqualFold(goos, "windows") || strings.EqualFold(goos, "darwin")) // download to case insensitive OS
 	caseSensitiveToRemote := fromTo.To() == ELocation.File() // upload to Windows-like cloud file system
 	insensitive := caseInsenstiveDownload || caseSensitiveToRemote
@@ -174,6 +174,47 @@ func (d *TrailingDotOption) Parse(s string) error {
 	return err
 }
 
+// //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+var EArchiveFormat = ArchiveFormat(0)
+
+// ArchiveFormat says whether an upload packs the whole enumerated tree into a single archive blob, rather than
+// uploading one blob per file.
+type ArchiveFormat uint8
+
+func (ArchiveFormat) None() ArchiveFormat  { return ArchiveFormat(0) }
+func (ArchiveFormat) Tar() ArchiveFormat   { return ArchiveFormat(1) }
+func (ArchiveFormat) TarGz() ArchiveFormat { return ArchiveFormat(2) }
+
+func (a ArchiveFormat) String() string {
+	return enum.StringInt(a, reflect.TypeOf(a))
+}
+
+func (a *ArchiveFormat) Parse(s string) error {
+	// allow empty to mean "None"
+	if s == "" {
+		*a = EArchiveFormat.None()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(a), s, true, true)
+	if err == nil {
+		*a = val.(ArchiveFormat)
+	}
+	return err
+}
+
+// ContentType returns the MIME type given to an archive blob when the user doesn't specify one.
+func (a ArchiveFormat) ContentType() string {
+	switch a {
+	case EArchiveFormat.Tar():
+		return "application/x-tar"
+	case EArchiveFormat.TarGz():
+		return "application/gzip"
+	default:
+		return ""
+	}
+}
+
 // //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 var EPermanentDeleteOption = PermanentDeleteOption(3) // Default to "None"
 
@@ -235,6 +276,76 @@ func (dd DeleteDestination) String() string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// SyncConflictPolicy says what a two-way sync does with a file that changed on both sides since the last sync
+type SyncConflictPolicy uint8
+
+var ESyncConflictPolicy = SyncConflictPolicy(0)
+
+func (SyncConflictPolicy) NewerWins() SyncConflictPolicy { return SyncConflictPolicy(0) } // the more recently modified version replaces the other
+func (SyncConflictPolicy) KeepBoth() SyncConflictPolicy  { return SyncConflictPolicy(1) } // the older version is also kept, under a new name
+func (SyncConflictPolicy) Fail() SyncConflictPolicy      { return SyncConflictPolicy(2) } // the file is left alone, and the sync fails
+
+func (p *SyncConflictPolicy) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(p), s, true, true)
+	if err == nil {
+		*p = val.(SyncConflictPolicy)
+	}
+	return err
+}
+
+func (p SyncConflictPolicy) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// SyncCompareMode says how sync decides whether a file that exists at both the source and the destination is transferred
+type SyncCompareMode uint8
+
+var ESyncCompareMode = SyncCompareMode(0)
+
+func (SyncCompareMode) Default() SyncCompareMode    { return SyncCompareMode(0) } // a more recent LMT at the source, or a differing hash with --compare-hash
+func (SyncCompareMode) SizeOnly() SyncCompareMode   { return SyncCompareMode(1) } // a differing size
+func (SyncCompareMode) LMTAndSize() SyncCompareMode { return SyncCompareMode(2) } // a more recent LMT at the source, or a differing size
+func (SyncCompareMode) HashOnly() SyncCompareMode   { return SyncCompareMode(3) } // a differing hash, or a hash that's missing on either side
+func (SyncCompareMode) Metadata() SyncCompareMode   { return SyncCompareMode(4) } // a differing value of a given metadata key
+
+func (m *SyncCompareMode) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(m), s, true, true)
+	if err == nil {
+		*m = val.(SyncCompareMode)
+	}
+	return err
+}
+
+func (m SyncCompareMode) String() string {
+	return enum.StringInt(m, reflect.TypeOf(m))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// SyncReportFormat is the format of the report that a dry run of sync writes of what it would do
+type SyncReportFormat uint8
+
+var ESyncReportFormat = SyncReportFormat(0)
+
+func (SyncReportFormat) Json() SyncReportFormat { return SyncReportFormat(0) }
+func (SyncReportFormat) Csv() SyncReportFormat  { return SyncReportFormat(1) }
+
+func (f *SyncReportFormat) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(f), s, true, true)
+	if err == nil {
+		*f = val.(SyncReportFormat)
+	}
+	return err
+}
+
+func (f SyncReportFormat) String() string {
+	return enum.StringInt(f, reflect.TypeOf(f))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 // represents one possible response
 var EResponseOption = ResponseOption{ResponseType: "", UserFriendlyResponseType: "", ResponseString: ""}
 
@@ -383,13 +494,37 @@ func (ll LogLevel) ToPipelineLogLevel() pipeline.LogLevel {
 var EJobPriority = JobPriority(0)
 
 // JobPriority defines the transfer priorities supported by the Storage Transfer Engine's channels
-// The default priority is Normal
+// The default priority is Normal. When several jobs run in the same process, a job's priority also
+// decides its share of the connections and bandwidth.
 type JobPriority uint8
 
 func (JobPriority) Normal() JobPriority { return JobPriority(0) }
 func (JobPriority) Low() JobPriority    { return JobPriority(1) }
+func (JobPriority) High() JobPriority   { return JobPriority(2) }
 func (jp JobPriority) String() string {
-	return enum.StringInt(uint8(jp), reflect.TypeOf(jp))
+	return enum.StringInt(jp, reflect.TypeOf(jp))
+}
+
+func (jp *JobPriority) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(jp), s, true, true)
+	if err == nil {
+		*jp = val.(JobPriority)
+	}
+	return err
+}
+
+// Implementing MarshalJSON() method for type JobPriority
+func (jp JobPriority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jp.String())
+}
+
+// Implementing UnmarshalJSON() method for type JobPriority
+func (jp *JobPriority) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return jp.Parse(s)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -482,6 +617,7 @@ func (Location) S3() Location        { return Location(6) }
 func (Location) Benchmark() Location { return Location(7) }
 func (Location) GCP() Location       { return Location(8) }
 func (Location) None() Location      { return Location(9) } // None is used in case we're transferring properties
+func (Location) HTTP() Location      { return Location(10) } // HTTP is any plain HTTP(S) URL, read with GETs and no listing
 
 func (l Location) String() string {
 	return enum.StringInt(l, reflect.TypeOf(l))
@@ -508,7 +644,7 @@ func fromToValue(from Location, to Location) FromTo {
 
 func (l Location) IsRemote() bool {
 	switch l {
-	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.GCP():
+	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.GCP(), ELocation.HTTP():
 		return true
 	case ELocation.Local(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown(), ELocation.None():
 		return false
@@ -531,7 +667,7 @@ func (l Location) IsFolderAware() bool {
 	switch l {
 	case ELocation.BlobFS(), ELocation.File(), ELocation.Local():
 		return true
-	case ELocation.Blob(), ELocation.S3(), ELocation.GCP(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown(), ELocation.None():
+	case ELocation.Blob(), ELocation.S3(), ELocation.GCP(), ELocation.HTTP(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown(), ELocation.None():
 		return false
 	default:
 		panic("unexpected location, please specify if it is folder-aware")
@@ -560,6 +696,8 @@ func (FromTo) BlobPipe() FromTo     { return fromToValue(ELocation.Blob(), ELoca
 func (FromTo) PipeBlob() FromTo     { return fromToValue(ELocation.Pipe(), ELocation.Blob()) }
 func (FromTo) FilePipe() FromTo     { return fromToValue(ELocation.File(), ELocation.Pipe()) }
 func (FromTo) PipeFile() FromTo     { return fromToValue(ELocation.Pipe(), ELocation.File()) }
+func (FromTo) BlobFSPipe() FromTo   { return fromToValue(ELocation.BlobFS(), ELocation.Pipe()) }
+func (FromTo) PipeBlobFS() FromTo   { return fromToValue(ELocation.Pipe(), ELocation.BlobFS()) }
 func (FromTo) BlobTrash() FromTo    { return fromToValue(ELocation.Blob(), ELocation.Unknown()) }
 func (FromTo) FileTrash() FromTo    { return fromToValue(ELocation.File(), ELocation.Unknown()) }
 func (FromTo) BlobFSTrash() FromTo  { return fromToValue(ELocation.BlobFS(), ELocation.Unknown()) }
@@ -576,6 +714,12 @@ func (FromTo) BlobFile() FromTo     { return fromToValue(ELocation.Blob(), ELoca
 func (FromTo) FileFile() FromTo     { return fromToValue(ELocation.File(), ELocation.File()) }
 func (FromTo) S3Blob() FromTo       { return fromToValue(ELocation.S3(), ELocation.Blob()) }
 func (FromTo) GCPBlob() FromTo      { return fromToValue(ELocation.GCP(), ELocation.Blob()) }
+func (FromTo) S3Local() FromTo      { return fromToValue(ELocation.S3(), ELocation.Local()) }
+func (FromTo) GCPLocal() FromTo     { return fromToValue(ELocation.GCP(), ELocation.Local()) }
+func (FromTo) BlobS3() FromTo       { return fromToValue(ELocation.Blob(), ELocation.S3()) }
+func (FromTo) LocalLocal() FromTo   { return fromToValue(ELocation.Local(), ELocation.Local()) }
+func (FromTo) HTTPBlob() FromTo     { return fromToValue(ELocation.HTTP(), ELocation.Blob()) }
+func (FromTo) HTTPLocal() FromTo    { return fromToValue(ELocation.HTTP(), ELocation.Local()) }
 func (FromTo) BlobNone() FromTo     { return fromToValue(ELocation.Blob(), ELocation.None()) }
 func (FromTo) BlobFSNone() FromTo   { return fromToValue(ELocation.BlobFS(), ELocation.None()) }
 func (FromTo) FileNone() FromTo     { return fromToValue(ELocation.File(), ELocation.None()) }
@@ -627,6 +771,12 @@ func (ft FromTo) IsDownload() bool {
 	return ft.From().IsRemote() && ft.To().IsLocal() && ft.To() != ELocation.None() && ft.To() != ELocation.Unknown()
 }
 
+// IsLocalToLocal returns true for copies between local paths. They are transferred like downloads,
+// since the destination is written the same way, but they are not downloads wherever the remote source matters.
+func (ft FromTo) IsLocalToLocal() bool {
+	return ft == EFromTo.LocalLocal()
+}
+
 func (ft FromTo) IsS2S() bool {
 	return ft.From().IsRemote() && ft.To().IsRemote() && ft.To() != ELocation.None() && ft.To() != ELocation.Unknown()
 }
@@ -720,6 +870,10 @@ func (t TransferStatus) StatusLocked() bool { // Is an overwrite necessary to ch
 	return t <= ETransferStatus.Failed() || t == ETransferStatus.Success()
 }
 
+func (t TransferStatus) DidFail() bool { // Did the transfer fail, rather than being skipped or cancelled?
+	return t == ETransferStatus.Failed() || t == ETransferStatus.BlobTierFailure() || t == ETransferStatus.TierAvailabilityCheckFailure()
+}
+
 // Transfer is ready to transfer and not started transferring yet
 func (TransferStatus) NotStarted() TransferStatus { return TransferStatus(0) }
 
@@ -1485,12 +1639,49 @@ type CompressionType uint8
 func (CompressionType) None() CompressionType        { return CompressionType(0) }
 func (CompressionType) ZLib() CompressionType        { return CompressionType(1) }
 func (CompressionType) GZip() CompressionType        { return CompressionType(2) }
+func (CompressionType) ZStd() CompressionType        { return CompressionType(3) }
 func (CompressionType) Unsupported() CompressionType { return CompressionType(255) }
 
 func (ct CompressionType) String() string {
 	return enum.StringInt(ct, reflect.TypeOf(ct))
 }
 
+func (ct *CompressionType) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(ct), s, true, true)
+	if err == nil {
+		*ct = val.(CompressionType)
+	}
+	return err
+}
+
+// ContentEncoding returns the value of the Content-Encoding header that describes data compressed with this type
+func (ct CompressionType) ContentEncoding() string {
+	switch ct {
+	case ECompressionType.GZip():
+		return "gzip"
+	case ECompressionType.ZLib():
+		return "deflate"
+	case ECompressionType.ZStd():
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// FileExtension returns the extension that is conventionally given to files compressed with this type
+func (ct CompressionType) FileExtension() string {
+	switch ct {
+	case ECompressionType.GZip():
+		return ".gz"
+	case ECompressionType.ZLib():
+		return ".zz"
+	case ECompressionType.ZStd():
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
 func GetCompressionType(contentEncoding string) (CompressionType, error) {
 	switch strings.ToLower(contentEncoding) {
 	case "":
@@ -1499,6 +1690,8 @@ func GetCompressionType(contentEncoding string) (CompressionType, error) {
 		return ECompressionType.GZip(), nil
 	case "deflate":
 		return ECompressionType.ZLib(), nil
+	case "zstd":
+		return ECompressionType.ZStd(), nil
 	default:
 		return ECompressionType.Unsupported(), fmt.Errorf("encoding type '%s' is not recognised as a supported encoding type for auto-decompression", contentEncoding)
 	}
@@ -1725,6 +1918,20 @@ func (SyncHashType) MD5() SyncHashType {
 	return 1
 }
 
+// CRC64 is the CRC-64 that Azure Storage uses for transactional integrity checks
+func (SyncHashType) CRC64() SyncHashType {
+	return 2
+}
+
+func (SyncHashType) SHA256() SyncHashType {
+	return 3
+}
+
+// XXHash64 is the 64-bit variant of xxHash, a fast non-cryptographic hash
+func (SyncHashType) XXHash64() SyncHashType {
+	return 4
+}
+
 func (ht *SyncHashType) Parse(s string) error {
 	val, err := enum.ParseInt(reflect.TypeOf(ht), s, true, true)
 	if err == nil {
@@ -1,16 +1,87 @@
 package common
 
 import (
-	"github.com/JeffreyRichter/enum/enum"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"hash/crc64"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/JeffreyRichter/enum/enum"
+	"github.com/cespare/xxhash/v2"
 )
 
 // AzCopyHashDataStream is used as both the name of a data stream, xattr key, and the suffix of os-agnostic hash data files.
 // The local traverser intentionally skips over files with this suffix.
 const AzCopyHashDataStream = `.azcopysyncmeta`
 
+// the polynomial of the CRC-64 used by Azure Storage
+const azureStorageCrc64Polynomial = 0x9A6C9329AC4BC9B5
+
+var azureStorageCrc64Table = crc64.MakeTable(azureStorageCrc64Polynomial)
+
+// NewHasher returns a hash.Hash that computes hashes of this type, or nil if the type isn't a hash.
+func (ht SyncHashType) NewHasher() hash.Hash {
+	switch ht {
+	case ESyncHashType.MD5():
+		return md5.New()
+	case ESyncHashType.CRC64():
+		return crc64.New(azureStorageCrc64Table)
+	case ESyncHashType.SHA256():
+		return sha256.New()
+	case ESyncHashType.XXHash64():
+		return xxhash.New()
+	default:
+		return nil
+	}
+}
+
+// MetadataKey returns the metadata key under which sync keeps hashes of this type on remote objects.
+// MD5 has no key, since it's kept in Content-MD5 instead.
+func (ht SyncHashType) MetadataKey() string {
+	switch ht {
+	case ESyncHashType.CRC64():
+		return "azcopycrc64"
+	case ESyncHashType.SHA256():
+		return "azcopysha256"
+	case ESyncHashType.XXHash64():
+		return "azcopyxxhash64"
+	default:
+		return ""
+	}
+}
+
+// HashFromMetadata returns the hash of this type that sync keeps in the given metadata of a remote object, or nil if it has none.
+func (ht SyncHashType) HashFromMetadata(metadata Metadata) []byte {
+	key := ht.MetadataKey()
+	if key == "" {
+		return nil
+	}
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) { // remote services don't all preserve the case of metadata keys
+			sum, err := base64.StdEncoding.DecodeString(v)
+			if err != nil || len(sum) == 0 {
+				return nil // treat an unreadable hash like a missing one
+			}
+			return sum
+		}
+	}
+	return nil
+}
+
+// HashToMetadata returns a copy of the given metadata that holds the given hash of this type, where HashFromMetadata looks for it
+func (ht SyncHashType) HashToMetadata(metadata Metadata, sum []byte) Metadata {
+	metadata = metadata.Clone()
+	if key := ht.MetadataKey(); key != "" {
+		metadata[key] = base64.StdEncoding.EncodeToString(sum)
+	}
+	return metadata
+}
+
 type SyncHashData struct {
 	Mode SyncHashType
 	Data string // base64 encoded
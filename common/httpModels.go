@@ -0,0 +1,155 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// HTTPResourceProperties holds the properties of a generic HTTP(S) resource, as found in the headers of a HEAD
+// or GET response. Plain web servers have no listing API, so these headers are all we will ever know about the resource.
+type HTTPResourceProperties struct {
+	StatusCode int
+	Header     http.Header
+}
+
+func (h HTTPResourceProperties) ContentType() string {
+	return h.Header.Get("Content-Type")
+}
+
+func (h HTTPResourceProperties) CacheControl() string {
+	return h.Header.Get("Cache-Control")
+}
+
+func (h HTTPResourceProperties) ContentDisposition() string {
+	return h.Header.Get("Content-Disposition")
+}
+
+func (h HTTPResourceProperties) ContentEncoding() string {
+	return h.Header.Get("Content-Encoding")
+}
+
+func (h HTTPResourceProperties) ContentLanguage() string {
+	return h.Header.Get("Content-Language")
+}
+
+// ContentMD5 returns the (rarely sent) Content-MD5 header, or nil if it's absent or malformed.
+// Unlike in Azure, an ETag from a generic server is opaque, so we never try to read a hash out of it.
+func (h HTTPResourceProperties) ContentMD5() []byte {
+	md5, err := base64.StdEncoding.DecodeString(h.Header.Get("Content-MD5"))
+	if err != nil || len(md5) == 0 {
+		return nil
+	}
+	return md5
+}
+
+// ETag returns the entity tag of the resource, or an empty string if the server didn't send one.
+func (h HTTPResourceProperties) ETag() string {
+	return h.Header.Get("ETag")
+}
+
+// LastModified returns the Last-Modified time of the resource, or the zero time if the server didn't send one.
+func (h HTTPResourceProperties) LastModified() time.Time {
+	lmt, err := http.ParseTime(h.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return lmt
+}
+
+// Size returns the full size of the resource. For a partial response that's the total from Content-Range,
+// since Content-Length only covers the range that was returned.
+func (h HTTPResourceProperties) Size() (int64, error) {
+	if h.StatusCode == http.StatusPartialContent {
+		contentRange := h.Header.Get("Content-Range")
+		i := strings.LastIndex(contentRange, "/")
+		if i < 0 {
+			return 0, fmt.Errorf("the server returned an invalid Content-Range %q", contentRange)
+		}
+		size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("the server did not return the size of the resource in Content-Range %q", contentRange)
+		}
+		return size, nil
+	}
+
+	contentLength := h.Header.Get("Content-Length")
+	size, err := strconv.ParseInt(contentLength, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("the server did not return the size of the resource in Content-Length %q", contentLength)
+	}
+	return size, nil
+}
+
+// GetHTTPResourceProperties fetches the properties of a generic HTTP(S) resource through the given pipeline.
+// It sends a HEAD request, and falls back to a single byte ranged GET for the servers that don't allow HEAD.
+func GetHTTPResourceProperties(ctx context.Context, p pipeline.Pipeline, u url.URL) (HTTPResourceProperties, error) {
+	props, err := getHTTPResourceProperties(ctx, p, u, http.MethodHead)
+	if err != nil {
+		return props, err
+	}
+
+	if props.StatusCode == http.StatusMethodNotAllowed || props.StatusCode == http.StatusNotImplemented {
+		props, err = getHTTPResourceProperties(ctx, p, u, http.MethodGet)
+		if err != nil {
+			return props, err
+		}
+	}
+
+	if props.StatusCode != http.StatusOK && props.StatusCode != http.StatusPartialContent {
+		// any query parameter of a generic URL might be a credential, so leave the whole query out of the error
+		u.RawQuery = ""
+		return props, fmt.Errorf("the server returned %d %s for %s", props.StatusCode, http.StatusText(props.StatusCode), u.String())
+	}
+
+	return props, nil
+}
+
+func getHTTPResourceProperties(ctx context.Context, p pipeline.Pipeline, u url.URL, method string) (HTTPResourceProperties, error) {
+	req, err := pipeline.NewRequest(method, u, nil)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	// ask for the stored bytes, so that the size we get back is the size we will download
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := p.Do(ctx, nil, req)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+	r := resp.Response()
+	// don't drain the body: a server that ignores Range would send us the whole resource
+	_ = r.Body.Close()
+
+	return HTTPResourceProperties{StatusCode: r.StatusCode, Header: r.Header}, nil
+}
@@ -184,6 +184,7 @@ type GCPCredentialInfo struct {
 type S3CredentialInfo struct {
 	Endpoint string
 	Region   string
+	Insecure bool // use plain HTTP, which is only permitted for a custom S3-compatible endpoint
 }
 
 type CopyJobPartOrderErrorType string
@@ -226,6 +227,9 @@ type BlobTransferAttributes struct {
 	BlobTagsString           string                // when user explicitly provides blob tags
 	PermanentDeleteOption    PermanentDeleteOption // Permanently deletes soft-deleted snapshots when indicated by user
 	RehydratePriority        RehydratePriorityType // rehydrate priority of blob
+	ArchiveFormat            ArchiveFormat         // when uploading, pack the enumerated files into a single archive blob
+	CompressionType          CompressionType       // when uploading, compress the content of each file, and set its content-encoding to match
+	SyncHashType             SyncHashType          // when downloading, record the hash of this type that the source has in its metadata with the file
 }
 
 // This struct represents the optional attribute for file request header
@@ -313,11 +317,20 @@ type ListSyncJobSummaryResponse struct {
 	ListJobSummaryResponse
 	DeleteTotalTransfers     uint32 `json:",string"`
 	DeleteTransfersCompleted uint32 `json:",string"`
+	MoveTransfersCompleted   uint32 `json:",string"`
 }
 
 type ListJobTransfersRequest struct {
 	JobID    JobID
 	OfStatus TransferStatus
+
+	// Optional filters, which are applied on top of OfStatus. The zero value of each of them lets every transfer through.
+	PathPattern     string  // a glob matched against the path of the source relative to its root, or against the name if it has no separator
+	MinSize         int64   // the smallest source size, in bytes
+	MaxSize         int64   // the largest source size, in bytes; zero for no limit
+	ErrorCodes      []int32 // the error codes, which are the HTTP status codes that the transfers failed with
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
 }
 
 type ResumeJobRequest struct {
@@ -327,6 +340,9 @@ type ResumeJobRequest struct {
 	IncludeTransfer map[string]int
 	ExcludeTransfer map[string]int
 	CredentialInfo  CredentialInfo
+
+	// S2SSourceCredentialType is the credential type of the source of an S2S job, when it's read with something other than a SAS
+	S2SSourceCredentialType CredentialType
 }
 
 // represents the Details and details of a single transfer
@@ -336,7 +352,8 @@ type TransferDetail struct {
 	IsFolderProperties bool
 	TransferStatus     TransferStatus
 	TransferSize       uint64
-	ErrorCode          int32 `json:",string"`
+	ErrorCode          int32      `json:",string"`
+	CompletionTime     *time.Time `json:",omitempty"` // nil until the transfer is done
 }
 
 type CancelPauseResumeResponse struct {
@@ -21,7 +21,9 @@
 package common
 
 import (
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"strings"
 
 	minio "github.com/minio/minio-go"
@@ -68,6 +70,40 @@ func (oie *ObjectInfoExtension) ContentMD5() []byte {
 	return b
 }
 
+// SyncComparableMD5 returns the MD5 that sync compares the object by: the value for header Content-MD5,
+// or else the ETag when it is known to be the MD5 of the content, since S3 rarely stores that header.
+// Unlike ContentMD5, it's only good for comparing, and isn't given to the destination or used to validate a download.
+func (oie *ObjectInfoExtension) SyncComparableMD5() []byte {
+	if oie.ObjectInfo.Metadata.Get("Content-MD5") != "" {
+		return oie.ContentMD5()
+	}
+	return oie.etagMD5()
+}
+
+// etagMD5 returns the ETag as an MD5 hash, or nil if the ETag can't be trusted to be one.
+// Multipart uploads have ETags of the form "<hash>-<part count>", and objects encrypted with SSE-KMS or SSE-C have opaque ETags.
+// The encryption headers are only present when the object info came from a HEAD request, so listing results are never trusted.
+func (oie *ObjectInfoExtension) etagMD5() []byte {
+	headers := oie.ObjectInfo.Metadata
+	if len(headers) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(headers.Get("X-Amz-Server-Side-Encryption")), "aws:kms") ||
+		headers.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+		return nil
+	}
+
+	etag := strings.Trim(oie.ObjectInfo.ETag, "\"")
+	if len(etag) != 2*md5.Size {
+		return nil
+	}
+	b, err := hex.DecodeString(etag)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
 const s3MetadataPrefix = "x-amz-meta-"
 
 const s3MetadataPrefixLen = len(s3MetadataPrefix)
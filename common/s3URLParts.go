@@ -22,6 +22,7 @@ package common
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
@@ -50,9 +51,9 @@ type S3URLParts struct {
 	Region         string // Ex: endpoint region, e.g. "eu-west-1"
 	UnparsedParams string
 
-	isPathStyle bool
-	isDualStack bool
-	// TODO: Other S3 compatible service which might be with IP endpoint style
+	isPathStyle      bool
+	isDualStack      bool
+	isCustomEndpoint bool // the URL points to the S3-compatible service nominated with SetS3CustomEndpoint, rather than to AWS
 }
 
 const s3HostPattern = "^(?P<bucketName>.+\\.)?s3[.-](?P<dualStackOrRegionOrAWSDomain>[a-z0-9-]+)\\.(?P<regionOrAWSDomainOrCom>[a-z0-9-]+)"
@@ -64,12 +65,61 @@ const s3EssentialHostPart = "amazonaws.com"
 
 var s3HostRegex = regexp.MustCompile(s3HostPattern)
 
+// s3CustomEndpoint is the host (and optional port) of an S3-compatible service other than AWS, e.g. MinIO, Ceph or Wasabi.
+// Like the AWS credentials, it is ambient to the whole process rather than being saved in the job plan,
+// so it must be supplied again when a job is resumed.
+var s3CustomEndpoint string
+
+// SetS3CustomEndpoint nominates an S3-compatible service whose URLs are accepted as S3 URLs, in addition to those of AWS.
+// The endpoint may be given as host[:port], or as an http(s) URL with no path. Pass "" to clear it.
+// Both path-style (http://endpoint/bucket/key) and virtual-hosted-style (http://bucket.endpoint/key) URLs are accepted
+// for the service, and plain HTTP is used whenever the URL's scheme is http.
+func SetS3CustomEndpoint(endpoint string) error {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		s3CustomEndpoint = ""
+		return nil
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	if !strings.EqualFold(u.Scheme, "http") && !strings.EqualFold(u.Scheme, "https") {
+		return fmt.Errorf("invalid S3 endpoint %s: only http and https are supported", endpoint)
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+		return fmt.Errorf("invalid S3 endpoint %s: expected a host name with an optional port, e.g. minio.contoso.com:9000", endpoint)
+	}
+
+	s3CustomEndpoint = strings.ToLower(u.Host)
+	return nil
+}
+
+// GetS3CustomEndpoint returns the endpoint set by SetS3CustomEndpoint, or "" if there is none.
+func GetS3CustomEndpoint() string {
+	return s3CustomEndpoint
+}
+
+// IsS3CustomEndpointURL verifies if a given URL points to the S3-compatible service nominated with SetS3CustomEndpoint
+func IsS3CustomEndpointURL(u url.URL) bool {
+	return isS3CustomEndpointHost(strings.ToLower(u.Host))
+}
+
+func isS3CustomEndpointHost(host string) bool {
+	return s3CustomEndpoint != "" && (host == s3CustomEndpoint || strings.HasSuffix(host, "."+s3CustomEndpoint))
+}
+
 // IsS3URL verifies if a given URL points to S3 URL supported by AzCopy-v10
 func IsS3URL(u url.URL) bool {
 	if _, isS3URL := findS3URLMatches(strings.ToLower(u.Host)); isS3URL {
 		return true
 	}
-	return false
+	return IsS3CustomEndpointURL(u)
 }
 
 func findS3URLMatches(host string) (matches []string, isS3Host bool) {
@@ -85,11 +135,6 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	// S3's bucket name should be in lower case
 	host := strings.ToLower(u.Host)
 
-	matchSlices, isS3URL := findS3URLMatches(host)
-	if !isS3URL {
-		return S3URLParts{}, errors.New(invalidS3URLErrorMessage)
-	}
-
 	path := u.Path
 	// Remove the initial '/' if exists
 	if path != "" && path[0] == '/' {
@@ -101,6 +146,27 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 		Host:   host,
 	}
 
+	matchSlices, isS3URL := findS3URLMatches(host)
+	if !isS3URL {
+		if !isS3CustomEndpointHost(host) {
+			return S3URLParts{}, errors.New(invalidS3URLErrorMessage)
+		}
+
+		// The host can't tell us a region here, so leave it to the client to look up the bucket location, as it does for s3.amazonaws.com
+		up.isCustomEndpoint = true
+		up.Endpoint = s3CustomEndpoint
+		if host == s3CustomEndpoint {
+			up.isPathStyle = true
+			up.setBucketAndKeyFromPath(path)
+		} else {
+			up.BucketName = strings.TrimSuffix(host, "."+s3CustomEndpoint)
+			up.ObjectKey = path
+		}
+
+		up.setParamsFromQuery(u)
+		return up, nil
+	}
+
 	// Check what's the path style, and parse accordingly.
 	if matchSlices[1] != "" { // Go's implementation is a bit strange, even if the first subexp fail to be matched, "" will be returned for that sub exp
 		// In this case, it would be in virtual-hosted-style URL, and has host prefix like bucket.s3[-.]
@@ -111,13 +177,7 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	} else {
 		// In this case, it would be in path-style URL. Host prefix like s3[-.], and path contains the bucket name and object id.
 		up.isPathStyle = true
-
-		if bucketEndIndex := strings.Index(path, "/"); bucketEndIndex != -1 {
-			up.BucketName = path[:bucketEndIndex]
-			up.ObjectKey = path[bucketEndIndex+1:]
-		} else {
-			up.BucketName = path
-		}
+		up.setBucketAndKeyFromPath(path)
 
 		up.Endpoint = host
 	}
@@ -131,18 +191,43 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 		up.Region = matchSlices[2]
 	}
 
+	up.setParamsFromQuery(u)
+
+	return up, nil
+}
+
+// setBucketAndKeyFromPath parses a path-style URL's path, which contains the bucket name and object key.
+func (p *S3URLParts) setBucketAndKeyFromPath(path string) {
+	if bucketEndIndex := strings.Index(path, "/"); bucketEndIndex != -1 {
+		p.BucketName = path[:bucketEndIndex]
+		p.ObjectKey = path[bucketEndIndex+1:]
+	} else {
+		p.BucketName = path
+	}
+}
+
+func (p *S3URLParts) setParamsFromQuery(u url.URL) {
 	// Convert the query parameters to a case-sensitive map & trim whitespace
 	paramsMap := u.Query()
 
 	if versionStr, ok := caseInsensitiveValues(paramsMap).Get(versionQueryParamKey); ok {
-		up.Version = versionStr[0]
+		p.Version = versionStr[0]
 		// If we recognized the query parameter, remove it from the map
 		delete(paramsMap, versionQueryParamKey)
 	}
 
-	up.UnparsedParams = paramsMap.Encode()
+	p.UnparsedParams = paramsMap.Encode()
+}
 
-	return up, nil
+// IsCustomEndpoint returns true if the URL points to the S3-compatible service nominated with SetS3CustomEndpoint, rather than to AWS.
+func (p *S3URLParts) IsCustomEndpoint() bool {
+	return p.isCustomEndpoint
+}
+
+// IsInsecure returns true if the service must be reached over plain HTTP.
+// That is only ever the case for a custom endpoint; AWS URLs are always accessed over HTTPS, whatever their scheme.
+func (p *S3URLParts) IsInsecure() bool {
+	return p.isCustomEndpoint && strings.EqualFold(p.Scheme, "http")
 }
 
 // URL returns a URL object whose fields are initialized from the S3URLParts fields.
@@ -21,9 +21,12 @@
 package common
 
 import (
+	"encoding/hex"
+	"net/http"
 	"net/url"
 	"strings"
 
+	minio "github.com/minio/minio-go"
 	chk "gopkg.in/check.v1"
 )
 
@@ -205,3 +208,79 @@ func (s *s3URLPartsTestSuite) TestS3URLParseNegative(c *chk.C) {
 	c.Assert(err, chk.NotNil)
 	c.Assert(strings.Contains(err.Error(), invalidS3URLErrorMessage), chk.Equals, true)
 }
+
+func (s *s3URLPartsTestSuite) TestS3URLParseCustomEndpoint(c *chk.C) {
+	defer func() { _ = SetS3CustomEndpoint("") }()
+
+	// without a custom endpoint, S3-compatible services are rejected
+	u, _ := url.Parse("http://127.0.0.1:9000/bucket/keydir/keyname")
+	c.Assert(IsS3URL(*u), chk.Equals, false)
+
+	err := SetS3CustomEndpoint("http://127.0.0.1:9000/")
+	c.Assert(err, chk.IsNil)
+	c.Assert(GetS3CustomEndpoint(), chk.Equals, "127.0.0.1:9000")
+	c.Assert(IsS3URL(*u), chk.Equals, true)
+
+	// path style, over plain HTTP
+	p, err := NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsCustomEndpoint(), chk.Equals, true)
+	c.Assert(p.IsInsecure(), chk.Equals, true)
+	c.Assert(p.Endpoint, chk.Equals, "127.0.0.1:9000")
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keydir/keyname")
+	c.Assert(p.Region, chk.Equals, "")
+	c.Assert(p.String(), chk.Equals, "http://127.0.0.1:9000/bucket/keydir/keyname")
+
+	// virtual-hosted style, over HTTPS
+	err = SetS3CustomEndpoint("minio.contoso.com")
+	c.Assert(err, chk.IsNil)
+	u, _ = url.Parse("https://bucket.minio.contoso.com/keyname?versionId=v1")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsInsecure(), chk.Equals, false)
+	c.Assert(p.Endpoint, chk.Equals, "minio.contoso.com")
+	c.Assert(p.BucketName, chk.Equals, "bucket")
+	c.Assert(p.ObjectKey, chk.Equals, "keyname")
+	c.Assert(p.Version, chk.Equals, "v1")
+	c.Assert(p.String(), chk.Equals, "https://bucket.minio.contoso.com/keyname?versionId=v1")
+
+	// AWS URLs are unaffected, and never use plain HTTP
+	u, _ = url.Parse("http://bucket.s3.amazonaws.com/keyname")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsCustomEndpoint(), chk.Equals, false)
+	c.Assert(p.IsInsecure(), chk.Equals, false)
+
+	// hosts that merely contain the endpoint are not accepted
+	u, _ = url.Parse("https://notminio.contoso.com/bucket")
+	_, err = NewS3URLParts(*u)
+	c.Assert(err, chk.NotNil)
+
+	err = SetS3CustomEndpoint("ftp://minio.contoso.com")
+	c.Assert(err, chk.NotNil)
+	err = SetS3CustomEndpoint("https://minio.contoso.com/bucket")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *s3URLPartsTestSuite) TestS3SyncComparableMD5FromETag(c *chk.C) {
+	md5Hex := "9e107d9d372bb6826bd81d3542a419d6"
+	stat := func(etag string, headers http.Header) *ObjectInfoExtension {
+		return &ObjectInfoExtension{ObjectInfo: minio.ObjectInfo{ETag: etag, Metadata: headers}}
+	}
+
+	// a plain ETag from a HEAD request is the MD5 of the content, but only for comparing it
+	expected, _ := hex.DecodeString(md5Hex)
+	c.Assert(stat(`"`+md5Hex+`"`, http.Header{"Etag": {md5Hex}}).SyncComparableMD5(), chk.DeepEquals, expected)
+	c.Assert(stat(`"`+md5Hex+`"`, http.Header{"Etag": {md5Hex}}).ContentMD5(), chk.IsNil)
+
+	// an explicit Content-MD5 wins over the ETag
+	c.Assert(stat(md5Hex, http.Header{"Content-Md5": {"AQI="}}).SyncComparableMD5(), chk.DeepEquals, []byte{1, 2})
+	c.Assert(stat(md5Hex, http.Header{"Content-Md5": {"AQI="}}).ContentMD5(), chk.DeepEquals, []byte{1, 2})
+
+	// multipart, SSE-KMS and SSE-C ETags aren't hashes of the content, and listings carry no headers to tell
+	c.Assert(stat(md5Hex+"-2", http.Header{"Etag": {md5Hex}}).SyncComparableMD5(), chk.IsNil)
+	c.Assert(stat(md5Hex, http.Header{"X-Amz-Server-Side-Encryption": {"aws:kms"}}).SyncComparableMD5(), chk.IsNil)
+	c.Assert(stat(md5Hex, http.Header{"X-Amz-Server-Side-Encryption-Customer-Algorithm": {"AES256"}}).SyncComparableMD5(), chk.IsNil)
+	c.Assert(stat(md5Hex, nil).SyncComparableMD5(), chk.IsNil)
+}
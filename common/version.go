@@ -4,4 +4,5 @@ const AzcopyVersion = "10.19.0"
 const UserAgent = "AzCopy/" + AzcopyVersion
 const S3ImportUserAgent = "S3Import " + UserAgent
 const GCPImportUserAgent = "GCPImport " + UserAgent
+const HttpImportUserAgent = "HttpImport " + UserAgent
 const BenchmarkUserAgent = "Benchmark " + UserAgent
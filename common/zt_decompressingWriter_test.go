@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
+	"github.com/klauspost/compress/zstd"
 	chk "gopkg.in/check.v1"
 	"io"
 	"math/rand"
@@ -65,6 +66,10 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_SuccessCases(c *chk.C
 		{"big zlib", ECompressionType.ZLib(), 10 * 1024 * 1024, rand.Intn(1024*1024) + 1},
 		{"sml zlib", ECompressionType.ZLib(), 1024, rand.Intn(1024*1024) + 1},
 		{"1bytzlib", ECompressionType.ZLib(), 1234, 1},
+
+		{"big zstd", ECompressionType.ZStd(), 10 * 1024 * 1024, rand.Intn(1024*1024) + 1},
+		{"sml zstd", ECompressionType.ZStd(), 1024, rand.Intn(1024*1024) + 1},
+		{"1bytzstd", ECompressionType.ZStd(), 1234, 1},
 	}
 
 	for _, cs := range cases {
@@ -95,6 +100,7 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_EarlyClose(c *chk.C)
 	cases := []CompressionType{
 		ECompressionType.GZip(),
 		ECompressionType.ZLib(),
+		ECompressionType.ZStd(),
 	}
 	for _, tp := range cases {
 		// given:
@@ -126,6 +132,10 @@ func (d *decompressingWriterSuite) getTestData(c *chk.C, tp CompressionType, ori
 	var comp io.WriteCloser = zlib.NewWriter(compBuf)
 	if tp == ECompressionType.GZip() {
 		comp = gzip.NewWriter(compBuf)
+	} else if tp == ECompressionType.ZStd() {
+		enc, err := zstd.NewWriter(compBuf)
+		c.Assert(err, chk.IsNil)
+		comp = enc
 	}
 	_, err := io.Copy(comp, bytes.NewReader(originalData))
 	// write into buf by way of comp
@@ -135,6 +145,53 @@ func (d *decompressingWriterSuite) getTestData(c *chk.C, tp CompressionType, ori
 	return originalData, compressedData
 }
 
+func (d *decompressingWriterSuite) TestDecompressingWriter_CompressedChunks(c *chk.C) {
+	for _, tp := range []CompressionType{ECompressionType.GZip(), ECompressionType.ZStd()} {
+		// given:
+		// data that is compressed one chunk at a time, as it is when uploading with compression
+		originalData := d.genCompressibleTestData(3*1024*1024 + 123)
+		chunkSize := 1024 * 1024
+		var compressedData []byte
+		for offset := 0; offset < len(originalData); offset += chunkSize {
+			end := offset + chunkSize
+			if end > len(originalData) {
+				end = len(originalData)
+			}
+			compressedChunk, err := CompressChunk(tp, bytes.NewReader(originalData[offset:end]))
+			c.Assert(err, chk.IsNil)
+			compressedData = append(compressedData, compressedChunk...)
+		}
+
+		// when:
+		// the concatenated chunks are decompressed as one stream
+		destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+		decWriter := NewDecompressingWriter(destFile, tp)
+		_, err := io.Copy(decWriter, bytes.NewReader(compressedData))
+		c.Assert(err, chk.IsNil)
+		c.Assert(decWriter.Close(), chk.IsNil)
+
+		// then:
+		// we get the whole of the original data back
+		c.Assert(destFile.Bytes(), chk.DeepEquals, originalData)
+	}
+}
+
+func (d *decompressingWriterSuite) TestCompressChunk_Empty(c *chk.C) {
+	for _, tp := range []CompressionType{ECompressionType.GZip(), ECompressionType.ZStd()} {
+		// an empty file still gets a valid (if tiny) compressed stream, so that it can be decompressed on download
+		compressedData, err := CompressChunk(tp, bytes.NewReader(nil))
+		c.Assert(err, chk.IsNil)
+		c.Assert(len(compressedData) > 0, chk.Equals, true)
+
+		destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+		decWriter := NewDecompressingWriter(destFile, tp)
+		_, err = decWriter.Write(compressedData)
+		c.Assert(err, chk.IsNil)
+		c.Assert(decWriter.Close(), chk.IsNil)
+		c.Assert(destFile.Len(), chk.Equals, 0)
+	}
+}
+
 /* Manual sanity check of compressible data gen
 func (d *decompressingWriterSuite) TestDecompressingWriter_GenTestData(c *chk.C) {
 	f, _ := os.Create("<yourfoldergoeshere>\\testGen4373462.dat")
@@ -102,6 +102,10 @@ var JobsAdmin interface {
 
 	SetConcurrencySettingsToAuto()
 
+	// SetDaemonMode makes the jobs created from now on run as in a long-running process that serves many jobs,
+	// each with its own log file, instead of as the job of the current command.
+	SetDaemonMode()
+
 	// JobMgrCleanUp do the JobMgr cleanup.
 	JobMgrCleanUp(jobId common.JobID)
 	ListJobs(givenStatus common.JobStatus) common.ListJobsResponse
@@ -136,6 +140,7 @@ func initJobsAdmin(appCtx context.Context, concurrency ste.ConcurrencySettings,
 		logDir:                  azcopyLogPathFolder,
 		planDir:                 azcopyJobPlanFolder,
 		pacer:                   pacer,
+		jobShares:               ste.NewJobShares(),
 		slicePool:               common.NewMultiSizeSlicePool(common.MaxBlockBlobBlockSize),
 		cacheLimiter:            common.NewCacheLimiter(maxRamBytesToUse),
 		fileCountLimiter:        common.NewCacheLimiter(int64(concurrency.MaxOpenDownloadFiles)),
@@ -250,6 +255,7 @@ type jobsAdmin struct {
 	planDir                 string // Initialize to directory where Job Part Plans are stored
 	appCtx                  context.Context
 	pacer                   ste.PacerAdmin
+	jobShares               *ste.JobShares // divides the workers and bandwidth between the jobs running together
 	slicePool               common.ByteSlicePooler
 	cacheLimiter            common.CacheLimiter
 	fileCountLimiter        common.CacheLimiter
@@ -258,6 +264,7 @@ type jobsAdmin struct {
 	provideBenchmarkResults bool
 	cpuMonitor              common.CPUMonitor
 	jobLogger               common.ILoggerResetable
+	daemonMode              bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -297,8 +304,14 @@ func (ja *jobsAdmin) JobMgrEnsureExists(jobID common.JobID,
 
 	return ja.jobIDToJobMgr.EnsureExists(jobID,
 		func() ste.IJobMgr {
+			jobLogger := ja.jobLogger
+			if ja.daemonMode {
+				// the log of the current command isn't the log of this job, so the job gets its own
+				jobLogger = common.NewJobLogger(jobID, level, ja.logDir, "" /* logFileNameSuffix */)
+				jobLogger.OpenLog()
+			}
 			// Return existing or new IJobMgr to caller
-			return ste.NewJobMgr(ja.concurrency, jobID, ja.appCtx, ja.cpuMonitor, level, commandString, ja.logDir, ja.concurrencyTuner, ja.pacer, ja.slicePool, ja.cacheLimiter, ja.fileCountLimiter, ja.jobLogger, false, sourceBlobToken)
+			return ste.NewJobMgr(ja.concurrency, jobID, ja.appCtx, ja.cpuMonitor, level, commandString, ja.logDir, ja.concurrencyTuner, ja.pacer, ja.jobShares, ja.slicePool, ja.cacheLimiter, ja.fileCountLimiter, jobLogger, ja.daemonMode, sourceBlobToken)
 		})
 }
 
@@ -472,6 +485,11 @@ func (ja *jobsAdmin) SetConcurrencySettingsToAuto() {
 	ja.concurrencyTuner = ja.createConcurrencyTuner()
 }
 
+// SetDaemonMode must be called before any job is created, since it doesn't apply to the jobs that already exist.
+func (ja *jobsAdmin) SetDaemonMode() {
+	ja.daemonMode = true
+}
+
 // TODO: I think something is wrong here: I think delete and cleanup should be merged together.
 // DeleteJobInfo api deletes an entry of given JobId the JobsInfo
 // TODO: add the clean up logic for all Jobparts.
@@ -24,10 +24,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
-	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -61,7 +62,6 @@ func MainSTE(concurrency ste.ConcurrencySettings, targetRateInMegaBitsPerSec flo
 	initJobsAdmin(steCtx, concurrency, targetRateInMegaBitsPerSec, azcopyJobPlanFolder, azcopyLogPathFolder, providePerfAdvice)
 	// No need to read the existing JobPartPlan files since Azcopy is running in process
 	// JobsAdmin.ResurrectJobParts()
-	// TODO: We may want to list listen first and terminate if there is already an instance listening
 
 	// if we've a custom mime map
 	if path := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.MimeMapping()); path != "" {
@@ -79,83 +79,7 @@ func MainSTE(concurrency ste.ConcurrencySettings, targetRateInMegaBitsPerSec flo
 		ste.EnvironmentMimeMap = config.MIMETypeMapping
 	}
 
-	deserialize := func(request *http.Request, v interface{}) {
-		// TODO: Check the HTTP verb here?
-		// reading the entire request body and closing the request body
-		body, err := io.ReadAll(request.Body)
-		request.Body.Close()
-		if err != nil {
-			JobsAdmin.Panic(fmt.Errorf("error deserializing HTTP request"))
-		}
-		_ = json.Unmarshal(body, v)
-	}
-	serialize := func(v interface{}, response http.ResponseWriter) {
-		payload, err := json.Marshal(response)
-		if err != nil {
-			JobsAdmin.Panic(fmt.Errorf("error serializing HTTP response"))
-		}
-		// sending successful response back to front end
-		response.WriteHeader(http.StatusAccepted)
-		_, _ = response.Write(payload)
-	}
-	http.HandleFunc(common.ERpcCmd.CopyJobPartOrder().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			var payload common.CopyJobPartOrderRequest
-			deserialize(request, &payload)
-			serialize(ExecuteNewCopyJobPartOrder(payload), writer)
-		})
-	http.HandleFunc(common.ERpcCmd.ListJobs().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			// var payload common.ListRequest
-			// deserialize(request, &payload)
-			serialize(ListJobs(common.EJobStatus.All()), writer)
-		})
-	http.HandleFunc(common.ERpcCmd.ListJobSummary().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			var payload common.JobID
-			deserialize(request, &payload)
-			serialize(GetJobSummary(payload), writer)
-		})
-	http.HandleFunc(common.ERpcCmd.ListJobTransfers().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			var payload common.ListJobTransfersRequest
-			deserialize(request, &payload)
-			serialize(ListJobTransfers(payload), writer) // TODO: make struct
-		})
-	/*
-		http.HandleFunc(common.ERpcCmd.CancelJob().Pattern(),
-			func(writer http.ResponseWriter, request *http.Request) {
-				var payload common.JobID
-				deserialize(request, &payload)
-				serialize(CancelPauseJobOrder(payload, common.EJobStatus.Cancelling()), writer)
-			})
-		http.HandleFunc(common.ERpcCmd.PauseJob().Pattern(),
-			func(writer http.ResponseWriter, request *http.Request) {
-				var payload common.JobID
-				deserialize(request, &payload)
-				serialize(CancelPauseJobOrder(payload, common.EJobStatus.Paused()), writer)
-			})
-	*/
-	http.HandleFunc(common.ERpcCmd.ResumeJob().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			var payload common.ResumeJobRequest
-			deserialize(request, &payload)
-			serialize(ResumeJobOrder(payload), writer)
-		})
-
-	http.HandleFunc(common.ERpcCmd.GetJobFromTo().Pattern(),
-		func(writer http.ResponseWriter, request *http.Request) {
-			var payload common.GetJobFromToRequest
-			deserialize(request, &payload)
-			serialize(GetJobFromTo(payload), writer)
-		})
-
-	// Listen for front-end requests
-	// if err := http.ListenAndServe("localhost:1337", nil); err != nil {
-	//	fmt.Print("Server already initialized")
-	//	return err
-	// }
-	return nil // TODO: don't return (like normal main)
+	return nil
 }
 
 // /////////////////////////////////////////////////////////////////////////////
@@ -324,6 +248,13 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		}
 	}
 
+	if errorMsg := mixedDirectionResumeError(jm, jpm.Plan().FromTo); len(errorMsg) != 0 {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              fmt.Sprintf("cannot resume job with JobId %s. %s", req.JobID, errorMsg),
+		}
+	}
+
 	// If the credential type is is Anonymous, to resume the Job destinationSAS / sourceSAS needs to be provided
 	// Depending on the FromType, sourceSAS or destinationSAS is checked.
 	if req.CredentialInfo.CredentialType == common.ECredentialType.Anonymous() {
@@ -375,6 +306,13 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		}
 	}
 
+	if errorMsg := blobS3ResumeError(req, jpm.Plan()); len(errorMsg) != 0 {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              fmt.Sprintf("cannot resume job with JobId %s. %s", req.JobID, errorMsg),
+		}
+	}
+
 	// After creating the Job mgr, set the include / exclude list of transfer.
 	jm.SetIncludeExclude(req.IncludeTransfer, req.ExcludeTransfer)
 	jpp0 := jpm.Plan()
@@ -400,7 +338,8 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		// Get credential info from RPC request, and set in InMemoryTransitJobState.
 		jm.SetInMemoryTransitJobState(
 			ste.InMemoryTransitJobState{
-				CredentialInfo: req.CredentialInfo,
+				CredentialInfo:          req.CredentialInfo,
+				S2SSourceCredentialType: req.S2SSourceCredentialType,
 			})
 
 		jpp0.SetJobStatus(common.EJobStatus.InProgress())
@@ -440,6 +379,35 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 	return jr
 }
 
+// mixedDirectionResumeError returns why a job whose parts don't all transfer in the same direction can't be resumed, or nothing if they do.
+// A two-way sync transfers in both directions in one job, but a resumed job gives the source SAS to the source of every part,
+// and the destination SAS to the destination, so the parts of the other direction would get the SAS of the wrong side.
+func mixedDirectionResumeError(jm ste.IJobMgr, fromTo common.FromTo) string {
+	for p := ste.PartNumber(1); true; p++ {
+		jpm, found := jm.JobPartMgr(p)
+		if !found {
+			break
+		}
+		if partFromTo := jpm.Plan().FromTo; partFromTo != fromTo {
+			return fmt.Sprintf("It transfers both %s and %s, as a two-way sync does, which can't be resumed. Run the sync again instead, which also retries the files that failed", fromTo, partFromTo)
+		}
+	}
+	return ""
+}
+
+// blobS3ResumeError returns why a BlobS3 job can't be resumed with the given request, or nothing if it can.
+// When copying to S3 we authenticate to the destination with an access key, so the check for anonymous credentials doesn't apply,
+// but the source blob still needs its SAS back, unless it's public or read with OAuth.
+func blobS3ResumeError(req common.ResumeJobRequest, plan *ste.JobPartPlanHeader) string {
+	if plan.FromTo != common.EFromTo.BlobS3() || len(req.SourceSAS) != 0 || req.S2SSourceCredentialType.IsAzureOAuth() {
+		return ""
+	}
+	if common.IsSourcePublicBlob(string(plan.SourceRoot[:plan.SourceRootLength]), steCtx) {
+		return ""
+	}
+	return "The source-sas switch must be provided to resume the job"
+}
+
 // GetJobSummary api returns the job progress summary of an active job
 /*
 * Return following Properties in Job Progress Summary
@@ -518,6 +486,9 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 	if part0PlanStatus == common.EJobStatus.Cancelled() {
 		js.JobStatus = part0PlanStatus
 		js.PerformanceAdvice = JobsAdmin.TryGetPerformanceAdvice(js.TotalBytesExpected, js.TotalTransfers-js.TransfersSkipped, part0.Plan().FromTo, dir, p)
+	} else if part0PlanStatus == common.EJobStatus.Paused() {
+		// a paused job isn't done, but it isn't in progress either
+		js.JobStatus = part0PlanStatus
 	} else {
 		// Job is completed if Job order is complete AND ALL transfers are completed/failed
 		// FIX: active or inactive state, then job order is said to be completed if final part of job has been ordered.
@@ -717,15 +688,70 @@ func ListJobTransfers(r common.ListJobTransfersRequest) common.ListJobTransfersR
 					!(r.OfStatus == common.ETransferStatus.Failed() && transferEntry.TransferStatus() <= common.ETransferStatus.Failed())) {
 				continue
 			}
+			if !transferMatches(r, jpp, t) {
+				continue
+			}
 			// getting source and destination of a transfer at index index for given jobId and part number.
 			src, dst, isFolder := jpp.TransferSrcDstStrings(t)
-			ljt.Details = append(ljt.Details,
-				common.TransferDetail{Src: src, Dst: dst, IsFolderProperties: isFolder, TransferStatus: transferEntry.TransferStatus(), ErrorCode: transferEntry.ErrorCode()})
+			detail := common.TransferDetail{Src: src, Dst: dst, IsFolderProperties: isFolder, TransferStatus: transferEntry.TransferStatus(),
+				TransferSize: uint64(transferEntry.SourceSize), ErrorCode: transferEntry.ErrorCode()}
+			if completedAt := transferEntry.CompletedAt(); !completedAt.IsZero() {
+				detail.CompletionTime = &completedAt
+			}
+			ljt.Details = append(ljt.Details, detail)
 		}
 	}
 	return ljt
 }
 
+// transferMatches says whether the transfer at the given index passes the optional filters of the request
+func transferMatches(r common.ListJobTransfersRequest, jpp *ste.JobPartPlanHeader, t uint32) bool {
+	transferEntry := jpp.Transfer(t)
+	if transferEntry.SourceSize < r.MinSize || (r.MaxSize != 0 && transferEntry.SourceSize > r.MaxSize) {
+		return false
+	}
+	if len(r.ErrorCodes) != 0 {
+		found := false
+		for _, errorCode := range r.ErrorCodes {
+			found = found || transferEntry.ErrorCode() == errorCode
+		}
+		if !found {
+			return false
+		}
+	}
+	if !r.CompletedAfter.IsZero() || !r.CompletedBefore.IsZero() {
+		// transfers that aren't done have no completion time, so they are left out of any date range
+		completedAt := transferEntry.CompletedAt()
+		if completedAt.IsZero() ||
+			(!r.CompletedAfter.IsZero() && completedAt.Before(r.CompletedAfter)) ||
+			(!r.CompletedBefore.IsZero() && completedAt.After(r.CompletedBefore)) {
+			return false
+		}
+	}
+	if r.PathPattern != "" {
+		relativePath, _ := jpp.GetRelativeSrcDstStrings(t)
+		if relativePath == "" {
+			// the source root is the file itself
+			relativePath = path.Base(strings.ReplaceAll(string(jpp.SourceRoot[:jpp.SourceRootLength]), "\\", "/"))
+		}
+		if jpp.FromTo.From().IsRemote() {
+			// the relative paths of remote sources are escaped in the plan
+			if unescaped, err := url.PathUnescape(relativePath); err == nil {
+				relativePath = unescaped
+			}
+		}
+		relativePath = strings.TrimPrefix(strings.ReplaceAll(relativePath, "\\", "/"), "/")
+		if !strings.Contains(r.PathPattern, "/") {
+			// like the include-pattern flag of copy, a pattern without a separator is matched against the name
+			relativePath = path.Base(relativePath)
+		}
+		if matched, _ := path.Match(r.PathPattern, relativePath); !matched {
+			return false
+		}
+	}
+	return true
+}
+
 func GetJobLCMWrapper(jobID common.JobID) common.LifecycleMgr {
 	jobmgr, found := JobsAdmin.JobMgr(jobID)
 	lcm := common.GetLifecycleMgr()
@@ -0,0 +1,189 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package jobsAdmin
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+// Hookup to the testing framework
+func Test(t *testing.T) { chk.TestingT(t) }
+
+type listJobTransfersSuite struct{}
+
+var _ = chk.Suite(&listJobTransfersSuite{})
+
+// createPlan writes a job part plan with the given transfers, and maps it
+func (s *listJobTransfersSuite) createPlan(c *chk.C, fromTo common.FromTo, sourceRoot string, transfers ...common.CopyTransfer) *ste.JobPartPlanMMF {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	jobID := common.NewJobID()
+	planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion))
+	planFile.Create(common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		IsFinalPart:     true,
+		FromTo:          fromTo,
+		SourceRoot:      common.ResourceString{Value: sourceRoot},
+		DestinationRoot: common.ResourceString{Value: "/data/destination"},
+		Transfers:       common.Transfers{List: transfers},
+	})
+	return planFile.Map()
+}
+
+// matching returns the indexes of the transfers of the plan that the request lets through
+func (s *listJobTransfersSuite) matching(r common.ListJobTransfersRequest, jpp *ste.JobPartPlanHeader) []uint32 {
+	matched := []uint32{}
+	for t := uint32(0); t < jpp.NumTransfers; t++ {
+		if transferMatches(r, jpp, t) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+func (s *listJobTransfersSuite) TestPathPattern(c *chk.C) {
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source",
+		common.CopyTransfer{Source: "/report.csv", Destination: "/report.csv"},
+		common.CopyTransfer{Source: "/logs/app.log", Destination: "/logs/app.log"},
+		common.CopyTransfer{Source: "/logs/2023/app.csv", Destination: "/logs/2023/app.csv"},
+	)
+	defer mmf.Unmap()
+
+	testCases := []struct {
+		pattern  string
+		expected []uint32
+	}{
+		{"", []uint32{0, 1, 2}},
+		// without a separator, the pattern is matched against the name, wherever the file is
+		{"*.csv", []uint32{0, 2}},
+		{"app.*", []uint32{1, 2}},
+		// with one, it's matched against the whole relative path, without a leading separator
+		{"logs/*", []uint32{1}},
+		{"logs/*/*.csv", []uint32{2}},
+		{"/logs/*", []uint32{}},
+		{"[", []uint32{}}, // a malformed pattern matches nothing
+	}
+	for _, t := range testCases {
+		c.Check(s.matching(common.ListJobTransfersRequest{PathPattern: t.pattern}, mmf.Plan()), chk.DeepEquals, t.expected, chk.Commentf(t.pattern))
+	}
+}
+
+func (s *listJobTransfersSuite) TestPathPatternOfRemoteSource(c *chk.C) {
+	// the relative paths of remote sources are escaped in the plan, but the pattern is matched against the real names
+	mmf := s.createPlan(c, common.EFromTo.BlobLocal(), "https://account.blob.core.windows.net/container",
+		common.CopyTransfer{Source: "/my%20dir/a%23b.txt", Destination: "/my dir/a#b.txt"},
+		common.CopyTransfer{Source: "/other/c.txt", Destination: "/other/c.txt"},
+	)
+	defer mmf.Unmap()
+
+	c.Assert(s.matching(common.ListJobTransfersRequest{PathPattern: "my dir/*"}, mmf.Plan()), chk.DeepEquals, []uint32{0})
+	c.Assert(s.matching(common.ListJobTransfersRequest{PathPattern: "a#b.txt"}, mmf.Plan()), chk.DeepEquals, []uint32{0})
+	c.Assert(s.matching(common.ListJobTransfersRequest{PathPattern: "*%20*"}, mmf.Plan()), chk.DeepEquals, []uint32{})
+}
+
+func (s *listJobTransfersSuite) TestPathPatternOfSingleFile(c *chk.C) {
+	// when a single file is transferred, its relative path is empty, and the name comes from the source root
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source/report.csv",
+		common.CopyTransfer{Source: "", Destination: ""},
+	)
+	defer mmf.Unmap()
+
+	c.Assert(s.matching(common.ListJobTransfersRequest{PathPattern: "*.csv"}, mmf.Plan()), chk.DeepEquals, []uint32{0})
+	c.Assert(s.matching(common.ListJobTransfersRequest{PathPattern: "*.log"}, mmf.Plan()), chk.DeepEquals, []uint32{})
+}
+
+func (s *listJobTransfersSuite) TestSizeRange(c *chk.C) {
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source",
+		common.CopyTransfer{Source: "/empty", Destination: "/empty", SourceSize: 0},
+		common.CopyTransfer{Source: "/small", Destination: "/small", SourceSize: 10},
+		common.CopyTransfer{Source: "/big", Destination: "/big", SourceSize: 1000},
+	)
+	defer mmf.Unmap()
+
+	testCases := []struct {
+		minSize, maxSize int64
+		expected         []uint32
+	}{
+		{0, 0, []uint32{0, 1, 2}}, // a maximum of zero is no limit
+		{10, 0, []uint32{1, 2}},
+		{0, 10, []uint32{0, 1}}, // the bounds are inclusive
+		{10, 10, []uint32{1}},
+		{11, 999, []uint32{}},
+	}
+	for _, t := range testCases {
+		c.Check(s.matching(common.ListJobTransfersRequest{MinSize: t.minSize, MaxSize: t.maxSize}, mmf.Plan()), chk.DeepEquals, t.expected,
+			chk.Commentf("%d-%d", t.minSize, t.maxSize))
+	}
+}
+
+func (s *listJobTransfersSuite) TestErrorCodes(c *chk.C) {
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source",
+		common.CopyTransfer{Source: "/ok", Destination: "/ok"},
+		common.CopyTransfer{Source: "/conflict", Destination: "/conflict"},
+		common.CopyTransfer{Source: "/forbidden", Destination: "/forbidden"},
+	)
+	defer mmf.Unmap()
+	mmf.Plan().Transfer(1).SetErrorCode(409, true)
+	mmf.Plan().Transfer(2).SetErrorCode(403, true)
+
+	c.Assert(s.matching(common.ListJobTransfersRequest{}, mmf.Plan()), chk.DeepEquals, []uint32{0, 1, 2})
+	c.Assert(s.matching(common.ListJobTransfersRequest{ErrorCodes: []int32{409}}, mmf.Plan()), chk.DeepEquals, []uint32{1})
+	c.Assert(s.matching(common.ListJobTransfersRequest{ErrorCodes: []int32{403, 409}}, mmf.Plan()), chk.DeepEquals, []uint32{1, 2})
+	c.Assert(s.matching(common.ListJobTransfersRequest{ErrorCodes: []int32{500}}, mmf.Plan()), chk.DeepEquals, []uint32{})
+}
+
+func (s *listJobTransfersSuite) TestCompletionTimeRange(c *chk.C) {
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source",
+		common.CopyTransfer{Source: "/pending", Destination: "/pending"},
+		common.CopyTransfer{Source: "/early", Destination: "/early"},
+		common.CopyTransfer{Source: "/late", Destination: "/late"},
+	)
+	defer mmf.Unmap()
+	early := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	late := early.Add(time.Hour)
+	mmf.Plan().Transfer(1).SetCompletionTime(early)
+	mmf.Plan().Transfer(2).SetCompletionTime(late)
+
+	testCases := []struct {
+		name          string
+		after, before time.Time
+		expected      []uint32
+	}{
+		{"no range", time.Time{}, time.Time{}, []uint32{0, 1, 2}},
+		// a transfer that isn't done is left out of any range
+		{"after", early.Add(time.Minute), time.Time{}, []uint32{2}},
+		{"before", time.Time{}, early.Add(time.Minute), []uint32{1}},
+		{"inclusive", early, late, []uint32{1, 2}},
+		{"between", early.Add(time.Minute), late.Add(-time.Minute), []uint32{}},
+	}
+	for _, t := range testCases {
+		c.Check(s.matching(common.ListJobTransfersRequest{CompletedAfter: t.after, CompletedBefore: t.before}, mmf.Plan()), chk.DeepEquals, t.expected,
+			chk.Commentf(t.name))
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package jobsAdmin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-azcopy/v10/ste"
+)
+
+type resumeJobSuite struct{}
+
+var _ = chk.Suite(&resumeJobSuite{})
+
+// blobSource is a stand-in for the source account of a job, which is public or not
+type blobSource struct {
+	*httptest.Server
+	requests int32
+}
+
+func newBlobSource(public bool) *blobSource {
+	s := &blobSource{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if public && r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	return s
+}
+
+func (s *resumeJobSuite) createPlan(c *chk.C, fromTo common.FromTo, sourceRoot string) *ste.JobPartPlanMMF {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+
+	jobID := common.NewJobID()
+	planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), 0, ste.DataSchemaVersion))
+	planFile.Create(common.CopyJobPartOrderRequest{
+		JobID:           jobID,
+		IsFinalPart:     true,
+		FromTo:          fromTo,
+		SourceRoot:      common.ResourceString{Value: sourceRoot},
+		DestinationRoot: common.ResourceString{Value: "https://s3.amazonaws.com/bucket"},
+		Transfers:       common.Transfers{List: []common.CopyTransfer{{Source: "/blob.txt", Destination: "/blob.txt"}}},
+	})
+	return planFile.Map()
+}
+
+func (s *resumeJobSuite) TestResumeBlobS3WithSourceSAS(c *chk.C) {
+	source := newBlobSource(false)
+	defer source.Close()
+	mmf := s.createPlan(c, common.EFromTo.BlobS3(), source.URL+"/account/container")
+	defer mmf.Unmap()
+
+	// without the SAS, a private source can't be read
+	c.Assert(blobS3ResumeError(common.ResumeJobRequest{}, mmf.Plan()), chk.Equals, "The source-sas switch must be provided to resume the job")
+	c.Assert(atomic.LoadInt32(&source.requests) > 0, chk.Equals, true)
+
+	// with it, the job can be resumed without asking the source
+	atomic.StoreInt32(&source.requests, 0)
+	c.Assert(blobS3ResumeError(common.ResumeJobRequest{SourceSAS: "sv=2020-10-02&sig=signature"}, mmf.Plan()), chk.Equals, "")
+	c.Assert(atomic.LoadInt32(&source.requests), chk.Equals, int32(0))
+}
+
+func (s *resumeJobSuite) TestResumeBlobS3WithSourceOAuth(c *chk.C) {
+	source := newBlobSource(false)
+	defer source.Close()
+	mmf := s.createPlan(c, common.EFromTo.BlobS3(), source.URL+"/account/container")
+	defer mmf.Unmap()
+
+	// the credential of the job is the S3 access key, but the source is read with OAuth, so it needs no SAS
+	for _, credType := range []common.CredentialType{common.ECredentialType.OAuthToken(), common.ECredentialType.MDOAuthToken()} {
+		req := common.ResumeJobRequest{
+			CredentialInfo:          common.CredentialInfo{CredentialType: common.ECredentialType.S3AccessKey()},
+			S2SSourceCredentialType: credType,
+		}
+		c.Assert(blobS3ResumeError(req, mmf.Plan()), chk.Equals, "", chk.Commentf(credType.String()))
+	}
+	c.Assert(atomic.LoadInt32(&source.requests), chk.Equals, int32(0))
+
+	// while the source type of a public source is anonymous, and it's found to be public
+	public := newBlobSource(true)
+	defer public.Close()
+	publicMMF := s.createPlan(c, common.EFromTo.BlobS3(), public.URL+"/account/container/blob.txt")
+	defer publicMMF.Unmap()
+	req := common.ResumeJobRequest{S2SSourceCredentialType: common.ECredentialType.Anonymous()}
+	c.Assert(blobS3ResumeError(req, publicMMF.Plan()), chk.Equals, "")
+}
+
+func (s *resumeJobSuite) TestResumeOtherJobsIsNotCheckedForBlobS3(c *chk.C) {
+	mmf := s.createPlan(c, common.EFromTo.LocalBlob(), "/data/source")
+	defer mmf.Unmap()
+	c.Assert(blobS3ResumeError(common.ResumeJobRequest{}, mmf.Plan()), chk.Equals, "")
+}
+
+// TestResumeMixedDirectionJob resumes a job like the ones of a two-way sync, which transfer in both directions
+func (s *resumeJobSuite) TestResumeMixedDirectionJob(c *chk.C) {
+	if JobsAdmin == nil {
+		initJobsAdmin(steCtx, ste.NewConcurrencySettings(1000, false), 0, c.MkDir(), c.MkDir(), false)
+	}
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = JobsAdmin.(*jobsAdmin).planDir
+
+	jobID := common.NewJobID()
+	for partNum, fromTo := range []common.FromTo{common.EFromTo.LocalBlob(), common.EFromTo.BlobLocal()} {
+		sourceRoot, destinationRoot := "/data/source", "https://account.blob.core.windows.net/container"
+		if fromTo == common.EFromTo.BlobLocal() {
+			sourceRoot, destinationRoot = destinationRoot, sourceRoot
+		}
+		planFile := ste.JobPartPlanFileName(fmt.Sprintf(ste.JobPartPlanFileNameFormat, jobID.String(), partNum, ste.DataSchemaVersion))
+		planFile.Create(common.CopyJobPartOrderRequest{
+			JobID:           jobID,
+			PartNum:         common.PartNumber(partNum),
+			IsFinalPart:     partNum == 1,
+			FromTo:          fromTo,
+			Fpo:             common.EFolderPropertiesOption.NoFolders(),
+			SourceRoot:      common.ResourceString{Value: sourceRoot},
+			DestinationRoot: common.ResourceString{Value: destinationRoot},
+			Transfers:       common.Transfers{List: []common.CopyTransfer{{Source: "/file.txt", Destination: "/file.txt"}}},
+		})
+	}
+	defer JobsAdmin.JobMgrCleanUp(jobID)
+
+	resp := ResumeJobOrder(common.ResumeJobRequest{JobID: jobID, DestinationSAS: "sv=2020-10-02&sig=signature",
+		CredentialInfo: common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()}})
+	c.Assert(resp.CancelledPauseResumed, chk.Equals, false)
+	c.Assert(resp.ErrorMsg, chk.Equals, fmt.Sprintf("cannot resume job with JobId %s. It transfers both LocalBlob and BlobLocal, as a two-way sync does, "+
+		"which can't be resumed. Run the sync again instead, which also retries the files that failed", jobID))
+
+	// and nothing was restarted
+	jm, found := JobsAdmin.JobMgr(jobID)
+	c.Assert(found, chk.Equals, true)
+	jm.IterateJobParts(false, func(partNum common.PartNumber, jpm ste.IJobPartMgr) {
+		c.Assert(jpm.Plan().JobStatus(), chk.Equals, common.EJobStatus.InProgress())
+		c.Assert(jpm.Plan().Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/Azure/azure-storage-azcopy/v10/common"
@@ -13,7 +14,7 @@ import (
 // dataSchemaVersion defines the data schema version of JobPart order files supported by
 // current version of azcopy
 // To be Incremented every time when we release azcopy with changed dataSchema
-const DataSchemaVersion common.Version = 18
+const DataSchemaVersion common.Version = 21
 
 const (
 	CustomHeaderMaxBytes = 256
@@ -340,6 +341,12 @@ type JobPartPlanDstBlob struct {
 	BlockSize int64
 
 	SetPropertiesFlags common.SetPropertiesFlags
+
+	// Specifies whether the job uploads a whole directory tree into one archive blob
+	ArchiveFormat common.ArchiveFormat
+
+	// Specifies whether the content of each file is compressed as it is uploaded
+	CompressionType common.CompressionType
 }
 
 // JobPartPlanDstFile holds additional settings required when the destination is a file
@@ -358,6 +365,9 @@ type JobPartPlanDstLocal struct {
 
 	// says how MD5 verification failures should be actioned
 	MD5VerificationOption common.HashValidationOption
+
+	// Specifies the type of the hash that sync keeps in the metadata of the source, and records in the hash data of the destination file
+	SyncHashType common.SyncHashType
 }
 
 // //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -433,6 +443,20 @@ func (jppt *JobPartPlanTransfer) SetTransferStatus(status common.TransferStatus,
 	}
 }
 
+// CompletedAt returns the time at which the transfer was done, or the zero time if it isn't done yet
+func (jppt *JobPartPlanTransfer) CompletedAt() time.Time {
+	completionTime := atomic.LoadUint64(&jppt.CompletionTime)
+	if completionTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(completionTime))
+}
+
+// SetCompletionTime records the time at which the transfer was done, whether it succeeded or not
+func (jppt *JobPartPlanTransfer) SetCompletionTime(t time.Time) {
+	atomic.StoreUint64(&jppt.CompletionTime, uint64(t.UnixNano()))
+}
+
 // ErrorCode returns the transfer's errorCode.
 func (jppt *JobPartPlanTransfer) ErrorCode() int32 {
 	return atomic.LoadInt32(&jppt.atomicErrorCode)
@@ -74,6 +74,27 @@ func (jpfn JobPartPlanFileName) Map() *JobPartPlanMMF {
 	return (*JobPartPlanMMF)(mmf)
 }
 
+// ReadJobPartPlanHeader reads the header of a job part plan file that isn't mapped, such as one that was copied from another machine,
+// for it to be checked before it's put in the plan folder. The file must be long enough to hold the transfers that the header lists.
+func ReadJobPartPlanHeader(file io.Reader, fileSize int64) (*JobPartPlanHeader, error) {
+	header := &JobPartPlanHeader{}
+	headerSize := unsafe.Sizeof(*header)
+	if fileSize < int64(headerSize) {
+		return nil, errors.New("the file is too short to be a job part plan file")
+	}
+	// the header is written as it's laid out in memory; see Create
+	headerBytes := unsafe.Slice((*byte)(unsafe.Pointer(header)), headerSize)
+	if _, err := io.ReadFull(file, headerBytes); err != nil {
+		return nil, err
+	}
+
+	transfersOffset := (int64(headerSize) + int64(header.CommandStringLength) + 7) & ^int64(7)
+	if fileSize < transfersOffset+int64(unsafe.Sizeof(JobPartPlanTransfer{}))*int64(header.NumTransfers) {
+		return nil, fmt.Errorf("the file is too short to hold the %d transfers of the job part plan", header.NumTransfers)
+	}
+	return header, nil
+}
+
 // createJobPartPlanFile creates the memory map JobPartPlanHeader using the given JobPartOrder and JobPartPlanBlobData
 func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	if jpfn.Exists() {
@@ -203,10 +224,13 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			CpkScopeInfoLength:       uint16(len(order.CpkOptions.CpkScopeInfo)),
 			IsSourceEncrypted:        order.CpkOptions.IsSourceEncrypted,
 			SetPropertiesFlags:       order.SetPropertiesFlags,
+			ArchiveFormat:            order.BlobAttributes.ArchiveFormat,
+			CompressionType:          order.BlobAttributes.CompressionType,
 		},
 		DstLocalData: JobPartPlanDstLocal{
 			PreserveLastModifiedTime: order.BlobAttributes.PreserveLastModifiedTime,
 			MD5VerificationOption:    order.BlobAttributes.MD5ValidationOption, // here because it relates to downloads (file destination)
+			SyncHashType:             order.BlobAttributes.SyncHashType,
 		},
 		PreservePermissions:     order.PreserveSMBPermissions,
 		PreserveSMBInfo:         order.PreserveSMBInfo,
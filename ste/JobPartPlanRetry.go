@@ -0,0 +1,192 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// A job can be retried by a new job that holds only the transfers of the first one that failed.
+// The link between the two is recorded next to the plan files of the new job, so that "jobs rm" and "jobs clean" remove it along with them,
+// like the member list of an archive job.
+const retryLinkFileNameFormat = "%v--retry.steV%d.link"
+
+// RetryLink records that a job retries the failed transfers of another job
+type RetryLink struct {
+	JobID       common.JobID // the job that retries the transfers
+	RetryOf     common.JobID // the job whose transfers failed
+	Transfers   uint32       // the number of transfers that are retried
+	Destination string       `json:",omitempty"` // set when the transfers are retried to another destination than the one of the first job
+	CreatedAt   time.Time
+}
+
+func RetryLinkPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+retryLinkFileNameFormat, common.AzcopyJobPlanFolder, common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+func WriteRetryLink(link RetryLink) error {
+	content, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(RetryLinkPath(link.JobID), content, common.DEFAULT_FILE_PERM)
+}
+
+// ReadRetryLinks returns the links of all the retry jobs in the plan folder, so that a job can be traced both to the job it retries and to the jobs that retry it
+func ReadRetryLinks() ([]RetryLink, error) {
+	files, err := os.ReadDir(common.AzcopyJobPlanFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := fmt.Sprintf(retryLinkFileNameFormat, "", DataSchemaVersion)
+	var links []RetryLink
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), suffix) {
+			continue
+		}
+		content, err := os.ReadFile(common.GenerateFullPath(common.AzcopyJobPlanFolder, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var link RetryLink
+		if err = json.Unmarshal(content, &link); err != nil {
+			return nil, fmt.Errorf("invalid retry link %s: %w", f.Name(), err)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// FailedTransfers returns the indices of the transfers of the part that failed
+func (jpph *JobPartPlanHeader) FailedTransfers() []uint32 {
+	var failed []uint32
+	for t := uint32(0); t < jpph.NumTransfers; t++ {
+		if jpph.Transfer(t).TransferStatus().DidFail() {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+// stringsLength returns the length of the strings of the transfer, which follow each other from its SrcOffset
+func (jppt *JobPartPlanTransfer) stringsLength() int64 {
+	return int64(jppt.SrcLength) + int64(jppt.DstLength) + int64(jppt.SrcContentTypeLength) +
+		int64(jppt.SrcContentEncodingLength) + int64(jppt.SrcContentLanguageLength) + int64(jppt.SrcContentDispositionLength) +
+		int64(jppt.SrcCacheControlLength) + int64(jppt.SrcContentMD5Length) + int64(jppt.SrcMetadataLength) +
+		int64(jppt.SrcBlobTypeLength) + int64(jppt.SrcBlobTierLength) + int64(jppt.SrcBlobVersionIDLength) + int64(jppt.SrcBlobSnapshotIDLength) + int64(jppt.SrcBlobTagsLength)
+}
+
+// CreateRetry writes a job part plan file that holds some of the transfers of an existing part, which usually belongs to another job.
+// The part keeps all the options of the existing one, as well as its command string, while the transfers start over.
+// If a destination root is given, it replaces the one of the existing part; the destinations of the transfers are relative to it.
+func (jpfn JobPartPlanFileName) CreateRetry(from *JobPartPlanHeader, transfers []uint32, isFinalPart bool, destinationRoot *common.ResourceString) error {
+	jobID, partNum, err := jpfn.Parse()
+	if err != nil {
+		return err
+	}
+	if jpfn.Exists() {
+		return fmt.Errorf("the job part plan file %s already exists", jpfn)
+	}
+	if from.Version != DataSchemaVersion {
+		return fmt.Errorf("the job part has version %d, but this version of AzCopy only supports version %d", from.Version, DataSchemaVersion)
+	}
+
+	// the options are constant, so they can be copied as they are, while the fields that change as the part runs are reset
+	jpph := *from
+	jpph.StartTime = time.Now().UnixNano()
+	jpph.JobID = jobID
+	jpph.PartNum = partNum
+	jpph.IsFinalPart = isFinalPart
+	jpph.NumTransfers = uint32(len(transfers))
+	jpph.atomicJobStatus = common.EJobStatus.InProgress()
+	jpph.atomicPartStatus = common.EJobStatus.InProgress()
+	if destinationRoot != nil {
+		if len(destinationRoot.Value) > len(jpph.DestinationRoot) {
+			return fmt.Errorf("destination root string is too large: %q", destinationRoot.Value)
+		}
+		if len(destinationRoot.ExtraQuery) > len(jpph.DestExtraQuery) {
+			return fmt.Errorf("destination extra query strings too large: %q", destinationRoot.ExtraQuery)
+		}
+		// do NOT copy the SAS, since we do NOT persist SASs
+		jpph.DestinationRoot, jpph.DestExtraQuery = [1000]byte{}, [1000]byte{}
+		jpph.DestinationRootLength = uint16(copy(jpph.DestinationRoot[:], destinationRoot.Value))
+		jpph.DestExtraQueryLength = uint16(copy(jpph.DestExtraQuery[:], destinationRoot.ExtraQuery))
+	}
+
+	file, err := os.Create(jpfn.GetJobPartPlanPath())
+	if err != nil {
+		return fmt.Errorf("couldn't create job part plan file %q: %w", jpfn, err)
+	}
+	writer := bufio.NewWriter(file)
+
+	// the file is laid out as Create lays it out: the header, the command string, padding to 8 bytes, the transfers, and then their strings
+	write := func(p unsafe.Pointer, size uintptr) {
+		if err == nil {
+			_, err = writer.Write(unsafe.Slice((*byte)(p), size))
+		}
+	}
+	write(unsafe.Pointer(&jpph), unsafe.Sizeof(jpph))
+	commandString := from.CommandString()
+	if err == nil {
+		_, err = writer.WriteString(commandString)
+	}
+	eof := int64(unsafe.Sizeof(jpph)) + int64(len(commandString))
+	if paddingLen := ((eof + 7) & ^7) - eof; err == nil && paddingLen != 0 {
+		_, err = writer.Write(make([]byte, paddingLen))
+		eof += paddingLen
+	}
+
+	srcOffset := eof + int64(unsafe.Sizeof(JobPartPlanTransfer{}))*int64(len(transfers))
+	for _, t := range transfers {
+		jppt := *from.Transfer(t)
+		jppt.SrcOffset = srcOffset
+		jppt.CompletionTime = 0
+		jppt.atomicTransferStatus = common.ETransferStatus.Started() // Default
+		jppt.atomicErrorCode = 0
+		write(unsafe.Pointer(&jppt), unsafe.Sizeof(jppt))
+		srcOffset += jppt.stringsLength()
+	}
+	for _, t := range transfers {
+		jppt := from.Transfer(t)
+		write(unsafe.Pointer(uintptr(unsafe.Pointer(from))+uintptr(jppt.SrcOffset)), uintptr(jppt.stringsLength()))
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(jpfn.GetJobPartPlanPath())
+		return fmt.Errorf("couldn't write job part plan file %q: %w", jpfn, err)
+	}
+	return nil
+}
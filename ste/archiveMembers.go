@@ -0,0 +1,104 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// ArchiveMember is one enumerated file, folder or symlink that an archive upload packs into its blob.
+// An archive job has a single transfer (the source root to the archive blob), so the members can't be persisted
+// as transfers in the job part plan. Instead, the front-end writes them to a member list next to the plan files,
+// which is what lets the job be resumed.
+type ArchiveMember struct {
+	Name         string            `json:"name"`         // the path inside the archive, always using forward slashes
+	RelativePath string            `json:"relativePath"` // the path of the source, relative to the source root of the job
+	EntityType   common.EntityType `json:"entityType"`
+}
+
+// The member list lives in the plan folder, and has the job ID and ".steV" in its name,
+// so that "jobs rm" and "jobs clean" remove it along with the plan files.
+// It must not end with the plan file extension though, or it would be mistaken for a job part plan.
+const archiveMemberListFileNameFormat = "%v--archive.steV%d.members"
+
+func ArchiveMemberListPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+archiveMemberListFileNameFormat, common.AzcopyJobPlanFolder, common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+// ArchiveMemberListWriter streams the members of an archive job to its member list, one JSON object per line,
+// so that the front-end never holds the whole enumerated tree in memory.
+type ArchiveMemberListWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewArchiveMemberListWriter(jobID common.JobID) (*ArchiveMemberListWriter, error) {
+	file, err := os.Create(ArchiveMemberListPath(jobID))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create the archive member list: %w", err)
+	}
+
+	buffer := bufio.NewWriter(file)
+	return &ArchiveMemberListWriter{file: file, buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+}
+
+func (w *ArchiveMemberListWriter) Write(member ArchiveMember) error {
+	return w.encoder.Encode(member)
+}
+
+func (w *ArchiveMemberListWriter) Close() error {
+	err := w.buffer.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readArchiveMemberList calls process for each member of the archive job, in the order they were enumerated.
+func readArchiveMemberList(jobID common.JobID, process func(ArchiveMember) error) error {
+	file, err := os.Open(ArchiveMemberListPath(jobID))
+	if err != nil {
+		return fmt.Errorf("couldn't open the archive member list, which is saved with the job plan files: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var member ArchiveMember
+		err = decoder.Decode(&member)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("the archive member list is corrupt: %w", err)
+		}
+
+		if err = process(member); err != nil {
+			return err
+		}
+	}
+}
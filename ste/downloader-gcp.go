@@ -0,0 +1,105 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"time"
+
+	gcpUtils "cloud.google.com/go/storage"
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type gcpDownloader struct {
+	jptm   IJobPartTransferMgr
+	txInfo TransferInfo
+
+	// GCS has no azure pipeline, so the client (and the parsed URL it is bound to) come from the source info provider
+	gcpClient   *gcpUtils.Client
+	gcpURLParts common.GCPURLParts
+}
+
+func newGCPDownloader() downloader {
+	return &gcpDownloader{}
+}
+
+func (bd *gcpDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	bd.jptm = jptm
+	bd.txInfo = jptm.Info()
+
+	sip, err := newGCPSourceInfoProvider(jptm)
+	if err != nil {
+		jptm.FailActiveDownload("Creating GCP client", err)
+		return
+	}
+	gcpsip := sip.(*gcpSourceInfoProvider)
+	bd.gcpClient = gcpsip.gcpClient
+	bd.gcpURLParts = gcpsip.gcpURLParts
+}
+
+func (bd *gcpDownloader) Epilogue() {
+	// nothing to clean up, the GCP client is cached by gcpClientFactory
+}
+
+// Returns a chunk-func for GCS downloads
+func (bd *gcpDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if bd.gcpClient == nil {
+			jptm.FailActiveDownload("Downloading response body", errors.New("GCP client was not initialized"))
+			return
+		}
+
+		// Read the object exactly as stored. Otherwise GCS would transparently decompress gzip-encoded objects,
+		// and neither the chunk lengths nor the MD5 hash (which is computed over the stored bytes) would line up.
+		obj := bd.gcpClient.Bucket(bd.gcpURLParts.BucketName).Object(bd.gcpURLParts.ObjectKey).ReadCompressed(true)
+
+		// At this point we create an HTTP(S) request for the desired portion of the object, and
+		// wait until we get the headers back... but we have not yet read its whole body.
+		// The GCS client encapsulates any retries that may be necessary to get to the point of receiving response headers.
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		reader, err := obj.NewRangeReader(jptm.Context(), id.OffsetInFile(), length)
+		if err != nil {
+			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
+			return
+		}
+		defer reader.Close()
+
+		// Verify that the object has not been changed via a client side LMT check.
+		// The Last-Modified header only has second precision, whereas the listing gives us milliseconds.
+		remoteLastModified := reader.Attrs.LastModified
+		if lmt := jptm.LastModifiedTime(); !lmt.IsZero() && !remoteLastModified.IsZero() &&
+			!remoteLastModified.Truncate(time.Second).Equal(lmt.Truncate(time.Second)) {
+			jptm.FailActiveDownload("GCP object modified during transfer",
+				errors.New("GCP object modified during transfer"))
+			return
+		}
+
+		// Enqueue the response body to be written out to disk
+		// The GCS reader resumes interrupted reads internally, so closing it is not a way to force a retry
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), reader, pacer), false)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
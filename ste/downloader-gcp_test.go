@@ -0,0 +1,194 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// fakeGCS serves ranged reads of objects, path-style, the way the GCS XML API does.
+// The GCS client talks to it when STORAGE_EMULATOR_HOST points at it.
+type fakeGCS struct {
+	*httptest.Server
+	lmt time.Time
+
+	lock    sync.Mutex
+	objects map[string][]byte // keyed by bucket/object
+	ranges  []string
+}
+
+func newFakeGCS() *fakeGCS {
+	g := &fakeGCS{objects: make(map[string][]byte), lmt: time.Now().UTC().Truncate(time.Second)}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serveHTTP))
+	return g
+}
+
+func (g *fakeGCS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	content, ok := g.objects[strings.TrimPrefix(r.URL.Path, "/")]
+	if r.Method != http.MethodGet || !ok {
+		http.NotFound(w, r)
+		return
+	}
+	rangeHeader := r.Header.Get("Range")
+	g.ranges = append(g.ranges, rangeHeader)
+
+	var first, last int64
+	if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &first, &last); err != nil || last >= int64(len(content)) {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, len(content)))
+	w.Header().Set("Content-Length", strconv.FormatInt(last-first+1, 10))
+	w.Header().Set("Last-Modified", g.lmt.Format(http.TimeFormat))
+	w.Header().Set("X-Goog-Generation", "1")
+	w.WriteHeader(http.StatusPartialContent)
+	_, _ = w.Write(content[first : last+1])
+}
+
+func (g *fakeGCS) putObject(bucket, object string, content []byte) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.objects[bucket+"/"+object] = content
+}
+
+type gcpDownloaderSuite struct {
+	gcs      *fakeGCS
+	listed   time.Time // the last modified time that the enumeration saw
+	restores []func()
+}
+
+var _ = chk.Suite(&gcpDownloaderSuite{})
+
+func (s *gcpDownloaderSuite) setEnv(key, value string) {
+	old, existed := os.LookupEnv(key)
+	os.Setenv(key, value)
+	s.restores = append(s.restores, func() {
+		if existed {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func (s *gcpDownloaderSuite) SetUpTest(c *chk.C) {
+	s.gcs = newFakeGCS()
+	s.listed = s.gcs.lmt
+	s.setEnv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(s.gcs.URL, "http://"))
+
+	// the source info provider insists on reading a key file, although the emulated client doesn't authenticate
+	keyFile := filepath.Join(c.MkDir(), "key.json")
+	c.Assert(os.WriteFile(keyFile, []byte("{}"), 0600), chk.IsNil)
+	s.setEnv(common.EEnvironmentVariable.GoogleAppCredentials().Name, keyFile)
+
+	// clients are cached for the lifetime of the process, so start from none, to get one that talks to this test's server
+	gcpClientFactory = common.NewGCPClientFactory()
+}
+
+func (s *gcpDownloaderSuite) TearDownTest(c *chk.C) {
+	gcpClientFactory = common.NewGCPClientFactory()
+	for i := len(s.restores) - 1; i >= 0; i-- {
+		s.restores[i]()
+	}
+	s.restores = nil
+	s.gcs.Close()
+}
+
+// download downloads the given object of the fake GCS service in chunks of chunkSize, validating its MD5 against contentMD5
+func (s *gcpDownloaderSuite) download(c *chk.C, object string, size int64, contentMD5 []byte) (*testTransfer, string) {
+	destination := filepath.Join(c.MkDir(), "object")
+	dstFile, err := os.Create(destination)
+	c.Assert(err, chk.IsNil)
+	defer dstFile.Close()
+
+	t := newTestTransfer(common.EFromTo.GCPLocal(), TransferInfo{
+		Source:        "https://storage.cloud.google.com/bucket/" + object,
+		Destination:   destination,
+		SourceSize:    size,
+		EntityType:    common.EEntityType.File(),
+		SrcProperties: SrcProperties{SrcHTTPHeaders: common.ResourceHTTPHeaders{ContentMD5: contentMD5}},
+	})
+	t.lmt = s.listed
+	t.md5Option = common.EHashValidationOption.FailIfDifferent()
+	err = runDownload(c, t, newGCPDownloader(), dstFile, 4*1024)
+	c.Assert(err == nil, chk.Equals, len(t.failures) == 0 || t.failures[0] == errMd5Mismatch) // the MD5 is only checked once the write succeeded
+	return t, destination
+}
+
+func gcpTestContent() []byte {
+	content := make([]byte, 10*1024+5)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func (s *gcpDownloaderSuite) TestGCPDownloaderValidatesTheMD5OfTheObject(c *chk.C) {
+	content := gcpTestContent()
+	s.gcs.putObject("bucket", "dir/object.bin", content)
+	hash := md5.Sum(content)
+
+	t, destination := s.download(c, "dir/object.bin", int64(len(content)), hash[:])
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	written, err := os.ReadFile(destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(written, content), chk.Equals, true)
+	c.Assert(s.gcs.ranges, chk.DeepEquals, []string{"bytes=0-4095", "bytes=4096-8191", "bytes=8192-10244"})
+}
+
+func (s *gcpDownloaderSuite) TestGCPDownloaderFailsWhenTheMD5DoesNotMatch(c *chk.C) {
+	content := gcpTestContent()
+	s.gcs.putObject("bucket", "dir/object.bin", content)
+	hash := md5.Sum(append([]byte("not "), content...))
+
+	t, _ := s.download(c, "dir/object.bin", int64(len(content)), hash[:])
+	c.Assert(t.failures, chk.DeepEquals, []error{errMd5Mismatch})
+}
+
+func (s *gcpDownloaderSuite) TestGCPDownloaderFailsWhenTheObjectWasModified(c *chk.C) {
+	content := gcpTestContent()
+	s.gcs.putObject("bucket", "dir/object.bin", content)
+	hash := md5.Sum(content)
+	s.gcs.lmt = s.gcs.lmt.Add(time.Minute)
+
+	t, _ := s.download(c, "dir/object.bin", int64(len(content)), hash[:])
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(t.failures[0], chk.ErrorMatches, "GCP object modified during transfer")
+	c.Assert(s.gcs.ranges, chk.HasLen, 1) // the first chunk fails the transfer, which skips the rest
+}
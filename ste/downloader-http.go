@@ -0,0 +1,145 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+var errHTTPSourceModified = errors.New("the source was modified during transfer")
+
+type httpDownloader struct {
+	jptm   IJobPartTransferMgr
+	txInfo TransferInfo
+
+	// strong entity tag of the source as of the start of the download, used to pin every chunk to the same version
+	etag string
+}
+
+func newHTTPDownloader() downloader {
+	return &httpDownloader{}
+}
+
+func (bd *httpDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	bd.jptm = jptm
+	bd.txInfo = jptm.Info()
+
+	if bd.txInfo.SourceSize == 0 {
+		return // there are no chunks to pin to a version of the source
+	}
+
+	sip, err := newHTTPSourceInfoProvider(jptm)
+	if err != nil {
+		jptm.FailActiveDownload("Creating source info provider", err)
+		return
+	}
+	props, err := sip.(*httpSourceInfoProvider).getFreshProperties()
+	if err != nil {
+		jptm.FailActiveDownload("Getting source properties", err)
+		return
+	}
+
+	// The Last-Modified header only has second precision, so compare at that precision
+	if lmt := jptm.LastModifiedTime(); !lmt.IsZero() && !props.LastModified().Truncate(time.Second).Equal(lmt.Truncate(time.Second)) {
+		jptm.FailActiveDownload("Getting source properties", errHTTPSourceModified)
+		return
+	}
+
+	// weak entity tags can't be used with If-Match, so they are no better than no entity tag at all
+	if etag := props.ETag(); etag != "" && !strings.HasPrefix(etag, "W/") {
+		bd.etag = etag
+	}
+}
+
+func (bd *httpDownloader) Epilogue() {
+	// nothing to clean up
+}
+
+// Returns a chunk-func for generic HTTP(S) downloads
+func (bd *httpDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		u, err := url.Parse(bd.txInfo.Source)
+		if err != nil {
+			jptm.FailActiveDownload("Parsing source URL", err)
+			return
+		}
+
+		req, err := pipeline.NewRequest(http.MethodGet, *u, nil)
+		if err != nil {
+			jptm.FailActiveDownload("Creating request", err)
+			return
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", id.OffsetInFile(), id.OffsetInFile()+length-1))
+		req.Header.Set("Accept-Encoding", "identity") // otherwise the lengths would not line up
+
+		// set access conditions, to protect against inconsistencies from changes-while-being-read
+		if bd.etag != "" {
+			req.Header.Set("If-Match", bd.etag)
+		} else if lmt := jptm.LastModifiedTime(); !lmt.IsZero() {
+			req.Header.Set("If-Unmodified-Since", lmt.UTC().Format(http.TimeFormat))
+		}
+
+		// At this point we create an HTTP(S) request for the desired portion of the resource, and
+		// wait until we get the headers back... but we have not yet read its whole body.
+		// The pipeline encapsulates any retries that may be necessary to get to the point of receiving response headers.
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		resp, err := srcPipeline.Do(jptm.Context(), nil, req)
+		if err != nil {
+			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
+			return
+		}
+		body := resp.Response().Body
+		defer body.Close()
+
+		switch status := resp.Response().StatusCode; {
+		case status == http.StatusPartialContent:
+		case status == http.StatusOK && id.OffsetInFile() == 0 && length == bd.txInfo.SourceSize:
+			// the server ignored the range, but it covers the whole resource anyway
+		case status == http.StatusOK:
+			jptm.FailActiveDownload("Downloading response body",
+				errors.New("the server does not support ranged reads, so the source can only be downloaded in a single chunk. Try a --block-size-mb at least as large as the file"))
+			return
+		case status == http.StatusPreconditionFailed:
+			jptm.FailActiveDownload("Downloading response body", errHTTPSourceModified)
+			return
+		default:
+			jptm.FailActiveDownload("Downloading response body", fmt.Errorf("the server returned %d %s", status, http.StatusText(status)))
+			return
+		}
+
+		// Enqueue the response body to be written out to disk
+		// There is no retry reader for a generic server, so this chunk is not retryable by closing it
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), false)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
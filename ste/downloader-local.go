@@ -0,0 +1,121 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// localDownloader is used for local-to-local copies. The destination side is exactly the same as for a download,
+// so we reuse all of that (file creation, the ChunkedFileWriter, MD5 checks, POSIX properties etc.) and just read the chunks from a local file
+type localDownloader struct {
+	jptm    IJobPartTransferMgr
+	txInfo  TransferInfo
+	sip     ILocalSourceInfoProvider
+	srcFile common.CloseableReaderAt
+}
+
+func newLocalDownloader() downloader {
+	return &localDownloader{}
+}
+
+func (ld *localDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	ld.jptm = jptm
+	ld.txInfo = jptm.Info()
+
+	sip, err := newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		jptm.FailActiveDownload("Creating source info provider", err)
+		return
+	}
+	ld.sip = sip.(ILocalSourceInfoProvider)
+
+	ld.srcFile, err = ld.sip.OpenSourceFile()
+	if err != nil {
+		jptm.FailActiveDownload("Opening source file", err)
+		return
+	}
+}
+
+func (ld *localDownloader) Epilogue() {
+	if ld.srcFile != nil {
+		_ = ld.srcFile.Close()
+	}
+
+	if ld.jptm != nil && ld.sip != nil {
+		if ld.jptm.IsLive() && ld.txInfo.PreservePOSIXProperties {
+			unixSIP, _ := ld.sip.(IUNIXPropertyBearingSourceInfoProvider)
+			if uld, ok := (interface{})(ld).(unixPropertyAwareDownloader); ok && unixSIP != nil && unixSIP.HasUNIXProperties() {
+				adapter, err := unixSIP.GetUNIXProperties()
+				if err != nil {
+					ld.jptm.FailActiveDownload("get unix properties", err)
+					return
+				}
+
+				stage, err := uld.ApplyUnixProperties(adapter)
+				if err != nil {
+					ld.jptm.FailActiveDownload("set unix properties: "+stage, err)
+				}
+			}
+		}
+	}
+}
+
+// Returns a chunk-func for local-to-local copies
+func (ld *localDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if ld.srcFile == nil {
+			jptm.FailActiveDownload("Reading source file", errors.New("source file was not opened"))
+			return
+		}
+
+		// There are no access conditions on a local read. Instead, the source's LMT is checked once the whole file has been written,
+		// in epilogueWithCleanupDownload.
+		// Reading from disk is not retryable by closing the body, in the way that a network response is.
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		body := io.NopCloser(io.NewSectionReader(ld.srcFile, id.OffsetInFile(), length))
+		err := destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), false)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
+
+func (ld *localDownloader) CreateSymlink(jptm IJobPartTransferMgr) error {
+	sip, err := newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		return err
+	}
+	symsip := sip.(ISymlinkBearingSourceInfoProvider) // local always implements this
+	symlinkInfo, err := symsip.ReadLink()
+	if err != nil {
+		return err
+	}
+
+	// create the link
+	return os.Symlink(symlinkInfo, jptm.Info().Destination)
+}
@@ -0,0 +1,154 @@
+//go:build linux
+// +build linux
+
+package ste
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"golang.org/x/sys/unix"
+)
+
+// CreateFile covers the following UNIX properties:
+// File Mode, File Type
+// TODO: Consolidate with the blob and blobFS downloaders, which this mirrors
+func (ld *localDownloader) CreateFile(jptm IJobPartTransferMgr, destination string, size int64, writeThrough bool, t FolderCreationTracker) (file io.WriteCloser, needChunks bool, err error) {
+	var sip ISourceInfoProvider
+	sip, err = newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		return
+	}
+
+	unixSIP := sip.(IUNIXPropertyBearingSourceInfoProvider) // local files always have unix properties on linux
+
+	err = common.CreateParentDirectoryIfNotExist(destination, t)
+	if err != nil {
+		return
+	}
+
+	// try to remove the file before we create something else over it
+	_ = os.Remove(destination)
+
+	needChunks = size > 0
+	var mode = uint32(common.DEFAULT_FILE_PERM)
+	if jptm.Info().PreservePOSIXProperties {
+		var stat common.UnixStatAdapter
+		stat, err = unixSIP.GetUNIXProperties()
+		if err != nil {
+			return
+		}
+
+		if !stat.Extended() || stat.StatxMask()&common.STATX_MODE == common.STATX_MODE {
+			mode = stat.FileMode() | common.DEFAULT_FILE_PERM // We need to retain access to the file until we're well & done with it
+		}
+
+		switch {
+		case mode&common.S_IFBLK == common.S_IFBLK || mode&common.S_IFCHR == common.S_IFCHR:
+			// the file is representative of a device and does not need to be written to
+			err = unix.Mknod(destination, mode, int(stat.RDevice()))
+			return nil, false, err
+		case mode&common.S_IFIFO == common.S_IFIFO || mode&common.S_IFSOCK == common.S_IFSOCK:
+			// the file is a pipe and does not need to be written to
+			err = unix.Mknod(destination, mode, 0)
+			return nil, false, err
+		}
+	}
+
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if writeThrough {
+		flags |= os.O_SYNC
+	}
+
+	file, err = os.OpenFile(destination, flags, os.FileMode(mode)&os.ModePerm)
+	if err != nil {
+		return
+	}
+
+	if size == 0 {
+		return
+	}
+
+	err = syscall.Fallocate(int(file.(*os.File).Fd()), 0, 0, size)
+	if err == syscall.ENOTSUP {
+		err = file.(*os.File).Truncate(size) // err will get returned at the end
+	}
+
+	return
+}
+
+func (ld *localDownloader) ApplyUnixProperties(adapter common.UnixStatAdapter) (stage string, err error) {
+	// At this point, mode has already been applied. Let's work out what we need to apply, and apply the rest.
+	destination := ld.txInfo.Destination
+
+	fi, err := os.Stat(destination)
+	if err != nil {
+		return "stat", err
+	}
+	stat := fi.Sys().(*syscall.Stat_t)
+
+	mode := os.FileMode(adapter.FileMode())
+	uid, gid := adapter.Owner(), adapter.Group()
+	atime, mtime := adapter.ATime(), adapter.MTime()
+
+	if adapter.Extended() {
+		// only apply what statx actually returned for the source. stx_attributes is not persisted.
+		mask := adapter.StatxMask()
+
+		if !common.StatXReturned(mask, common.STATX_MODE) {
+			mode = os.FileMode(common.DEFAULT_FILE_PERM)
+		}
+		if !common.StatXReturned(mask, common.STATX_UID) {
+			uid = stat.Uid
+		}
+		if !common.StatXReturned(mask, common.STATX_GID) {
+			gid = stat.Gid
+		}
+		if !common.StatXReturned(mask, common.STATX_ATIME) && adapter.ATime().IsZero() { // workaround for noatime when underlying fs supports atime
+			atime = time.Unix(stat.Atim.Unix())
+		}
+		if !common.StatXReturned(mask, common.STATX_MTIME) {
+			mtime = time.Unix(stat.Mtim.Unix())
+		}
+	}
+
+	if err = os.Chmod(destination, mode); err != nil {
+		return "chmod", err
+	}
+	if err = os.Chown(destination, int(uid), int(gid)); err != nil {
+		return "chown", err
+	}
+	if err = os.Chtimes(destination, atime, mtime); err != nil {
+		return "chtimes", err
+	}
+
+	return
+}
+
+func (ld *localDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
+	if !jptm.Info().PreservePOSIXProperties {
+		return nil
+	}
+
+	sip, err := newLocalSourceInfoProvider(jptm)
+	if err != nil {
+		return err
+	}
+
+	ld.txInfo = jptm.Info() // inform our localDownloader a bit.
+
+	props, err := sip.(IUNIXPropertyBearingSourceInfoProvider).GetUNIXProperties()
+	if err != nil {
+		return err
+	}
+	stage, err := ld.ApplyUnixProperties(props)
+	if err != nil {
+		return fmt.Errorf("set unix properties: %s; %w", stage, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+//go:build linux
+// +build linux
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// sourceOwner returns the owner to give the source, which can only differ from ours when we run as root
+func sourceOwner() (uid, gid int) {
+	if os.Geteuid() == 0 {
+		return 1234, 5678
+	}
+	return os.Geteuid(), os.Getegid()
+}
+
+func (s *localDownloaderSuite) TestLocalDownloaderPreservesUnixProperties(c *chk.C) {
+	dir := c.MkDir()
+	source := filepath.Join(dir, "source.txt")
+	c.Assert(os.WriteFile(source, []byte("posix properties"), 0600), chk.IsNil)
+	c.Assert(os.Chmod(source, 0751), chk.IsNil)
+	uid, gid := sourceOwner()
+	c.Assert(os.Chown(source, uid, gid), chk.IsNil)
+	atime, mtime := time.Unix(1500000000, 0), time.Unix(1600000000, 0)
+	c.Assert(os.Chtimes(source, atime, mtime), chk.IsNil)
+
+	destination := filepath.Join(dir, "sub", "destination.txt")
+	t := newTestTransfer(common.EFromTo.LocalLocal(), TransferInfo{Source: source, Destination: destination, SourceSize: 16, EntityType: common.EEntityType.File(), PreservePOSIXProperties: true})
+	dl := newLocalDownloader().(*localDownloader)
+	dstFile, needChunks, err := dl.CreateFile(t, destination, t.info.SourceSize, false, &nullFolderTracker{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(needChunks, chk.Equals, true)
+	c.Assert(runDownload(c, t, dl, dstFile.(*os.File), 5), chk.IsNil)
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	// reading the source may have moved its atime on (relatime), so the destination should have the source's atime as of now
+	srcInfo, err := os.Stat(source)
+	c.Assert(err, chk.IsNil)
+	fi, err := os.Stat(destination)
+	c.Assert(err, chk.IsNil)
+	stat := fi.Sys().(*syscall.Stat_t)
+	c.Assert(fi.Mode().Perm(), chk.Equals, os.FileMode(0751))
+	c.Assert(int(stat.Uid), chk.Equals, uid)
+	c.Assert(int(stat.Gid), chk.Equals, gid)
+	c.Assert(stat.Atim, chk.Equals, srcInfo.Sys().(*syscall.Stat_t).Atim)
+	c.Assert(fi.ModTime().Equal(mtime), chk.Equals, true)
+
+	written, err := os.ReadFile(destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(written), chk.Equals, "posix properties")
+}
+
+func (s *localDownloaderSuite) TestApplyUnixPropertiesOnlyAppliesWhatStatxReturned(c *chk.C) {
+	destination := filepath.Join(c.MkDir(), "destination.txt")
+	c.Assert(os.WriteFile(destination, []byte("posix properties"), 0600), chk.IsNil)
+	before, err := os.Stat(destination)
+	c.Assert(err, chk.IsNil)
+	uid, gid := before.Sys().(*syscall.Stat_t).Uid, before.Sys().(*syscall.Stat_t).Gid
+
+	// the source's statx returned its mode and atime, but neither its owner nor its mtime
+	adapter := StatxTAdapter{
+		Mask:  unix.STATX_MODE | unix.STATX_ATIME,
+		Mode:  0640,
+		Uid:   4321,
+		Gid:   8765,
+		Atime: unix.StatxTimestamp{Sec: 1500000000},
+		Mtime: unix.StatxTimestamp{Sec: 1600000000},
+	}
+	dl := &localDownloader{txInfo: TransferInfo{Destination: destination}}
+	stage, err := dl.ApplyUnixProperties(adapter)
+	c.Assert(err, chk.IsNil, chk.Commentf("stage %s", stage))
+
+	fi, err := os.Stat(destination)
+	c.Assert(err, chk.IsNil)
+	stat := fi.Sys().(*syscall.Stat_t)
+	c.Assert(fi.Mode().Perm(), chk.Equals, os.FileMode(0640))
+	c.Assert(stat.Uid, chk.Equals, uid)
+	c.Assert(stat.Gid, chk.Equals, gid)
+	c.Assert(time.Unix(stat.Atim.Unix()).Equal(time.Unix(1500000000, 0)), chk.Equals, true)
+	c.Assert(fi.ModTime().Equal(before.ModTime()), chk.Equals, true)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package ste
+
+func (ld *localDownloader) SetFolderProperties(jptm IJobPartTransferMgr) error {
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type localDownloaderSuite struct{}
+
+var _ = chk.Suite(&localDownloaderSuite{})
+
+func (s *localDownloaderSuite) TestLocalDownloaderCopiesTheSourceInChunks(c *chk.C) {
+	dir := c.MkDir()
+	content := make([]byte, 10*1024+7) // not a multiple of the chunk size, so the last chunk is short
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	source := filepath.Join(dir, "source.bin")
+	c.Assert(os.WriteFile(source, content, 0644), chk.IsNil)
+
+	destination := filepath.Join(dir, "destination.bin")
+	dstFile, err := os.Create(destination)
+	c.Assert(err, chk.IsNil)
+
+	t := newTestTransfer(common.EFromTo.LocalLocal(), TransferInfo{Source: source, Destination: destination, SourceSize: int64(len(content)), EntityType: common.EEntityType.File()})
+	c.Assert(runDownload(c, t, newLocalDownloader(), dstFile, 4*1024), chk.IsNil)
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	written, err := os.ReadFile(destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(written, content), chk.Equals, true)
+}
+
+func (s *localDownloaderSuite) TestLocalDownloaderFailsWhenTheSourceIsGone(c *chk.C) {
+	dir := c.MkDir()
+	t := newTestTransfer(common.EFromTo.LocalLocal(), TransferInfo{Source: filepath.Join(dir, "missing.bin"), Destination: filepath.Join(dir, "destination.bin"), SourceSize: 10, EntityType: common.EEntityType.File()})
+
+	dl := newLocalDownloader()
+	dl.Prologue(t, nil)
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(os.IsNotExist(t.failures[0]), chk.Equals, true)
+	dl.Epilogue()
+}
@@ -0,0 +1,105 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	minio "github.com/minio/minio-go"
+)
+
+type s3Downloader struct {
+	jptm   IJobPartTransferMgr
+	txInfo TransferInfo
+
+	// S3 has no azure pipeline, so the client (and the parsed URL it is bound to) come from the source info provider
+	s3Client  *minio.Client
+	s3URLPart common.S3URLParts
+}
+
+func newS3Downloader() downloader {
+	return &s3Downloader{}
+}
+
+func (bd *s3Downloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
+	bd.jptm = jptm
+	bd.txInfo = jptm.Info()
+
+	sip, err := newS3SourceInfoProvider(jptm)
+	if err != nil {
+		jptm.FailActiveDownload("Creating S3 client", err)
+		return
+	}
+	s3sip := sip.(*s3SourceInfoProvider)
+	bd.s3Client = s3sip.s3Client
+	bd.s3URLPart = s3sip.s3URLPart
+}
+
+func (bd *s3Downloader) Epilogue() {
+	// nothing to clean up, the S3 client is cached by s3ClientFactory
+}
+
+// Returns a chunk-func for S3 downloads
+func (bd *s3Downloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	return createDownloadChunkFunc(jptm, id, func() {
+		if bd.s3Client == nil {
+			jptm.FailActiveDownload("Downloading response body", errors.New("S3 client was not initialized"))
+			return
+		}
+
+		// request only the range of the object that belongs to this chunk
+		opts := minio.GetObjectOptions{}
+		if err := opts.SetRange(id.OffsetInFile(), id.OffsetInFile()+length-1); err != nil {
+			jptm.FailActiveDownload("Setting download range", err)
+			return
+		}
+
+		// set access conditions, to protect against inconsistencies from changes-while-being-read
+		if lmt := jptm.LastModifiedTime(); !lmt.IsZero() {
+			if err := opts.SetUnmodified(lmt); err != nil {
+				jptm.FailActiveDownload("Setting access conditions", err)
+				return
+			}
+		}
+
+		// At this point we create an HTTP(S) request for the desired portion of the object, and
+		// wait until we get the headers back... but we have not yet read its whole body.
+		// Core.GetObject issues the request immediately (unlike Client.GetObject, which defers it until the first read).
+		jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+		body, _, err := minio.Core{Client: bd.s3Client}.GetObject(bd.s3URLPart.BucketName, bd.s3URLPart.ObjectKey, opts)
+		if err != nil {
+			jptm.FailActiveDownload("Downloading response body", err) // cancel entire transfer because this chunk has failed
+			return
+		}
+		defer body.Close()
+
+		// Enqueue the response body to be written out to disk
+		// Unlike the azure SDKs, minio has no retry reader for the body, so this chunk is not retryable by closing it
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		err = destWriter.EnqueueChunk(jptm.Context(), id, length, newPacedResponseBody(jptm.Context(), body, pacer), false)
+		if err != nil {
+			jptm.FailActiveDownload("Enqueuing chunk", err)
+			return
+		}
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	minio "github.com/minio/minio-go"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type s3DownloaderSuite struct{}
+
+var _ = chk.Suite(&s3DownloaderSuite{})
+
+// download downloads the given key of the fake S3 service in chunks of chunkSize, and returns the transfer and the destination
+func (s *s3DownloaderSuite) download(c *chk.C, s3 *fakeS3, key string, size, chunkSize int64, dl downloader) (*testTransfer, string) {
+	destination := filepath.Join(c.MkDir(), "object")
+	dstFile, err := os.Create(destination)
+	c.Assert(err, chk.IsNil)
+	defer dstFile.Close()
+
+	t := newTestTransfer(common.EFromTo.S3Local(), TransferInfo{Source: s3.objectURL(key), Destination: destination, SourceSize: size, EntityType: common.EEntityType.File()})
+	err = runDownload(c, t, dl, dstFile, chunkSize)
+	c.Assert(err == nil, chk.Equals, len(t.failures) == 0) // the write only fails when the transfer did
+	return t, destination
+}
+
+func (s *s3DownloaderSuite) TestS3DownloaderDownloadsRangesOfTheObject(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	content := make([]byte, 10*1024+5)
+	for i := range content {
+		content[i] = byte(i % 249)
+	}
+	s3.putObject("dir/object.bin", content)
+
+	t, destination := s.download(c, s3, "dir/object.bin", int64(len(content)), 4*1024, newS3Downloader())
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	written, err := os.ReadFile(destination)
+	c.Assert(err, chk.IsNil)
+	c.Assert(bytes.Equal(written, content), chk.Equals, true)
+	c.Assert(s3.ranges, chk.DeepEquals, []string{"bytes=0-4095", "bytes=4096-8191", "bytes=8192-10244"})
+}
+
+// deletingDownloader deletes the object from the fake S3 service once it has downloaded the first chunk of it
+type deletingDownloader struct {
+	downloader
+	s3  *fakeS3
+	key string
+}
+
+func (d *deletingDownloader) GenerateDownloadFunc(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline, destWriter common.ChunkedFileWriter, id common.ChunkID, length int64, pacer pacer) chunkFunc {
+	download := d.downloader.GenerateDownloadFunc(jptm, srcPipeline, destWriter, id, length, pacer)
+	return func(workerID int) {
+		download(workerID)
+		d.s3.deleteObject(d.key)
+	}
+}
+
+func (s *s3DownloaderSuite) TestS3DownloaderFailsWhenTheObjectIsGonePartway(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	s3.putObject("object.bin", content)
+
+	dl := &deletingDownloader{downloader: newS3Downloader(), s3: s3, key: "object.bin"}
+	t, _ := s.download(c, s3, "object.bin", int64(len(content)), 4*1024, dl)
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(minio.ToErrorResponse(t.failures[0]).Code, chk.Equals, "NoSuchKey")
+}
+
+func (s *s3DownloaderSuite) TestS3DownloaderFailsWithBadCredentials(c *chk.C) {
+	s3, done := newFakeS3(c, "AKIAWRONG")
+	defer done()
+	s3.putObject("object.bin", []byte("content"))
+
+	t, _ := s.download(c, s3, "object.bin", 7, 4*1024, newS3Downloader())
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(minio.ToErrorResponse(t.failures[0]).Code, chk.Equals, "InvalidAccessKeyId")
+}
@@ -0,0 +1,275 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+const (
+	// How long a job that has had more than its share of the bandwidth sleeps before checking again whether it's its turn
+	fairShareWaitDuration = 50 * time.Millisecond
+
+	// How far, in seconds of traffic at the bandwidth cap, a job may get ahead of its share before it yields to the other jobs
+	fairShareSlackSeconds = 0.1
+)
+
+// priorityWeight returns the weight of a job of the given priority, relative to the other jobs running in the process
+func priorityWeight(priority common.JobPriority) float64 {
+	switch priority {
+	case common.EJobPriority.Low():
+		return 1
+	case common.EJobPriority.High():
+		return 16
+	default:
+		return 4
+	}
+}
+
+// JobShares divides the chunk workers and the bandwidth of the process between the jobs running in it, in proportion
+// to the weights of their priorities, so that a small urgent job isn't starved by a big background one.
+// A job that runs alone has everything, as if there were no sharing.
+type JobShares struct {
+	mu   sync.Mutex
+	jobs map[common.JobID]*jobShare
+}
+
+type jobShare struct {
+	weight float64
+
+	// bytes the pacer has allocated to the job, divided by its weight. The job that has been served least is the one
+	// whose turn it is.
+	served float64
+
+	// number of the job's requests that are waiting for the pacer
+	waiting int
+
+	// notified when the shares of the jobs change
+	changedCh chan<- struct{}
+}
+
+func NewJobShares() *JobShares {
+	return &JobShares{jobs: make(map[common.JobID]*jobShare)}
+}
+
+// join starts sharing with the given job. changedCh, which must be buffered, is notified whenever the job's share changes.
+// changedCh also identifies the job manager that joined: when a job is resumed, its new manager takes the share over
+// from the old one, whose cleanup may only call leave later on.
+func (s *JobShares) join(jobID common.JobID, priority common.JobPriority, changedCh chan<- struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.jobs[jobID]; ok {
+		if existing.changedCh != changedCh {
+			existing.weight = priorityWeight(priority)
+			existing.changedCh = changedCh
+			s.notifyChanged()
+		}
+		return
+	}
+	// a job joins at the position of the one served least, so it can't claim the bandwidth of the time it wasn't running
+	served := math.Inf(1)
+	for _, other := range s.jobs {
+		served = math.Min(served, other.served)
+	}
+	if math.IsInf(served, 1) {
+		served = 0
+	}
+	s.jobs[jobID] = &jobShare{weight: priorityWeight(priority), served: served, changedCh: changedCh}
+	s.notifyChanged()
+}
+
+// leave stops sharing with the given job, typically because it's done. The others divide its share.
+// Nothing happens if the job's share was taken over by another manager than the one that joined with changedCh.
+func (s *JobShares) leave(jobID common.JobID, changedCh chan<- struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[jobID]; !ok || job.changedCh != changedCh {
+		return
+	}
+	delete(s.jobs, jobID)
+	s.notifyChanged()
+}
+
+func (s *JobShares) notifyChanged() {
+	for _, job := range s.jobs {
+		select {
+		case job.changedCh <- struct{}{}:
+		default:
+			// a notification is already pending
+		}
+	}
+}
+
+// share returns the fraction of the process that the given job is entitled to.
+// A job that isn't sharing, because it isn't running, is entitled to all of it.
+func (s *JobShares) share(jobID common.JobID) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return 1
+	}
+	totalWeight := float64(0)
+	for _, other := range s.jobs {
+		totalWeight += other.weight
+	}
+	return job.weight / totalWeight
+}
+
+// scale returns the given job's share of the given number of workers. A job is never left without a worker, unless there are none.
+func (s *JobShares) scale(jobID common.JobID, count int) int {
+	if count <= 0 {
+		return count
+	}
+	scaled := int(math.Round(float64(count) * s.share(jobID)))
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled
+}
+
+// awaitTurn blocks the given job while it has had more than its share of the bandwidth, and other jobs are waiting for theirs.
+// slackBytes is how far ahead of its share the job may get. Each call must be followed by a call to endTurn.
+func (s *JobShares) awaitTurn(ctx context.Context, jobID common.JobID, slackBytes float64) error {
+	s.mu.Lock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	job.waiting++
+	for !s.isTurn(job, slackBytes/job.weight) {
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			s.endTurn(jobID, 0)
+			return ctx.Err()
+		case <-time.After(fairShareWaitDuration):
+		}
+		s.mu.Lock()
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// isTurn returns whether the given job may have bandwidth now, because no job waiting for bandwidth has been served
+// less than it, by more than the given slack. Must be called with the lock held.
+func (s *JobShares) isTurn(job *jobShare, slack float64) bool {
+	leastServed := math.Inf(1)
+	for _, other := range s.jobs {
+		if other != job && other.waiting > 0 {
+			leastServed = math.Min(leastServed, other.served)
+		}
+	}
+	if math.IsInf(leastServed, 1) {
+		return true // nobody else wants bandwidth
+	}
+	if job.served < leastServed-slack {
+		// the job was idle while the others were served; it can't claim the bandwidth it didn't use
+		job.served = leastServed - slack
+	}
+	return job.served <= leastServed+slack
+}
+
+// endTurn records that the given job has stopped waiting, and was served the given number of bytes
+func (s *JobShares) endTurn(jobID common.JobID, bytesServed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[jobID]; ok && job.waiting > 0 {
+		job.waiting--
+		job.served += float64(bytesServed) / job.weight
+	}
+}
+
+// returnBytes records that the given job didn't use bytes it was served
+func (s *JobShares) returnBytes(jobID common.JobID, byteCount int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[jobID]; ok {
+		job.served -= float64(byteCount) / job.weight
+	}
+}
+
+// fairPacer paces the traffic of one job through the pacer of the process. When the bandwidth is capped, a job that has
+// had more than its share of it waits for the other jobs that are waiting for theirs.
+type fairPacer struct {
+	global PacerAdmin
+	shares *JobShares
+	jobID  common.JobID
+
+	// only one of the job's requests at a time waits in the pacer of the process, since whichever request polls
+	// the pacer first gets the bandwidth, whoever's turn it is
+	turnCh chan struct{}
+}
+
+func newFairPacer(global PacerAdmin, shares *JobShares, jobID common.JobID) *fairPacer {
+	return &fairPacer{global: global, shares: shares, jobID: jobID, turnCh: make(chan struct{}, 1)}
+}
+
+func (p *fairPacer) RequestTrafficAllocation(ctx context.Context, byteCount int64) error {
+	target := p.global.GetTargetBytesPerSecond()
+	if target == 0 {
+		// nothing to share, the job gets as much bandwidth as its workers can use
+		return p.global.RequestTrafficAllocation(ctx, byteCount)
+	}
+
+	select {
+	case p.turnCh <- struct{}{}:
+		defer func() { <-p.turnCh }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := p.shares.awaitTurn(ctx, p.jobID, float64(target)*fairShareSlackSeconds); err != nil {
+		return err
+	}
+	if err := p.global.RequestTrafficAllocation(ctx, byteCount); err != nil {
+		p.shares.endTurn(p.jobID, 0)
+		return err
+	}
+	p.shares.endTurn(p.jobID, byteCount)
+	return nil
+}
+
+func (p *fairPacer) UpdateTargetBytesPerSecond(newTarget int64) {
+	p.global.UpdateTargetBytesPerSecond(newTarget)
+}
+
+func (p *fairPacer) UndoRequest(byteCount int64) {
+	p.global.UndoRequest(byteCount)
+	if byteCount > 0 {
+		p.shares.returnBytes(p.jobID, byteCount)
+	}
+}
+
+// Close doesn't close the pacer of the process, which is shared with the other jobs
+func (p *fairPacer) Close() error {
+	return nil
+}
@@ -102,6 +102,9 @@ type IJobMgr interface {
 	SuccessfulBytesInActiveFiles() uint64
 	CancelPauseJobOrder(desiredJobStatus common.JobStatus) common.CancelPauseResumeResponse
 	IsDaemon() bool
+	// IsDone returns whether the job has been completed, cancelled or paused, and all its parts are done.
+	// A JobMgr runs its job only once; a done job is resumed by a new JobMgr.
+	IsDone() bool
 
 	// Cleanup Functions
 	DeferredCleanupJobMgr()
@@ -111,7 +114,7 @@ type IJobMgr interface {
 
 func NewJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx context.Context, cpuMon common.CPUMonitor, level common.LogLevel,
 	commandString string, logFileFolder string, tuner ConcurrencyTuner,
-	pacer PacerAdmin, slicePool common.ByteSlicePooler, cacheLimiter common.CacheLimiter, fileCountLimiter common.CacheLimiter,
+	pacer PacerAdmin, jobShares *JobShares, slicePool common.ByteSlicePooler, cacheLimiter common.CacheLimiter, fileCountLimiter common.CacheLimiter,
 	jobLogger common.ILoggerResetable, daemonMode bool, sourceBlobToken azblob.Credential) IJobMgr {
 	const channelSize = 100000
 	// PartsChannelSize defines the number of JobParts which can be placed into the
@@ -178,10 +181,12 @@ func NewJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx conte
 			exitNotificationCh:  make(chan struct{}),
 			scalebackRequestCh:  make(chan struct{}),
 			requestSlowTuneCh:   make(chan struct{}),
+			sharesChangedCh:     make(chan struct{}, 1),
 			done:                make(chan struct{}, 1),
 		},
 		concurrencyTuner: tuner,
 		pacer:            pacer,
+		jobShares:        jobShares,
 		slicePool:        slicePool,
 		cacheLimiter:     cacheLimiter,
 		fileCountLimiter: fileCountLimiter,
@@ -190,6 +195,11 @@ func NewJobMgr(concurrency ConcurrencySettings, jobID common.JobID, appCtx conte
 		isDaemon:         daemonMode,
 		sourceBlobToken:  sourceBlobToken,
 		/*Other fields remain zero-value until this job is scheduled */}
+	jm.jobPacer = pacer
+	if daemonMode {
+		// only a process that serves many jobs runs several at a time, which then take turns for the bandwidth
+		jm.jobPacer = newFairPacer(pacer, jobShares, jobID)
+	}
 	jm.Reset(appCtx, commandString)
 	// One routine constantly monitors the partsChannel.  It takes the JobPartManager from
 	// the Channel and schedules the transfers of that JobPart.
@@ -289,6 +299,7 @@ type jobMgr struct {
 	// atomicAllTransfersScheduled defines whether all job parts have been iterated and resumed or not
 	atomicAllTransfersScheduled     int32
 	atomicFinalPartOrderedIndicator int32
+	atomicJobDone                   int32
 	atomicTransferDirection         common.TransferDirection
 
 	concurrency          ConcurrencySettings
@@ -332,6 +343,8 @@ type jobMgr struct {
 	concurrencyTuner    ConcurrencyTuner
 	cpuMon              common.CPUMonitor
 	pacer               PacerAdmin
+	jobPacer            pacer // paces the traffic of this job within its share of the bandwidth
+	jobShares           *JobShares
 	slicePool           common.ByteSlicePooler
 	cacheLimiter        common.CacheLimiter
 	fileCountLimiter    common.CacheLimiter
@@ -420,7 +433,7 @@ func (jm *jobMgr) logPerfInfo(displayStrings []string, constraint common.PerfCon
 func (jm *jobMgr) AddJobPart(partNum PartNumber, planFile JobPartPlanFileName, existingPlanMMF *JobPartPlanMMF, sourceSAS string,
 	destinationSAS string, scheduleTransfers bool, completionChan chan struct{}) IJobPartMgr {
 	jpm := &jobPartMgr{jobMgr: jm, filename: planFile, sourceSAS: sourceSAS,
-		destinationSAS: destinationSAS, pacer: jm.pacer,
+		destinationSAS: destinationSAS, pacer: jm.jobPacer,
 		slicePool:         jm.slicePool,
 		cacheLimiter:      jm.cacheLimiter,
 		fileCountLimiter:  jm.fileCountLimiter,
@@ -472,7 +485,7 @@ func (jm *jobMgr) AddJobOrder(order common.CopyJobPartOrderRequest) IJobPartMgr
 		filename:         jppfn,
 		sourceSAS:        order.SourceRoot.SAS,
 		destinationSAS:   order.DestinationRoot.SAS,
-		pacer:            jm.pacer,
+		pacer:            jm.jobPacer,
 		slicePool:        jm.slicePool,
 		cacheLimiter:     jm.cacheLimiter,
 		fileCountLimiter: jm.fileCountLimiter,
@@ -531,7 +544,7 @@ func (jm *jobMgr) setDirection(fromTo common.FromTo) {
 	if fromTo.IsUpload() {
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Upload())
 	}
-	if fromTo.IsDownload() {
+	if fromTo.IsDownload() || fromTo.IsLocalToLocal() {
 		jm.atomicTransferDirection.AtomicStore(common.ETransferDirection.Download())
 		jm.RequestTuneSlowly()
 	}
@@ -607,22 +620,35 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 		select {
 		case <-jm.reportCancelCh:
 			jobPart0Mgr, ok := jm.jobPartMgrs.Get(0)
-			if ok {
+			if !ok {
+				jm.Log(pipeline.LogError, "part0Plan of job invalid")
+			} else if !jm.IsDone() { // once done, the plan may be resumed by another JobMgr, so its status is no longer this one's to check
 				part0plan := jobPart0Mgr.Plan()
 				if part0plan.JobStatus() == common.EJobStatus.InProgress() ||
 					part0plan.JobStatus() == common.EJobStatus.Cancelling() {
 					jm.Panic(fmt.Errorf("reportCancelCh received cancel event while job still not completed, Job(%s) in state: %s",
 						jm.jobID.String(), part0plan.JobStatus()))
 				}
-			} else {
-				jm.Log(pipeline.LogError, "part0Plan of job invalid")
 			}
 			jm.Log(pipeline.LogInfo, "reportJobPartDoneHandler done called")
 			return
 
 		case partProgressInfo := <-jm.jobPartProgress:
+			if jm.IsDone() {
+				// a part of a job that was cancelled or paused, or the cancellation of a job manager being cleaned up.
+				// Either way, the job's status is no longer this manager's to report
+				if partProgressInfo.completionChan != nil {
+					close(partProgressInfo.completionChan)
+				}
+				continue
+			}
 			jobPart0Mgr, ok := jm.jobPartMgrs.Get(0)
 			if !ok {
+				if jm.ctx.Err() != nil {
+					// the job manager is being cleaned up, and its parts were removed
+					jm.Log(pipeline.LogInfo, "part0Plan of cancelled job was removed")
+					continue
+				}
 				jm.Panic(fmt.Errorf("Failed to find Job %v, Part #0", jm.jobID))
 			}
 			part0Plan := jobPart0Mgr.Plan()
@@ -643,7 +669,7 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 			isCancelling := jobStatus == common.EJobStatus.Cancelling()
 			shouldComplete := (haveFinalPart && allKnownPartsDone) || // If we have all of the parts, they should all exit cleanly, so the job can be resumed properly.
 				(isCancelling && !haveFinalPart) // If we're cancelling, it's OK to try to exit early; the user already accepted this job cannot be resumed. Outgoing requests will fail anyway, so nothing can properly clean up.
-			if shouldComplete {
+			if shouldComplete && !jm.IsDone() { // the parts of a job that was cancelled or paused may still report afterwards
 				// Inform StatusManager that all parts are done.
 				close(jm.jstm.xferDone)
 				// Wait  for all XferDone messages to be processed by statusManager. Front end
@@ -677,6 +703,8 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 				// reset counters
 				atomic.StoreUint32(&jm.partsDone, 0)
 				jobProgressInfo = jobPartProgressInfo{}
+				atomic.StoreInt32(&jm.atomicJobDone, 1)
+				jm.jobShares.leave(jm.jobID, jm.poolSizingChannels.sharesChangedCh) // the jobs still running divide its share
 
 				// flush logs
 				jm.chunkStatusLogger.FlushLog() // TODO: remove once we sort out what will be calling CloseLog (currently nothing)
@@ -795,6 +823,7 @@ type poolSizingChannels struct {
 	exitNotificationCh  chan struct{}
 	scalebackRequestCh  chan struct{}
 	requestSlowTuneCh   chan struct{}
+	sharesChangedCh     chan struct{} // buffered, so that the job shares never wait for the pool sizer
 	done                chan struct{}
 }
 
@@ -807,7 +836,7 @@ func (jm *jobMgr) CurrentMainPoolSize() int {
 
 func (jm *jobMgr) ScheduleTransfer(priority common.JobPriority, jptm IJobPartTransferMgr) {
 	switch priority { // priority determines which channel handles the job part's transfers
-	case common.EJobPriority.Normal():
+	case common.EJobPriority.Normal(), common.EJobPriority.High():
 		// jptm.SetChunkChannel(ja.xferChannels.normalChunckCh)
 		jm.coordinatorChannels.normalTransferCh <- jptm
 	case common.EJobPriority.Low():
@@ -820,7 +849,7 @@ func (jm *jobMgr) ScheduleTransfer(priority common.JobPriority, jptm IJobPartTra
 
 func (jm *jobMgr) ScheduleChunk(priority common.JobPriority, chunkFunc chunkFunc) {
 	switch priority { // priority determines which channel handles the job part's transfers
-	case common.EJobPriority.Normal():
+	case common.EJobPriority.Normal(), common.EJobPriority.High():
 		jm.xferChannels.normalChunckCh <- chunkFunc
 	case common.EJobPriority.Low():
 		jm.xferChannels.lowChunkCh <- chunkFunc
@@ -882,8 +911,9 @@ func (jm *jobMgr) poolSizer() {
 	throughputMonitoringInterval := initialMonitoringInterval
 	slowTuneCh := jm.poolSizingChannels.requestSlowTuneCh
 
-	// get initial pool size
-	targetConcurrency, reason := jm.concurrencyTuner.GetRecommendedConcurrency(-1, jm.cpuMon.CPUContentionExists())
+	// get initial pool size. The tuner recommends a size for the whole process, of which this job gets its share
+	recommendedConcurrency, reason := jm.concurrencyTuner.GetRecommendedConcurrency(-1, jm.cpuMon.CPUContentionExists())
+	targetConcurrency := jm.jobShares.scale(jm.jobID, recommendedConcurrency)
 	logConcurrency(targetConcurrency, reason)
 
 	// loop for ever, driving the actual concurrency towards the most up-to-date target
@@ -904,7 +934,11 @@ func (jm *jobMgr) poolSizer() {
 		// wait for something to happen (maybe ack from the worker of the change, else a timer interval)
 		select {
 		case <-jm.poolSizingChannels.done:
+			recommendedConcurrency = 0
 			targetConcurrency = 0
+		case <-jm.poolSizingChannels.sharesChangedCh:
+			// a job has started or finished, so this job's share of the pool has changed
+			targetConcurrency = jm.jobShares.scale(jm.jobID, recommendedConcurrency)
 		case <-jm.poolSizingChannels.entryNotificationCh:
 			// new worker has started
 			actualConcurrency++
@@ -929,7 +963,8 @@ func (jm *jobMgr) poolSizer() {
 					if megabitsPerSec > 4000 {
 						throughputMonitoringInterval = expandedMonitoringInterval // start averaging throughputs over longer time period, since in some tests it takes a little longer to get a good average
 					}
-					targetConcurrency, reason = jm.concurrencyTuner.GetRecommendedConcurrency(int(megabitsPerSec), jm.cpuMon.CPUContentionExists())
+					recommendedConcurrency, reason = jm.concurrencyTuner.GetRecommendedConcurrency(int(megabitsPerSec), jm.cpuMon.CPUContentionExists())
+					targetConcurrency = jm.jobShares.scale(jm.jobID, recommendedConcurrency)
 					logConcurrency(targetConcurrency, reason)
 				} else {
 					// we weren't in steady state before, but given that throughputMonitoringInterval has now elapsed,
@@ -960,12 +995,15 @@ func (jm *jobMgr) scheduleJobParts() {
 		select {
 		case <-jm.xferChannels.scheduleCloseCh:
 			jm.Log(pipeline.LogInfo, "ScheduleJobParts done called")
+			jm.jobShares.leave(jm.jobID, jm.poolSizingChannels.sharesChangedCh)
 			jm.poolSizingChannels.done <- struct{}{}
 			return
 
 		case jobPart := <-jm.xferChannels.partsChannel:
 
 			if !startedPoolSizer {
+				// from now on, the job shares the workers and bandwidth of the process with the other jobs running in it
+				jm.jobShares.join(jm.jobID, jobPart.Plan().Priority, jm.poolSizingChannels.sharesChangedCh)
 				// spin up a GR to coordinate dynamic sizing of the main pool
 				// It will automatically spin up the right number of chunk processors
 				go jm.poolSizer()
@@ -1128,6 +1166,10 @@ func (jm *jobMgr) IsDaemon() bool {
 	return jm.isDaemon
 }
 
+func (jm *jobMgr) IsDone() bool {
+	return atomic.LoadInt32(&jm.atomicJobDone) == 1
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 type jobPartToJobPartMgr struct {
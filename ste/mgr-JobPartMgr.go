@@ -34,6 +34,7 @@ type IJobPartMgr interface {
 	GetOverwriteOption() common.OverwriteOption
 	GetForceIfReadOnly() bool
 	AutoDecompress() bool
+	CompressionType() common.CompressionType
 	ScheduleChunks(chunkFunc chunkFunc)
 	RescheduleTransfer(jptm IJobPartTransferMgr)
 	BlobTypeOverride() common.BlobType
@@ -208,6 +209,24 @@ func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryO
 	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
 }
 
+// NewHTTPPipeline creates a pipeline for reading from generic HTTP(S) servers.
+// Such sources are only ever read anonymously (any credential is already in the URL), so there's no credential policy.
+func NewHTTPPipeline(o azblob.PipelineOptions, r XferRetryOptions, client *http.Client, statsAcc *PipelineNetworkStats) pipeline.Pipeline {
+	// Closest to API goes first; closest to the wire goes last
+	f := []pipeline.Factory{
+		azblob.NewTelemetryPolicyFactory(o.Telemetry),
+		NewHTTPXferRetryPolicyFactory(r),    // actually retry the operation
+		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		pipeline.MethodFactoryMarker(),      // indicates at what stage in the pipeline the method factory is invoked
+		NewRequestLogPolicyFactory(RequestLogOptions{
+			LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold,
+			SyslogDisabled:               common.IsForceLoggingDisabled(),
+		}),
+		newXferStatsPolicyFactory(statsAcc),
+	}
+	return pipeline.NewPipeline(f, pipeline.Options{HTTPSender: newAzcopyHTTPClientFactory(client), Log: o.Log})
+}
+
 // NewBlobFSPipeline creates a pipeline for transfers to and from BlobFS Service
 // The blobFS operations currently in azcopy are supported by SharedKey Credentials
 func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryOptions, p pacer, client *http.Client, statsAcc *PipelineNetworkStats) pipeline.Pipeline {
@@ -377,6 +396,12 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context, sourceBlobToken
 		plan.SetJobStatus(common.EJobStatus.Completed())
 		return
 	}
+	if plan.NumTransfers == 0 {
+		/* No transfer will report this part done, e.g. the final part of a job whose last transfers were in an earlier part */
+		plan.SetJobPartStatus(common.EJobStatus.Completed())
+		jpm.jobMgr.ReportJobPartDone(jobPartProgressInfo{completionChan: jpm.closeOnCompletion})
+		return
+	}
 
 	// get the list of include / exclude transfers
 	includeTransfer, excludeTransfer := jpm.jobMgr.IncludeExclude()
@@ -432,7 +457,7 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context, sourceBlobToken
 	jpm.preserveLastModifiedTime = plan.DstLocalData.PreserveLastModifiedTime
 
 	jpm.blobTypeOverride = plan.DstBlobData.BlobType
-	jpm.newJobXfer = computeJobXfer(plan.FromTo, plan.DstBlobData.BlobType)
+	jpm.newJobXfer = computeJobXfer(plan.FromTo, plan.DstBlobData.BlobType, plan.DstBlobData.ArchiveFormat)
 
 	jpm.priority = plan.Priority
 
@@ -554,6 +579,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 		userAgent = common.S3ImportUserAgent
 	} else if fromTo.From() == common.ELocation.GCP() {
 		userAgent = common.GCPImportUserAgent
+	} else if fromTo.From() == common.ELocation.HTTP() {
+		userAgent = common.HttpImportUserAgent
 	} else if fromTo.From() == common.ELocation.Benchmark() || fromTo.To() == common.ELocation.Benchmark() {
 		userAgent = common.BenchmarkUserAgent
 	} else {
@@ -582,7 +609,8 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 	if (fromTo.IsS2S() || fromTo.IsDownload()) && (fromTo.From() == common.ELocation.Blob() || fromTo.From() == common.ELocation.BlobFS()) {
 		sourceCred := azblob.NewAnonymousCredential()
 		jobState := jpm.jobMgr.getInMemoryTransitJobState()
-		if fromTo.To().CanForwardOAuthTokens() && jobState.S2SSourceCredentialType.IsAzureOAuth() {
+		// the S3 sender reads the source itself, rather than having the destination read it, so it needs the token too
+		if (fromTo.To().CanForwardOAuthTokens() || fromTo.To() == common.ELocation.S3()) && jobState.S2SSourceCredentialType.IsAzureOAuth() {
 			if jpm.sourceCredential == nil {
 				sourceCred = common.CreateBlobCredential(ctx, jobState.CredentialInfo.WithType(jobState.S2SSourceCredentialType), credOption)
 				jpm.sourceCredential = sourceCred
@@ -647,6 +675,20 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 		}, jpm.pacer, jpm.jobMgr.HttpClient(), statsAccForSip, jpm.planMMF.Plan().DstFileData.TrailingDot)
 	}
 
+	// Set up a source pipeline for generic HTTP(S) sources if necessary
+	if (fromTo.IsS2S() || fromTo.IsDownload()) && fromTo.From() == common.ELocation.HTTP() {
+		jpm.sourceProviderPipeline = NewHTTPPipeline(
+			azblob.PipelineOptions{
+				Log: jpm.jobMgr.PipelineLogInfo(),
+				Telemetry: azblob.TelemetryOptions{
+					Value: userAgent,
+				},
+			},
+			xferRetryOption,
+			jpm.jobMgr.HttpClient(),
+			statsAccForSip)
+	}
+
 	switch {
 	case fromTo.IsS2S() && (fromTo.To() == common.ELocation.Blob() || fromTo.To() == common.ELocation.BlobFS()), // destination determines pipeline for S2S, blobfs uses blob for S2S
 		 fromTo.IsUpload() && fromTo.To() == common.ELocation.Blob(), // destination determines pipeline for upload
@@ -729,6 +771,17 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context, sourceBlobToken azbl
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			jpm.jobMgr.PipelineNetworkStats())
+	case fromTo.IsDownload() && fromTo.From() == common.ELocation.HTTP():
+		jpm.pipeline = NewHTTPPipeline(
+			azblob.PipelineOptions{
+				Log: jpm.jobMgr.PipelineLogInfo(),
+				Telemetry: azblob.TelemetryOptions{
+					Value: userAgent,
+				},
+			},
+			xferRetryOption,
+			jpm.jobMgr.HttpClient(),
+			jpm.jobMgr.PipelineNetworkStats())
 	case fromTo.IsS2S() && fromTo.To() == common.ELocation.File(),
 	     fromTo.IsUpload() && fromTo.To() == common.ELocation.File(),
 		 fromTo.IsDownload() && fromTo.From() == common.ELocation.File(),
@@ -788,6 +841,10 @@ func (jpm *jobPartMgr) AutoDecompress() bool {
 	return jpm.Plan().AutoDecompress
 }
 
+func (jpm *jobPartMgr) CompressionType() common.CompressionType {
+	return jpm.Plan().DstBlobData.CompressionType
+}
+
 func (jpm *jobPartMgr) resourceDstData(fullFilePath string, dataFileToXfer []byte) (headers common.ResourceHTTPHeaders,
 	metadata common.Metadata, blobTags common.BlobTags, cpkOptions common.CpkOptions) {
 	if jpm.planMMF.Plan().DstBlobData.NoGuessMimeType {
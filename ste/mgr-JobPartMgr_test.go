@@ -21,9 +21,13 @@
 package ste
 
 import (
-	chk "gopkg.in/check.v1"
+	"context"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
 )
 
 // Hookup to the testing framework
@@ -60,3 +64,42 @@ func (s *jobPartMgrTestSuite) TestInferContentType(c *chk.C) {
 		c.Assert(strings.Contains(contentType, expectedType), chk.Equals, true)
 	}
 }
+
+// partDoneRecorder is a job manager that records the parts reported done
+type partDoneRecorder struct {
+	IJobMgr
+	reported []jobPartProgressInfo
+}
+
+func (r *partDoneRecorder) ReportJobPartDone(progressInfo jobPartProgressInfo) {
+	r.reported = append(r.reported, progressInfo)
+}
+
+func (s *jobPartMgrTestSuite) TestScheduleTransfersOfPartWithoutTransfers(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+	jobID := common.NewJobID()
+
+	newPart := func(partNum common.PartNumber) (*jobPartMgr, *partDoneRecorder) {
+		planFile := JobPartPlanFileName(fmt.Sprintf(JobPartPlanFileNameFormat, jobID.String(), partNum, DataSchemaVersion))
+		planFile.Create(common.CopyJobPartOrderRequest{JobID: jobID, PartNum: partNum, IsFinalPart: true, FromTo: common.EFromTo.LocalBlob()})
+		jobMgr := &partDoneRecorder{}
+		return &jobPartMgr{jobMgr: jobMgr, planMMF: planFile.Map(), closeOnCompletion: make(chan struct{})}, jobMgr
+	}
+
+	// an empty final part, after the parts that had the transfers, is done as soon as it's scheduled,
+	// since none of its transfers will report it done
+	jpm, jobMgr := newPart(1)
+	defer jpm.planMMF.Unmap()
+	jpm.ScheduleTransfers(context.Background(), nil)
+	c.Assert(jpm.Plan().JobPartStatus(), chk.Equals, common.EJobStatus.Completed())
+	c.Assert(jobMgr.reported, chk.HasLen, 1)
+	c.Assert(jobMgr.reported[0].completionChan, chk.Equals, jpm.closeOnCompletion)
+
+	// while a job with no transfers at all is completed at once
+	jpm, jobMgr = newPart(0)
+	defer jpm.planMMF.Unmap()
+	jpm.ScheduleTransfers(context.Background(), nil)
+	c.Assert(jpm.Plan().JobStatus(), chk.Equals, common.EJobStatus.Completed())
+	c.Assert(jobMgr.reported, chk.HasLen, 0)
+}
@@ -40,6 +40,8 @@ type IJobPartTransferMgr interface {
 	GetForceIfReadOnly() bool
 	ShouldDecompress() bool
 	GetSourceCompressionType() (common.CompressionType, error)
+	ShouldCompress() bool
+	GetDestinationCompressionType() common.CompressionType
 	ReportChunkDone(id common.ChunkID) (lastChunk bool, chunksDone uint32)
 	TransferStatusIgnoringCancellation() common.TransferStatus
 	SetStatus(status common.TransferStatus)
@@ -267,6 +269,15 @@ func (jptm *jobPartTransferMgr) GetSourceCompressionType() (common.CompressionTy
 	return common.GetCompressionType(encoding)
 }
 
+func (jptm *jobPartTransferMgr) ShouldCompress() bool {
+	return jptm.GetDestinationCompressionType() != common.ECompressionType.None()
+}
+
+// GetDestinationCompressionType returns the compression, if any, to apply to the content of the file as it is uploaded
+func (jptm *jobPartTransferMgr) GetDestinationCompressionType() common.CompressionType {
+	return jptm.jobPartMgr.CompressionType()
+}
+
 func (jptm *jobPartTransferMgr) Info() TransferInfo {
 	if jptm.transferInfo != nil {
 		return *jptm.transferInfo
@@ -487,7 +498,7 @@ func (jptm *jobPartTransferMgr) HoldsDestinationLock() bool {
 
 func (jptm *jobPartTransferMgr) useFileCountLimiter() bool {
 	ft := jptm.FromTo()    // TODO: consider changing isDownload (and co) to have struct receiver instead of pointer receiver, so don't need variable like this
-	return ft.IsDownload() // count-based limits are only applied for download a present
+	return ft.IsDownload() || ft.IsLocalToLocal() // count-based limits are only applied for download a present
 }
 
 func (jptm *jobPartTransferMgr) RescheduleTransfer() {
@@ -954,6 +965,7 @@ func (jptm *jobPartTransferMgr) ReportTransferDone() uint32 {
 	if atomic.SwapUint32(&jptm.atomicCompletionIndicator, 1) != 0 {
 		panic("cannot report the same transfer done twice")
 	}
+	jptm.jobPartPlanTransfer.SetCompletionTime(time.Now())
 
 	// Update Status Manager
 	jptm.jobPartMgr.SendXferDoneMsg(xferDoneMsg{Src: jptm.Info().Source,
@@ -51,6 +51,9 @@ type PacerAdmin interface {
 
 	// GetTotalTraffic returns the cumulative count of all traffic that has been processed
 	GetTotalTraffic() int64
+
+	// GetTargetBytesPerSecond returns the rate to which traffic is capped, or zero if it isn't
+	GetTargetBytesPerSecond() int64
 }
 
 const (
@@ -210,3 +213,7 @@ func (p *tokenBucketPacer) UpdateTargetBytesPerSecond(value int64) {
 func (p *tokenBucketPacer) GetTotalTraffic() int64 {
 	return atomic.LoadInt64(&p.atomicGrandTotal)
 }
+
+func (p *tokenBucketPacer) GetTargetBytesPerSecond() int64 {
+	return p.targetBytesPerSecond()
+}
@@ -23,6 +23,7 @@ package ste
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"sync/atomic"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -101,8 +102,12 @@ func (u *blockBlobUploader) generatePutBlock(id common.ChunkID, blockIndex int32
 
 		// step 3: put block to remote
 		u.jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		body := newPacedRequestBody(u.jptm.Context(), reader, u.pacer)
-		_, err := u.destBlockBlobURL.StageBlock(u.jptm.Context(), encodedBlockID, body, azblob.LeaseAccessConditions{}, nil, u.cpkToApply)
+		body, err := u.requestBody(reader)
+		if err != nil {
+			u.jptm.FailActiveUpload("Compressing block", err)
+			return
+		}
+		_, err = u.destBlockBlobURL.StageBlock(u.jptm.Context(), encodedBlockID, body, azblob.LeaseAccessConditions{}, nil, u.cpkToApply)
 		if err != nil {
 			u.jptm.FailActiveUpload("Staging block", err)
 			return
@@ -137,7 +142,7 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 			destBlobTier = azblob.AccessTierNone
 		}
 
-		if jptm.Info().SourceSize == 0 {
+		if jptm.Info().SourceSize == 0 && !jptm.ShouldCompress() { // an empty file still has content once compressed
 			_, err = u.destBlockBlobURL.Upload(jptm.Context(), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{}, destBlobTier, blobTags, u.cpkToApply, azblob.ImmutabilityPolicyOptions{})
 		} else {
 			// File with content
@@ -151,7 +156,11 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 			u.headersToApply.ContentMD5 = md5Hash
 
 			// Upload the file
-			body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
+			body, compressErr := u.requestBody(reader)
+			if compressErr != nil {
+				jptm.FailActiveUpload("Compressing blob", compressErr)
+				return
+			}
 			_, err = u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply,
 				azblob.BlobAccessConditions{}, u.destBlobTier, blobTags, u.cpkToApply, azblob.ImmutabilityPolicyOptions{})
 		}
@@ -172,6 +181,21 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 	})
 }
 
+// requestBody returns the body of the request that uploads the given chunk.
+// If the file is to be compressed, the chunk is compressed on its own, so that each block holds a complete
+// gzip member or ZStandard frame (see common.CompressChunk).
+func (u *blockBlobUploader) requestBody(reader common.SingleChunkReader) (io.ReadSeeker, error) {
+	if !u.jptm.ShouldCompress() {
+		return newPacedRequestBody(u.jptm.Context(), reader, u.pacer), nil
+	}
+
+	compressed, err := common.CompressChunk(u.jptm.GetDestinationCompressionType(), reader)
+	if err != nil {
+		return nil, err
+	}
+	return newPacedRequestBody(u.jptm.Context(), bytes.NewReader(compressed), u.pacer), nil
+}
+
 func (u *blockBlobUploader) Epilogue() {
 	jptm := u.jptm
 
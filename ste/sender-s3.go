@@ -0,0 +1,459 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	minio "github.com/minio/minio-go"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// S3 multipart upload limits. See https://docs.aws.amazon.com/AmazonS3/latest/userguide/qfacts.html
+const (
+	s3MinPartSize        = 5 * 1024 * 1024
+	s3MaxPartSize        = 5 * 1024 * 1024 * 1024
+	s3MaxNumberOfParts   = 10000
+	s3NoSuchKeyErrorCode = "NoSuchKey"
+)
+
+// urlToS3Copier copies a blob to S3. S3 cannot pull from a URL, so unlike the blob copiers this is a
+// download+upload: each chunk is read from the source blob and sent as one part of an S3 multipart upload.
+// Objects that fit in a single chunk are sent with a single PutObject instead.
+type urlToS3Copier struct {
+	jptm      IJobPartTransferMgr
+	sip       IRemoteSourceInfoProvider
+	pacer     pacer
+	chunkSize int64
+	numChunks uint32
+
+	srcBlobURL   azblob.BlobURL
+	srcCpkToRead azblob.ClientProvidedKeyOptions
+
+	s3Client  *minio.Client
+	s3URLPart common.S3URLParts
+
+	// Headers, metadata and tags that we will apply to the destination object
+	putOptions minio.PutObjectOptions
+
+	uploadID               string
+	atomicChunksWritten    int32
+	atomicPutListIndicator int32
+	muParts                *sync.Mutex
+	completedParts         []minio.CompletePart // indexed by block index, so they are already in the order S3 requires
+	partsFromPreviousRun   map[int]minio.ObjectPart
+}
+
+func newURLToS3Copier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	srcInfoProvider := sip.(IRemoteSourceInfoProvider) // "downcast" to the type we know it really has
+	info := jptm.Info()
+
+	if info.IsFolderPropertiesTransfer() || info.EntityType != common.EEntityType.File() {
+		return nil, fmt.Errorf("only files can be copied to S3")
+	}
+
+	chunkSize, numChunks, err := getS3PartParams(info.SourceSize, info.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize >= jptm.CacheLimiter().StrictLimit() {
+		// each part is buffered in memory before it is sent
+		return nil, fmt.Errorf("cannot use a part size of %d bytes, since only %d bytes of memory are available for chunks", chunkSize, jptm.CacheLimiter().StrictLimit())
+	}
+
+	destURL, err := url.Parse(destination)
+	if err != nil {
+		return nil, err
+	}
+	s3URLPart, err := common.NewS3URLParts(*destURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client, credType, err := getS3ClientForTransfer(jptm, s3URLPart)
+	if err != nil {
+		return nil, err
+	}
+	if credType != common.ECredentialType.S3AccessKey() {
+		return nil, errors.New("an S3 access key is required to write to S3. Set the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+	}
+
+	srcURL, err := srcInfoProvider.PreSignedSourceURL()
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := srcInfoProvider.Properties()
+	if err != nil {
+		return nil, err
+	}
+
+	srcCpkToRead := azblob.ClientProvidedKeyOptions{}
+	if jptm.IsSourceEncrypted() {
+		srcCpkToRead = common.ToClientProvidedKeyOptions(jptm.CpkInfo(), jptm.CpkScopeInfo())
+	}
+
+	return &urlToS3Copier{
+		jptm:           jptm,
+		sip:            srcInfoProvider,
+		pacer:          pacer,
+		chunkSize:      chunkSize,
+		numChunks:      numChunks,
+		srcBlobURL:     azblob.NewBlobURL(*srcURL, jptm.SourceProviderPipeline()),
+		srcCpkToRead:   srcCpkToRead,
+		s3Client:       s3Client,
+		s3URLPart:      s3URLPart,
+		putOptions:     newS3PutObjectOptions(props),
+		muParts:        &sync.Mutex{},
+		completedParts: make([]minio.CompletePart, numChunks),
+	}, nil
+}
+
+// getS3PartParams adjusts the job's block size to fit the S3 part size and part count limits
+func getS3PartParams(srcSize int64, blockSize int64) (chunkSize int64, numChunks uint32, err error) {
+	chunkSize = blockSize
+	if srcSize > chunkSize && chunkSize < s3MinPartSize {
+		chunkSize = s3MinPartSize // only the last part may be smaller than this
+	}
+
+	for srcSize > chunkSize*s3MaxNumberOfParts {
+		chunkSize *= 2
+	}
+
+	if chunkSize > s3MaxPartSize && srcSize > chunkSize {
+		return 0, 0, fmt.Errorf("source of size %d is too large to be copied to S3 in at most %d parts of %d bytes", srcSize, s3MaxNumberOfParts, int64(s3MaxPartSize))
+	}
+
+	return chunkSize, getNumChunks(srcSize, chunkSize), nil
+}
+
+// newS3PutObjectOptions maps the source's content headers, metadata and tags to their S3 equivalents
+func newS3PutObjectOptions(props *SrcProperties) minio.PutObjectOptions {
+	userMetadata := make(map[string]string, len(props.SrcMetadata)+1)
+	for k, v := range props.SrcMetadata {
+		userMetadata[k] = v // minio adds the x-amz-meta- prefix
+	}
+
+	if len(props.SrcBlobTags) > 0 {
+		tags := url.Values{}
+		for k, v := range props.SrcBlobTags {
+			tags.Set(k, v)
+		}
+		userMetadata["X-Amz-Tagging"] = tags.Encode() // x-amz-* headers are passed through as-is
+	}
+
+	return minio.PutObjectOptions{
+		UserMetadata:       userMetadata,
+		ContentType:        props.SrcHTTPHeaders.ContentType,
+		ContentEncoding:    props.SrcHTTPHeaders.ContentEncoding,
+		ContentDisposition: props.SrcHTTPHeaders.ContentDisposition,
+		ContentLanguage:    props.SrcHTTPHeaders.ContentLanguage,
+		CacheControl:       props.SrcHTTPHeaders.CacheControl,
+	}
+}
+
+func (s *urlToS3Copier) core() minio.Core {
+	return minio.Core{Client: s.s3Client}
+}
+
+func (s *urlToS3Copier) ChunkSize() int64 {
+	return s.chunkSize
+}
+
+func (s *urlToS3Copier) NumChunks() uint32 {
+	return s.numChunks
+}
+
+func (s *urlToS3Copier) RemoteFileExists() (bool, time.Time, error) {
+	oi, err := s.s3Client.StatObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == s3NoSuchKeyErrorCode {
+			return false, time.Time{}, nil
+		}
+		return false, time.Time{}, err
+	}
+	return true, oi.LastModified, nil
+}
+
+func (s *urlToS3Copier) GetDestinationLength() (int64, error) {
+	oi, err := s.s3Client.StatObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return -1, err
+	}
+	return oi.Size, nil
+}
+
+func (s *urlToS3Copier) Prologue(ps common.PrologueState) (destinationModified bool) {
+	if s.numChunks == 1 {
+		return false // sent with a single PutObject, no multipart upload required
+	}
+
+	if s.jptm.RestartedTransfer() {
+		s.findResumableUpload()
+	}
+
+	if s.uploadID == "" {
+		uploadID, err := s.core().NewMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.putOptions)
+		if err != nil {
+			s.jptm.FailActiveSend("Creating multipart upload", err)
+			return false
+		}
+		s.uploadID = uploadID
+	}
+
+	return true
+}
+
+// findResumableUpload looks for a multipart upload left behind by an earlier run of this transfer.
+// S3 doesn't let us name the parts the way we name blocks, so we only pick up an upload if it is the only one in progress
+// for the destination key, and every part in it has the size this transfer would have given it.
+func (s *urlToS3Copier) findResumableUpload() {
+	restartMsg := "findResumableUpload: %s. Restarting whole file."
+	key := s.s3URLPart.ObjectKey
+
+	result, err := s.core().ListMultipartUploads(s.s3URLPart.BucketName, key, "", "", "", 1000)
+	if err != nil {
+		s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogError, fmt.Sprintf(restartMsg, "Failed to list multipart uploads"))
+		return
+	}
+
+	uploadID := ""
+	for _, u := range result.Uploads {
+		if u.Key != key {
+			continue
+		}
+		if uploadID != "" {
+			s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, fmt.Sprintf(restartMsg, "Found more than one multipart upload for the object"))
+			return
+		}
+		uploadID = u.UploadID
+	}
+	if uploadID == "" {
+		s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "No multipart upload found.")
+		return
+	}
+
+	srcSize := s.jptm.Info().SourceSize
+	lastPartSize := srcSize - int64(s.numChunks-1)*s.chunkSize
+	parts := make(map[int]minio.ObjectPart)
+	partNumberMarker := 0
+	for {
+		listed, err := s.core().ListObjectParts(s.s3URLPart.BucketName, key, uploadID, partNumberMarker, s3MaxNumberOfParts)
+		if err != nil {
+			s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogError, fmt.Sprintf(restartMsg, "Failed to list parts"))
+			return
+		}
+
+		for _, part := range listed.ObjectParts {
+			expectedSize := common.Iffint64(part.PartNumber == int(s.numChunks), lastPartSize, s.chunkSize)
+			if part.PartNumber < 1 || part.PartNumber > int(s.numChunks) || part.Size != expectedSize {
+				s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, fmt.Sprintf(restartMsg, "Part size mismatch on multipart upload"))
+				return
+			}
+			parts[part.PartNumber] = part
+		}
+
+		if !listed.IsTruncated {
+			break
+		}
+		partNumberMarker = listed.NextPartNumberMarker
+	}
+
+	// We are here only if all the parts have the layout this transfer uses
+	s.uploadID = uploadID
+	s.partsFromPreviousRun = parts
+}
+
+func (s *urlToS3Copier) GenerateCopyFunc(id common.ChunkID, blockIndex int32, adjustedChunkSize int64, chunkIsWholeFile bool) chunkFunc {
+	if s.numChunks == 1 {
+		setPutListNeed(&s.atomicPutListIndicator, putListNotNeeded)
+		return s.generatePutObject(id, adjustedChunkSize)
+	}
+	setPutListNeed(&s.atomicPutListIndicator, putListNeeded)
+	return s.generatePutObjectPart(id, blockIndex, adjustedChunkSize)
+}
+
+// readSourceRange reads the given range of the source blob into memory.
+// S3 needs the length (and ideally the MD5) of the body up front, and must be able to replay it on retries, so we can't just stream.
+func (s *urlToS3Copier) readSourceRange(id common.ChunkID, length int64) ([]byte, error) {
+	jptm := s.jptm
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), length, func() bool { return false }); err != nil {
+		return nil, err
+	}
+	defer jptm.CacheLimiter().Remove(length)
+
+	jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+	get, err := s.srcBlobURL.Download(jptm.Context(), id.OffsetInFile(), length,
+		azblob.BlobAccessConditions{ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfUnmodifiedSince: jptm.LastModifiedTime()}},
+		false, s.srcCpkToRead)
+	if err != nil {
+		return nil, err
+	}
+
+	jptm.LogChunkStatus(id, common.EWaitReason.Body())
+	u := s.srcBlobURL.URL()
+	body := get.Body(azblob.RetryReaderOptions{
+		MaxRetryRequests:         MaxRetryPerDownloadBody,
+		NotifyFailedRead:         common.NewReadLogFunc(jptm, &u),
+		ClientProvidedKeyOptions: s.srcCpkToRead,
+	})
+	defer body.Close()
+
+	buffer := make([]byte, length)
+	if _, err = io.ReadFull(newPacedResponseBody(jptm.Context(), body, s.pacer), buffer); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+func md5Base64(data []byte) string {
+	hash := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// generatePutObject generates a func to send the whole object in one request
+func (s *urlToS3Copier) generatePutObject(id common.ChunkID, length int64) chunkFunc {
+	return createSendToRemoteChunkFunc(s.jptm, id, func() {
+		jptm := s.jptm
+
+		data, err := s.readSourceRange(id, length)
+		if err != nil {
+			jptm.FailActiveSend("Reading source", err)
+			return
+		}
+
+		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		// Core.PutObject takes headers and user metadata in one map, and sorts them back out by name
+		metadata := common.Metadata(s.putOptions.UserMetadata).Clone()
+		headers := map[string]string{
+			"Content-Type":        s.putOptions.ContentType,
+			"Content-Encoding":    s.putOptions.ContentEncoding,
+			"Content-Disposition": s.putOptions.ContentDisposition,
+			"Content-Language":    s.putOptions.ContentLanguage,
+			"Cache-Control":       s.putOptions.CacheControl,
+		}
+		for k, v := range headers {
+			if v != "" {
+				metadata[k] = v
+			}
+		}
+
+		_, err = s.core().PutObject(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, bytes.NewReader(data), length, md5Base64(data), "", metadata, nil)
+		if err != nil {
+			jptm.FailActiveSend("Putting object", err)
+			return
+		}
+
+		atomic.AddInt32(&s.atomicChunksWritten, 1)
+	})
+}
+
+// generatePutObjectPart generates a func to send one chunk as one part of the multipart upload
+func (s *urlToS3Copier) generatePutObjectPart(id common.ChunkID, blockIndex int32, length int64) chunkFunc {
+	return createSendToRemoteChunkFunc(s.jptm, id, func() {
+		jptm := s.jptm
+		partNumber := int(blockIndex) + 1 // S3 part numbers are 1-based
+
+		if part, ok := s.partsFromPreviousRun[partNumber]; ok {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, fmt.Sprintf("Skipping chunk %d as it was already transferred.", blockIndex))
+			s.setCompletedPart(blockIndex, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+			atomic.AddInt32(&s.atomicChunksWritten, 1)
+			return
+		}
+
+		data, err := s.readSourceRange(id, length)
+		if err != nil {
+			jptm.FailActiveSend("Reading source", err)
+			return
+		}
+
+		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		part, err := s.core().PutObjectPart(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID, partNumber,
+			bytes.NewReader(data), length, md5Base64(data), "", nil)
+		if err != nil {
+			jptm.FailActiveSend("Uploading part", err)
+			return
+		}
+
+		s.setCompletedPart(blockIndex, minio.CompletePart{PartNumber: partNumber, ETag: part.ETag})
+		atomic.AddInt32(&s.atomicChunksWritten, 1)
+	})
+}
+
+func (s *urlToS3Copier) setCompletedPart(index int32, part minio.CompletePart) {
+	s.muParts.Lock()
+	defer s.muParts.Unlock()
+	if s.completedParts[index].PartNumber != 0 {
+		panic(errors.New("part set twice for one block"))
+	}
+	s.completedParts[index] = part
+}
+
+func (s *urlToS3Copier) Epilogue() {
+	jptm := s.jptm
+
+	s.muParts.Lock()
+	parts := s.completedParts
+	s.completedParts = nil
+	s.muParts.Unlock()
+
+	shouldCompleteUpload := getPutListNeed(&s.atomicPutListIndicator)
+	if shouldCompleteUpload == putListNeedUnknown && !jptm.WasCanceled() {
+		panic(errors.New("'put list' need flag was never set"))
+	}
+
+	if jptm.IsLive() && shouldCompleteUpload == putListNeeded {
+		jptm.Log(pipeline.LogDebug, fmt.Sprintf("Conclude Transfer with multipart upload %s of %d parts", s.uploadID, len(parts)))
+		if _, err := s.core().CompleteMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID, parts); err != nil {
+			jptm.FailActiveSend("Completing multipart upload", err)
+			return
+		}
+	}
+}
+
+func (s *urlToS3Copier) Cleanup() {
+	jptm := s.jptm
+
+	// Parts of an incomplete upload are billed until the upload is aborted, so don't leave them behind.
+	// As with uncommitted blocks, a crashed (rather than failed or cancelled) run leaves them for a resume to pick up.
+	if jptm.IsDeadInflight() && s.uploadID != "" {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Aborting multipart upload due to failure or cancellation")
+		if err := s.core().AbortMultipartUpload(s.s3URLPart.BucketName, s.s3URLPart.ObjectKey, s.uploadID); err != nil {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Failed to abort multipart upload: "+err.Error())
+		}
+	}
+}
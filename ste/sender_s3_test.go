@@ -0,0 +1,210 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	minio "github.com/minio/minio-go"
+	chk "gopkg.in/check.v1"
+)
+
+type s3SenderSuite struct{}
+
+var _ = chk.Suite(&s3SenderSuite{})
+
+func (s *s3SenderSuite) TestGetS3PartParams(c *chk.C) {
+	// small objects are sent whole, whatever the block size
+	_, numChunks, err := getS3PartParams(1024, 8*1024*1024)
+	c.Assert(err, chk.IsNil)
+	c.Assert(numChunks, chk.Equals, uint32(1))
+
+	// parts other than the last one must be at least 5MiB
+	chunkSize, numChunks, err := getS3PartParams(12*1024*1024, 1024*1024)
+	c.Assert(err, chk.IsNil)
+	c.Assert(chunkSize, chk.Equals, int64(s3MinPartSize))
+	c.Assert(numChunks, chk.Equals, uint32(3))
+
+	// the part size grows so the object fits in the maximum number of parts
+	chunkSize, numChunks, err = getS3PartParams(s3MaxNumberOfParts*s3MinPartSize+1, s3MinPartSize)
+	c.Assert(err, chk.IsNil)
+	c.Assert(chunkSize, chk.Equals, int64(2*s3MinPartSize))
+	c.Assert(numChunks <= s3MaxNumberOfParts, chk.Equals, true)
+}
+
+func (s *s3SenderSuite) TestNewS3PutObjectOptions(c *chk.C) {
+	props := &SrcProperties{
+		SrcHTTPHeaders: common.ResourceHTTPHeaders{ContentType: "text/plain", CacheControl: "no-cache"},
+		SrcMetadata:    common.Metadata{"owner": "azcopy"},
+		SrcBlobTags:    common.BlobTags{"env": "test"},
+	}
+
+	opts := newS3PutObjectOptions(props)
+	c.Assert(opts.ContentType, chk.Equals, "text/plain")
+	c.Assert(opts.CacheControl, chk.Equals, "no-cache")
+	c.Assert(opts.UserMetadata["owner"], chk.Equals, "azcopy")
+	c.Assert(opts.UserMetadata["X-Amz-Tagging"], chk.Equals, "env=test")
+}
+
+// newFakeBlobSource serves the given content as a blob, as a stand-in for the source account
+func newFakeBlobSource(content []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, end := int64(0), int64(len(content))-1
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			_, _ = fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		}
+		w.Header().Set("ETag", `"0x8D000000000000"`)
+		w.Header().Set("Last-Modified", time.Unix(1600000000, 0).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start : end+1])
+	}))
+}
+
+// blobSourceStub is the source info provider of a blob, with the properties the blob source info provider would get for it
+type blobSourceStub struct {
+	IRemoteSourceInfoProvider
+	url   *url.URL
+	props SrcProperties
+}
+
+func (b blobSourceStub) PreSignedSourceURL() (*url.URL, error) { return b.url, nil }
+func (b blobSourceStub) Properties() (*SrcProperties, error)   { return &b.props, nil }
+
+// copyToS3 copies content from a fake blob source to the given key of the fake S3 service, and returns the transfer
+func (s *s3SenderSuite) copyToS3(c *chk.C, s3 *fakeS3, key string, content []byte, restarted bool) *testTransfer {
+	source := newFakeBlobSource(content)
+	defer source.Close()
+	srcURL, err := url.Parse(source.URL + "/account/container/blob")
+	c.Assert(err, chk.IsNil)
+
+	t := newTestTransfer(common.EFromTo.BlobS3(), TransferInfo{Source: srcURL.String(), Destination: s3.objectURL(key),
+		SourceSize: int64(len(content)), BlockSize: s3MinPartSize, EntityType: common.EEntityType.File()})
+	t.restarted = restarted
+	t.srcPipeline = azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+	sip := blobSourceStub{url: srcURL, props: SrcProperties{
+		SrcHTTPHeaders: common.ResourceHTTPHeaders{ContentType: "text/csv"},
+		SrcMetadata:    common.Metadata{"owner": "azcopy"},
+	}}
+
+	sender, err := newURLToS3Copier(t, t.info.Destination, nil, NewNullAutoPacer(), sip)
+	c.Assert(err, chk.IsNil)
+	runCopy(t, sender.(s2sCopier))
+	return t
+}
+
+func (s *s3SenderSuite) sourceOfParts(numParts int) []byte {
+	content := make([]byte, (numParts-1)*s3MinPartSize+1234) // the last part is short
+	for i := range content {
+		content[i] = byte(i % 253)
+	}
+	return content
+}
+
+func (s *s3SenderSuite) TestS3SenderCopiesInParts(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	content := s.sourceOfParts(3)
+
+	t := s.copyToS3(c, s3, "dir/object.csv", content, false)
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	written, ok := s3.object("dir/object.csv")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(bytes.Equal(written, content), chk.Equals, true)
+	c.Assert(s3.partsPut, chk.Equals, 3)
+	c.Assert(s3.uploads, chk.HasLen, 0)
+
+	// the headers and metadata are given when the upload is created
+	headers := s3.headers["bucket/dir/object.csv"]
+	c.Assert(headers.Get("Content-Type"), chk.Equals, "text/csv")
+	c.Assert(headers.Get("X-Amz-Meta-Owner"), chk.Equals, "azcopy")
+}
+
+func (s *s3SenderSuite) TestS3SenderPutsSmallObjectsWhole(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	content := []byte("a,b,c\n1,2,3\n")
+
+	t := s.copyToS3(c, s3, "small.csv", content, false)
+	c.Assert(t.failures, chk.HasLen, 0)
+
+	written, ok := s3.object("small.csv")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(string(written), chk.Equals, string(content))
+	c.Assert(s3.partsPut, chk.Equals, 0)
+
+	headers := s3.headers["bucket/small.csv"]
+	c.Assert(headers.Get("Content-MD5"), chk.Equals, md5Base64(content))
+	c.Assert(headers.Get("Content-Type"), chk.Equals, "text/csv")
+	c.Assert(headers.Get("X-Amz-Meta-Owner"), chk.Equals, "azcopy")
+}
+
+func (s *s3SenderSuite) TestS3SenderAbortsTheUploadWhenAPartFails(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	s3.rejectPart = 2
+
+	t := s.copyToS3(c, s3, "object.csv", s.sourceOfParts(3), false)
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(minio.ToErrorResponse(t.failures[0]).Code, chk.Equals, "InvalidDigest")
+
+	// the parts that were sent aren't left behind to be billed
+	_, ok := s3.object("object.csv")
+	c.Assert(ok, chk.Equals, false)
+	c.Assert(s3.uploads, chk.HasLen, 0)
+}
+
+func (s *s3SenderSuite) TestS3SenderResumesTheUploadOfARestartedTransfer(c *chk.C) {
+	s3, done := newFakeS3(c, fakeS3AccessKey)
+	defer done()
+	content := s.sourceOfParts(3)
+
+	// an earlier run sent the first part before it was interrupted
+	s3.uploads["upload-0"] = &fakeS3Upload{key: "object.csv", headers: http.Header{}, parts: map[int][]byte{1: content[:s3MinPartSize]}}
+
+	t := s.copyToS3(c, s3, "object.csv", content, true)
+	c.Assert(t.failures, chk.HasLen, 0)
+	written, ok := s3.object("object.csv")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(bytes.Equal(written, content), chk.Equals, true)
+	c.Assert(s3.partsPut, chk.Equals, 2)
+}
+
+func (s *s3SenderSuite) TestS3SenderFailsWithBadCredentials(c *chk.C) {
+	s3, done := newFakeS3(c, "AKIAWRONG")
+	defer done()
+
+	t := s.copyToS3(c, s3, "object.csv", s.sourceOfParts(2), false)
+	c.Assert(t.failures, chk.HasLen, 1)
+	c.Assert(minio.ToErrorResponse(t.failures[0]).Code, chk.Equals, "InvalidAccessKeyId")
+	_, ok := s3.object("object.csv")
+	c.Assert(ok, chk.Equals, false)
+}
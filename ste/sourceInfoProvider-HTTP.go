@@ -0,0 +1,63 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// Source info provider for generic HTTP(S) URLs.
+// The URL (including any query string) is handed to the service as is, so the defaults cover everything but fresh properties.
+type httpSourceInfoProvider struct {
+	defaultRemoteSourceInfoProvider
+}
+
+func newHTTPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	base, err := newDefaultRemoteSourceInfoProvider(jptm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpSourceInfoProvider{defaultRemoteSourceInfoProvider: *base}, nil
+}
+
+// getFreshProperties asks the server for the current headers of the resource
+func (p *httpSourceInfoProvider) getFreshProperties() (common.HTTPResourceProperties, error) {
+	sourceURL, err := p.PreSignedSourceURL()
+	if err != nil {
+		return common.HTTPResourceProperties{}, err
+	}
+
+	return common.GetHTTPResourceProperties(p.jptm.Context(), p.jptm.SourceProviderPipeline(), *sourceURL)
+}
+
+// GetFreshFileLastModifiedTime returns the zero time if the server doesn't send Last-Modified,
+// which is also what the enumerator recorded in that case.
+func (p *httpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	props, err := p.getFreshProperties()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return props.LastModified(), nil
+}
@@ -47,6 +47,14 @@ func (f localFileSourceInfoProvider) Properties() (*SrcProperties, error) {
 
 	headers, metadata, blobTags, _ := f.jptm.ResourceDstData(nil) // we don't have a known MIME type yet, so pass nil for the sniffed content of thefile
 
+	// the enumerator may attach metadata to an individual file (e.g. the hash that sync compares by)
+	if srcMetadata := f.transferInfo.SrcMetadata; len(srcMetadata) > 0 {
+		metadata = metadata.Clone()
+		for k, v := range srcMetadata {
+			metadata[k] = v
+		}
+	}
+
 	return &SrcProperties{
 		SrcHTTPHeaders: common.ResourceHTTPHeaders{
 			ContentType:        headers.ContentType,
@@ -63,27 +63,36 @@ func newS3SourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, err
 		return nil, err
 	}
 
+	p.s3Client, p.credType, err = getS3ClientForTransfer(jptm, p.s3URLPart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// getS3ClientForTransfer returns the (cached) S3 client for the endpoint in s3URLPart.
+// The credential type is inferred from the AWS environment variables, since S3 credentials never go through the job plan.
+func getS3ClientForTransfer(jptm IJobPartTransferMgr, s3URLPart common.S3URLParts) (*minio.Client, common.CredentialType, error) {
+	credType := common.ECredentialType.S3AccessKey()
 	if os.Getenv("AWS_ACCESS_KEY_ID") == "" && os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
-		p.credType = common.ECredentialType.S3PublicBucket()
-	} else {
-		p.credType = common.ECredentialType.S3AccessKey()
+		credType = common.ECredentialType.S3PublicBucket()
 	}
-	p.s3Client, err = s3ClientFactory.GetS3Client(p.jptm.Context(), common.CredentialInfo{
-		CredentialType: p.credType,
+
+	s3Client, err := s3ClientFactory.GetS3Client(jptm.Context(), common.CredentialInfo{
+		CredentialType: credType,
 		S3CredentialInfo: common.S3CredentialInfo{
-			Endpoint: p.s3URLPart.Endpoint,
-			Region:   p.s3URLPart.Region,
+			Endpoint: s3URLPart.Endpoint,
+			Region:   s3URLPart.Region,
+			Insecure: s3URLPart.IsInsecure(),
 		},
 	}, common.CredentialOpOptions{
-		LogInfo:  func(str string) { p.jptm.Log(pipeline.LogInfo, str) },
-		LogError: func(str string) { p.jptm.Log(pipeline.LogError, str) },
+		LogInfo:  func(str string) { jptm.Log(pipeline.LogInfo, str) },
+		LogError: func(str string) { jptm.Log(pipeline.LogError, str) },
 		Panic:    func(err error) { panic(err) },
 	}, jptm)
-	if err != nil {
-		return nil, err
-	}
 
-	return &p, nil
+	return s3Client, credType, err
 }
 
 func (p *s3SourceInfoProvider) PreSignedSourceURL() (*url.URL, error) {
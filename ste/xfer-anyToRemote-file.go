@@ -534,7 +534,8 @@ func epilogueWithCleanupSendToRemote(jptm IJobPartTransferMgr, s sender, sip ISo
 	//  or should we redefine epilogue to be success-path only, and only call it in that case?
 	s.Epilogue() // Perform service-specific cleanup before jptm cleanup. Some services may actually require setup to make the file actually appear.
 
-	if jptm.IsLive() && info.DestLengthValidation {
+	// the length of compressed content can't be compared to the length of the source
+	if jptm.IsLive() && info.DestLengthValidation && !jptm.ShouldCompress() {
 		_, isS2SCopier := s.(s2sCopier)
 		shouldCheckLength := true
 		destLength, err := s.GetDestinationLength()
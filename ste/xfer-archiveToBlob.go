@@ -0,0 +1,309 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// how many blocks of the archive may be staged at once. The archive is produced by a single stream,
+// so (like uploads from a pipe) its blocks are buffered in memory while they are staged
+const archiveUploadParallelism = 5
+
+// the index of an archive is uploaded next to it, as a blob with the name of the archive plus this suffix
+const ArchiveIndexBlobSuffix = ".index.jsonl"
+
+// the metadata key, set on the archive blob, that names its index blob
+const ArchiveIndexMetadataKey = "azcopyarchiveindex"
+
+var errArchiveUploadStopped = errors.New("the upload of the archive stopped")
+
+// archiveIndexEntry describes where one member landed in the archive, so that it can be extracted with a ranged read
+// instead of a download of the whole archive. The offsets are into the tar stream, i.e. before any gzip compression.
+type archiveIndexEntry struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	HeaderOffset int64     `json:"headerOffset"`
+	DataOffset   int64     `json:"dataOffset"`
+}
+
+// ArchiveToBlob uploads the whole source tree of the job, as listed in its archive member list, into a single tar block blob.
+// The tar is produced as a stream, and cut into blocks that are staged in parallel as they fill up.
+// The transfer is retried as a whole on resume, since a gzip stream can't be restarted part way through.
+func ArchiveToBlob(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, format common.ArchiveFormat) {
+
+	// If the transfer was cancelled, then reporting transfer as done and increasing the bytestransferred by the size of the source.
+	if jptm.WasCanceled() {
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// schedule the work as a chunk, so it will run on the main goroutine pool, instead of the
+	// smaller "transfer initiation pool", where this code runs.
+	id := common.NewChunkID(jptm.Info().Source, 0, 0)
+	cf := createChunkFunc(true, jptm, id, func() { doArchiveToBlob(jptm, p, pacer, format) })
+	jptm.ScheduleChunks(cf)
+}
+
+func doArchiveToBlob(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, format common.ArchiveFormat) {
+	info := jptm.Info()
+	defer jptm.ReportTransferDone()
+
+	u, err := url.Parse(info.Destination)
+	if err != nil {
+		jptm.FailActiveUpload("Parsing destination URL", err)
+		return
+	}
+	archiveURL := azblob.NewBlockBlobURL(*u, p)
+	indexParts := azblob.NewBlobURLParts(*u)
+	indexParts.BlobName += ArchiveIndexBlobSuffix
+	indexURL := azblob.NewBlockBlobURL(indexParts.URL(), p)
+	cpkToApply := common.ToClientProvidedKeyOptions(jptm.CpkInfo(), jptm.CpkScopeInfo())
+
+	if jptm.GetOverwriteOption() != common.EOverwriteOption.True() {
+		exists, dstLmt, existenceErr := remoteObjectExists(archiveURL.GetProperties(jptm.Context(), azblob.BlobAccessConditions{}, cpkToApply))
+		if existenceErr != nil {
+			jptm.LogSendError(info.Source, info.Destination, "Could not check destination file existence. "+existenceErr.Error(), 0)
+			jptm.SetStatus(common.ETransferStatus.Failed())
+			return
+		}
+		if exists {
+			shouldOverwrite := false
+			if jptm.GetOverwriteOption() == common.EOverwriteOption.Prompt() {
+				shouldOverwrite = jptm.GetOverwritePrompter().ShouldOverwrite(strings.Split(info.Destination, "?")[0], common.EEntityType.File())
+			} else if jptm.GetOverwriteOption() == common.EOverwriteOption.IfSourceNewer() {
+				// the transfer carries the LMT of the most recently modified member
+				shouldOverwrite = jptm.LastModifiedTime().After(dstLmt)
+			}
+
+			if !shouldOverwrite {
+				jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "File already exists, so will be skipped")
+				jptm.SetStatus(common.ETransferStatus.SkippedEntityAlreadyExists())
+				return
+			}
+		}
+	}
+
+	// the index is spooled to disk while the archive is written, because a tree of millions of files has an index
+	// too big to comfortably hold in memory
+	indexFile, err := os.Create(ArchiveMemberListPath(info.JobID) + ".index")
+	if err != nil {
+		jptm.FailActiveUpload("Creating archive index", err)
+		return
+	}
+	defer func() {
+		_ = indexFile.Close()
+		_ = os.Remove(indexFile.Name())
+	}()
+	indexBuffer := bufio.NewWriter(indexFile)
+
+	// the tar is written by its own goroutine, and read by the block stager through a pipe
+	pipeReader, pipeWriter := io.Pipe()
+	archiveErr := make(chan error, 1)
+	go func() {
+		err := writeArchive(pipeWriter, format, info.Source, indexBuffer, func(process func(ArchiveMember) error) error {
+			return readArchiveMemberList(info.JobID, process)
+		})
+		_ = pipeWriter.CloseWithError(err)
+		archiveErr <- err
+	}()
+
+	headers, metadata, blobTags, _ := jptm.ResourceDstData(nil)
+	archiveMetadata := metadata.Clone()
+	archiveMetadata[ArchiveIndexMetadataKey] = indexParts.BlobName
+
+	accessTier := azblob.AccessTierNone
+	if blockBlobTier, _ := jptm.BlobTiers(); blockBlobTier != common.EBlockBlobTier.None() {
+		accessTier = blockBlobTier.ToAccessTierType()
+	}
+	// TODO: Remove this snippet once service starts supporting CPK with blob tier
+	if cpkToApply.EncryptionScope != nil || (cpkToApply.EncryptionKey != nil && cpkToApply.EncryptionKeySha256 != nil) {
+		accessTier = azblob.AccessTierNone
+	}
+
+	jptm.SetDestinationIsModified()
+	// the stream is paced as it's read into blocks, since the blocks are staged outside of the senders
+	_, err = azblob.UploadStreamToBlockBlob(jptm.Context(), newPacedResponseBody(jptm.Context(), pipeReader, pacer), archiveURL, azblob.UploadStreamToBlockBlobOptions{
+		BufferSize:               int(info.BlockSize),
+		MaxBuffers:               archiveUploadParallelism,
+		BlobHTTPHeaders:          headers.ToAzBlobHTTPHeaders(),
+		Metadata:                 archiveMetadata.ToAzBlobMetadata(),
+		BlobTagsMap:              blobTags.ToAzBlobTagsMap(),
+		BlobAccessTier:           accessTier,
+		ClientProvidedKeyOptions: cpkToApply,
+	})
+	// unblock the writer, in case the upload stopped before reading the whole archive
+	_ = pipeReader.CloseWithError(errArchiveUploadStopped)
+	if writeErr := <-archiveErr; writeErr != nil && writeErr != errArchiveUploadStopped {
+		jptm.FailActiveUpload("Writing archive", writeErr)
+		return
+	} else if err != nil {
+		jptm.FailActiveUpload("Uploading archive", err)
+		return
+	}
+
+	if err = indexBuffer.Flush(); err == nil {
+		_, err = indexFile.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		_, err = azblob.UploadStreamToBlockBlob(jptm.Context(), newPacedRequestBody(jptm.Context(), indexFile, pacer), indexURL, azblob.UploadStreamToBlockBlobOptions{
+			BufferSize:               int(info.BlockSize),
+			MaxBuffers:               archiveUploadParallelism,
+			BlobHTTPHeaders:          azblob.BlobHTTPHeaders{ContentType: "application/x-ndjson"},
+			ClientProvidedKeyOptions: cpkToApply,
+		})
+	}
+	if err != nil {
+		jptm.FailActiveUpload("Uploading archive index", err)
+		return
+	}
+
+	if jptm.IsLive() {
+		jptm.SetStatus(common.ETransferStatus.Success())
+		jptm.Log(pipeline.LogInfo, fmt.Sprintf("UPLOADSUCCESSFUL: %s", strings.Split(info.Destination, "?")[0]))
+	}
+}
+
+// writeArchive writes the members, read from the local sourceRoot, to w as a tar of the given format,
+// and writes the index of the tar to index as JSON lines.
+func writeArchive(w io.Writer, format common.ArchiveFormat, sourceRoot string, index io.Writer, forEachMember func(func(ArchiveMember) error) error) error {
+	var gzipWriter *gzip.Writer
+	if format == common.EArchiveFormat.TarGz() {
+		gzipWriter = gzip.NewWriter(w)
+		w = gzipWriter
+	}
+	// count what goes into the tar stream, so that the index offsets can be used on the uncompressed tar
+	counter := &countingWriter{w: w}
+	tarWriter := tar.NewWriter(counter)
+	indexEncoder := json.NewEncoder(index)
+
+	err := forEachMember(func(member ArchiveMember) error {
+		sourcePath := sourceRoot
+		if member.RelativePath != "" {
+			sourcePath = filepath.Join(sourceRoot, filepath.FromSlash(member.RelativePath))
+		}
+
+		var fileInfo os.FileInfo
+		var err error
+		link := ""
+		if member.EntityType == common.EEntityType.Symlink() {
+			if fileInfo, err = os.Lstat(sourcePath); err == nil {
+				link, err = os.Readlink(sourcePath)
+			}
+		} else {
+			// a file may be a symlink that was followed during enumeration, so use Stat rather than Lstat
+			fileInfo, err = common.OSStat(sourcePath)
+		}
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, link)
+		if err != nil {
+			return err
+		}
+		header.Name = member.Name
+		// pad out the previous entry, so that the header of this one starts at the current offset
+		if err = tarWriter.Flush(); err != nil {
+			return err
+		}
+		entry := archiveIndexEntry{Size: header.Size, LastModified: fileInfo.ModTime(), HeaderOffset: counter.n}
+
+		switch member.EntityType {
+		case common.EEntityType.Folder():
+			entry.Type = "folder"
+			if !strings.HasSuffix(header.Name, "/") {
+				header.Name += "/"
+			}
+		case common.EEntityType.Symlink():
+			entry.Type = "symlink"
+		default:
+			entry.Type = "file"
+			if !fileInfo.Mode().IsRegular() {
+				return fmt.Errorf("%s is not a regular file", sourcePath)
+			}
+		}
+
+		entry.Name = header.Name
+
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		// the data follows straight after the header (and any extended header the name needed)
+		entry.DataOffset = counter.n
+
+		if entry.Type == "file" {
+			file, err := common.OSOpenFile(sourcePath, os.O_RDONLY, 0)
+			if err != nil {
+				return err
+			}
+			n, err := io.CopyN(tarWriter, file, header.Size)
+			_ = file.Close()
+			if err == io.EOF {
+				return fmt.Errorf("%s shrank from %d to %d bytes while it was being archived", sourcePath, header.Size, n)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return indexEncoder.Encode(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	if gzipWriter != nil {
+		return gzipWriter.Close()
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,178 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+type archiveSuite struct{}
+
+var _ = chk.Suite(&archiveSuite{})
+
+func (s *archiveSuite) createSourceTree(c *chk.C) (root string, members []ArchiveMember, contents map[string][]byte) {
+	root = c.MkDir()
+	contents = map[string][]byte{
+		"dir/a.txt":     []byte("hello"),
+		"dir/sub/b.bin": bytes.Repeat([]byte{7}, 1000), // not a multiple of the tar block size, so it gets padded
+	}
+	c.Assert(os.MkdirAll(filepath.Join(root, "sub"), 0755), chk.IsNil)
+	c.Assert(os.MkdirAll(filepath.Join(root, "empty"), 0755), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "a.txt"), contents["dir/a.txt"], 0644), chk.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(root, "sub", "b.bin"), contents["dir/sub/b.bin"], 0644), chk.IsNil)
+
+	members = []ArchiveMember{
+		{Name: "dir/", RelativePath: "", EntityType: common.EEntityType.Folder()},
+		{Name: "dir/a.txt", RelativePath: "a.txt", EntityType: common.EEntityType.File()},
+		{Name: "dir/empty", RelativePath: "empty", EntityType: common.EEntityType.Folder()},
+		{Name: "dir/sub/b.bin", RelativePath: "sub/b.bin", EntityType: common.EEntityType.File()},
+	}
+	return
+}
+
+func forEachOf(members []ArchiveMember) func(func(ArchiveMember) error) error {
+	return func(process func(ArchiveMember) error) error {
+		for _, m := range members {
+			if err := process(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func readIndex(c *chk.C, index []byte) []archiveIndexEntry {
+	var entries []archiveIndexEntry
+	scanner := bufio.NewScanner(bytes.NewReader(index))
+	for scanner.Scan() {
+		var e archiveIndexEntry
+		c.Assert(json.Unmarshal(scanner.Bytes(), &e), chk.IsNil)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (s *archiveSuite) TestWriteArchiveTar(c *chk.C) {
+	root, members, contents := s.createSourceTree(c)
+
+	var archive, index bytes.Buffer
+	err := writeArchive(&archive, common.EArchiveFormat.Tar(), root, &index, forEachOf(members))
+	c.Assert(err, chk.IsNil)
+
+	// every member is in the tar, in the enumerated order, with its content
+	reader := tar.NewReader(bytes.NewReader(archive.Bytes()))
+	names := []string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, chk.IsNil)
+		names = append(names, header.Name)
+		if expected, ok := contents[header.Name]; ok {
+			c.Assert(header.Typeflag, chk.Equals, byte(tar.TypeReg))
+			data, err := io.ReadAll(reader)
+			c.Assert(err, chk.IsNil)
+			c.Assert(data, chk.DeepEquals, expected)
+		} else {
+			c.Assert(header.Typeflag, chk.Equals, byte(tar.TypeDir))
+		}
+	}
+	c.Assert(names, chk.DeepEquals, []string{"dir/", "dir/a.txt", "dir/empty/", "dir/sub/b.bin"})
+
+	// the index points straight at the content of each file
+	entries := readIndex(c, index.Bytes())
+	c.Assert(entries, chk.HasLen, len(members))
+	for _, e := range entries {
+		if expected, ok := contents[e.Name]; ok {
+			c.Assert(e.Type, chk.Equals, "file")
+			c.Assert(e.Size, chk.Equals, int64(len(expected)))
+			c.Assert(archive.Bytes()[e.DataOffset:e.DataOffset+e.Size], chk.DeepEquals, expected)
+		} else {
+			c.Assert(e.Type, chk.Equals, "folder")
+			c.Assert(strings.HasSuffix(e.Name, "/"), chk.Equals, true)
+		}
+		c.Assert(e.DataOffset-e.HeaderOffset, chk.Equals, int64(512))
+	}
+}
+
+func (s *archiveSuite) TestWriteArchiveTarGz(c *chk.C) {
+	root, members, contents := s.createSourceTree(c)
+
+	var archive, index bytes.Buffer
+	err := writeArchive(&archive, common.EArchiveFormat.TarGz(), root, &index, forEachOf(members))
+	c.Assert(err, chk.IsNil)
+
+	gzipReader, err := gzip.NewReader(&archive)
+	c.Assert(err, chk.IsNil)
+	tarball, err := io.ReadAll(gzipReader)
+	c.Assert(err, chk.IsNil)
+
+	// the offsets of the index are into the uncompressed tar
+	for _, e := range readIndex(c, index.Bytes()) {
+		if expected, ok := contents[e.Name]; ok {
+			c.Assert(tarball[e.DataOffset:e.DataOffset+e.Size], chk.DeepEquals, expected)
+		}
+	}
+}
+
+func (s *archiveSuite) TestWriteArchiveMissingSource(c *chk.C) {
+	root, members, _ := s.createSourceTree(c)
+	c.Assert(os.Remove(filepath.Join(root, "a.txt")), chk.IsNil)
+
+	var archive, index bytes.Buffer
+	err := writeArchive(&archive, common.EArchiveFormat.Tar(), root, &index, forEachOf(members))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *archiveSuite) TestArchiveMemberListRoundTrip(c *chk.C) {
+	defer func(planFolder string) { common.AzcopyJobPlanFolder = planFolder }(common.AzcopyJobPlanFolder)
+	common.AzcopyJobPlanFolder = c.MkDir()
+	_, members, _ := s.createSourceTree(c)
+	jobID := common.NewJobID()
+
+	writer, err := NewArchiveMemberListWriter(jobID)
+	c.Assert(err, chk.IsNil)
+	for _, m := range members {
+		c.Assert(writer.Write(m), chk.IsNil)
+	}
+	c.Assert(writer.Close(), chk.IsNil)
+
+	var read []ArchiveMember
+	err = readArchiveMemberList(jobID, func(m ArchiveMember) error {
+		read = append(read, m)
+		return nil
+	})
+	c.Assert(err, chk.IsNil)
+	c.Assert(read, chk.DeepEquals, members)
+}
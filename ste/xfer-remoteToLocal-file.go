@@ -393,6 +393,17 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Error closing file: "+closeErr.Error()) // log this way so that this line will be logged even if transfer is already failed
 		}
 
+		// A local source can't be protected with access conditions, as remote ones are, so check that it didn't change while we read it
+		if jptm.IsLive() && jptm.FromTo().From() == common.ELocation.Local() {
+			fi, err := common.OSStat(info.Source)
+			if err != nil {
+				jptm.FailActiveDownload("Checking source's last modified time", err)
+			} else if !fi.ModTime().Equal(jptm.LastModifiedTime()) {
+				common.DocumentationForDependencyOnChangeDetection() // <-- read the documentation here ***
+				jptm.FailActiveDownload("Checking source's last modified time", errors.New("source modified during transfer"))
+			}
+		}
+
 		// Check MD5 (but only if file was fully flushed and saved - else no point and may not have actualAsSaved hash anyway)
 		if jptm.IsLive() {
 			comparison := md5Comparer{
@@ -482,29 +493,16 @@ redoCompletion:
 			panic("reached branch where jptm is assumed to be live, but it isn't")
 		}
 
-		// Attempt to put MD5 data if necessary, compliant with the sync hash scheme
+		// Attempt to put hash data if necessary, compliant with the sync hash scheme
+		plan := jptm.(*jobPartTransferMgr).jobPartMgr.Plan()
 		if jptm.ShouldPutMd5() {
-			fi, err := os.Stat(info.Destination)
-			if err != nil {
-				jptm.FailActiveDownload("saving MD5 data (stat to pull LMT)", err)
+			if !putSyncHashData(jptm, info, plan, common.ESyncHashType.MD5(), info.SrcHTTPHeaders.ContentMD5) {
 				goto redoCompletion // let fail as expected
 			}
-
-			plan := jptm.(*jobPartTransferMgr).jobPartMgr.Plan()
-			dataRoot := string(plan.DestinationRoot[:plan.DestinationRootLength])
-			_, idx := jptm.TransferIndex()
-			_, relDest := plan.TransferSrcDstRelatives(idx)
-			adapter, err := common.NewHashDataAdapter(common.LocalHashDir, dataRoot, common.LocalHashStorageMode)
-			if err != nil {
-				jptm.FailActiveDownload("initializing hash adapter", err)
-			}
-			err = adapter.SetHashData(relDest, &common.SyncHashData{
-				Mode: common.ESyncHashType.MD5(),
-				LMT:  fi.ModTime(),
-				Data: base64.StdEncoding.EncodeToString(info.SrcHTTPHeaders.ContentMD5),
-			})
-			if err != nil {
-				jptm.FailActiveDownload("saving MD5 data (writing alternate data stream)", err)
+		} else if hashType := plan.DstLocalData.SyncHashType; hashType.MetadataKey() != "" {
+			// the other hash types live in the metadata of the source, if it was uploaded by sync.
+			// If it wasn't, there's nothing to record, and the next sync hashes the file itself.
+			if sum := hashType.HashFromMetadata(info.SrcMetadata); sum != nil && !putSyncHashData(jptm, info, plan, hashType, sum) {
 				goto redoCompletion // let fail as expected
 			}
 		}
@@ -530,6 +528,35 @@ redoCompletion:
 	jptm.ReportTransferDone()
 }
 
+// putSyncHashData records the hash of a downloaded file where sync looks for it, so that the next sync doesn't have to
+// hash the file again. It fails the transfer, and returns false, if the hash can't be recorded.
+func putSyncHashData(jptm IJobPartTransferMgr, info TransferInfo, plan *JobPartPlanHeader, hashType common.SyncHashType, sum []byte) bool {
+	fi, err := os.Stat(info.Destination)
+	if err != nil {
+		jptm.FailActiveDownload(fmt.Sprintf("saving %s data (stat to pull LMT)", hashType), err)
+		return false
+	}
+
+	dataRoot := string(plan.DestinationRoot[:plan.DestinationRootLength])
+	_, idx := jptm.TransferIndex()
+	_, relDest := plan.TransferSrcDstRelatives(idx)
+	adapter, err := common.NewHashDataAdapter(common.LocalHashDir, dataRoot, common.LocalHashStorageMode)
+	if err != nil {
+		jptm.FailActiveDownload("initializing hash adapter", err)
+		return false
+	}
+	err = adapter.SetHashData(relDest, &common.SyncHashData{
+		Mode: hashType,
+		LMT:  fi.ModTime(),
+		Data: base64.StdEncoding.EncodeToString(sum),
+	})
+	if err != nil {
+		jptm.FailActiveDownload(fmt.Sprintf("saving %s data (writing alternate data stream)", hashType), err)
+		return false
+	}
+	return true
+}
+
 // create an empty file and its parent directories, without any content
 func createEmptyFile(jptm IJobPartTransferMgr, destinationPath string) error {
 	err := common.CreateParentDirectoryIfNotExist(destinationPath, jptm.GetFolderCreationTracker())
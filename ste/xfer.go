@@ -63,6 +63,7 @@ type newJobXfer func(jptm IJobPartTransferMgr, pipeline pipeline.Pipeline, pacer
 // same as newJobXfer, but with an extra parameter
 type newJobXferWithDownloaderFactory = func(jptm IJobPartTransferMgr, pipeline pipeline.Pipeline, pacer pacer, df downloaderFactory)
 type newJobXferWithSenderFactory = func(jptm IJobPartTransferMgr, pipeline pipeline.Pipeline, pacer pacer, sf senderFactory, sipf sourceInfoProviderFactory)
+type newJobXferWithArchiveFormat = func(jptm IJobPartTransferMgr, pipeline pipeline.Pipeline, pacer pacer, format common.ArchiveFormat)
 
 // Takes a multi-purpose download function, and makes it ready to user with a specific type of downloader
 func parameterizeDownload(targetFunction newJobXferWithDownloaderFactory, df downloaderFactory) newJobXfer {
@@ -78,8 +79,15 @@ func parameterizeSend(targetFunction newJobXferWithSenderFactory, sf senderFacto
 	}
 }
 
+// Takes an archive upload function, and makes it ready to use with a specific archive format
+func parameterizeArchive(targetFunction newJobXferWithArchiveFormat, format common.ArchiveFormat) newJobXfer {
+	return func(jptm IJobPartTransferMgr, pipeline pipeline.Pipeline, pacer pacer) {
+		targetFunction(jptm, pipeline, pacer, format)
+	}
+}
+
 // the xfer factory is generated based on the type of source and destination
-func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
+func computeJobXfer(fromTo common.FromTo, blobType common.BlobType, archiveFormat common.ArchiveFormat) newJobXfer {
 
 	//local helper functions
 
@@ -91,6 +99,14 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 			return newAzureFilesDownloader
 		case common.ELocation.BlobFS():
 			return newBlobFSDownloader
+		case common.ELocation.S3():
+			return newS3Downloader
+		case common.ELocation.GCP():
+			return newGCPDownloader
+		case common.ELocation.HTTP():
+			return newHTTPDownloader
+		case common.ELocation.Local():
+			return newLocalDownloader
 		default:
 			panic("unexpected source type")
 		}
@@ -101,9 +117,10 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 		if isFromRemote {
 			// sending from remote = doing an S2S copy
 			switch fromTo.To() {
-			case common.ELocation.Blob(),
-				common.ELocation.S3(), common.ELocation.GCP():
+			case common.ELocation.Blob(), common.ELocation.GCP():
 				return newURLToBlobCopier
+			case common.ELocation.S3():
+				return newURLToS3Copier
 			case common.ELocation.File():
 				return newURLToAzureFileCopier
 			case common.ELocation.BlobFS():
@@ -142,12 +159,19 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 			return newS3SourceInfoProvider
 		case common.ELocation.GCP():
 			return newGCPSourceInfoProvider
+		case common.ELocation.HTTP():
+			return newHTTPSourceInfoProvider
 		default:
 			panic("unexpected source type")
 		}
 	}
 
 	// main computeJobXfer logic
+	if fromTo == common.EFromTo.LocalBlob() && archiveFormat != common.EArchiveFormat.None() {
+		// the whole job is a single transfer, of the source directory into one archive blob
+		return parameterizeArchive(ArchiveToBlob, archiveFormat)
+	}
+
 	switch fromTo {
 	case common.EFromTo.BlobTrash():
 		return DeleteBlob
@@ -158,7 +182,7 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 	case common.EFromTo.BlobNone(), common.EFromTo.BlobFSNone(), common.EFromTo.FileNone():
 		return SetProperties
 	default:
-		if fromTo.IsDownload() {
+		if fromTo.IsDownload() || fromTo.IsLocalToLocal() {
 			return parameterizeDownload(remoteToLocal, getDownloader(fromTo.From()))
 		} else {
 			return parameterizeSend(anyToRemote, getSenderFactory(fromTo), getSipFactory(fromTo.From()))
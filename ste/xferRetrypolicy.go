@@ -465,6 +465,83 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 	})
 }
 
+// NewHTTPXferRetryPolicyFactory creates a retry policy for generic HTTP(S) servers. Unlike the storage retry policies it
+// never touches the URL (a timeout query parameter would invalidate a pre-signed URL), and since there's no error
+// response factory in front of it, it decides what to retry from the status code.
+func NewHTTPXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
+	o = o.defaults() // Force defaults to be calculated
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (response pipeline.Response, err error) {
+			maxTries := o.MaxTries
+			if _, ok := ctx.Value(retrySuppressionContextKey).(struct{}); ok {
+				maxTries = 1 // retries are suppressed by the context
+			}
+			for try := int32(1); try <= maxTries; try++ {
+				logf("\n=====> Try=%d\n", try)
+				time.Sleep(o.calcDelay(try)) // The 1st try returns 0 delay
+
+				// Clone the original request to ensure that each try starts with the original (unmutated) request.
+				requestCopy := request.Copy()
+				err = requestCopy.RewindBody()
+				common.PanicIfErr(err)
+
+				tryCtx, tryCancel := context.WithTimeout(ctx, o.TryTimeout)
+				response, err = next.Do(tryCtx, requestCopy)
+
+				action := "" // This MUST get changed within the switch code below
+				switch {
+				case ctx.Err() != nil:
+					action = "NoRetry: Op timeout"
+				case err != nil:
+					if _, ok := err.(net.Error); ok {
+						action = "Retry: net.Error"
+					} else if err == io.ErrUnexpectedEOF {
+						action = "Retry: io.UnexpectedEOF"
+					} else {
+						action = "NoRetry: unrecognized error"
+					}
+				case isRetriableHTTPStatusCode(response.Response().StatusCode):
+					action = "Retry: retriable status code"
+				default:
+					action = "NoRetry: HTTP request completed"
+				}
+
+				logf("Action=%s\n", action)
+				if action[0] != 'R' || try == maxTries { // Retry only if action starts with 'R', and there are tries left
+					if err != nil {
+						tryCancel()
+					} else {
+						// the per-try context must live as long as the body, so close them together
+						response.Response().Body = &contextCancelReadCloser{cf: tryCancel, body: response.Response().Body}
+					}
+					break
+				}
+				if response != nil && response.Response() != nil {
+					// If we're going to retry and we got a previous response, then flush its body to avoid leaking its TCP connection
+					_, _ = io.Copy(io.Discard, response.Response().Body)
+					response.Response().Body.Close()
+				}
+				tryCancel()
+			}
+			return response, err // Not retryable or too many retries; return the last response/error
+		}
+	})
+}
+
+func isRetriableHTTPStatusCode(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
 var successStatusCodes = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent, http.StatusPartialContent}
 
 func isSuccessStatusCode(resp *http.Response) bool {
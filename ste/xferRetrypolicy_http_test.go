@@ -0,0 +1,96 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type httpPipelineSuite struct{}
+
+var _ = chk.Suite(&httpPipelineSuite{})
+
+func (s *httpPipelineSuite) TestHTTPPipelineRetriesWithoutTouchingURL(c *chk.C) {
+	const rawQuery = "X-Amz-Signature=abc&X-Amz-Expires=60"
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		queries = append(queries, r.URL.RawQuery)
+		if len(queries) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	p := NewHTTPPipeline(azblob.PipelineOptions{},
+		XferRetryOptions{MaxTries: 3, RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond},
+		http.DefaultClient, nil)
+
+	u, _ := url.Parse(server.URL + "/file?" + rawQuery)
+	req, err := pipeline.NewRequest(http.MethodGet, *u, nil)
+	c.Assert(err, chk.IsNil)
+	resp, err := p.Do(context.Background(), nil, req)
+	c.Assert(err, chk.IsNil)
+	resp.Response().Body.Close()
+
+	// the 503 was retried, and both tries went to exactly the URL we were given, since it may be pre-signed
+	c.Assert(resp.Response().StatusCode, chk.Equals, http.StatusOK)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(queries, chk.DeepEquals, []string{rawQuery, rawQuery})
+}
+
+func (s *httpPipelineSuite) TestHTTPPipelineDoesNotRetryClientErrors(c *chk.C) {
+	var tries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tries, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	p := NewHTTPPipeline(azblob.PipelineOptions{},
+		XferRetryOptions{MaxTries: 3, RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond},
+		http.DefaultClient, nil)
+
+	u, _ := url.Parse(server.URL + "/file")
+	req, err := pipeline.NewRequest(http.MethodGet, *u, nil)
+	c.Assert(err, chk.IsNil)
+	resp, err := p.Do(context.Background(), nil, req)
+	c.Assert(err, chk.IsNil)
+	resp.Response().Body.Close()
+
+	c.Assert(resp.Response().StatusCode, chk.Equals, http.StatusForbidden)
+	c.Assert(atomic.LoadInt32(&tries), chk.Equals, int32(1))
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobSharesSuite struct{}
+
+var _ = chk.Suite(&jobSharesSuite{})
+
+func (s *jobSharesSuite) TestJobSharesFollowPriorityWeights(c *chk.C) {
+	shares := NewJobShares()
+	migration, restore := common.NewJobID(), common.NewJobID()
+
+	// a job that runs alone gets everything, even before it joins
+	c.Assert(shares.share(migration), chk.Equals, float64(1))
+	shares.join(migration, common.EJobPriority.Low(), make(chan struct{}, 1))
+	c.Assert(shares.share(migration), chk.Equals, float64(1))
+	c.Assert(shares.scale(migration, 64), chk.Equals, 64)
+
+	// a normal job gets four times the share of a low one
+	restoreChanged := make(chan struct{}, 1)
+	shares.join(restore, common.EJobPriority.Normal(), restoreChanged)
+	c.Assert(shares.share(migration), chk.Equals, 0.2)
+	c.Assert(shares.share(restore), chk.Equals, 0.8)
+	c.Assert(shares.scale(migration, 64), chk.Equals, 13)
+	c.Assert(shares.scale(restore, 64), chk.Equals, 51)
+
+	// no job is left without a worker, unless there are none
+	c.Assert(shares.scale(migration, 2), chk.Equals, 1)
+	c.Assert(shares.scale(migration, 0), chk.Equals, 0)
+
+	// the others divide the share of a job that leaves
+	shares.leave(restore, restoreChanged)
+	c.Assert(shares.share(migration), chk.Equals, float64(1))
+}
+
+func (s *jobSharesSuite) TestJobSharesNotifyChanges(c *chk.C) {
+	shares := NewJobShares()
+	first, second := common.NewJobID(), common.NewJobID()
+	firstChanged, secondChanged := make(chan struct{}, 1), make(chan struct{}, 1)
+
+	shares.join(first, common.EJobPriority.Normal(), firstChanged)
+	<-firstChanged
+	shares.join(second, common.EJobPriority.High(), secondChanged)
+	shares.leave(second, secondChanged)
+
+	// notifications don't pile up, and a job that left isn't notified anymore
+	c.Assert(len(firstChanged), chk.Equals, 1)
+	c.Assert(len(secondChanged), chk.Equals, 1)
+	<-secondChanged
+	shares.leave(first, firstChanged)
+	c.Assert(len(secondChanged), chk.Equals, 0)
+}
+
+func (s *jobSharesSuite) TestJobSharesSurviveResume(c *chk.C) {
+	shares := NewJobShares()
+	job, other := common.NewJobID(), common.NewJobID()
+	shares.join(other, common.EJobPriority.Normal(), make(chan struct{}, 1))
+
+	// the job is paused, and resumed by a new manager before the deferred cleanup of the old one runs
+	oldChanged, newChanged := make(chan struct{}, 1), make(chan struct{}, 1)
+	shares.join(job, common.EJobPriority.Normal(), oldChanged)
+	shares.join(job, common.EJobPriority.High(), newChanged)
+	c.Assert(shares.share(job), chk.Equals, 0.8)
+	c.Assert(len(newChanged), chk.Equals, 1)
+
+	// the cleanup of the old manager leaves the share of the new one alone
+	shares.leave(job, oldChanged)
+	c.Assert(shares.share(job), chk.Equals, 0.8)
+	c.Assert(shares.share(other), chk.Equals, 0.2)
+
+	// until the new manager is done with the job
+	shares.leave(job, newChanged)
+	c.Assert(shares.share(other), chk.Equals, float64(1))
+}
+
+func (s *jobSharesSuite) TestJobSharesTakeTurns(c *chk.C) {
+	shares := NewJobShares()
+	ahead, behind := common.NewJobID(), common.NewJobID()
+	shares.join(ahead, common.EJobPriority.Normal(), make(chan struct{}, 1))
+	shares.join(behind, common.EJobPriority.Normal(), make(chan struct{}, 1))
+	const slack = 100
+
+	// a job that got ahead still has bandwidth while nobody else wants it
+	c.Assert(shares.awaitTurn(context.Background(), ahead, slack), chk.IsNil)
+	shares.endTurn(ahead, 1000)
+
+	// but it waits while a job that is behind wants bandwidth too
+	c.Assert(shares.awaitTurn(context.Background(), behind, slack), chk.IsNil)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*fairShareWaitDuration)
+	defer cancel()
+	c.Assert(shares.awaitTurn(ctx, ahead, slack), chk.Equals, context.DeadlineExceeded)
+
+	// until that job catches up
+	shares.endTurn(behind, 1000)
+	c.Assert(shares.awaitTurn(context.Background(), behind, slack), chk.IsNil)
+	c.Assert(shares.awaitTurn(context.Background(), ahead, slack), chk.IsNil)
+	shares.endTurn(ahead, 0)
+	shares.endTurn(behind, 0)
+
+	// a job that joins late can't claim the bandwidth of the time it wasn't running
+	late := common.NewJobID()
+	shares.join(late, common.EJobPriority.Normal(), make(chan struct{}, 1))
+	c.Assert(shares.awaitTurn(context.Background(), late, slack), chk.IsNil)
+	c.Assert(shares.awaitTurn(context.Background(), ahead, slack), chk.IsNil)
+	shares.endTurn(ahead, 0)
+	shares.endTurn(late, 0)
+}
+
+func (s *jobSharesSuite) TestFairPacerGivesTurnsWhenCapped(c *chk.C) {
+	shares := NewJobShares()
+	ahead, behind := common.NewJobID(), common.NewJobID()
+	shares.join(ahead, common.EJobPriority.Normal(), make(chan struct{}, 1))
+	shares.join(behind, common.EJobPriority.Normal(), make(chan struct{}, 1))
+	shares.jobs[ahead].served = 1000 * 1000
+	shares.jobs[behind].waiting = 1 // as if it were waiting for the pacer
+
+	uncapped := NewTokenBucketPacer(0, 0)
+	defer uncapped.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*fairShareWaitDuration)
+	defer cancel()
+	c.Assert(newFairPacer(uncapped, shares, ahead).RequestTrafficAllocation(ctx, 1000), chk.IsNil)
+
+	capped := NewTokenBucketPacer(1000, 0)
+	defer capped.Close()
+	start := time.Now()
+	c.Assert(newFairPacer(capped, shares, ahead).RequestTrafficAllocation(ctx, 1), chk.Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) >= 2*fairShareWaitDuration, chk.Equals, true)
+	c.Assert(shares.jobs[ahead].waiting, chk.Equals, 0)
+}
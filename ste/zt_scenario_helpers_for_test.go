@@ -0,0 +1,383 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	minio "github.com/minio/minio-go"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/v10/common"
+)
+
+// testTransfer is a transfer manager for running a downloader or a sender over one transfer, without a job behind it.
+// The test runs the chunks itself, one after the other. As in a real transfer, a failure cancels the rest of them.
+type testTransfer struct {
+	IJobPartTransferMgr
+	ctx          context.Context
+	cancel       context.CancelFunc
+	fromTo       common.FromTo
+	info         TransferInfo
+	lmt          time.Time
+	restarted    bool
+	srcPipeline  pipeline.Pipeline
+	cacheLimiter common.CacheLimiter
+	md5Option    common.HashValidationOption
+	failures     []error
+}
+
+func newTestTransfer(fromTo common.FromTo, info TransferInfo) *testTransfer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &testTransfer{ctx: ctx, cancel: cancel, fromTo: fromTo, info: info,
+		cacheLimiter: common.NewCacheLimiter(1024 * 1024 * 1024), md5Option: common.EHashValidationOption.NoCheck()}
+}
+
+func (t *testTransfer) Info() TransferInfo                                     { return t.info }
+func (t *testTransfer) FromTo() common.FromTo                                  { return t.fromTo }
+func (t *testTransfer) Context() context.Context                               { return t.ctx }
+func (t *testTransfer) LastModifiedTime() time.Time                            { return t.lmt }
+func (t *testTransfer) RestartedTransfer() bool                                { return t.restarted }
+func (t *testTransfer) SourceProviderPipeline() pipeline.Pipeline              { return t.srcPipeline }
+func (t *testTransfer) CacheLimiter() common.CacheLimiter                      { return t.cacheLimiter }
+func (t *testTransfer) IsSourceEncrypted() bool                                { return false }
+func (t *testTransfer) MD5ValidationOption() common.HashValidationOption       { return t.md5Option }
+func (t *testTransfer) WasCanceled() bool                                      { return t.ctx.Err() != nil }
+func (t *testTransfer) IsLive() bool                                           { return len(t.failures) == 0 }
+func (t *testTransfer) IsDeadInflight() bool                                   { return len(t.failures) != 0 }
+func (t *testTransfer) OccupyAConnection()                                     {}
+func (t *testTransfer) ReleaseAConnection()                                    {}
+func (t *testTransfer) SetDestinationIsModified()                              {}
+func (t *testTransfer) LogChunkStatus(common.ChunkID, common.WaitReason)       {}
+func (t *testTransfer) ReportChunkDone(common.ChunkID) (bool, uint32)          { return false, 0 }
+func (t *testTransfer) LogAtLevelForCurrentTransfer(pipeline.LogLevel, string) {}
+func (t *testTransfer) ShouldLog(pipeline.LogLevel) bool                       { return false }
+func (t *testTransfer) Log(pipeline.LogLevel, string)                          {}
+func (t *testTransfer) Panic(err error)                                        { panic(err) }
+func (t *testTransfer) FailActiveDownload(where string, err error)             { t.fail(err) }
+func (t *testTransfer) FailActiveSend(where string, err error)                 { t.fail(err) }
+
+func (t *testTransfer) fail(err error) {
+	t.failures = append(t.failures, err)
+	t.cancel()
+}
+
+// runDownload runs the downloader over the transfer in chunks of chunkSize, writing to dstFile as remoteToLocal does.
+// It returns the error of flushing what was written, which is the context's when the transfer failed.
+// As in the epilogue of remoteToLocal, a transfer that is still live then has its MD5 checked against the source's.
+func runDownload(c *chk.C, t *testTransfer, dl downloader, dstFile *os.File, chunkSize int64) error {
+	numChunks := uint32((t.info.SourceSize + chunkSize - 1) / chunkSize)
+	writer := common.NewChunkedFileWriter(t.ctx, common.NewMultiSizeSlicePool(chunkSize), t.cacheLimiter,
+		common.NewChunkStatusLogger(common.NewJobID(), common.NewNullCpuMonitor(), c.MkDir(), false), dstFile, numChunks, MaxRetryPerDownloadBody,
+		t.md5Option, len(t.info.SrcHTTPHeaders.ContentMD5) > 0)
+
+	dl.Prologue(t, t.srcPipeline)
+	for offset := int64(0); offset < t.info.SourceSize; offset += chunkSize {
+		length := chunkSize
+		if offset+length > t.info.SourceSize {
+			length = t.info.SourceSize - offset
+		}
+		id := common.NewChunkID(t.info.Destination, offset, length)
+		dl.GenerateDownloadFunc(t, t.srcPipeline, writer, id, length, NewNullAutoPacer())(0)
+	}
+
+	md5OfFileAsWritten, err := writer.Flush(t.ctx)
+	dl.Epilogue()
+	if t.IsLive() {
+		comparison := md5Comparer{expected: t.info.SrcHTTPHeaders.ContentMD5, actualAsSaved: md5OfFileAsWritten, validationOption: t.md5Option, logger: t}
+		if err := comparison.Check(); err != nil {
+			t.FailActiveDownload("Checking MD5 hash", err)
+		}
+	}
+	return err
+}
+
+// runCopy runs the copier over the transfer, one chunk after another, as anyToRemote does.
+// Like there, the chunks are scheduled even when the prologue failed, and they are what skip the work.
+func runCopy(t *testTransfer, s s2sCopier) {
+	s.Prologue(common.PrologueState{})
+	chunkSize, size := s.ChunkSize(), t.info.SourceSize
+	for blockIndex := int32(0); blockIndex < int32(s.NumChunks()); blockIndex++ {
+		offset := int64(blockIndex) * chunkSize
+		length := chunkSize
+		if offset+length > size {
+			length = size - offset
+		}
+		id := common.NewChunkID(t.info.Source, offset, length)
+		s.GenerateCopyFunc(id, blockIndex, length, s.NumChunks() == 1)(0)
+	}
+	s.Epilogue()
+	s.Cleanup()
+}
+
+// fakeS3AccessKey is the only access key that fakeS3 accepts
+const fakeS3AccessKey = "AKIAFAKES3"
+
+// fakeS3 is a stand-in for the part of the S3 API that AzCopy uses, served over plain HTTP with path-style URLs.
+// It only checks the access key that requests are signed with, not the signature.
+type fakeS3 struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	objects      map[string][]byte      // by bucket/key
+	headers      map[string]http.Header // the request headers each object was created with
+	uploads      map[string]*fakeS3Upload
+	nextUploadID int
+	rejectPart   int // a part number whose upload is rejected, if not zero
+	partsPut     int
+	ranges       []string // of the objects read
+}
+
+type fakeS3Upload struct {
+	key     string
+	headers http.Header
+	parts   map[int][]byte
+}
+
+// newFakeS3 starts a fake S3 service, and nominates it as the S3 custom endpoint until the returned func is called.
+// AzCopy signs its requests with the given access key meanwhile.
+func newFakeS3(c *chk.C, accessKey string) (*fakeS3, func()) {
+	s := &fakeS3{objects: map[string][]byte{}, headers: map[string]http.Header{}, uploads: map[string]*fakeS3Upload{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	c.Assert(common.SetS3CustomEndpoint(s.URL), chk.IsNil)
+
+	env := map[string]string{"AWS_ACCESS_KEY_ID": accessKey, "AWS_SECRET_ACCESS_KEY": "secret"}
+	previous := map[string]string{}
+	for k, v := range env {
+		previous[k] = os.Getenv(k)
+		c.Assert(os.Setenv(k, v), chk.IsNil)
+	}
+
+	return s, func() {
+		for k, v := range previous {
+			_ = os.Setenv(k, v)
+		}
+		_ = common.SetS3CustomEndpoint("")
+		s.Close()
+	}
+}
+
+func (s *fakeS3) objectURL(key string) string {
+	return s.URL + "/bucket/" + key
+}
+
+func (s *fakeS3) putObject(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects["bucket/"+key] = data
+}
+
+func (s *fakeS3) object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects["bucket/"+key]
+	return data, ok
+}
+
+func (s *fakeS3) deleteObject(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, "bucket/"+key)
+}
+
+func fakeS3ETag(data []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(data))
+}
+
+// readS3Body reads the payload of a request, which minio streams in signed chunks over plain HTTP
+func readS3Body(r *http.Request) []byte {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, _ := io.ReadAll(r.Body)
+		return data
+	}
+
+	var data []byte
+	body := bufio.NewReader(r.Body)
+	for {
+		header, err := body.ReadString('\n')
+		if err != nil {
+			return data
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(header), ";", 2)[0], 16, 64)
+		if err != nil || size == 0 {
+			return data
+		}
+		chunk := make([]byte, size+2) // followed by CRLF
+		if _, err = io.ReadFull(body, chunk); err != nil {
+			return data
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func writeS3XML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func (s *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential="+fakeS3AccessKey+"/") {
+		writeS3Error(w, http.StatusForbidden, "InvalidAccessKeyId")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		bucket, key = path[:i], path[i+1:]
+	}
+	query := r.URL.Query()
+	_, isUploads := query["uploads"]
+
+	switch {
+	case key == "" && query["location"] != nil:
+		writeS3XML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+	case key == "" && isUploads:
+		result := minio.ListMultipartUploadsResult{Bucket: bucket}
+		for id, u := range s.uploads {
+			if strings.HasPrefix(u.key, query.Get("prefix")) {
+				result.Uploads = append(result.Uploads, minio.ObjectMultipartInfo{Key: u.key, UploadID: id})
+			}
+		}
+		writeS3XML(w, result)
+	case r.Method == http.MethodPost && isUploads:
+		s.nextUploadID++
+		id := fmt.Sprintf("upload-%d", s.nextUploadID)
+		s.uploads[id] = &fakeS3Upload{key: key, headers: r.Header.Clone(), parts: map[int][]byte{}}
+		_, _ = fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case query.Get("uploadId") != "":
+		s.serveUpload(w, r, bucket, key, query)
+	case r.Method == http.MethodPut:
+		data := readS3Body(r)
+		s.objects[path] = data
+		s.headers[path] = r.Header.Clone()
+		w.Header().Set("ETag", fakeS3ETag(data))
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		data, ok := s.objects[path]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			}
+			return
+		}
+		w.Header().Set("ETag", fakeS3ETag(data))
+		w.Header().Set("Last-Modified", time.Unix(1600000000, 0).UTC().Format(http.TimeFormat))
+		start, end := int64(0), int64(len(data))-1
+		if rng := r.Header.Get("Range"); rng != "" {
+			s.ranges = append(s.ranges, rng)
+			_, _ = fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data[start : end+1])
+		}
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (s *fakeS3) serveUpload(w http.ResponseWriter, r *http.Request, bucket, key string, query url.Values) {
+	id := query.Get("uploadId")
+	upload, ok := s.uploads[id]
+	if !ok || upload.key != key {
+		writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if partNumber == s.rejectPart {
+			writeS3Error(w, http.StatusBadRequest, "InvalidDigest")
+			return
+		}
+		data := readS3Body(r)
+		upload.parts[partNumber] = data
+		s.partsPut++
+		w.Header().Set("ETag", fakeS3ETag(data))
+	case http.MethodGet:
+		result := minio.ListObjectPartsResult{Bucket: bucket, Key: key, UploadID: id}
+		for n := 1; n <= len(upload.parts); n++ {
+			if data, ok := upload.parts[n]; ok {
+				result.ObjectParts = append(result.ObjectParts, minio.ObjectPart{PartNumber: n, ETag: fakeS3ETag(data), Size: int64(len(data))})
+			}
+		}
+		writeS3XML(w, result)
+	case http.MethodPost:
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&complete); err != nil {
+			writeS3Error(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var data []byte
+		for i, p := range complete.Parts {
+			part, ok := upload.parts[p.PartNumber]
+			if p.PartNumber != i+1 || !ok || strings.Trim(fakeS3ETag(part), `"`) != strings.Trim(p.ETag, `"`) {
+				writeS3Error(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			data = append(data, part...)
+		}
+		s.objects[bucket+"/"+key] = data
+		s.headers[bucket+"/"+key] = upload.headers
+		delete(s.uploads, id)
+		_, _ = fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, bucket, key, fakeS3ETag(data))
+	case http.MethodDelete:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}